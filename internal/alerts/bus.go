@@ -0,0 +1,231 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// Event types published on the bus
+const (
+	EventAlert      = "alert"
+	EventRepeat     = "repeat" // an existing alert occurred again
+	EventTransition = "transition"
+)
+
+// ErrTooManySubscribers is returned when the cap on stream subscribers is
+// reached
+var ErrTooManySubscribers = errors.New("too many alert stream subscribers")
+
+// Event is a new alert or a change of an existing one. IDs increase
+// monotonically for the lifetime of the bus.
+type Event struct {
+	ID         uint64                  `json:"id"`
+	Type       string                  `json:"type"`
+	Alert      models.Alert            `json:"alert"`
+	Transition *models.AlertTransition `json:"transition,omitempty"`
+}
+
+// Bus fans alert events out to subscribers and keeps the most recent ones so
+// reconnecting clients can catch up
+type Bus struct {
+	seq            uint64
+	recent         []Event
+	size           int
+	subscribers    map[*Subscription]struct{}
+	streams        int // subscribers counted against maxSubscribers
+	maxSubscribers int
+	mu             sync.Mutex
+}
+
+// Subscription receives the events published after it was created. C is
+// closed when the subscriber falls too far behind or unsubscribes.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	bus    *Bus
+	stream bool
+}
+
+// NewBus keeps the last size events for replay and allows at most
+// maxSubscribers concurrent stream subscriptions. Internal followers are not
+// counted.
+func NewBus(size, maxSubscribers int) *Bus {
+	return &Bus{
+		size:           size,
+		subscribers:    make(map[*Subscription]struct{}),
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// Publish assigns the next ID to an event and delivers it to every subscriber
+func (b *Bus) Publish(typ string, a models.Alert, t *models.AlertTransition) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	ev := Event{ID: b.seq, Type: typ, Alert: a, Transition: t}
+	b.recent = append(b.recent, ev)
+	if len(b.recent) > b.size {
+		b.recent = append([]Event(nil), b.recent[len(b.recent)-b.size:]...)
+	}
+	for s := range b.subscribers {
+		select {
+		case s.ch <- ev:
+		default:
+			// The client resumes from its Last-Event-ID after reconnecting.
+			b.remove(s)
+		}
+	}
+	return ev
+}
+
+// Subscribe registers a stream subscriber, such as an HTTP client, and
+// returns the retained events after lastID. An ID from before a restart
+// replays everything retained.
+func (b *Bus) Subscribe(lastID uint64) (*Subscription, []Event, error) {
+	return b.subscribe(lastID, true)
+}
+
+// subscribe registers a subscriber; only streams count against the cap, so
+// clients cannot crowd out internal followers such as the notifier
+func (b *Bus) subscribe(lastID uint64, stream bool) (*Subscription, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if stream && b.streams >= b.maxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+	if lastID > b.seq {
+		lastID = 0
+	}
+	var missed []Event
+	for _, ev := range b.recent {
+		if ev.ID > lastID {
+			missed = append(missed, ev)
+		}
+	}
+	ch := make(chan Event, 64)
+	s := &Subscription{C: ch, ch: ch, bus: b, stream: stream}
+	b.subscribers[s] = struct{}{}
+	if stream {
+		b.streams++
+	}
+	return s, missed, nil
+}
+
+// remove drops a subscriber and closes its channel. Callers hold b.mu.
+func (b *Bus) remove(s *Subscription) {
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+	delete(b.subscribers, s)
+	if s.stream {
+		b.streams--
+	}
+	close(s.ch)
+}
+
+// LastID returns the ID of the latest published event
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Unsubscribe removes the subscription and closes its channel
+func (s *Subscription) Unsubscribe() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// publishingStorage publishes every stored alert and transition on a bus.
+// Alerts matching an active silence are stored as suppressed and are kept off
+// the bus, transitions included, until the silence ends or is deleted.
+type publishingStorage struct {
+	storage.Storage
+	bus *Bus
+}
+
+// WithBus wraps a storage so that alerts added or transitioned through it are
+// published on the bus
+func WithBus(s storage.Storage, bus *Bus) storage.Storage {
+	return &publishingStorage{Storage: s, bus: bus}
+}
+
+func (s *publishingStorage) AddAlert(a models.Alert) error {
+	_, _, err := s.UpsertAlert(a)
+	return err
+}
+
+func (s *publishingStorage) UpsertAlert(a models.Alert) (models.Alert, bool, error) {
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now()
+	}
+	silences, err := storage.All(context.Background(), s.Storage.GetSilences, storage.ListOptions{})
+	if err != nil {
+		log.Printf("Alert %s not checked against all silences: %v", a.ID, err)
+	}
+	for _, sl := range silences {
+		if sl.Matches(a) {
+			a.Status = models.AlertSuppressed
+			a.SilenceID = sl.ID
+			break
+		}
+	}
+	a, created, err := s.Storage.UpsertAlert(a)
+	if err != nil || a.SilenceID != "" {
+		return a, created, err
+	}
+	if created {
+		s.bus.Publish(EventAlert, a, nil)
+	} else {
+		s.bus.Publish(EventRepeat, a, nil)
+	}
+	return a, created, nil
+}
+
+func (s *publishingStorage) TransitionAlert(id string, t models.AlertTransition) (models.Alert, error) {
+	a, err := s.Storage.TransitionAlert(id, t)
+	if err != nil {
+		return a, err
+	}
+	last := &a.History[len(a.History)-1]
+	if s.silenced(a, last.At) {
+		return a, nil
+	}
+	s.bus.Publish(EventTransition, a, last)
+	return a, nil
+}
+
+// silenced reports whether the silence that suppressed an alert when it was
+// raised is still active at t. Once it expires or is deleted, the alert's
+// transitions, such as reopening it, are published again.
+func (s *publishingStorage) silenced(a models.Alert, t time.Time) bool {
+	if a.SilenceID == "" {
+		return false
+	}
+	sl, err := s.Storage.GetSilence(a.SilenceID)
+	return err == nil && sl.State(t) == models.SilenceActive
+}
+
+// Filter selects alerts by cluster, severity and status. Empty sets match
+// everything.
+type Filter struct {
+	Clusters   map[string]bool
+	Severities map[string]bool
+	Statuses   map[string]bool
+}
+
+// Match reports whether an alert passes the filter
+func (f Filter) Match(a models.Alert) bool {
+	return matches(f.Clusters, a.ClusterID) && matches(f.Severities, a.Severity) && matches(f.Statuses, a.Status)
+}
+
+func matches(set map[string]bool, value string) bool {
+	return len(set) == 0 || set[value]
+}
@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+func TestFollowersDoNotCountAgainstCap(t *testing.T) {
+	bus := NewBus(10, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	followed := make(chan Event, 10)
+	for range 3 {
+		go Follow(ctx, bus, func(ev Event) { followed <- ev })
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		bus.mu.Lock()
+		n := len(bus.subscribers)
+		bus.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of 3 followers subscribed", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	sub, _, err := bus.Subscribe(0)
+	if err != nil {
+		t.Fatalf("stream subscription next to followers: %v", err)
+	}
+	if _, _, err := bus.Subscribe(0); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("second stream subscription = %v, want %v", err, ErrTooManySubscribers)
+	}
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	sub, _, err = bus.Subscribe(0)
+	if err != nil {
+		t.Fatalf("stream subscription after unsubscribing: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	bus.Publish(EventAlert, models.Alert{ID: "a1"}, nil)
+	for range 3 {
+		select {
+		case ev := <-followed:
+			if ev.Alert.ID != "a1" {
+				t.Errorf("followed %+v", ev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("event not handed to every follower")
+		}
+	}
+	if ev := <-sub.C; ev.Alert.ID != "a1" {
+		t.Errorf("stream got %+v", ev)
+	}
+}
+
+func TestSilencedAlertTransitions(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mem := storage.NewMemoryStorage()
+	for _, sl := range []models.Silence{
+		{ID: "s1", Namespace: "web", StartsAt: start, EndsAt: start.Add(time.Hour)},
+		{ID: "s2", Namespace: "api", StartsAt: start, EndsAt: start.Add(24 * time.Hour)},
+	} {
+		if err := mem.AddSilence(sl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bus := NewBus(10, 1)
+	store := WithBus(mem, bus)
+	for id, ns := range map[string]string{"a1": "web", "a2": "db", "a3": "api"} {
+		a, _, err := store.UpsertAlert(models.Alert{ID: id, ClusterID: "c1", Namespace: ns, Severity: models.SeverityHigh, Message: "m", Timestamp: start.Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if silenced := a.Status == models.AlertSuppressed; silenced != (ns != "db") {
+			t.Fatalf("alert %s = %+v", id, a)
+		}
+	}
+
+	steps := []struct {
+		alert string
+		to    string
+		at    time.Time
+		want  bool
+	}{
+		{"a1", models.AlertOpen, start.Add(2 * time.Minute), false},         // reopened while the silence is active
+		{"a1", models.AlertAcknowledged, start.Add(3 * time.Minute), false}, // still silenced
+		{"a2", models.AlertSuppressed, start.Add(4 * time.Minute), true},    // suppressed by hand, not by a silence
+		{"a1", models.AlertResolved, start.Add(2 * time.Hour), true},        // the silence has expired
+		{"a1", models.AlertOpen, start.Add(3 * time.Hour), true},            // reopened after it
+		{"a3", models.AlertOpen, start.Add(3 * time.Hour), true},            // its silence is deleted below
+	}
+	for i, step := range steps {
+		if i == len(steps)-1 {
+			if err := mem.DeleteSilence("s2"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		before := bus.LastID()
+		a, err := store.TransitionAlert(step.alert, models.AlertTransition{To: step.to, By: "u1", At: step.at})
+		if err != nil {
+			t.Fatalf("%s to %s: %v", step.alert, step.to, err)
+		}
+		if published := bus.LastID() > before; published != step.want {
+			t.Errorf("%s to %s at %s: published = %v, want %v", step.alert, step.to, step.at.Format(time.Kitchen), published, step.want)
+			continue
+		}
+		if step.want {
+			sub, events, _ := bus.subscribe(before, false)
+			sub.Unsubscribe()
+			if ev := events[0]; ev.Type != EventTransition || ev.Alert.ID != a.ID || ev.Transition.To != step.to {
+				t.Errorf("published %+v", ev)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Follow hands every event published on the bus to handle until the context
+// is cancelled. A dropped subscription resumes from the last handled event.
+// Followers do not count against the bus's cap on stream subscribers.
+func Follow(ctx context.Context, bus *Bus, handle func(Event)) {
+	last := bus.LastID()
+	for {
+		sub, missed, err := bus.subscribe(last, false)
+		if err != nil {
+			log.Printf("Could not subscribe to alerts: %v", err)
+		} else {
+			for _, ev := range missed {
+				handle(ev)
+				last = ev.ID
+			}
+			if !drain(ctx, sub, func(ev Event) {
+				handle(ev)
+				last = ev.ID
+			}) {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// drain handles events until the subscription is dropped, or returns false
+// once the context is cancelled
+func drain(ctx context.Context, sub *Subscription, handle func(Event)) bool {
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case ev, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			handle(ev)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Image check identifiers
+const (
+	CheckLatestTag       = "latest-tag"
+	CheckUntagged        = "untagged"
+	CheckNotPinned       = "not-pinned"
+	CheckRegistryAllowed = "registry-not-allowed"
+	CheckPullPolicy      = "pull-policy"
+)
+
+// ImageRef is a parsed container image reference
+type ImageRef struct {
+	Registry   string // e.g. docker.io
+	Repository string // e.g. library/nginx
+	Tag        string
+	Digest     string
+}
+
+// Name returns registry/repository without tag or digest
+func (r ImageRef) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// ParseImage splits an image reference, applying Docker Hub defaults for
+// references without a registry
+func ParseImage(image string) ImageRef {
+	var ref ImageRef
+	if i := strings.Index(image, "@"); i >= 0 {
+		ref.Digest = image[i+1:]
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		ref.Tag = image[i+1:]
+		image = image[:i]
+	}
+
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = "docker.io"
+		ref.Repository = image
+	}
+	if ref.Registry == "docker.io" && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref
+}
+
+// RegistryAllowed reports whether an image comes from one of the allowed
+// registries or repository prefixes. An empty allowlist allows everything.
+func RegistryAllowed(ref ImageRef, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	name := ref.Name()
+	for _, a := range allowed {
+		a = strings.TrimSuffix(a, "/")
+		if name == a || strings.HasPrefix(name, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckImages inspects every container image of the pods. Registry checks use
+// the allowlist of the policy covering each pod's namespace.
+func CheckImages(clusterID string, pods []corev1.Pod, policies []models.Policy) []models.ImageFinding {
+	now := time.Now()
+	var findings []models.ImageFinding
+	for _, pod := range pods {
+		var containers []corev1.Container
+		containers = append(containers, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, c := range containers {
+			add := func(check, severity, policyID, format string, args ...interface{}) {
+				findings = append(findings, models.ImageFinding{
+					ClusterID:  clusterID,
+					PolicyID:   policyID,
+					Namespace:  pod.Namespace,
+					Pod:        pod.Name,
+					Container:  c.Name,
+					Image:      c.Image,
+					Check:      check,
+					Severity:   severity,
+					Message:    fmt.Sprintf(format, args...),
+					DetectedAt: now,
+				})
+			}
+
+			ref := ParseImage(c.Image)
+			mutable := false
+			switch {
+			case ref.Digest != "":
+			case ref.Tag == "":
+				mutable = true
+				add(CheckUntagged, models.SeverityMedium, "", "image %s has no tag and resolves to latest", c.Image)
+			case ref.Tag == "latest":
+				mutable = true
+				add(CheckLatestTag, models.SeverityMedium, "", "image %s uses the latest tag", c.Image)
+			}
+			if ref.Digest == "" {
+				add(CheckNotPinned, models.SeverityLow, "", "image %s is not pinned by digest", c.Image)
+			}
+			if mutable && c.ImagePullPolicy != "" && c.ImagePullPolicy != corev1.PullAlways {
+				add(CheckPullPolicy, models.SeverityLow, "", "mutable image %s uses imagePullPolicy %s instead of Always", c.Image, c.ImagePullPolicy)
+			}
+			for _, p := range policies {
+				if p.Namespace != "" && p.Namespace != pod.Namespace {
+					continue
+				}
+				if !RegistryAllowed(ref, p.AllowedRegistries) {
+					add(CheckRegistryAllowed, models.SeverityHigh, p.ID, "image %s is not from a registry allowed by policy %q", c.Image, p.Name)
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// ImageFindingKey identifies a finding across scans
+func ImageFindingKey(f models.ImageFinding) string {
+	return strings.Join([]string{f.Namespace, f.Pod, f.Container, f.Image, f.Check, f.PolicyID}, "/")
+}
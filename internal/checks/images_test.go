@@ -0,0 +1,152 @@
+package checks
+
+import (
+	"slices"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const digest = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestParseImage(t *testing.T) {
+	for _, tc := range []struct {
+		image string
+		want  ImageRef
+	}{
+		{"nginx", ImageRef{"docker.io", "library/nginx", "", ""}},
+		{"nginx:1.27", ImageRef{"docker.io", "library/nginx", "1.27", ""}},
+		{"bitnami/redis:7", ImageRef{"docker.io", "bitnami/redis", "7", ""}},
+		{"ghcr.io/acme/api:v2", ImageRef{"ghcr.io", "acme/api", "v2", ""}},
+		{"registry:5000/team/app", ImageRef{"registry:5000", "team/app", "", ""}},
+		{"localhost/app:dev", ImageRef{"localhost", "app", "dev", ""}},
+		{"nginx@" + digest, ImageRef{"docker.io", "library/nginx", "", digest}},
+		{"quay.io/acme/api:v2@" + digest, ImageRef{"quay.io", "acme/api", "v2", digest}},
+	} {
+		if got := ParseImage(tc.image); got != tc.want {
+			t.Errorf("ParseImage(%q) = %+v, want %+v", tc.image, got, tc.want)
+		}
+	}
+}
+
+func TestRegistryAllowed(t *testing.T) {
+	allowed := []string{"ghcr.io/acme/", "docker.io/library"}
+	for _, tc := range []struct {
+		image string
+		want  bool
+	}{
+		{"ghcr.io/acme/api:v2", true},
+		{"ghcr.io/acme/tools/cli:1", true},
+		{"ghcr.io/acmecorp/api:v2", false},
+		{"nginx:1.27", true},
+		{"bitnami/redis:7", false},
+		{"quay.io/acme/api:v2", false},
+	} {
+		if got := RegistryAllowed(ParseImage(tc.image), allowed); got != tc.want {
+			t.Errorf("RegistryAllowed(%q) = %v, want %v", tc.image, got, tc.want)
+		}
+	}
+	if !RegistryAllowed(ParseImage("quay.io/any/thing"), nil) {
+		t.Error("an empty allowlist rejected an image")
+	}
+}
+
+func TestCheckImages(t *testing.T) {
+	pod := func(namespace string, containers ...corev1.Container) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "app"},
+			Spec:       corev1.PodSpec{Containers: containers},
+		}
+	}
+	policies := []models.Policy{
+		{ID: "p-all", Name: "internal only", AllowedRegistries: []string{"ghcr.io/acme"}},
+		{ID: "p-web", Name: "web", Namespace: "web", AllowedRegistries: []string{"ghcr.io/acme", "docker.io/library"}},
+		{ID: "p-any", Name: "no allowlist"},
+	}
+
+	for _, tc := range []struct {
+		name string
+		pod  corev1.Pod
+		want []string // check/policy
+	}{
+		{
+			name: "pinned and allowed",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "ghcr.io/acme/api@" + digest}),
+		},
+		{
+			name: "tag without digest",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "ghcr.io/acme/api:v2"}),
+			want: []string{CheckNotPinned + "/"},
+		},
+		{
+			name: "latest with IfNotPresent",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "ghcr.io/acme/api:latest", ImagePullPolicy: corev1.PullIfNotPresent}),
+			want: []string{CheckLatestTag + "/", CheckNotPinned + "/", CheckPullPolicy + "/"},
+		},
+		{
+			name: "untagged with Always",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "ghcr.io/acme/api", ImagePullPolicy: corev1.PullAlways}),
+			want: []string{CheckUntagged + "/", CheckNotPinned + "/"},
+		},
+		{
+			name: "digest overrides latest",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "ghcr.io/acme/api:latest@" + digest, ImagePullPolicy: corev1.PullNever}),
+		},
+		{
+			name: "registry outside every allowlist",
+			pod:  pod("api", corev1.Container{Name: "app", Image: "quay.io/evil/miner@" + digest}),
+			want: []string{CheckRegistryAllowed + "/p-all"},
+		},
+		{
+			name: "namespace policy applies only in its namespace",
+			pod:  pod("web", corev1.Container{Name: "app", Image: "nginx@" + digest}),
+			want: []string{CheckRegistryAllowed + "/p-all"},
+		},
+		{
+			name: "init containers are checked",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "api", Name: "app"},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "init", Image: "busybox@" + digest}},
+					Containers:     []corev1.Container{{Name: "app", Image: "ghcr.io/acme/api@" + digest}},
+				},
+			},
+			want: []string{CheckRegistryAllowed + "/p-all"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range CheckImages("c1", []corev1.Pod{tc.pod}, policies) {
+				got = append(got, f.Check+"/"+f.PolicyID)
+				if f.ClusterID != "c1" || f.Namespace != tc.pod.Namespace || f.Pod != "app" || f.Message == "" || f.DetectedAt.IsZero() {
+					t.Errorf("finding = %+v", f)
+				}
+				want := map[string]string{
+					CheckLatestTag:       models.SeverityMedium,
+					CheckUntagged:        models.SeverityMedium,
+					CheckNotPinned:       models.SeverityLow,
+					CheckPullPolicy:      models.SeverityLow,
+					CheckRegistryAllowed: models.SeverityHigh,
+				}[f.Check]
+				if f.Severity != want {
+					t.Errorf("%s severity = %s, want %s", f.Check, f.Severity, want)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("findings = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImageFindingKey(t *testing.T) {
+	f := models.ImageFinding{Namespace: "web", Pod: "app", Container: "c", Image: "nginx", Check: CheckRegistryAllowed, PolicyID: "p1"}
+	other := f
+	other.PolicyID = "p2"
+	if ImageFindingKey(f) == ImageFindingKey(other) {
+		t.Error("findings of different policies share a key")
+	}
+}
@@ -0,0 +1,139 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Scanner periodically runs the posture checks against every connected
+// cluster, stores the findings and raises alerts for new ones
+type Scanner struct {
+	Storage storage.Storage
+	K8s     *k8s.ClusterManager
+}
+
+func NewScanner(store storage.Storage, mgr *k8s.ClusterManager) *Scanner {
+	return &Scanner{Storage: store, K8s: mgr}
+}
+
+// Run scans all clusters every interval until the context is cancelled
+func (s *Scanner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		clusters, err := storage.All(ctx, s.Storage.GetClusters, storage.ListOptions{})
+		if err != nil {
+			log.Printf("Scan cannot list clusters: %v", err)
+		}
+		for _, c := range clusters {
+			client, err := s.K8s.GetClient(c.ID, c.KubeConfig)
+			if err != nil {
+				log.Printf("Scan skipped for cluster %s: %v", c.ID, err)
+				continue
+			}
+			if _, err := s.ScanImages(ctx, c.ID, client); err != nil {
+				log.Printf("Image scan failed for cluster %s: %v", c.ID, err)
+			}
+			if _, err := s.AuditSecrets(ctx, c.ID, client); err != nil {
+				log.Printf("Secret audit failed for cluster %s: %v", c.ID, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScanImages checks the images of every pod of a cluster, raises one alert per
+// container with findings not seen in the previous scan and stores the result
+func (s *Scanner) ScanImages(ctx context.Context, clusterID string, client kubernetes.Interface) ([]models.ImageFinding, error) {
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	policies, err := storage.All(ctx, s.Storage.GetPolicies, storage.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	findings := CheckImages(clusterID, pods.Items, policies)
+
+	previous := make(map[string]bool)
+	for _, f := range s.Storage.GetImageFindings(clusterID) {
+		previous[ImageFindingKey(f)] = true
+	}
+
+	type container struct{ namespace, pod, name string }
+	var order []container
+	fresh := make(map[container][]models.ImageFinding)
+	for _, f := range findings {
+		if previous[ImageFindingKey(f)] {
+			continue
+		}
+		c := container{f.Namespace, f.Pod, f.Container}
+		if _, ok := fresh[c]; !ok {
+			order = append(order, c)
+		}
+		fresh[c] = append(fresh[c], f)
+	}
+	for _, c := range order {
+		// findings are only saved once alerted, so the next scan retries them
+		if err := s.Storage.AddAlert(imageAlert(clusterID, c.namespace, c.pod, c.name, fresh[c])); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Storage.SaveImageFindings(clusterID, findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// AuditSecrets runs the secret hygiene audit of a cluster and stores the result
+func (s *Scanner) AuditSecrets(ctx context.Context, clusterID string, client kubernetes.Interface) ([]models.SecretFinding, error) {
+	findings, err := k8s.AuditSecrets(ctx, client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Storage.SaveSecretFindings(clusterID, findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+func imageAlert(clusterID, namespace, pod, container string, findings []models.ImageFinding) models.Alert {
+	severity := models.SeverityInfo
+	policyID := ""
+	msgs := make([]string, len(findings))
+	for i, f := range findings {
+		msgs[i] = f.Message
+		if models.SeverityRank(f.Severity) > models.SeverityRank(severity) {
+			severity = f.Severity
+		}
+		if f.PolicyID != "" {
+			policyID = f.PolicyID
+		}
+	}
+	return models.Alert{
+		ID:        k8s.NewAlertID(),
+		ClusterID: clusterID,
+		PolicyID:  policyID,
+		Rule:      "image-posture",
+		Namespace: namespace,
+		Resource:  "Pod " + namespace + "/" + pod + " container " + container,
+		Severity:  severity,
+		Message:   fmt.Sprintf("Image posture issues in %s/%s container %s: %s", namespace, pod, container, strings.Join(msgs, "; ")),
+		Timestamp: time.Now(),
+	}
+}
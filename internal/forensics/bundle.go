@@ -0,0 +1,95 @@
+package forensics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+// NewBundle hashes the files of a capture for an alert. It returns the bundle
+// with its manifest, capture time and ID set, and the file contents keyed by
+// SHA-256. The ID covers the alert and the capture time along with the
+// manifest, so two captures of identical content, such as the Events of a pod
+// that is already gone, remain separate bundles.
+func NewBundle(alertID string, collectedAt time.Time, files map[string][]byte) (models.EvidenceBundle, map[string][]byte) {
+	blobs := make(map[string][]byte, len(files))
+	b := models.EvidenceBundle{AlertID: alertID, CollectedAt: collectedAt}
+	for path, data := range files {
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		blobs[hash] = data
+		b.Files = append(b.Files, models.EvidenceFile{Path: path, SHA256: hash, Size: len(data)})
+	}
+	sort.Slice(b.Files, func(i, j int) bool { return b.Files[i].Path < b.Files[j].Path })
+	b.ID = BundleID(b)
+	return b, blobs
+}
+
+// BundleID is the SHA-256 of a bundle's alert ID, capture time in RFC 3339
+// and manifest, each followed by a newline
+func BundleID(b models.EvidenceBundle) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", b.AlertID, b.CollectedAt.UTC().Format(time.RFC3339Nano), Manifest(b))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Manifest is the canonical JSON of a bundle's files
+func Manifest(b models.EvidenceBundle) []byte {
+	data, _ := json.Marshal(b.Files)
+	return data
+}
+
+// WriteTarGz writes a bundle as a gzipped tarball under a directory named
+// after the bundle. Besides the files it holds bundle.json with the capture
+// details, MANIFEST.json and SHA256SUMS for checking with sha256sum -c.
+func WriteTarGz(w io.Writer, b models.EvidenceBundle, blob func(sha256 string) ([]byte, error)) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	dir := "evidence-" + b.ID[:12] + "/"
+
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: dir + name, Mode: 0o644, Size: int64(len(data)), ModTime: b.CollectedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	details, _ := json.MarshalIndent(b, "", "  ")
+	if err := add("bundle.json", details); err != nil {
+		return err
+	}
+	if err := add("MANIFEST.json", Manifest(b)); err != nil {
+		return err
+	}
+	var sums []byte
+	for _, f := range b.Files {
+		sums = fmt.Appendf(sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	if err := add("SHA256SUMS", sums); err != nil {
+		return err
+	}
+	for _, f := range b.Files {
+		data, err := blob(f.SHA256)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		if err := add(f.Path, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
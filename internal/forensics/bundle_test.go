@@ -0,0 +1,175 @@
+package forensics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+var capturedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testFiles() map[string][]byte {
+	return map[string][]byte{
+		"pod.yaml":         []byte("apiVersion: v1\nkind: Pod\n"),
+		"events.yaml":      []byte("- reason: Killing\n"),
+		"logs/app.log":     []byte("2026-03-01T11:59:59Z started\n"),
+		"logs/sidecar.log": []byte("2026-03-01T11:59:59Z started\n"), // same content as app.log
+	}
+}
+
+func TestNewBundle(t *testing.T) {
+	b, blobs := NewBundle("a1", capturedAt, testFiles())
+	if b.AlertID != "a1" || !b.CollectedAt.Equal(capturedAt) {
+		t.Errorf("bundle = %+v", b)
+	}
+	var paths []string
+	for _, f := range b.Files {
+		paths = append(paths, f.Path)
+		sum := sha256.Sum256(testFiles()[f.Path])
+		if f.SHA256 != hex.EncodeToString(sum[:]) || f.Size != len(testFiles()[f.Path]) {
+			t.Errorf("manifest entry %+v does not match the file", f)
+		}
+		if !bytes.Equal(blobs[f.SHA256], testFiles()[f.Path]) {
+			t.Errorf("blob of %s = %q", f.Path, blobs[f.SHA256])
+		}
+	}
+	if got := strings.Join(paths, " "); got != "events.yaml logs/app.log logs/sidecar.log pod.yaml" {
+		t.Errorf("manifest paths = %s, want them sorted", got)
+	}
+	if len(blobs) != 3 {
+		t.Errorf("got %d blobs, want identical files stored once", len(blobs))
+	}
+
+	// the ID is reproducible from the bundle and changes with the alert,
+	// the capture time and the content
+	again, _ := NewBundle("a1", capturedAt, testFiles())
+	if again.ID != b.ID || BundleID(b) != b.ID || len(b.ID) != 64 {
+		t.Errorf("ID %s is not reproducible", b.ID)
+	}
+	changed := testFiles()
+	changed["pod.yaml"] = []byte("apiVersion: v1\nkind: Pod\nstatus: {}\n")
+	for name, other := range map[string]string{
+		"other alert":   first(NewBundle("a2", capturedAt, testFiles())).ID,
+		"later capture": first(NewBundle("a1", capturedAt.Add(time.Second), testFiles())).ID,
+		"other content": first(NewBundle("a1", capturedAt, changed)).ID,
+	} {
+		if other == b.ID {
+			t.Errorf("%s has the same ID", name)
+		}
+	}
+}
+
+func first(b models.EvidenceBundle, _ map[string][]byte) models.EvidenceBundle { return b }
+
+func TestRepeatedCapturesAreKeptApart(t *testing.T) {
+	// a pod that is already gone leaves the same Events for every capture
+	store := storage.NewMemoryStorage()
+	files := map[string][]byte{"events.yaml": []byte("- reason: Killing\n")}
+	for i := range 2 {
+		b, blobs := NewBundle("a1", capturedAt.Add(time.Duration(i)*time.Minute), files)
+		if err := store.SaveEvidence(b, blobs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundles, err := storage.All(context.Background(), store.GetEvidenceBundles, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 2 {
+		t.Errorf("stored %d bundles, want 2", len(bundles))
+	}
+}
+
+func TestWriteTarGz(t *testing.T) {
+	b, blobs := NewBundle("a1", capturedAt, testFiles())
+	b.ClusterID, b.Namespace, b.Pod = "c1", "web", "api"
+
+	var buf bytes.Buffer
+	err := WriteTarGz(&buf, b, func(sum string) ([]byte, error) {
+		data, ok := blobs[sum]
+		if !ok {
+			return nil, fmt.Errorf("no blob %s", sum)
+		}
+		return data, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	dir := "evidence-" + b.ID[:12] + "/"
+	contents := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, ok := strings.CutPrefix(hdr.Name, dir)
+		if !ok {
+			t.Errorf("%s is outside %s", hdr.Name, dir)
+		}
+		if !hdr.ModTime.Equal(capturedAt) {
+			t.Errorf("%s modified at %s, want the capture time", hdr.Name, hdr.ModTime)
+		}
+		if contents[name], err = io.ReadAll(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every manifest entry matches the file in the tarball, and the tarball
+	// holds nothing else besides the bundle's own metadata
+	var manifest []models.EvidenceFile
+	if err := json.Unmarshal(contents["MANIFEST.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != len(b.Files) || len(contents) != len(manifest)+3 {
+		t.Fatalf("manifest lists %d files, tarball holds %d entries", len(manifest), len(contents))
+	}
+	var sums strings.Builder
+	for _, f := range manifest {
+		data, ok := contents[f.Path]
+		sum := sha256.Sum256(data)
+		if !ok || hex.EncodeToString(sum[:]) != f.SHA256 || len(data) != f.Size {
+			t.Errorf("%s in the tarball does not match its manifest entry %+v", f.Path, f)
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	if got := string(contents["SHA256SUMS"]); got != sums.String() {
+		t.Errorf("SHA256SUMS = %q, want %q", got, sums.String())
+	}
+
+	var details models.EvidenceBundle
+	if err := json.Unmarshal(contents["bundle.json"], &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.ID != b.ID || details.Pod != "api" || BundleID(details) != b.ID {
+		t.Errorf("bundle.json = %+v, want ID %s reproducible from it", details, b.ID)
+	}
+}
+
+func TestWriteTarGzMissingBlob(t *testing.T) {
+	b, _ := NewBundle("a1", capturedAt, testFiles())
+	err := WriteTarGz(io.Discard, b, func(sum string) ([]byte, error) { return nil, storage.ErrNotFound })
+	if err == nil || !strings.Contains(err.Error(), "events.yaml") {
+		t.Errorf("err = %v, want the missing file named", err)
+	}
+}
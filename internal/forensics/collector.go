@@ -0,0 +1,105 @@
+package forensics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/alerts"
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// ActionCaptureEvidence is the action_taken of the report written for a capture
+const ActionCaptureEvidence = "capture-evidence"
+
+// captureTimeout bounds one capture; logs of many containers can be slow
+const captureTimeout = time.Minute
+
+// Collector captures forensic evidence for the pod implicated by an alert as
+// soon as the alert fires, before the pod is replaced or deleted. Each
+// capture is stored as a bundle of content-addressed files and written up as
+// an incident report that carries the bundle ID.
+type Collector struct {
+	Storage     storage.Storage
+	K8s         *k8s.ClusterManager
+	MinSeverity string        // alerts below this severity are not captured automatically
+	slots       chan struct{} // bounds concurrent captures
+}
+
+func NewCollector(store storage.Storage, mgr *k8s.ClusterManager, minSeverity string) *Collector {
+	return &Collector{Storage: store, K8s: mgr, MinSeverity: minSeverity, slots: make(chan struct{}, 4)}
+}
+
+// Run captures evidence for every new alert that implicates a pod until the
+// context is cancelled
+func (c *Collector) Run(ctx context.Context, bus *alerts.Bus) {
+	alerts.Follow(ctx, bus, func(ev alerts.Event) {
+		if ev.Type != alerts.EventAlert || models.SeverityRank(ev.Alert.Severity) < models.SeverityRank(c.MinSeverity) {
+			return
+		}
+		if _, _, ok := ev.Alert.Pod(); !ok {
+			return
+		}
+		select {
+		case c.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func(a models.Alert) {
+			defer func() { <-c.slots }()
+			if _, err := c.Capture(ctx, a); err != nil {
+				log.Printf("Evidence capture for alert %s failed: %v", a.ID, err)
+			}
+		}(ev.Alert)
+	})
+}
+
+// Capture collects and stores the evidence for the pod an alert implicates
+func (c *Collector) Capture(ctx context.Context, a models.Alert) (models.EvidenceBundle, error) {
+	namespace, name, ok := a.Pod()
+	if !ok {
+		return models.EvidenceBundle{}, fmt.Errorf("alert %s does not implicate a pod", a.ID)
+	}
+	cluster, err := c.Storage.GetCluster(a.ClusterID)
+	if err != nil {
+		return models.EvidenceBundle{}, err
+	}
+	client, err := c.K8s.GetClient(cluster.ID, cluster.KubeConfig)
+	if err != nil {
+		return models.EvidenceBundle{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+	files, errs, err := k8s.CollectEvidence(ctx, client, namespace, name)
+	if err != nil {
+		return models.EvidenceBundle{}, err
+	}
+
+	b, blobs := NewBundle(a.ID, time.Now(), files)
+	b.ClusterID = a.ClusterID
+	b.Namespace, b.Pod = namespace, name
+	b.Errors = errs
+	if err := c.Storage.SaveEvidence(b, blobs); err != nil {
+		return b, err
+	}
+
+	result := fmt.Sprintf("Captured %d files", len(b.Files))
+	if len(errs) > 0 {
+		result += "; missing: " + strings.Join(errs, "; ")
+	}
+	err = c.Storage.AddReport(models.IncidentReport{
+		ID:         models.NewID("rep-"),
+		AlertID:    a.ID,
+		EvidenceID: b.ID,
+		Details:    fmt.Sprintf("Forensic evidence for pod %s/%s in cluster %s", namespace, name, a.ClusterID),
+		Action:     ActionCaptureEvidence,
+		Result:     result,
+		Timestamp:  b.CollectedAt,
+	})
+	return b, err
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/response"
+
+	"github.com/gorilla/mux"
+)
+
+// Response action handlers
+func (h *ResourceHandler) GetActions(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetResponseActions(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
+
+func (h *ResourceHandler) GetAction(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Storage.GetResponseAction(mux.Vars(r)["actionId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// CreateAction requests a response action. Requested actions always wait for
+// an Administrator; only policies run actions automatically. When alert_id is
+// given the cluster defaults to the alert's.
+func (h *ResourceHandler) CreateAction(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var a models.ResponseAction
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if a.AlertID != "" {
+		alert, err := h.Storage.GetAlert(a.AlertID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if a.ClusterID == "" {
+			a.ClusterID = alert.ClusterID
+		}
+	}
+	if _, err := h.Storage.GetCluster(a.ClusterID); err != nil {
+		http.Error(w, "Unknown cluster", http.StatusBadRequest)
+		return
+	}
+	a.PolicyID, a.Auto = "", false
+	a.RequestedBy = claims.UserID
+
+	a, err := h.Responder.Request(r.Context(), a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+// ApproveAction runs a pending action and returns it with its outcome. A
+// failed execution is still a decided action, so it is returned with 200.
+func (h *ResourceHandler) ApproveAction(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["actionId"]
+	if _, err := h.Storage.GetResponseAction(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	// Run to completion even if the client goes away; a drain cut short
+	// would leave the node cordoned without a report.
+	a, err := h.Responder.Approve(context.WithoutCancel(r.Context()), id, claims.UserID)
+	if err != nil {
+		writeDecisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// RejectAction takes an optional {"reason": "..."} body
+func (h *ResourceHandler) RejectAction(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["actionId"]
+	if _, err := h.Storage.GetResponseAction(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	a, err := h.Responder.Reject(id, claims.UserID, req.Reason)
+	if err != nil {
+		writeDecisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+func writeDecisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, response.ErrNotPending) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), storageStatus(err))
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
+
+	"github.com/gorilla/mux"
+)
+
+// maxAuditBatch bounds the size of one webhook request body
+const maxAuditBatch = 10 << 20
+
+type auditResponse struct {
+	Received int `json:"received"`
+	Alerts   int `json:"alerts"`
+}
+
+// ReceiveAudit is the Kubernetes audit webhook backend of a cluster. The API
+// server authenticates with the cluster's audit token as a bearer token and
+// posts audit.k8s.io/v1 EventList batches.
+func (h *ResourceHandler) ReceiveAudit(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Storage.GetCluster(mux.Vars(r)["clusterId"])
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if c.AuditToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.AuditToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var batch kubernetes.AuditEventList
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAuditBatch)).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if batch.Kind != "EventList" || batch.APIVersion != "audit.k8s.io/v1" {
+		http.Error(w, "Expected an audit.k8s.io/v1 EventList", http.StatusBadRequest)
+		return
+	}
+
+	alerts := h.Audit.Detect(c.ID, batch.Items)
+	for _, a := range alerts {
+		// the API server retries a failed batch; repeats fold into the same alerts
+		if err := h.Storage.AddAlert(a); err != nil {
+			http.Error(w, err.Error(), storageStatus(err))
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(auditResponse{Received: len(batch.Items), Alerts: len(alerts)})
+}
+
+// RotateAuditToken issues a new audit webhook token for a cluster. The old
+// token stops working immediately. It is mounted for Administrators only, as
+// whoever holds the token can post audit events.
+func (h *ResourceHandler) RotateAuditToken(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["clusterId"]
+	token, err := newAuditToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.Storage.SetAuditToken(id, token); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"audit_token": token})
+}
+
+func newAuditToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const execBatch = `{"apiVersion": "audit.k8s.io/v1", "kind": "EventList", "items": [
+	{"stage": "ResponseComplete", "verb": "create", "user": {"username": "alice"},
+	 "objectRef": {"resource": "pods", "namespace": "web", "name": "api", "subresource": "exec"},
+	 "responseStatus": {"code": 101}},
+	{"stage": "ResponseComplete", "verb": "get", "user": {"username": "system:serviceaccount:web:api"},
+	 "objectRef": {"resource": "configmaps", "namespace": "web", "name": "settings"},
+	 "responseStatus": {"code": 200}}
+]}`
+
+func auditRouter(t *testing.T) (*mux.Router, storage.Storage) {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	if err := store.AddCluster(models.Cluster{ID: "c1", Name: "prod", AuditToken: "secret-token"}); err != nil {
+		t.Fatal(err)
+	}
+	h := &ResourceHandler{Storage: store, Audit: kubernetes.NewAuditDetector()}
+	r := mux.NewRouter()
+	r.HandleFunc("/clusters/{clusterId}/audit", h.ReceiveAudit).Methods("POST")
+	return r, store
+}
+
+func postAudit(r http.Handler, cluster, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/clusters/"+cluster+"/audit", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReceiveAudit(t *testing.T) {
+	r, store := auditRouter(t)
+
+	for _, tc := range []struct {
+		name, cluster, token, body string
+		status                     int
+	}{
+		{"unknown cluster", "c2", "secret-token", execBatch, http.StatusUnauthorized},
+		{"no token", "c1", "", execBatch, http.StatusUnauthorized},
+		{"wrong token", "c1", "secret-tokem", execBatch, http.StatusUnauthorized},
+		{"malformed body", "c1", "secret-token", `{"kind": "EventList"`, http.StatusBadRequest},
+		{"not an event list", "c1", "secret-token", `{"apiVersion": "audit.k8s.io/v1", "kind": "Event"}`, http.StatusBadRequest},
+		{"old api version", "c1", "secret-token", `{"apiVersion": "audit.k8s.io/v1beta1", "kind": "EventList"}`, http.StatusBadRequest},
+	} {
+		if rec := postAudit(r, tc.cluster, tc.token, tc.body); rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.status)
+		}
+	}
+	stored, err := storage.All(context.Background(), store.GetAlerts, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 0 {
+		t.Fatalf("rejected batches stored %d alerts", len(stored))
+	}
+
+	rec := postAudit(r, "c1", "secret-token", execBatch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp auditResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp != (auditResponse{Received: 2, Alerts: 1}) {
+		t.Errorf("response = %+v, want 2 received and 1 alert", resp)
+	}
+	stored, err = storage.All(context.Background(), store.GetAlerts, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ClusterID != "c1" || stored[0].Rule != kubernetes.RuleAuditExec || stored[0].Actor != "alice" {
+		t.Errorf("stored alerts = %+v, want alice's exec on c1", stored)
+	}
+}
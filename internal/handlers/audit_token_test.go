@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/handlers"
+	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/middleware"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+const emptyBatch = `{"apiVersion": "audit.k8s.io/v1", "kind": "EventList", "items": []}`
+
+// tokenRouter mounts the audit routes the way main does
+func tokenRouter(t *testing.T) http.Handler {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	if err := store.AddCluster(models.Cluster{ID: "c1", Name: "prod", AuditToken: "old-token"}); err != nil {
+		t.Fatal(err)
+	}
+	h := &handlers.ResourceHandler{Storage: store, Audit: kubernetes.NewAuditDetector()}
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(middleware.AuthMiddleware)
+	api.HandleFunc("/clusters/{clusterId}/audit", h.ReceiveAudit).Methods("POST")
+	auditToken := api.PathPrefix("/clusters/{clusterId}/audit/token").Subrouter()
+	auditToken.Use(middleware.RequireRole("Administrator"))
+	auditToken.HandleFunc("", h.RotateAuditToken).Methods("POST")
+	return r
+}
+
+func session(t *testing.T, role models.Role) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &handlers.Claims{
+		UserID:           "u-" + string(role),
+		Role:             role,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(handlers.JwtKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func post(r http.Handler, path, bearer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRotateAuditTokenRequiresAdministrator(t *testing.T) {
+	r := tokenRouter(t)
+	for _, tc := range []struct {
+		name, bearer string
+		status       int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"audit token", "old-token", http.StatusUnauthorized},
+		{"forged session", session(t, models.RoleAdmin) + "x", http.StatusUnauthorized},
+		{"student", session(t, models.RoleStudent), http.StatusForbidden},
+		{"security analyst", session(t, models.RoleSecurityAnalyst), http.StatusForbidden},
+	} {
+		if rec := post(r, "/api/clusters/c1/audit/token", tc.bearer, ""); rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.status)
+		}
+	}
+	// the refused rotations left the token in place
+	if rec := post(r, "/api/clusters/c1/audit", "old-token", emptyBatch); rec.Code != http.StatusOK {
+		t.Errorf("audit with the original token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestRotateAuditToken(t *testing.T) {
+	r := tokenRouter(t)
+	admin := session(t, models.RoleAdmin)
+
+	rec := post(r, "/api/clusters/c1/audit/token", admin, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	token := body["audit_token"]
+	if len(token) != 64 || token == "old-token" {
+		t.Fatalf("new token = %q", token)
+	}
+	if rec := post(r, "/api/clusters/c1/audit", "old-token", emptyBatch); rec.Code != http.StatusUnauthorized {
+		t.Errorf("old token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := post(r, "/api/clusters/c1/audit", token, emptyBatch); rec.Code != http.StatusOK {
+		t.Errorf("new token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := post(r, "/api/clusters/c2/audit/token", admin, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown cluster: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
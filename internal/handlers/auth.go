@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -20,6 +21,19 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+type claimsKey struct{}
+
+// WithClaims stores the claims of an authenticated request in its context
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of an authenticated request
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
 type AuthHandler struct {
 	Storage storage.Storage
 }
@@ -40,7 +54,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	u.CreatedAt = time.Now()
 
 	if err := h.Storage.AddUser(u); err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+		http.Error(w, err.Error(), storageStatus(err))
 		return
 	}
 
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// redact hides the credentials of a channel in responses
+func redact(c models.Channel) models.Channel {
+	if c.Secret != "" {
+		c.Secret = "********"
+	}
+	if c.Password != "" {
+		c.Password = "********"
+	}
+	return c
+}
+
+func (h *ResourceHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetChannels(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	for i, c := range page.Items {
+		page.Items[i] = redact(c)
+	}
+	writePage(w, page)
+}
+
+func (h *ResourceHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	var c models.Channel
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.CreatedAt = time.Now()
+	c.ID = "ch-" + c.CreatedAt.Format("20060102150405.000000")
+	if err := h.Storage.AddChannel(c); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(redact(c))
+}
+
+func (h *ResourceHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.DeleteChannel(mux.Vars(r)["channelId"]); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestChannel sends a test notification and reports the delivery error, if any
+func (h *ResourceHandler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Storage.GetChannel(mux.Vars(r)["channelId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if err := h.Notifier.Test(r.Context(), c); err != nil {
+		http.Error(w, "Test notification failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "sent"})
+}
+
+// GetDeadLetters lists the notifications that could not be delivered
+func (h *ResourceHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetDeadLetters(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// storageStatus returns the HTTP status for an error of the storage: 404 for
+// a missing record, 409 for a conflict, 400 for a bad list query and 500 for
+// anything else
+func storageStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrInvalidQuery):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
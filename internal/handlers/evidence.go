@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"KubernetesSecurityMonitoringSystem/internal/forensics"
+
+	"github.com/gorilla/mux"
+)
+
+// Evidence handlers
+func (h *ResourceHandler) GetEvidenceBundles(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetEvidenceBundles(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
+
+func (h *ResourceHandler) GetEvidenceBundle(w http.ResponseWriter, r *http.Request) {
+	b, err := h.Storage.GetEvidenceBundle(mux.Vars(r)["bundleId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(b)
+}
+
+// DownloadEvidence streams a bundle as tar.gz
+func (h *ResourceHandler) DownloadEvidence(w http.ResponseWriter, r *http.Request) {
+	b, err := h.Storage.GetEvidenceBundle(mux.Vars(r)["bundleId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="evidence-`+b.ID[:12]+`.tar.gz"`)
+	if err := forensics.WriteTarGz(w, b, h.Storage.GetEvidenceBlob); err != nil {
+		// Headers are already sent; the truncated archive fails to unpack
+		log.Printf("Evidence download %s failed: %v", b.ID, err)
+	}
+}
+
+// CaptureEvidence collects evidence for an alert on demand, e.g. when the
+// automatic capture was skipped for a low severity alert
+func (h *ResourceHandler) CaptureEvidence(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Storage.GetAlert(mux.Vars(r)["alertId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if _, _, ok := a.Pod(); !ok {
+		http.Error(w, "Alert does not implicate a pod", http.StatusBadRequest)
+		return
+	}
+	b, err := h.Evidence.Capture(r.Context(), a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
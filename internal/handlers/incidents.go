@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+type incidentResponse struct {
+	models.Incident
+	Alerts   []models.Alert         `json:"alerts"`
+	Timeline []models.TimelineEntry `json:"timeline"`
+}
+
+// GetIncidents lists incidents, most recently active first, optionally
+// filtered by status and owner
+func (h *ResourceHandler) GetIncidents(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetIncidents(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
+
+// GetIncident returns an incident with its alerts and its full timeline. It
+// also serves /api/tests/{testId}.
+func (h *ResourceHandler) GetIncident(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["incidentId"]
+	if id == "" {
+		id = mux.Vars(r)["testId"]
+	}
+	inc, err := h.Storage.GetIncident(id)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	resp, err := h.incidentResponse(r.Context(), inc)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// incidentResponse merges the recorded timeline with the incident's alerts,
+// the response actions taken on them and the evidence captured for them
+func (h *ResourceHandler) incidentResponse(ctx context.Context, inc models.Incident) (incidentResponse, error) {
+	alerts, err := storage.All(ctx, h.Storage.GetAlerts, storage.ListOptions{IncidentID: inc.ID})
+	if err != nil {
+		return incidentResponse{}, err
+	}
+	resp := incidentResponse{Incident: inc, Alerts: []models.Alert{}}
+	alertIDs := make(map[string]bool)
+	for _, a := range alerts {
+		alertIDs[a.ID] = true
+		resp.Alerts = append(resp.Alerts, a)
+		resp.Timeline = append(resp.Timeline, models.TimelineEntry{
+			Type:    models.TimelineAlert,
+			AlertID: a.ID,
+			Text:    fmt.Sprintf("[%s] %s", a.Severity, a.Message),
+			At:      a.FirstSeen,
+		})
+	}
+	sort.Slice(resp.Alerts, func(i, j int) bool { return resp.Alerts[i].FirstSeen.Before(resp.Alerts[j].FirstSeen) })
+
+	actions, err := storage.All(ctx, h.Storage.GetResponseActions, storage.ListOptions{})
+	if err != nil {
+		return incidentResponse{}, err
+	}
+	for _, act := range actions {
+		if !alertIDs[act.AlertID] {
+			continue
+		}
+		target := act.Name
+		if act.Namespace != "" {
+			target = act.Namespace + "/" + act.Name
+		}
+		resp.Timeline = append(resp.Timeline, models.TimelineEntry{
+			Type:     models.TimelineAction,
+			ActionID: act.ID,
+			AlertID:  act.AlertID,
+			Text:     fmt.Sprintf("%s %s %s requested", act.Type, act.Kind, target),
+			By:       act.RequestedBy,
+			At:       act.CreatedAt,
+		})
+		if act.Status != models.ActionPending {
+			text := fmt.Sprintf("%s %s %s %s", act.Type, act.Kind, target, act.Status)
+			if act.Result != "" {
+				text += ": " + act.Result
+			}
+			at := act.ExecutedAt
+			if at.IsZero() {
+				at = act.DecidedAt
+			}
+			resp.Timeline = append(resp.Timeline, models.TimelineEntry{
+				Type:     models.TimelineAction,
+				ActionID: act.ID,
+				AlertID:  act.AlertID,
+				Text:     text,
+				By:       act.DecidedBy,
+				At:       at,
+			})
+		}
+	}
+
+	bundles, err := storage.All(ctx, h.Storage.GetEvidenceBundles, storage.ListOptions{})
+	if err != nil {
+		return incidentResponse{}, err
+	}
+	for _, b := range bundles {
+		if alertIDs[b.AlertID] && !containsEvidence(inc.EvidenceIDs, b.ID) {
+			resp.Timeline = append(resp.Timeline, models.TimelineEntry{
+				Type:       models.TimelineEvidence,
+				AlertID:    b.AlertID,
+				EvidenceID: b.ID,
+				Text:       fmt.Sprintf("Evidence captured for pod %s/%s", b.Namespace, b.Pod),
+				At:         b.CollectedAt,
+			})
+		}
+	}
+
+	resp.Timeline = append(resp.Timeline, inc.Timeline...)
+	sort.SliceStable(resp.Timeline, func(i, j int) bool { return resp.Timeline[i].At.Before(resp.Timeline[j].At) })
+	return resp, nil
+}
+
+func containsEvidence(ids []string, id string) bool {
+	for _, e := range ids {
+		if e == id {
+			return true
+		}
+	}
+	return false
+}
+
+type incidentCreate struct {
+	Title    string   `json:"title"`
+	AlertIDs []string `json:"alert_ids"`
+	Owner    string   `json:"owner"`
+	Severity string   `json:"severity"`
+	Comment  string   `json:"comment"`
+}
+
+// CreateIncident opens an incident from alerts. The alerts leave the incidents
+// they were grouped into.
+func (h *ResourceHandler) CreateIncident(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req incidentCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Title == "" || len(req.AlertIDs) == 0 {
+		http.Error(w, "title and alert_ids are required", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool)
+	var alerts []models.Alert
+	var ids []string
+	for _, id := range req.AlertIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		a, err := h.Storage.GetAlert(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("alert %s: %v", id, err), http.StatusBadRequest)
+			return
+		}
+		alerts = append(alerts, a)
+		ids = append(ids, id)
+	}
+
+	now := time.Now()
+	inc := models.OpenIncident("inc-"+now.Format("20060102150405.000000"), req.Title, claims.UserID, alerts)
+	inc.Timeline = []models.TimelineEntry{{
+		Type: models.TimelineStatus,
+		To:   models.IncidentOpen,
+		Text: fmt.Sprintf("Opened from %d alerts", len(ids)),
+		By:   claims.UserID,
+		At:   now,
+	}}
+	if req.Owner != "" || req.Severity != "" || req.Comment != "" {
+		if err := inc.Apply(models.IncidentUpdate{Owner: req.Owner, Severity: req.Severity, Comment: req.Comment, By: claims.UserID, At: now}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.Storage.CreateIncident(inc, ids); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	resp, err := h.incidentResponse(r.Context(), inc)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateIncident changes the status, owner or severity of an incident
+func (h *ResourceHandler) UpdateIncident(w http.ResponseWriter, r *http.Request) {
+	var u models.IncidentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch u.Status {
+	case "", models.IncidentOpen, models.IncidentInvestigating, models.IncidentMitigated, models.IncidentClosed:
+	default:
+		http.Error(w, "Unknown status "+u.Status, http.StatusBadRequest)
+		return
+	}
+	switch u.Severity {
+	case "", models.SeverityInfo, models.SeverityLow, models.SeverityMedium, models.SeverityHigh, models.SeverityCritical:
+	default:
+		http.Error(w, "Unknown severity "+u.Severity, http.StatusBadRequest)
+		return
+	}
+	h.applyIncidentUpdate(w, r, u)
+}
+
+// CommentIncident adds a comment to the timeline of an incident
+func (h *ResourceHandler) CommentIncident(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" {
+		http.Error(w, "text is required", http.StatusBadRequest)
+		return
+	}
+	h.applyIncidentUpdate(w, r, models.IncidentUpdate{Comment: req.Text})
+}
+
+// LinkIncidentEvidence attaches an evidence bundle to an incident
+func (h *ResourceHandler) LinkIncidentEvidence(w http.ResponseWriter, r *http.Request) {
+	var u models.IncidentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if u.EvidenceID == "" {
+		http.Error(w, "evidence_id is required", http.StatusBadRequest)
+		return
+	}
+	h.applyIncidentUpdate(w, r, models.IncidentUpdate{EvidenceID: u.EvidenceID, Comment: u.Comment})
+}
+
+// UpdatePostMortem writes the post-mortem of an incident
+func (h *ResourceHandler) UpdatePostMortem(w http.ResponseWriter, r *http.Request) {
+	var pm models.PostMortem
+	if err := json.NewDecoder(r.Body).Decode(&pm); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if pm.Summary == "" {
+		http.Error(w, "summary is required", http.StatusBadRequest)
+		return
+	}
+	h.applyIncidentUpdate(w, r, models.IncidentUpdate{PostMortem: &pm})
+}
+
+// CloseIncident closes an incident, optionally with its post-mortem and a
+// closing comment
+func (h *ResourceHandler) CloseIncident(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Comment    string             `json:"comment"`
+		PostMortem *models.PostMortem `json:"post_mortem"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	h.applyIncidentUpdate(w, r, models.IncidentUpdate{Status: models.IncidentClosed, Comment: req.Comment, PostMortem: req.PostMortem})
+}
+
+// applyIncidentUpdate records an update under the calling user. Linked
+// evidence must exist.
+func (h *ResourceHandler) applyIncidentUpdate(w http.ResponseWriter, r *http.Request, u models.IncidentUpdate) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["incidentId"]
+	if _, err := h.Storage.GetIncident(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if u.EvidenceID != "" {
+		if _, err := h.Storage.GetEvidenceBundle(u.EvidenceID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	u.By, u.At = claims.UserID, time.Now()
+	inc, err := h.Storage.UpdateIncident(id, u)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	resp, err := h.incidentResponse(r.Context(), inc)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// listOptions reads the query parameters shared by the list endpoints:
+// ?since= and ?until= (RFC 3339), ?cluster=, ?severity=, ?namespace=,
+// ?incident=, ?alert_id=, ?status=, ?owner=, ?q= for free text,
+// ?order=asc|desc, ?cursor= and ?limit=
+func listOptions(r *http.Request) (storage.ListOptions, error) {
+	q := r.URL.Query()
+	opts := storage.ListOptions{
+		ClusterID:  q.Get("cluster"),
+		Severity:   q.Get("severity"),
+		Namespace:  q.Get("namespace"),
+		IncidentID: q.Get("incident"),
+		AlertID:    q.Get("alert_id"),
+		Status:     q.Get("status"),
+		Owner:      q.Get("owner"),
+		Text:       q.Get("q"),
+		Order:      storage.Order(q.Get("order")),
+		Cursor:     q.Get("cursor"),
+	}
+	for name, t := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if v := q.Get(name); v != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return opts, fmt.Errorf("%w: %s: %v", storage.ErrInvalidQuery, name, err)
+			}
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("%w: limit: %v", storage.ErrInvalidQuery, err)
+		}
+		opts.Limit = n
+	}
+	return opts, nil
+}
+
+// nextCursorHeader carries the cursor of the next page of a list; it is
+// absent on the last page
+const nextCursorHeader = "X-Next-Cursor"
+
+// writePage writes a page of a list as a JSON array
+func writePage[T any](w http.ResponseWriter, page storage.Page[T]) {
+	if page.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, page.NextCursor)
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	json.NewEncoder(w).Encode(page.Items)
+}
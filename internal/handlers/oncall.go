@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// On-call schedule handlers
+func (h *ResourceHandler) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetSchedules(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
+
+func (h *ResourceHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var sc models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateSchedule(sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sc.CreatedAt = time.Now()
+	sc.ID = "sched-" + sc.CreatedAt.Format("20060102150405.000000")
+	if sc.RotationStart.IsZero() {
+		sc.RotationStart = sc.CreatedAt
+	}
+	if err := h.Storage.AddSchedule(sc); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sc)
+}
+
+// UpdateSchedule replaces the rotation and overrides of a schedule
+func (h *ResourceHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	existing, err := h.Storage.GetSchedule(mux.Vars(r)["scheduleId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	var sc models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateSchedule(sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sc.ID, sc.CreatedAt = existing.ID, existing.CreatedAt
+	if sc.RotationStart.IsZero() {
+		sc.RotationStart = existing.RotationStart
+	}
+	if err := h.Storage.UpdateSchedule(sc); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(sc)
+}
+
+func (h *ResourceHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.DeleteSchedule(mux.Vars(r)["scheduleId"]); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOnCall returns the user on call for a schedule now, or at ?at= (RFC 3339)
+func (h *ResourceHandler) GetOnCall(w http.ResponseWriter, r *http.Request) {
+	sc, err := h.Storage.GetSchedule(mux.Vars(r)["scheduleId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid at: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	id := sc.OnCall(at)
+	if id == "" {
+		http.Error(w, "Nobody is on call", http.StatusNotFound)
+		return
+	}
+	u, err := h.Storage.GetUser(id)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(u)
+}
+
+// validateSchedule also checks that every referenced user exists
+func (h *ResourceHandler) validateSchedule(sc models.Schedule) error {
+	if err := sc.Validate(); err != nil {
+		return err
+	}
+	ids := append([]string(nil), sc.Rotation...)
+	for _, o := range sc.Overrides {
+		ids = append(ids, o.UserID)
+	}
+	for _, id := range ids {
+		if _, err := h.Storage.GetUser(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Escalation policy handlers
+func (h *ResourceHandler) GetEscalationPolicies(w http.ResponseWriter, r *http.Request) {
+	policies := h.Storage.GetEscalationPolicies()
+	if policies == nil {
+		policies = []models.EscalationPolicy{}
+	}
+	json.NewEncoder(w).Encode(policies)
+}
+
+func (h *ResourceHandler) CreateEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	var p models.EscalationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := p.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, t := range p.Tiers {
+		if _, err := h.Storage.GetChannel(t.ChannelID); err != nil {
+			http.Error(w, "Unknown channel "+t.ChannelID, http.StatusBadRequest)
+			return
+		}
+		if t.ScheduleID != "" {
+			if _, err := h.Storage.GetSchedule(t.ScheduleID); err != nil {
+				http.Error(w, "Unknown schedule "+t.ScheduleID, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	p.CreatedAt = time.Now()
+	p.ID = "esc-" + p.CreatedAt.Format("20060102150405.000000")
+	if err := h.Storage.AddEscalationPolicy(p); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *ResourceHandler) DeleteEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.DeleteEscalationPolicy(mux.Vars(r)["policyId"]); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEscalations lists the pending escalations, earliest due first
+func (h *ResourceHandler) GetEscalations(w http.ResponseWriter, r *http.Request) {
+	escalations := h.Storage.GetEscalations()
+	if escalations == nil {
+		escalations = []models.Escalation{}
+	}
+	json.NewEncoder(w).Encode(escalations)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// Playbook handlers
+func (h *ResourceHandler) GetPlaybooks(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetPlaybooks(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
+}
+
+func (h *ResourceHandler) GetPlaybook(w http.ResponseWriter, r *http.Request) {
+	pb, err := h.Storage.GetPlaybook(mux.Vars(r)["playbookId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(pb)
+}
+
+func (h *ResourceHandler) CreatePlaybook(w http.ResponseWriter, r *http.Request) {
+	var pb models.Playbook
+	if err := json.NewDecoder(r.Body).Decode(&pb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := pb.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pb.CreatedAt = time.Now()
+	pb.ID = "pb-" + pb.CreatedAt.Format("20060102150405.000000")
+	if err := h.Storage.AddPlaybook(pb); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pb)
+}
+
+// UpdatePlaybook replaces the trigger and steps; runs already started keep
+// the steps they started with
+func (h *ResourceHandler) UpdatePlaybook(w http.ResponseWriter, r *http.Request) {
+	existing, err := h.Storage.GetPlaybook(mux.Vars(r)["playbookId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	var pb models.Playbook
+	if err := json.NewDecoder(r.Body).Decode(&pb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := pb.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pb.ID, pb.CreatedAt = existing.ID, existing.CreatedAt
+	if err := h.Storage.UpdatePlaybook(pb); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(pb)
+}
+
+func (h *ResourceHandler) DeletePlaybook(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.DeletePlaybook(mux.Vars(r)["playbookId"]); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunPlaybook runs a playbook against an object, given directly or as the
+// alert_id of a policy violation. Dry runs act on a fake clientset seeded
+// from the cluster and return the finished run. Live runs change the cluster,
+// so only Administrators may start them; they run in the background and are
+// returned with 202 to be followed under /playbooks/runs/{runId}.
+func (h *ResourceHandler) RunPlaybook(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	pb, err := h.Storage.GetPlaybook(mux.Vars(r)["playbookId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	var req struct {
+		AlertID   string `json:"alert_id"`
+		ClusterID string `json:"cluster_id"`
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		DryRun    bool   `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !req.DryRun && claims.Role != models.RoleAdmin {
+		http.Error(w, "Only Administrators may start live playbook runs", http.StatusForbidden)
+		return
+	}
+
+	run := models.PlaybookRun{
+		AlertID:   req.AlertID,
+		ClusterID: req.ClusterID,
+		Kind:      req.Kind,
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		DryRun:    req.DryRun,
+	}
+	if req.AlertID != "" {
+		a, err := h.Storage.GetAlert(req.AlertID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		run.PolicyID, run.Severity = a.PolicyID, a.Severity
+		if run.ClusterID == "" {
+			run.ClusterID = a.ClusterID
+		}
+	}
+	if run.ClusterID == "" || run.Kind == "" || run.Name == "" {
+		http.Error(w, "cluster_id, kind and name are required", http.StatusBadRequest)
+		return
+	}
+
+	if run.DryRun {
+		run, err = h.Playbooks.Run(r.Context(), pb, run)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(run)
+		return
+	}
+	run, err = h.Playbooks.Start(context.WithoutCancel(r.Context()), pb, run)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+func (h *ResourceHandler) GetPlaybookRuns(w http.ResponseWriter, r *http.Request) {
+	runs := h.Storage.GetPlaybookRuns(mux.Vars(r)["playbookId"])
+	if runs == nil {
+		runs = []models.PlaybookRun{}
+	}
+	json.NewEncoder(w).Encode(runs)
+}
+
+func (h *ResourceHandler) GetPlaybookRun(w http.ResponseWriter, r *http.Request) {
+	run, err := h.Storage.GetPlaybookRun(mux.Vars(r)["runId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(run)
+}
@@ -2,25 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"KubernetesSecurityMonitoringSystem/internal/alerts"
+	"KubernetesSecurityMonitoringSystem/internal/checks"
+	"KubernetesSecurityMonitoringSystem/internal/forensics"
 	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
 	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/notify"
+	"KubernetesSecurityMonitoringSystem/internal/policies"
+	"KubernetesSecurityMonitoringSystem/internal/response"
+	"KubernetesSecurityMonitoringSystem/internal/retention"
 	"KubernetesSecurityMonitoringSystem/internal/storage"
 
 	"github.com/gorilla/mux"
 )
 
 type ResourceHandler struct {
-	Storage storage.Storage
-	K8s     *kubernetes.ClusterManager
+	Storage   storage.Storage
+	K8s       *kubernetes.ClusterManager
+	Watchers  *kubernetes.WatcherManager
+	Scanner   *checks.Scanner
+	Audit     *kubernetes.AuditDetector
+	Alerts    *alerts.Bus
+	Notifier  *notify.Notifier
+	Responder *response.Responder
+	Playbooks *response.PlaybookRunner
+	Evidence  *forensics.Collector
+	Retention *retention.Compactor
 }
 
 // Cluster Handlers
 func (h *ResourceHandler) GetClusters(w http.ResponseWriter, r *http.Request) {
-	clusters := h.Storage.GetClusters()
-	json.NewEncoder(w).Encode(clusters)
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetClusters(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
 }
 
 func (h *ResourceHandler) CreateCluster(w http.ResponseWriter, r *http.Request) {
@@ -52,21 +80,78 @@ func (h *ResourceHandler) CreateCluster(w http.ResponseWriter, r *http.Request)
 
 	c.ID = time.Now().Format("20060102150405")
 	c.CreatedAt = time.Now()
-	h.Storage.AddCluster(c)
-	json.NewEncoder(w).Encode(c)
+	if c.AuditToken, err = newAuditToken(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.Storage.AddCluster(c); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	h.K8s.AddClient(c.ID, client)
+	h.Watchers.Start(c.ID, client)
+	json.NewEncoder(w).Encode(clusterCreated{
+		ID:         c.ID,
+		Name:       c.Name,
+		Status:     c.Status,
+		Metrics:    c.Metrics,
+		AuditToken: c.AuditToken,
+		CreatedAt:  c.CreatedAt,
+	})
 }
 
+// clusterCreated is the response to CreateCluster, the only one that shows the
+// audit token; the kubeconfig is never sent back
+type clusterCreated struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	Metrics    models.Metrics `json:"metrics"`
+	AuditToken string         `json:"audit_token"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// DeleteCluster deletes a cluster with its scan findings and benchmark runs.
+// The findings go first, so a failed cleanup leaves the cluster in place to
+// delete again.
 func (h *ResourceHandler) DeleteCluster(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["clusterId"]
-	h.Storage.DeleteCluster(id)
+	if _, err := h.Storage.GetCluster(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if err := h.Storage.DeleteScans(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if err := h.Storage.DeleteCISRuns(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if err := h.Storage.DeleteCluster(id); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	h.Watchers.Stop(id)
+	h.K8s.RemoveClient(id)
+	h.Audit.Forget(id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Policy Handlers
 func (h *ResourceHandler) GetPolicies(w http.ResponseWriter, r *http.Request) {
-	policies := h.Storage.GetPolicies()
-	json.NewEncoder(w).Encode(policies)
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetPolicies(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
 }
 
 func (h *ResourceHandler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
@@ -75,42 +160,207 @@ func (h *ResourceHandler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if _, err := policies.ParseRules(p.Rules); err != nil {
+		http.Error(w, "Invalid rules:\n"+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Severity != "" && p.Severity != models.SeverityInfo && models.SeverityRank(p.Severity) == 0 {
+		http.Error(w, "Unknown severity "+p.Severity, http.StatusBadRequest)
+		return
+	}
+	for _, typ := range p.Responses {
+		if models.DefaultActionKind(typ) == "" {
+			http.Error(w, "Unknown response action "+typ, http.StatusBadRequest)
+			return
+		}
+	}
+	for _, id := range p.Playbooks {
+		if _, err := h.Storage.GetPlaybook(id); err != nil {
+			http.Error(w, "Unknown playbook "+id, http.StatusBadRequest)
+			return
+		}
+	}
 	p.ID = time.Now().Format("20060102150405")
 	p.CreatedAt = time.Now()
-	h.Storage.AddPolicy(p)
+	if err := h.Storage.AddPolicy(p); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
 	json.NewEncoder(w).Encode(p)
 }
 
-// Alert Handlers (SSE placeholder)
-func (h *ResourceHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+// Alert Handlers
+
+// alertHeartbeat is how often an idle alert stream sends a comment so proxies
+// keep the connection open
+const alertHeartbeat = 15 * time.Second
 
+// GetAlerts streams alerts as server-sent events. A new connection first gets
+// a snapshot of the stored alerts, then every new alert and transition as an
+// event with an increasing ID. Reconnecting with Last-Event-ID replays the
+// events missed in between. ?cluster=, ?severity= and ?status= take comma
+// separated values and narrow both the snapshot and the events. The snapshot
+// is a page of the alerts list and takes its other parameters too; the cursor
+// of the next page is in X-Next-Cursor.
+func (h *ResourceHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
 		return
 	}
 
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	q := r.URL.Query()
+	filter := alerts.Filter{
+		Clusters:   valueSet(q.Get("cluster")),
+		Severities: valueSet(q.Get("severity")),
+		Statuses:   valueSet(q.Get("status")),
+	}
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	// storage filters on one value; the filter narrows to any of several
+	if len(filter.Clusters) != 1 {
+		opts.ClusterID = ""
+	}
+	if len(filter.Severities) != 1 {
+		opts.Severity = ""
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = q.Get("last_event_id")
+	}
+	resume, err := strconv.ParseUint(lastEventID, 10, 64)
+	fresh := err != nil
+	if fresh {
+		resume = h.Alerts.LastID()
+	}
+	sub, missed, err := h.Alerts.Subscribe(resume)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	snapshot := []models.Alert{}
+	if fresh {
+		page, err := h.Storage.GetAlerts(r.Context(), opts)
+		if err != nil {
+			http.Error(w, err.Error(), storageStatus(err))
+			return
+		}
+		for _, a := range page.Items {
+			if filter.Match(a) {
+				snapshot = append(snapshot, a)
+			}
+		}
+		if page.NextCursor != "" {
+			w.Header().Set(nextCursorHeader, page.NextCursor)
+		}
+	}
 
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	if fresh {
+		data, _ := json.Marshal(snapshot)
+		fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data)
+	}
+	send := func(ev alerts.Event) {
+		if !filter.Match(ev.Alert) {
+			return
+		}
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	}
+	for _, ev := range missed {
+		send(ev)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(alertHeartbeat)
+	defer heartbeat.Stop()
 	for {
 		select {
 		case <-r.Context().Done():
 			return
-		case <-ticker.C:
-			alerts := h.Storage.GetAlerts()
-			data, _ := json.Marshal(alerts)
-			w.Write([]byte("data: " + string(data) + "\n\n"))
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			send(ev)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
 			flusher.Flush()
 		}
 	}
 }
 
+// valueSet splits a comma separated query value into a set
+func valueSet(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+type alertUpdate struct {
+	Status   string `json:"status"`
+	Assignee string `json:"assignee"`
+	Note     string `json:"note"`
+}
+
+// UpdateAlert acknowledges, assigns, resolves, suppresses or reopens an alert
+// and records the change under the calling user
+func (h *ResourceHandler) UpdateAlert(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var u alertUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch u.Status {
+	case "", models.AlertOpen, models.AlertAcknowledged, models.AlertResolved, models.AlertSuppressed:
+	default:
+		http.Error(w, "Unknown status "+u.Status, http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.Storage.TransitionAlert(mux.Vars(r)["alertId"], models.AlertTransition{
+		To:       u.Status,
+		Assignee: u.Assignee,
+		Note:     u.Note,
+		By:       claims.UserID,
+		At:       time.Now(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
 // Incident Reports
 func (h *ResourceHandler) GetReports(w http.ResponseWriter, r *http.Request) {
-	reports := h.Storage.GetReports()
-	json.NewEncoder(w).Encode(reports)
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetReports(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
 }
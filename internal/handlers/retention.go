@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/retention"
+)
+
+// GetRetention reports the retention policy, the last compaction pass, the
+// archives and how many restored records are held from compaction
+func (h *ResourceHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	status, err := h.Retention.Status()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// RunRetention runs a compaction pass now and returns its outcome
+func (h *ResourceHandler) RunRetention(w http.ResponseWriter, r *http.Request) {
+	pass, err := h.Retention.Compact(r.Context(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(pass)
+}
+
+type restoreRequest struct {
+	Archive  string `json:"archive"`
+	HoldDays int    `json:"hold_days"`
+}
+
+// RestoreArchive re-imports an archive. Its records are held from compaction
+// for hold_days, seven by default, so they can be looked into.
+func (h *ResourceHandler) RestoreArchive(w http.ResponseWriter, r *http.Request) {
+	var req restoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.HoldDays < 0 {
+		http.Error(w, "hold_days must not be negative", http.StatusBadRequest)
+		return
+	}
+	hold := retention.DefaultHold
+	if req.HoldDays > 0 {
+		hold = time.Duration(req.HoldDays) * 24 * time.Hour
+	}
+	n, err := h.Retention.Restore(r.Context(), req.Archive, time.Now().Add(hold))
+	if errors.Is(err, retention.ErrNoArchive) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int{"restored": n})
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	"github.com/gorilla/mux"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// clusterClient loads the cluster named in the route and returns its client
+func (h *ResourceHandler) clusterClient(r *http.Request) (models.Cluster, k8sclient.Interface, int, error) {
+	id := mux.Vars(r)["clusterId"]
+	c, err := h.Storage.GetCluster(id)
+	if err != nil {
+		return models.Cluster{}, nil, storageStatus(err), err
+	}
+	client, err := h.K8s.GetClient(c.ID, c.KubeConfig)
+	if err != nil {
+		return c, nil, http.StatusBadGateway, err
+	}
+	return c, client, http.StatusOK, nil
+}
+
+// needsScan reports whether a cluster has never been scanned for a kind of
+// findings or a fresh scan is asked for with ?refresh=true. A scan that found
+// nothing is not repeated.
+func (h *ResourceHandler) needsScan(r *http.Request, clusterID, kind string) bool {
+	if r.URL.Query().Get("refresh") == "true" {
+		return true
+	}
+	_, err := h.Storage.LastScan(clusterID, kind)
+	return err != nil
+}
+
+type pssResponse struct {
+	ClusterID  string                           `json:"cluster_id"`
+	Namespaces []kubernetes.PSSNamespaceSummary `json:"namespaces"`
+	Findings   []models.PSSFinding              `json:"findings"`
+}
+
+// GetPodSecurity returns the Pod Security Standards findings of a cluster.
+// A scan runs when the cluster was never scanned or when ?refresh=true is
+// passed.
+func (h *ResourceHandler) GetPodSecurity(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["clusterId"]
+	findings := h.Storage.GetPSSFindings(id)
+
+	if h.needsScan(r, id, models.ScanPSS) {
+		c, client, status, err := h.clusterClient(r)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		findings, err = kubernetes.ScanPodSecurity(r.Context(), client, c.ID)
+		if err != nil {
+			http.Error(w, "Scan failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if err := h.Storage.SavePSSFindings(c.ID, findings); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(pssResponse{
+		ClusterID:  id,
+		Namespaces: kubernetes.SummarizePSS(findings),
+		Findings:   findings,
+	})
+}
+
+// GetRBAC returns the effective permissions and risky grants of every subject
+// of a cluster, optionally narrowed with ?kind=, ?namespace=, ?name= and ?risky=true
+func (h *ResourceHandler) GetRBAC(w http.ResponseWriter, r *http.Request) {
+	_, client, status, err := h.clusterClient(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	access, err := kubernetes.AnalyzeRBAC(r.Context(), client)
+	if err != nil {
+		http.Error(w, "RBAC analysis failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	q := r.URL.Query()
+	access = kubernetes.FilterSubjects(access, q.Get("kind"), q.Get("namespace"), q.Get("name"), q.Get("risky") == "true")
+	json.NewEncoder(w).Encode(access)
+}
+
+// GetNetwork returns the pod reachability graph of a cluster, or the
+// namespace-level graph with ?level=namespace
+func (h *ResourceHandler) GetNetwork(w http.ResponseWriter, r *http.Request) {
+	_, client, status, err := h.clusterClient(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	graph, err := kubernetes.AnalyzeNetwork(r.Context(), client)
+	if err != nil {
+		http.Error(w, "Network analysis failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if r.URL.Query().Get("level") == "namespace" {
+		graph = kubernetes.NamespaceGraph(graph)
+	}
+	json.NewEncoder(w).Encode(graph)
+}
+
+// GetImages returns the image posture findings of a cluster. A scan runs when
+// the cluster was never scanned or with ?refresh=true; ?format=csv downloads
+// the findings.
+func (h *ResourceHandler) GetImages(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["clusterId"]
+	findings := h.Storage.GetImageFindings(id)
+
+	if h.needsScan(r, id, models.ScanImages) {
+		c, client, status, err := h.clusterClient(r)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		findings, err = h.Scanner.ScanImages(r.Context(), c.ID, client)
+		if err != nil {
+			http.Error(w, "Scan failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"images-"+id+".csv\"")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"namespace", "pod", "container", "image", "check", "severity", "policy_id", "message", "detected_at"})
+		for _, f := range findings {
+			cw.Write([]string{f.Namespace, f.Pod, f.Container, f.Image, f.Check, f.Severity, f.PolicyID, f.Message, f.DetectedAt.Format(time.RFC3339)})
+		}
+		cw.Flush()
+		return
+	}
+	json.NewEncoder(w).Encode(findings)
+}
+
+// GetSecrets returns the secret hygiene findings of a cluster. An audit runs
+// when the cluster was never audited or with ?refresh=true.
+func (h *ResourceHandler) GetSecrets(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["clusterId"]
+	findings := h.Storage.GetSecretFindings(id)
+
+	if h.needsScan(r, id, models.ScanSecrets) {
+		c, client, status, err := h.clusterClient(r)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		findings, err = h.Scanner.AuditSecrets(r.Context(), c.ID, client)
+		if err != nil {
+			http.Error(w, "Audit failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(findings)
+}
+
+type cisRunResponse struct {
+	models.CISRun
+	Summary map[string]int `json:"summary"`
+}
+
+type cisRunSummary struct {
+	ID        string         `json:"id"`
+	Version   int            `json:"version"`
+	Benchmark string         `json:"benchmark"`
+	StartedAt time.Time      `json:"started_at"`
+	Summary   map[string]int `json:"summary"`
+}
+
+type cisDiffResponse struct {
+	From    int                    `json:"from"`
+	To      int                    `json:"to"`
+	Changes []kubernetes.CISChange `json:"changes"`
+}
+
+// RunCIS evaluates the CIS benchmark against a cluster and stores the result
+// as the next version
+func (h *ResourceHandler) RunCIS(w http.ResponseWriter, r *http.Request) {
+	c, client, status, err := h.clusterClient(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	started := time.Now()
+	controls, err := kubernetes.RunCISBenchmark(r.Context(), client)
+	if err != nil {
+		http.Error(w, "Benchmark failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	run, err := h.Storage.AddCISRun(models.CISRun{
+		ID:        started.Format("20060102150405"),
+		ClusterID: c.ID,
+		Benchmark: kubernetes.CISBenchmark,
+		StartedAt: started,
+		Controls:  controls,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cisRunResponse{CISRun: run, Summary: kubernetes.CISSummary(run.Controls)})
+}
+
+// GetCISRuns lists the stored benchmark runs of a cluster with their status counts
+func (h *ResourceHandler) GetCISRuns(w http.ResponseWriter, r *http.Request) {
+	runs := h.Storage.GetCISRuns(mux.Vars(r)["clusterId"])
+	summaries := make([]cisRunSummary, 0, len(runs))
+	for _, run := range runs {
+		summaries = append(summaries, cisRunSummary{
+			ID:        run.ID,
+			Version:   run.Version,
+			Benchmark: run.Benchmark,
+			StartedAt: run.StartedAt,
+			Summary:   kubernetes.CISSummary(run.Controls),
+		})
+	}
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// GetCISRun returns one stored benchmark run with all of its controls
+func (h *ResourceHandler) GetCISRun(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	version, _ := strconv.Atoi(vars["version"])
+	run, err := h.Storage.GetCISRun(vars["clusterId"], version)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(cisRunResponse{CISRun: run, Summary: kubernetes.CISSummary(run.Controls)})
+}
+
+// DiffCIS lists the controls whose status changed between ?from= and ?to=.
+// Without them the two latest runs are compared.
+func (h *ResourceHandler) DiffCIS(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["clusterId"]
+	runs := h.Storage.GetCISRuns(id)
+	if len(runs) == 0 {
+		http.Error(w, "No benchmark runs stored", http.StatusNotFound)
+		return
+	}
+
+	from, to := 0, runs[len(runs)-1].Version
+	if len(runs) > 1 {
+		from = runs[len(runs)-2].Version
+	}
+	q := r.URL.Query()
+	for name, v := range map[string]*int{"from": &from, "to": &to} {
+		if value := q.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" version", http.StatusBadRequest)
+				return
+			}
+			*v = n
+		}
+	}
+
+	var before models.CISRun
+	if from != 0 {
+		var err error
+		if before, err = h.Storage.GetCISRun(id, from); err != nil {
+			http.Error(w, err.Error(), storageStatus(err))
+			return
+		}
+	}
+	after, err := h.Storage.GetCISRun(id, to)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(cisDiffResponse{From: from, To: to, Changes: kubernetes.DiffCISRuns(before, after)})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+type silenceResponse struct {
+	models.Silence
+	State string `json:"state"`
+}
+
+func newSilenceResponse(sl models.Silence) silenceResponse {
+	return silenceResponse{Silence: sl, State: sl.State(time.Now())}
+}
+
+// GetSilences lists silences with their current state, most recent first.
+// ?state=active, pending or expired narrows the list.
+func (h *ResourceHandler) GetSilences(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	if state := r.URL.Query().Get("state"); state != "" {
+		opts.Status = state
+	}
+	page, err := h.Storage.GetSilences(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	resp := storage.Page[silenceResponse]{NextCursor: page.NextCursor}
+	for _, sl := range page.Items {
+		resp.Items = append(resp.Items, newSilenceResponse(sl))
+	}
+	writePage(w, resp)
+}
+
+func (h *ResourceHandler) GetSilence(w http.ResponseWriter, r *http.Request) {
+	sl, err := h.Storage.GetSilence(mux.Vars(r)["silenceId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(newSilenceResponse(sl))
+}
+
+// CreateSilence adds a silence created by the calling user. It starts now
+// unless starts_at is given.
+func (h *ResourceHandler) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var sl models.Silence
+	if err := json.NewDecoder(r.Body).Decode(&sl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sl.CreatedAt = time.Now()
+	if sl.StartsAt.IsZero() {
+		sl.StartsAt = sl.CreatedAt
+	}
+	if err := sl.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sl.ID = "sil-" + sl.CreatedAt.Format("20060102150405.000000")
+	sl.CreatedBy = claims.UserID
+	if err := h.Storage.AddSilence(sl); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newSilenceResponse(sl))
+}
+
+// UpdateSilence changes the matchers, time range or comment of a silence.
+// Setting ends_at to now expires it early.
+func (h *ResourceHandler) UpdateSilence(w http.ResponseWriter, r *http.Request) {
+	existing, err := h.Storage.GetSilence(mux.Vars(r)["silenceId"])
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	var sl models.Silence
+	if err := json.NewDecoder(r.Body).Decode(&sl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sl.ID, sl.CreatedBy, sl.CreatedAt = existing.ID, existing.CreatedBy, existing.CreatedAt
+	if sl.StartsAt.IsZero() {
+		sl.StartsAt = existing.StartsAt
+	}
+	if err := sl.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.Storage.UpdateSilence(sl); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	json.NewEncoder(w).Encode(newSilenceResponse(sl))
+}
+
+func (h *ResourceHandler) DeleteSilence(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.DeleteSilence(mux.Vars(r)["silenceId"]); err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -15,8 +15,17 @@ type UserHandler struct {
 }
 
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users := h.Storage.GetAllUsers()
-	json.NewEncoder(w).Encode(users)
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	page, err := h.Storage.GetAllUsers(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storageStatus(err))
+		return
+	}
+	writePage(w, page)
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -24,7 +33,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	id := vars["userId"]
 	user, err := h.Storage.GetUser(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), storageStatus(err))
 		return
 	}
 	json.NewEncoder(w).Encode(user)
@@ -40,7 +49,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 	u.ID = id
 	if err := h.Storage.UpdateUser(u); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), storageStatus(err))
 		return
 	}
 	json.NewEncoder(w).Encode(u)
@@ -50,7 +59,7 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["userId"]
 	if err := h.Storage.DeleteUser(id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), storageStatus(err))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,213 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+// Audit alert rules
+const (
+	RuleAuditExec       = "audit-exec"
+	RuleAuditSecretRead = "audit-secret-read"
+	RuleAuditAnonymous  = "audit-anonymous"
+	RuleAuditForbidden  = "audit-forbidden-burst"
+)
+
+// Forbidden responses from one user within ForbiddenWindow that count as a burst
+const (
+	ForbiddenBurst  = 10
+	ForbiddenWindow = time.Minute
+)
+
+// AuditEventList is a batch of audit.k8s.io/v1 events as posted by the
+// API server webhook backend. Only the fields used by the detection rules
+// are decoded.
+type AuditEventList struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Items      []AuditEvent `json:"items"`
+}
+
+// AuditEvent is a single audit.k8s.io/v1 Event
+type AuditEvent struct {
+	AuditID        string          `json:"auditID"`
+	Stage          string          `json:"stage"`
+	Verb           string          `json:"verb"`
+	RequestURI     string          `json:"requestURI"`
+	User           AuditUser       `json:"user"`
+	SourceIPs      []string        `json:"sourceIPs"`
+	ObjectRef      *AuditObjectRef `json:"objectRef,omitempty"`
+	ResponseStatus *struct {
+		Code int32 `json:"code"`
+	} `json:"responseStatus,omitempty"`
+	StageTimestamp time.Time `json:"stageTimestamp"`
+}
+
+// AuditUser is the authenticated user of a request
+type AuditUser struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
+// AuditObjectRef identifies the object a request was made against
+type AuditObjectRef struct {
+	Resource    string `json:"resource"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Subresource string `json:"subresource"`
+}
+
+// AuditDetector runs the audit detection rules. It keeps the recent forbidden
+// responses per cluster and user so bursts spanning several batches are found.
+type AuditDetector struct {
+	forbidden map[string][]time.Time
+	mu        sync.Mutex
+}
+
+func NewAuditDetector() *AuditDetector {
+	return &AuditDetector{forbidden: make(map[string][]time.Time)}
+}
+
+// Detect turns the suspicious events of a batch into alerts
+func (d *AuditDetector) Detect(clusterID string, events []AuditEvent) []models.Alert {
+	var alerts []models.Alert
+	raise := func(ev AuditEvent, rule, resource, severity, format string, args ...interface{}) {
+		ts := ev.StageTimestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		alerts = append(alerts, models.Alert{
+			ID:        NewAlertID(),
+			ClusterID: clusterID,
+			Rule:      rule,
+			Namespace: auditNamespace(ev),
+			Resource:  resource,
+			Severity:  severity,
+			Message:   fmt.Sprintf(format, args...),
+			Actor:     ev.User.Username,
+			Verb:      ev.Verb,
+			Timestamp: ts,
+		})
+	}
+
+	for _, ev := range events {
+		// Long running requests such as exec are logged at ResponseStarted
+		// and again at ResponseComplete; only the final stage is evaluated.
+		if ev.Stage != "" && ev.Stage != "ResponseComplete" && ev.Stage != "Panic" {
+			continue
+		}
+		code := int32(0)
+		if ev.ResponseStatus != nil {
+			code = ev.ResponseStatus.Code
+		}
+		ref := ev.ObjectRef
+		if ref == nil {
+			ref = &AuditObjectRef{}
+		}
+
+		if code == 403 {
+			if n := d.recordForbidden(clusterID, ev.User.Username, ev.StageTimestamp); n == ForbiddenBurst {
+				raise(ev, RuleAuditForbidden, "user "+ev.User.Username, models.SeverityMedium, "%s received %d forbidden responses within %s (last: %s %s)",
+					ev.User.Username, n, ForbiddenWindow, ev.Verb, ev.RequestURI)
+			}
+			continue
+		}
+		if code >= 400 {
+			continue
+		}
+
+		switch {
+		case isAnonymous(ev.User) && !publicEndpoint(ev.RequestURI):
+			raise(ev, RuleAuditAnonymous, ev.Verb+" "+ev.RequestURI, models.SeverityHigh, "Anonymous request %s %s from %s was allowed",
+				ev.Verb, ev.RequestURI, strings.Join(ev.SourceIPs, ", "))
+		case ref.Resource == "pods" && (ref.Subresource == "exec" || ref.Subresource == "attach"):
+			raise(ev, RuleAuditExec, "Pod "+ref.Namespace+"/"+ref.Name+" by "+ev.User.Username, models.SeverityMedium, "%s ran %s in pod %s/%s",
+				ev.User.Username, ref.Subresource, ref.Namespace, ref.Name)
+		case ref.Resource == "secrets" && (ev.Verb == "get" || ev.Verb == "list" || ev.Verb == "watch") && unusualSecretReader(ev.User, ref.Namespace):
+			target := "secrets in " + namespaceOrCluster(ref.Namespace)
+			if ref.Name != "" {
+				target = "secret " + ref.Namespace + "/" + ref.Name
+			}
+			raise(ev, RuleAuditSecretRead, target+" by "+ev.User.Username, models.SeverityHigh, "%s did %s on %s", ev.User.Username, ev.Verb, target)
+		}
+	}
+	return alerts
+}
+
+// recordForbidden adds a forbidden response and returns how many the user
+// received within the window
+func (d *AuditDetector) recordForbidden(clusterID, user string, at time.Time) int {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := clusterID + "/" + user
+	recent := d.forbidden[key][:0]
+	for _, t := range d.forbidden[key] {
+		if at.Sub(t) < ForbiddenWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, at)
+	d.forbidden[key] = recent
+	return len(recent)
+}
+
+// Forget drops the state kept for a cluster
+func (d *AuditDetector) Forget(clusterID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key := range d.forbidden {
+		if strings.HasPrefix(key, clusterID+"/") {
+			delete(d.forbidden, key)
+		}
+	}
+}
+
+func auditNamespace(ev AuditEvent) string {
+	if ev.ObjectRef == nil {
+		return ""
+	}
+	return ev.ObjectRef.Namespace
+}
+
+func isAnonymous(u AuditUser) bool {
+	return u.Username == "system:anonymous" || contains(u.Groups, "system:unauthenticated")
+}
+
+// publicEndpoint reports whether the path is served to anonymous users by the
+// default system:public-info-viewer role
+func publicEndpoint(uri string) bool {
+	path, _, _ := strings.Cut(uri, "?")
+	switch path {
+	case "/healthz", "/livez", "/readyz", "/version":
+		return true
+	}
+	return strings.HasPrefix(path, "/healthz/") || strings.HasPrefix(path, "/livez/") || strings.HasPrefix(path, "/readyz/")
+}
+
+// unusualSecretReader reports whether reading secrets is unexpected for the
+// user. Control plane components are expected to; service accounts only
+// within their own namespace; human users never.
+func unusualSecretReader(u AuditUser, namespace string) bool {
+	if strings.HasPrefix(u.Username, "system:serviceaccount:") {
+		saNamespace, _, _ := strings.Cut(strings.TrimPrefix(u.Username, "system:serviceaccount:"), ":")
+		return saNamespace != namespace && !systemNamespaces[saNamespace]
+	}
+	if strings.HasPrefix(u.Username, "system:") || contains(u.Groups, "system:nodes") {
+		return false
+	}
+	return true
+}
+
+func namespaceOrCluster(namespace string) string {
+	if namespace == "" {
+		return "all namespaces"
+	}
+	return "namespace " + namespace
+}
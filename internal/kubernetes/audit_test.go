@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+func auditEvent(user, verb, resource, namespace, name, subresource string, code int32) AuditEvent {
+	ev := AuditEvent{
+		Stage:          "ResponseComplete",
+		Verb:           verb,
+		RequestURI:     "/api/v1/namespaces/" + namespace + "/" + resource + "/" + name,
+		User:           AuditUser{Username: user},
+		SourceIPs:      []string{"10.0.0.7"},
+		ObjectRef:      &AuditObjectRef{Resource: resource, Namespace: namespace, Name: name, Subresource: subresource},
+		StageTimestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	ev.ResponseStatus = &struct {
+		Code int32 `json:"code"`
+	}{code}
+	return ev
+}
+
+func TestAuditDetect(t *testing.T) {
+	started := auditEvent("alice", "create", "pods", "web", "api", "exec", 101)
+	started.Stage = "ResponseStarted"
+	anonymous := auditEvent("system:anonymous", "list", "pods", "", "", "", 200)
+	anonymous.RequestURI = "/api/v1/pods"
+	unauthenticated := auditEvent("", "get", "", "", "", "", 200)
+	unauthenticated.User.Groups = []string{"system:unauthenticated"}
+	unauthenticated.RequestURI = "/apis/apps/v1/deployments"
+	unauthenticated.ObjectRef = nil
+	health := auditEvent("system:anonymous", "get", "", "", "", "", 200)
+	health.RequestURI = "/readyz/etcd?verbose"
+	node := auditEvent("system:node:worker-1", "get", "secrets", "web", "db", "", 200)
+	node.User.Groups = []string{"system:nodes"}
+
+	for _, tc := range []struct {
+		name     string
+		event    AuditEvent
+		rule     string // empty when nothing is raised
+		severity string
+		resource string
+	}{
+		{"exec", auditEvent("alice", "create", "pods", "web", "api", "exec", 101), RuleAuditExec, models.SeverityMedium, "Pod web/api by alice"},
+		{"attach", auditEvent("alice", "create", "pods", "web", "api", "attach", 101), RuleAuditExec, models.SeverityMedium, "Pod web/api by alice"},
+		{"exec not yet complete", started, "", "", ""},
+		{"failed exec", auditEvent("alice", "create", "pods", "web", "api", "exec", 404), "", "", ""},
+		{"logs", auditEvent("alice", "get", "pods", "web", "api", "log", 200), "", "", ""},
+		{"human reads a secret", auditEvent("alice", "get", "secrets", "web", "db", "", 200), RuleAuditSecretRead, models.SeverityHigh, "secret web/db by alice"},
+		{"human lists all secrets", auditEvent("alice", "list", "secrets", "", "", "", 200), RuleAuditSecretRead, models.SeverityHigh, "secrets in all namespaces by alice"},
+		{"human writes a secret", auditEvent("alice", "update", "secrets", "web", "db", "", 200), "", "", ""},
+		{"service account in its namespace", auditEvent("system:serviceaccount:web:api", "get", "secrets", "web", "db", "", 200), "", "", ""},
+		{"service account across namespaces", auditEvent("system:serviceaccount:ci:deployer", "watch", "secrets", "web", "", "", 200),
+			RuleAuditSecretRead, models.SeverityHigh, "secrets in namespace web by system:serviceaccount:ci:deployer"},
+		{"system service account", auditEvent("system:serviceaccount:kube-system:generic-garbage-collector", "list", "secrets", "web", "", "", 200), "", "", ""},
+		{"control plane", auditEvent("system:kube-controller-manager", "get", "secrets", "web", "db", "", 200), "", "", ""},
+		{"node", node, "", "", ""},
+		{"anonymous user", anonymous, RuleAuditAnonymous, models.SeverityHigh, "list /api/v1/pods"},
+		{"unauthenticated group", unauthenticated, RuleAuditAnonymous, models.SeverityHigh, "get /apis/apps/v1/deployments"},
+		{"anonymous health check", health, "", "", ""},
+		{"anonymous request denied", auditEvent("system:anonymous", "list", "pods", "", "", "", 401), "", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			alerts := NewAuditDetector().Detect("c1", []AuditEvent{tc.event})
+			if tc.rule == "" {
+				if len(alerts) != 0 {
+					t.Errorf("raised %+v, want nothing", alerts)
+				}
+				return
+			}
+			if len(alerts) != 1 {
+				t.Fatalf("raised %d alerts, want 1", len(alerts))
+			}
+			a := alerts[0]
+			if a.Rule != tc.rule || a.Severity != tc.severity || a.Resource != tc.resource {
+				t.Errorf("alert = %s %s %q, want %s %s %q", a.Rule, a.Severity, a.Resource, tc.rule, tc.severity, tc.resource)
+			}
+			if a.ID == "" || a.ClusterID != "c1" || a.Actor != tc.event.User.Username || a.Verb != tc.event.Verb || !a.Timestamp.Equal(tc.event.StageTimestamp) {
+				t.Errorf("alert = %+v", a)
+			}
+		})
+	}
+}
+
+func TestAuditForbiddenBurst(t *testing.T) {
+	d := NewAuditDetector()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	denied := func(user string, at time.Duration) AuditEvent {
+		ev := auditEvent(user, "list", "secrets", "web", "", "", 403)
+		ev.StageTimestamp = start.Add(at)
+		return ev
+	}
+	burst := func(user string, from time.Duration, n int) []AuditEvent {
+		var events []AuditEvent
+		for i := 0; i < n; i++ {
+			events = append(events, denied(user, from+time.Duration(i)*time.Second))
+		}
+		return events
+	}
+
+	// a burst spanning two batches is raised once, on the tenth response
+	if alerts := d.Detect("c1", burst("mallory", 0, ForbiddenBurst-1)); len(alerts) != 0 {
+		t.Fatalf("raised %+v before the burst was complete", alerts)
+	}
+	alerts := d.Detect("c1", burst("mallory", 10*time.Second, 2))
+	if len(alerts) != 1 || alerts[0].Rule != RuleAuditForbidden || alerts[0].Resource != "user mallory" || alerts[0].Severity != models.SeverityMedium {
+		t.Fatalf("alerts = %+v, want one forbidden burst for mallory", alerts)
+	}
+
+	// other users and clusters are counted apart
+	if alerts := d.Detect("c1", burst("eve", 0, ForbiddenBurst-1)); len(alerts) != 0 {
+		t.Errorf("eve raised %+v", alerts)
+	}
+	if alerts := d.Detect("c2", burst("mallory", 0, ForbiddenBurst-1)); len(alerts) != 0 {
+		t.Errorf("mallory on c2 raised %+v", alerts)
+	}
+
+	// responses older than the window no longer count
+	if alerts := d.Detect("c1", burst("eve", ForbiddenWindow+10*time.Second, 1)); len(alerts) != 0 {
+		t.Errorf("responses outside the window made a burst: %+v", alerts)
+	}
+
+	// Forget drops a cluster's counts
+	d.Forget("c2")
+	if alerts := d.Detect("c2", burst("mallory", 20*time.Second, 1)); len(alerts) != 0 {
+		t.Errorf("counts survived Forget: %+v", alerts)
+	}
+	if alerts := d.Detect("c2", burst("mallory", 21*time.Second, ForbiddenBurst-1)); len(alerts) != 1 {
+		t.Errorf("got %d alerts after a new burst, want 1", len(alerts))
+	}
+}
@@ -0,0 +1,323 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CISBenchmark names the benchmark the controls are taken from
+const CISBenchmark = "CIS Kubernetes Benchmark v1.8"
+
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// builtinClusterRoles are shipped with Kubernetes and use wildcards by design
+var builtinClusterRoles = map[string]bool{
+	"cluster-admin": true,
+	"admin":         true,
+	"edit":          true,
+	"view":          true,
+}
+
+// CISChange is a control whose status differs between two runs
+type CISChange struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Before string `json:"before"` // empty when the control is new
+	After  string `json:"after"`  // empty when the control was removed
+}
+
+// cisState holds everything the controls are evaluated against
+type cisState struct {
+	apiServer       string            // namespace/name of the kube-apiserver pod
+	apiServerFlags  map[string]string // nil when the pod is not visible
+	namespaces      []corev1.Namespace
+	serviceAccounts []corev1.ServiceAccount
+	access          []SubjectAccess
+	wildcardRoles   []string
+	workloads       []Workload
+	defaultServices []corev1.Service
+	networkPolicies map[string]int
+}
+
+// RunCISBenchmark evaluates the CIS controls that can be checked through the
+// Kubernetes API. Controls that need node or control plane host access, or
+// human judgement, are reported as manual with whatever evidence is visible.
+func RunCISBenchmark(ctx context.Context, client kubernetes.Interface) ([]models.CISControl, error) {
+	opts := metav1.ListOptions{}
+	state := &cisState{networkPolicies: make(map[string]int)}
+
+	systemPods, err := client.CoreV1().Pods("kube-system").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range systemPods.Items {
+		if p.Labels["component"] == "kube-apiserver" || strings.HasPrefix(p.Name, "kube-apiserver") {
+			state.apiServer = p.Namespace + "/" + p.Name
+			state.apiServerFlags = commandFlags(p.Spec.Containers)
+			break
+		}
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	state.namespaces = namespaces.Items
+	serviceAccounts, err := client.CoreV1().ServiceAccounts("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	state.serviceAccounts = serviceAccounts.Items
+	services, err := client.CoreV1().Services(metav1.NamespaceDefault).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	state.defaultServices = services.Items
+	networkPolicies, err := client.NetworkingV1().NetworkPolicies("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, np := range networkPolicies.Items {
+		state.networkPolicies[np.Namespace]++
+	}
+
+	roles, err := client.RbacV1().Roles("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles.Items {
+		if !systemNamespaces[r.Namespace] && hasWildcardRule(r.Rules) {
+			state.wildcardRoles = append(state.wildcardRoles, "Role "+r.Namespace+"/"+r.Name)
+		}
+	}
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range clusterRoles.Items {
+		if !builtinClusterRoles[r.Name] && !strings.HasPrefix(r.Name, "system:") && hasWildcardRule(r.Rules) {
+			state.wildcardRoles = append(state.wildcardRoles, "ClusterRole "+r.Name)
+		}
+	}
+
+	if state.access, err = AnalyzeRBAC(ctx, client); err != nil {
+		return nil, err
+	}
+	if state.workloads, err = ListWorkloads(ctx, client); err != nil {
+		return nil, err
+	}
+	return state.evaluate(), nil
+}
+
+func (s *cisState) evaluate() []models.CISControl {
+	var controls []models.CISControl
+	add := func(id, title, status string, evidence []string, none string) {
+		if len(evidence) == 0 {
+			evidence = []string{none}
+		}
+		controls = append(controls, models.CISControl{ID: id, Title: title, Status: status, Evidence: evidence})
+	}
+
+	// 1.2 API server, read from the flags of the static kube-apiserver pod
+	apiFlag := func(id, title, flag string, pass func(value string, set bool) bool) {
+		if s.apiServerFlags == nil {
+			add(id, title, models.CISManual, nil, "kube-apiserver pod is not visible in kube-system; check the control plane configuration")
+			return
+		}
+		value, set := s.apiServerFlags[flag]
+		evidence := s.apiServer + ": --" + flag + " is not set"
+		if set {
+			evidence = s.apiServer + ": --" + flag + "=" + value
+		}
+		add(id, title, cisStatus(pass(value, set)), []string{evidence}, "")
+	}
+	equals := func(want string) func(string, bool) bool {
+		return func(value string, set bool) bool { return set && value == want }
+	}
+	isSet := func(value string, set bool) bool { return set && value != "" }
+	includes := func(item string) func(string, bool) bool {
+		return func(value string, set bool) bool { return listContains(value, item) }
+	}
+	excludes := func(item string) func(string, bool) bool {
+		return func(value string, set bool) bool { return !listContains(value, item) }
+	}
+
+	apiFlag("1.2.1", "Ensure that the --anonymous-auth argument is set to false", "anonymous-auth", equals("false"))
+	apiFlag("1.2.2", "Ensure that the --token-auth-file parameter is not set", "token-auth-file",
+		func(value string, set bool) bool { return !set })
+	apiFlag("1.2.6", "Ensure that the --authorization-mode argument is not set to AlwaysAllow", "authorization-mode",
+		func(value string, set bool) bool { return set && !listContains(value, "AlwaysAllow") })
+	apiFlag("1.2.7", "Ensure that the --authorization-mode argument includes Node", "authorization-mode", includes("Node"))
+	apiFlag("1.2.8", "Ensure that the --authorization-mode argument includes RBAC", "authorization-mode", includes("RBAC"))
+	apiFlag("1.2.9", "Ensure that the admission control plugin EventRateLimit is set", "enable-admission-plugins", includes("EventRateLimit"))
+	apiFlag("1.2.10", "Ensure that the admission control plugin AlwaysAdmit is not set", "enable-admission-plugins", excludes("AlwaysAdmit"))
+	apiFlag("1.2.11", "Ensure that the admission control plugin AlwaysPullImages is set", "enable-admission-plugins", includes("AlwaysPullImages"))
+	apiFlag("1.2.13", "Ensure that the admission control plugin NamespaceLifecycle is set", "disable-admission-plugins", excludes("NamespaceLifecycle"))
+	apiFlag("1.2.14", "Ensure that the admission control plugin NodeRestriction is set", "enable-admission-plugins", includes("NodeRestriction"))
+	apiFlag("1.2.15", "Ensure that the --profiling argument is set to false", "profiling", equals("false"))
+	apiFlag("1.2.16", "Ensure that the --audit-log-path argument is set", "audit-log-path", isSet)
+	apiFlag("1.2.27", "Ensure that the --encryption-provider-config argument is set as appropriate", "encryption-provider-config", isSet)
+
+	// 5.1 RBAC and service accounts
+	var admins, secretReaders, defaultAccountGrants []string
+	for _, a := range s.access {
+		if a.Subject.Kind == rbacv1.ServiceAccountKind && a.Subject.Name == "default" && len(a.Permissions) > 0 {
+			defaultAccountGrants = append(defaultAccountGrants, a.Subject.String()+" is granted permissions via "+a.Permissions[0].Via)
+		}
+		if strings.HasPrefix(a.Subject.Name, "system:") || systemNamespaces[a.Subject.Namespace] {
+			continue
+		}
+		for _, r := range a.Risks {
+			switch r.Rule {
+			case "cluster-admin":
+				admins = append(admins, a.Subject.String()+" via "+r.Via)
+			case "secrets-read":
+				secretReaders = append(secretReaders, a.Subject.String()+" via "+r.Via)
+			}
+		}
+	}
+	add("5.1.1", "Ensure that the cluster-admin role is only used where required", models.CISManual,
+		admins, "no non-system subject is bound to cluster-admin")
+	add("5.1.2", "Minimize access to secrets", cisStatus(len(secretReaders) == 0),
+		secretReaders, "no non-system subject can read secrets")
+	add("5.1.3", "Minimize wildcard use in Roles and ClusterRoles", cisStatus(len(s.wildcardRoles) == 0),
+		s.wildcardRoles, "no custom Role or ClusterRole uses wildcards")
+
+	defaultAccounts := defaultAccountGrants
+	for _, sa := range s.serviceAccounts {
+		if sa.Name == "default" && (sa.AutomountServiceAccountToken == nil || *sa.AutomountServiceAccountToken) {
+			defaultAccounts = append(defaultAccounts, "ServiceAccount "+sa.Namespace+"/default automounts its token")
+		}
+	}
+	add("5.1.5", "Ensure that default service accounts are not actively used", cisStatus(len(defaultAccounts) == 0),
+		defaultAccounts, "default service accounts have no RBAC grants and do not automount tokens")
+	add("5.1.6", "Ensure that Service Account Tokens are only mounted where necessary", models.CISManual,
+		nil, "see the automount-token findings of the secret audit")
+
+	// 5.2 Pod Security Admission and 5.3 network policies
+	var unenforced, unprotected []string
+	for _, ns := range s.namespaces {
+		if systemNamespaces[ns.Name] {
+			continue
+		}
+		if ns.Labels["pod-security.kubernetes.io/enforce"] == "" {
+			unenforced = append(unenforced, "Namespace "+ns.Name+" has no pod-security.kubernetes.io/enforce label")
+		}
+		if s.networkPolicies[ns.Name] == 0 {
+			unprotected = append(unprotected, "Namespace "+ns.Name+" has no NetworkPolicy")
+		}
+	}
+	add("5.2.1", "Ensure that the cluster has at least one active policy control mechanism in place", cisStatus(len(unenforced) == 0),
+		unenforced, "every namespace enforces a Pod Security Standards profile")
+	add("5.3.2", "Ensure that all Namespaces have NetworkPolicies defined", cisStatus(len(unprotected) == 0),
+		unprotected, "every namespace has at least one NetworkPolicy")
+
+	// 5.7.4 default namespace
+	var inDefault []string
+	for _, wl := range s.workloads {
+		if wl.Namespace == metav1.NamespaceDefault && !wl.Controlled() {
+			inDefault = append(inDefault, wl.Kind+" default/"+wl.Name)
+		}
+	}
+	for _, svc := range s.defaultServices {
+		if svc.Name != "kubernetes" {
+			inDefault = append(inDefault, "Service default/"+svc.Name)
+		}
+	}
+	add("5.7.4", "The default namespace should not be used", cisStatus(len(inDefault) == 0),
+		inDefault, "the default namespace only contains the kubernetes service")
+
+	return controls
+}
+
+// DiffCISRuns lists the controls whose status changed from one run to another
+func DiffCISRuns(from, to models.CISRun) []CISChange {
+	before := make(map[string]models.CISControl)
+	for _, c := range from.Controls {
+		before[c.ID] = c
+	}
+	var changes []CISChange
+	for _, c := range to.Controls {
+		prev, ok := before[c.ID]
+		delete(before, c.ID)
+		if ok && prev.Status == c.Status {
+			continue
+		}
+		changes = append(changes, CISChange{ID: c.ID, Title: c.Title, Before: prev.Status, After: c.Status})
+	}
+	for _, c := range from.Controls {
+		if _, removed := before[c.ID]; removed {
+			changes = append(changes, CISChange{ID: c.ID, Title: c.Title, Before: c.Status})
+		}
+	}
+	return changes
+}
+
+// CISSummary counts controls per status
+func CISSummary(controls []models.CISControl) map[string]int {
+	summary := map[string]int{models.CISPass: 0, models.CISFail: 0, models.CISManual: 0}
+	for _, c := range controls {
+		summary[c.Status]++
+	}
+	return summary
+}
+
+// commandFlags parses the --name=value arguments of the first container.
+// Flags without a value are recorded as "true".
+func commandFlags(containers []corev1.Container) map[string]string {
+	flags := make(map[string]string)
+	if len(containers) == 0 {
+		return flags
+	}
+	args := make([]string, 0, len(containers[0].Command)+len(containers[0].Args))
+	args = append(args, containers[0].Command...)
+	args = append(args, containers[0].Args...)
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !found {
+			value = "true"
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+func hasWildcardRule(rules []rbacv1.PolicyRule) bool {
+	for _, r := range rules {
+		if hasAny(r.Verbs, "*") || hasAny(r.Resources, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// listContains reports whether a comma separated flag value contains item
+func listContains(list, item string) bool {
+	for _, v := range strings.Split(list, ",") {
+		if strings.TrimSpace(v) == item {
+			return true
+		}
+	}
+	return false
+}
+
+func cisStatus(pass bool) string {
+	if pass {
+		return models.CISPass
+	}
+	return models.CISFail
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func apiServerPod(args ...string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-apiserver-cp1", Labels: map[string]string{"component": "kube-apiserver"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "kube-apiserver", Command: append([]string{"kube-apiserver"}, args...),
+		}}},
+	}
+}
+
+func runCIS(t *testing.T, objects ...runtime.Object) map[string]models.CISControl {
+	t.Helper()
+	controls, err := RunCISBenchmark(context.Background(), fake.NewClientset(objects...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]models.CISControl)
+	for _, c := range controls {
+		if _, dup := byID[c.ID]; dup {
+			t.Errorf("control %s reported twice", c.ID)
+		}
+		if c.Title == "" || len(c.Evidence) == 0 {
+			t.Errorf("control %+v has no title or evidence", c)
+		}
+		byID[c.ID] = c
+	}
+	return byID
+}
+
+func TestRunCISBenchmarkHardened(t *testing.T) {
+	no := false
+	controls := runCIS(t,
+		apiServerPod("--anonymous-auth=false", "--authorization-mode=Node,RBAC",
+			"--enable-admission-plugins=NodeRestriction,EventRateLimit,AlwaysPullImages",
+			"--profiling=false", "--audit-log-path=/var/log/audit.log", "--encryption-provider-config=/etc/kubernetes/enc.yaml"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"pod-security.kubernetes.io/enforce": "restricted"}}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default"}, AutomountServiceAccountToken: &no},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default-deny"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"}},
+		// the built-in cluster-admin role may use wildcards
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+	)
+
+	for id, c := range controls {
+		want := models.CISPass
+		if id == "5.1.1" || id == "5.1.6" {
+			want = models.CISManual
+		}
+		if c.Status != want {
+			t.Errorf("control %s = %s %v, want %s", id, c.Status, c.Evidence, want)
+		}
+	}
+	if got := controls["1.2.6"].Evidence; !slices.Equal(got, []string{"kube-system/kube-apiserver-cp1: --authorization-mode=Node,RBAC"}) {
+		t.Errorf("1.2.6 evidence = %v", got)
+	}
+	if got := controls["1.2.2"].Evidence; !slices.Equal(got, []string{"kube-system/kube-apiserver-cp1: --token-auth-file is not set"}) {
+		t.Errorf("1.2.2 evidence = %v", got)
+	}
+}
+
+func TestRunCISBenchmarkWeak(t *testing.T) {
+	controls := runCIS(t,
+		apiServerPod("--anonymous-auth=true", "--authorization-mode=AlwaysAllow", "--token-auth-file=/etc/tokens.csv",
+			"--enable-admission-plugins=AlwaysAdmit", "--disable-admission-plugins=NamespaceLifecycle", "--profiling"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "scratch"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "scratch"}},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "everything"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default-everything"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "web", Name: "default"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "everything"},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "ops"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		},
+	)
+
+	for _, tc := range []struct {
+		id, status string
+		evidence   []string // prefixes, in order
+	}{
+		{"1.2.1", models.CISFail, []string{"kube-system/kube-apiserver-cp1: --anonymous-auth=true"}},
+		{"1.2.2", models.CISFail, []string{"kube-system/kube-apiserver-cp1: --token-auth-file=/etc/tokens.csv"}},
+		{"1.2.6", models.CISFail, nil},
+		{"1.2.7", models.CISFail, nil},
+		{"1.2.8", models.CISFail, nil},
+		{"1.2.10", models.CISFail, nil},
+		{"1.2.13", models.CISFail, nil},
+		{"1.2.15", models.CISFail, []string{"kube-system/kube-apiserver-cp1: --profiling=true"}},
+		{"1.2.16", models.CISFail, []string{"kube-system/kube-apiserver-cp1: --audit-log-path is not set"}},
+		{"5.1.1", models.CISManual, []string{"User ops via "}},
+		{"5.1.2", models.CISFail, []string{"ServiceAccount web/default via "}},
+		{"5.1.3", models.CISFail, []string{"Role web/everything"}},
+		{"5.1.5", models.CISFail, []string{"ServiceAccount web/default is granted permissions via ", "ServiceAccount web/default automounts its token"}},
+		{"5.2.1", models.CISFail, []string{"Namespace default has", "Namespace web has"}},
+		{"5.3.2", models.CISFail, []string{"Namespace default has", "Namespace web has"}},
+		{"5.7.4", models.CISFail, []string{"Pod default/scratch", "Service default/scratch"}},
+	} {
+		c, ok := controls[tc.id]
+		if !ok {
+			t.Errorf("control %s missing", tc.id)
+			continue
+		}
+		if c.Status != tc.status {
+			t.Errorf("control %s = %s %v, want %s", tc.id, c.Status, c.Evidence, tc.status)
+		}
+		if tc.evidence == nil {
+			continue
+		}
+		ok = len(c.Evidence) == len(tc.evidence)
+		for i := 0; ok && i < len(tc.evidence); i++ {
+			ok = strings.HasPrefix(c.Evidence[i], tc.evidence[i])
+		}
+		if !ok {
+			t.Errorf("control %s evidence = %q, want %q", tc.id, c.Evidence, tc.evidence)
+		}
+	}
+}
+
+func TestRunCISBenchmarkWithoutAPIServer(t *testing.T) {
+	controls := runCIS(t)
+	for id, c := range controls {
+		if strings.HasPrefix(id, "1.2.") && c.Status != models.CISManual {
+			t.Errorf("control %s = %s, want manual when the API server pod is not visible", id, c.Status)
+		}
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	got := commandFlags([]corev1.Container{
+		{Command: []string{"kube-apiserver", "--profiling"}, Args: []string{"--authorization-mode=Node,RBAC", "-v=2", "--audit-log-path="}},
+		{Command: []string{"sidecar", "--ignored=true"}},
+	})
+	want := map[string]string{"profiling": "true", "authorization-mode": "Node,RBAC", "audit-log-path": ""}
+	if !maps.Equal(got, want) {
+		t.Errorf("commandFlags = %v, want %v", got, want)
+	}
+}
+
+func TestDiffCISRuns(t *testing.T) {
+	run := func(statuses ...string) models.CISRun {
+		var r models.CISRun
+		for i := 0; i < len(statuses); i += 2 {
+			r.Controls = append(r.Controls, models.CISControl{ID: statuses[i], Title: "control " + statuses[i], Status: statuses[i+1]})
+		}
+		return r
+	}
+	from := run("1.2.1", models.CISFail, "1.2.2", models.CISPass, "5.1.1", models.CISManual, "5.1.3", models.CISPass)
+	to := run("1.2.1", models.CISPass, "1.2.2", models.CISPass, "5.1.1", models.CISManual, "5.7.4", models.CISFail)
+
+	want := []CISChange{
+		{ID: "1.2.1", Title: "control 1.2.1", Before: models.CISFail, After: models.CISPass},
+		{ID: "5.7.4", Title: "control 5.7.4", After: models.CISFail},
+		{ID: "5.1.3", Title: "control 5.1.3", Before: models.CISPass},
+	}
+	if got := DiffCISRuns(from, to); !slices.Equal(got, want) {
+		t.Errorf("DiffCISRuns = %+v, want %+v", got, want)
+	}
+	if got := DiffCISRuns(to, to); len(got) != 0 {
+		t.Errorf("a run differs from itself: %+v", got)
+	}
+}
+
+func TestCISSummary(t *testing.T) {
+	got := CISSummary([]models.CISControl{{Status: models.CISPass}, {Status: models.CISFail}, {Status: models.CISFail}})
+	want := map[string]int{models.CISPass: 1, models.CISFail: 2, models.CISManual: 0}
+	if !maps.Equal(got, want) {
+		t.Errorf("CISSummary = %v, want %v", got, want)
+	}
+}
@@ -47,6 +47,20 @@ func (m *ClusterManager) GetClient(clusterID, kubeConfigData string) (*kubernete
 	return newClient, nil
 }
 
+// AddClient caches an already verified clientset for a cluster
+func (m *ClusterManager) AddClient(clusterID string, client *kubernetes.Clientset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[clusterID] = client
+}
+
+// RemoveClient drops the cached clientset of a cluster
+func (m *ClusterManager) RemoveClient(clusterID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, clusterID)
+}
+
 // NewClientFromConfig creates a K8s clientset from a raw KubeConfig string
 func NewClientFromConfig(kubeConfigData string) (*kubernetes.Clientset, error) {
 	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeConfigData))
@@ -0,0 +1,157 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// NewDryRunClient returns a fake clientset seeded with copies of the object
+// and of everything an action on it may touch: for a pod its node and the
+// pods on that node, for a node its pods. Actions run against it behave like
+// on the cluster, which is left unchanged.
+func NewDryRunClient(ctx context.Context, client kubernetes.Interface, kind, namespace, name string) (kubernetes.Interface, error) {
+	var objects []runtime.Object
+	nodeName := ""
+	switch kind {
+	case "Pod":
+		pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		nodeName = pod.Spec.NodeName
+		if nodeName == "" {
+			objects = append(objects, pod)
+		}
+	case "Node":
+		nodeName = name
+	case "Deployment":
+		d, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, d)
+	case "StatefulSet":
+		s, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, s)
+	case "RoleBinding":
+		rb, err := client.RbacV1().RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, rb)
+	default:
+		return nil, fmt.Errorf("no dry run for %s objects", kind)
+	}
+
+	if nodeName != "" {
+		node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, node)
+		pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			objects = append(objects, &pods.Items[i])
+		}
+	}
+
+	for _, obj := range objects {
+		if m, ok := obj.(metav1.Object); ok {
+			m.SetManagedFields(nil)
+		}
+	}
+	fc := fake.NewClientset(objects...)
+	addScaleReactors(fc)
+	addEvictionReactor(fc)
+	return fc, nil
+}
+
+// addScaleReactors serves the scale subresource of Deployments and
+// StatefulSets from their spec.replicas, which the fake tracker does not do
+func addScaleReactors(fc *fake.Clientset) {
+	for _, resource := range []string{"deployments", "statefulsets"} {
+		gvr := appsv1.SchemeGroupVersion.WithResource(resource)
+		fc.PrependReactor("get", resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+			get := action.(clienttesting.GetAction)
+			if get.GetSubresource() != "scale" {
+				return false, nil, nil
+			}
+			obj, err := fc.Tracker().Get(gvr, get.GetNamespace(), get.GetName())
+			if err != nil {
+				return true, nil, err
+			}
+			replicas := replicasOf(obj)
+			if replicas == nil {
+				return true, nil, fmt.Errorf("%s %s has no replicas", resource, get.GetName())
+			}
+			return true, &autoscalingv1.Scale{
+				ObjectMeta: metav1.ObjectMeta{Name: get.GetName(), Namespace: get.GetNamespace()},
+				Spec:       autoscalingv1.ScaleSpec{Replicas: *replicas},
+			}, nil
+		})
+		fc.PrependReactor("update", resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+			update := action.(clienttesting.UpdateAction)
+			scale, ok := update.GetObject().(*autoscalingv1.Scale)
+			if update.GetSubresource() != "scale" || !ok {
+				return false, nil, nil
+			}
+			obj, err := fc.Tracker().Get(gvr, update.GetNamespace(), scale.Name)
+			if err != nil {
+				return true, nil, err
+			}
+			replicas := replicasOf(obj)
+			if replicas == nil {
+				return true, nil, fmt.Errorf("%s %s has no replicas", resource, scale.Name)
+			}
+			*replicas = scale.Spec.Replicas
+			return true, scale, fc.Tracker().Update(gvr, obj, update.GetNamespace())
+		})
+	}
+}
+
+func replicasOf(obj runtime.Object) *int32 {
+	var replicas **int32
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		replicas = &o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		replicas = &o.Spec.Replicas
+	default:
+		return nil
+	}
+	if *replicas == nil {
+		one := int32(1)
+		*replicas = &one
+	}
+	return *replicas
+}
+
+// addEvictionReactor deletes an evicted pod; the fake tracker would otherwise
+// ignore the eviction
+func addEvictionReactor(fc *fake.Clientset) {
+	pods := corev1.SchemeGroupVersion.WithResource("pods")
+	fc.PrependReactor("create", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		create := action.(clienttesting.CreateAction)
+		eviction, ok := create.GetObject().(*policyv1.Eviction)
+		if create.GetSubresource() != "eviction" || !ok {
+			return false, nil, nil
+		}
+		return true, nil, fc.Tracker().Delete(pods, eviction.Namespace, eviction.Name)
+	})
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func nodePod(namespace, name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "app", Image: "app:1"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestDryRunScaleLeavesClusterUnchanged(t *testing.T) {
+	ctx := context.Background()
+	replicas := int32(3)
+	real := fake.NewClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "db"}},
+	)
+	for _, target := range []struct{ kind, name string }{{"Deployment", "api"}, {"StatefulSet", "db"}} {
+		dry, err := NewDryRunClient(ctx, real, target.kind, "web", target.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := ExecuteAction(ctx, dry, models.ResponseAction{Type: models.ActionScaleToZero, Kind: target.kind, Namespace: "web", Name: target.name})
+		if err != nil {
+			t.Fatalf("scaling %s: %v", target.kind, err)
+		}
+		if out.Before == "" || out.After == "" || out.Before == out.After {
+			t.Errorf("%s outcome = %+v, want differing before and after", target.kind, out)
+		}
+	}
+
+	dry, err := NewDryRunClient(ctx, real, "Deployment", "web", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteAction(ctx, dry, models.ResponseAction{Type: models.ActionScaleToZero, Kind: "Deployment", Namespace: "web", Name: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := dry.AppsV1().Deployments("web").Get(ctx, "api", metav1.GetOptions{}); *d.Spec.Replicas != 0 {
+		t.Errorf("dry run deployment has %d replicas, want 0", *d.Spec.Replicas)
+	}
+	if d, _ := real.AppsV1().Deployments("web").Get(ctx, "api", metav1.GetOptions{}); *d.Spec.Replicas != 3 {
+		t.Errorf("cluster deployment has %d replicas, want 3", *d.Spec.Replicas)
+	}
+	if s, _ := real.AppsV1().StatefulSets("web").Get(ctx, "db", metav1.GetOptions{}); s.Spec.Replicas != nil {
+		t.Errorf("cluster statefulset replicas set to %d", *s.Spec.Replicas)
+	}
+}
+
+func TestDryRunDrainLeavesClusterUnchanged(t *testing.T) {
+	ctx := context.Background()
+	real := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}},
+		nodePod("web", "api", "n1"),
+		nodePod("jobs", "worker", "n1"),
+	)
+	dry, err := NewDryRunClient(ctx, real, "Pod", "web", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ExecuteAction(ctx, dry, models.ResponseAction{Type: models.ActionDrainNode, Kind: "Node", Name: "n1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Cordoned node n1, evicted 2 pods, skipped 0"; out.Result != want {
+		t.Errorf("result = %q, want %q", out.Result, want)
+	}
+
+	// the eviction reactor removed the pods from the dry run only
+	if _, err := dry.CoreV1().Pods("web").Get(ctx, "api", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("evicted pod still in the dry run: %v", err)
+	}
+	for _, p := range []struct{ namespace, name string }{{"web", "api"}, {"jobs", "worker"}} {
+		if _, err := real.CoreV1().Pods(p.namespace).Get(ctx, p.name, metav1.GetOptions{}); err != nil {
+			t.Errorf("cluster pod %s/%s: %v", p.namespace, p.name, err)
+		}
+	}
+	if n, _ := real.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{}); n.Spec.Unschedulable {
+		t.Error("dry run cordoned the cluster's node")
+	}
+}
+
+func TestDryRunUnknownKind(t *testing.T) {
+	if _, err := NewDryRunClient(context.Background(), fake.NewClientset(), "ConfigMap", "web", "x"); err == nil {
+		t.Error("dry run of a ConfigMap succeeded")
+	}
+}
@@ -0,0 +1,221 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Limits on the logs kept per container
+const (
+	evidenceLogLines = 1000
+	evidenceLogBytes = 5 << 20
+)
+
+// OwnerLink is one step of a pod's owner chain, from the pod outwards
+type OwnerLink struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+	Controller bool   `json:"controller"`
+}
+
+// CollectEvidence gathers what is needed to investigate a pod after it is
+// gone: the pod YAML, current and previous logs of every container, the
+// pod's Events, its owner chain, its node and the ConfigMaps it mounts. The
+// result maps file paths to contents. Parts that cannot be collected are
+// reported in errs; Events outlive the pod, so a deleted pod still yields a
+// bundle. err is only set when nothing could be collected.
+func CollectEvidence(ctx context.Context, client kubernetes.Interface, namespace, name string) (files map[string][]byte, errs []string, err error) {
+	files = make(map[string][]byte)
+	add := func(path string, obj interface{}) {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			return
+		}
+		files[path] = data
+	}
+
+	events, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + name,
+	})
+	if err != nil {
+		errs = append(errs, "events: "+err.Error())
+		events = &corev1.EventList{}
+	} else {
+		sort.Slice(events.Items, func(i, j int) bool { return eventTime(events.Items[i]).Before(eventTime(events.Items[j])) })
+		add("events.yaml", events.Items)
+	}
+
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		errs = append(errs, "pod: "+err.Error())
+		if len(events.Items) == 0 {
+			return nil, errs, fmt.Errorf("no evidence for pod %s/%s: %s", namespace, name, strings.Join(errs, "; "))
+		}
+		return files, errs, nil
+	}
+	pod.APIVersion, pod.Kind = "v1", "Pod"
+	add("pod.yaml", pod)
+
+	containers := append(append([]corev1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		for _, previous := range []bool{false, true} {
+			path := "logs/" + c.Name + ".log"
+			if previous {
+				path = "logs/" + c.Name + ".previous.log"
+				if !restarted(pod, c.Name) {
+					continue
+				}
+			}
+			lines, limit := int64(evidenceLogLines), int64(evidenceLogBytes)
+			data, err := client.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{
+				Container: c.Name, Previous: previous, TailLines: &lines, LimitBytes: &limit, Timestamps: true,
+			}).DoRaw(ctx)
+			if err != nil {
+				errs = append(errs, path+": "+err.Error())
+				continue
+			}
+			files[path] = data
+		}
+	}
+
+	owners, err := ownerChain(ctx, client, namespace, pod.OwnerReferences)
+	if err != nil {
+		errs = append(errs, "owners: "+err.Error())
+	}
+	add("owners.yaml", owners)
+
+	if pod.Spec.NodeName != "" {
+		node, err := client.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			errs = append(errs, "node: "+err.Error())
+		} else {
+			node.APIVersion, node.Kind = "v1", "Node"
+			add("node.yaml", node)
+		}
+	}
+
+	if names := configMapNames(pod); len(names) > 0 {
+		files["configmaps.txt"] = []byte(strings.Join(names, "\n") + "\n")
+	}
+	return files, errs, nil
+}
+
+func eventTime(ev corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+func restarted(pod *corev1.Pod, container string) bool {
+	statuses := append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.Name == container {
+			return s.RestartCount > 0 || s.LastTerminationState.Terminated != nil
+		}
+	}
+	return false
+}
+
+// ownerChain follows controller references from the pod up to the top level
+// workload, e.g. ReplicaSet then Deployment, or Job then CronJob
+func ownerChain(ctx context.Context, client kubernetes.Interface, namespace string, refs []metav1.OwnerReference) ([]OwnerLink, error) {
+	var chain []OwnerLink
+	for depth := 0; len(refs) > 0 && depth < 10; depth++ {
+		var next []metav1.OwnerReference
+		for _, ref := range refs {
+			chain = append(chain, OwnerLink{Kind: ref.Kind, Name: ref.Name, UID: string(ref.UID), Controller: ref.Controller != nil && *ref.Controller})
+			if ref.Controller == nil || !*ref.Controller {
+				continue
+			}
+			obj, err := getOwner(ctx, client, namespace, ref)
+			if err != nil {
+				return chain, err
+			}
+			if obj != nil {
+				next = obj.GetOwnerReferences()
+			}
+		}
+		refs = next
+	}
+	return chain, nil
+}
+
+// getOwner fetches the owner kinds that can themselves be owned; others
+// end the chain with a nil object
+func getOwner(ctx context.Context, client kubernetes.Interface, namespace string, ref metav1.OwnerReference) (metav1.Object, error) {
+	opts := metav1.GetOptions{}
+	var obj runtime.Object
+	var err error
+	switch ref.Kind {
+	case "ReplicaSet":
+		obj, err = client.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, opts)
+	case "Job":
+		obj, err = client.BatchV1().Jobs(namespace).Get(ctx, ref.Name, opts)
+	case "Deployment":
+		obj, err = client.AppsV1().Deployments(namespace).Get(ctx, ref.Name, opts)
+	case "StatefulSet":
+		obj, err = client.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, opts)
+	case "DaemonSet":
+		obj, err = client.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, opts)
+	case "CronJob":
+		obj, err = client.BatchV1().CronJobs(namespace).Get(ctx, ref.Name, opts)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", ref.Kind, ref.Name, err)
+	}
+	return obj.(metav1.Object), nil
+}
+
+// configMapNames lists the ConfigMaps a pod mounts as volumes, projects or
+// reads into its environment
+func configMapNames(pod *corev1.Pod) []string {
+	seen := make(map[string]bool)
+	for _, v := range pod.Spec.Volumes {
+		if v.ConfigMap != nil {
+			seen[v.ConfigMap.Name] = true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.ConfigMap != nil {
+					seen[src.ConfigMap.Name] = true
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				seen[from.ConfigMapRef.Name] = true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				seen[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
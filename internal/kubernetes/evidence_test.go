@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	yes := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, UID: types.UID("uid-" + name), Controller: &yes}}
+}
+
+func podEvent(name, reason string, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "web", Name: name},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "web", Name: "api-7d9f-x2"},
+		Reason:         reason,
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestCollectEvidence(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api-7d9f-x2", OwnerReferences: controllerRef("ReplicaSet", "api-7d9f")},
+		Spec: corev1.PodSpec{
+			NodeName:       "n1",
+			InitContainers: []corev1.Container{{Name: "migrate"}},
+			Containers: []corev1.Container{
+				{Name: "app", EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-env"}}}}},
+				{Name: "sidecar", Env: []corev1.EnvVar{{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "logging"}, Key: "level"},
+				}}}},
+			},
+			Volumes: []corev1.Volume{
+				{Name: "conf", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-conf"}}}},
+				{Name: "bundle", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"}}},
+				}}}},
+			},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "app", RestartCount: 3},
+			{Name: "sidecar"},
+		}},
+	}
+	client := fake.NewClientset(
+		pod,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api-7d9f", OwnerReferences: controllerRef("Deployment", "api")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}},
+		podEvent("e2", "BackOff", now),
+		podEvent("e1", "Started", now.Add(-time.Minute)),
+	)
+
+	files, errs, err := CollectEvidence(context.Background(), client, "web", "api-7d9f-x2")
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("err = %v, errs = %v", err, errs)
+	}
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	// only the restarted container has previous logs
+	want := []string{
+		"configmaps.txt", "events.yaml",
+		"logs/app.log", "logs/app.previous.log", "logs/migrate.log", "logs/sidecar.log",
+		"node.yaml", "owners.yaml", "pod.yaml",
+	}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("files = %v, want %v", paths, want)
+	}
+
+	var events []corev1.Event
+	if err := yaml.Unmarshal(files["events.yaml"], &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Reason != "Started" || events[1].Reason != "BackOff" {
+		t.Errorf("events not in time order: %+v", events)
+	}
+
+	var owners []OwnerLink
+	if err := yaml.Unmarshal(files["owners.yaml"], &owners); err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 2 || owners[0].Kind != "ReplicaSet" || owners[1].Kind != "Deployment" || owners[1].Name != "api" || !owners[1].Controller {
+		t.Errorf("owners = %+v, want ReplicaSet api-7d9f then Deployment api", owners)
+	}
+
+	if got := string(files["configmaps.txt"]); got != "api-conf\napi-env\nkube-root-ca.crt\nlogging\n" {
+		t.Errorf("configmaps.txt = %q", got)
+	}
+	for path, kind := range map[string]string{"pod.yaml": "kind: Pod", "node.yaml": "kind: Node"} {
+		if !strings.Contains(string(files[path]), kind) {
+			t.Errorf("%s does not say %q", path, kind)
+		}
+	}
+}
+
+func TestCollectEvidenceMissingOwner(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api-7d9f-x2", OwnerReferences: controllerRef("ReplicaSet", "api-7d9f")},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	files, errs, err := CollectEvidence(context.Background(), fake.NewClientset(pod), "web", "api-7d9f-x2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "owners: ReplicaSet api-7d9f") {
+		t.Errorf("errs = %v, want the missing ReplicaSet reported", errs)
+	}
+	var owners []OwnerLink
+	if err := yaml.Unmarshal(files["owners.yaml"], &owners); err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || owners[0].Name != "api-7d9f" {
+		t.Errorf("owners = %+v, want the chain up to the missing owner", owners)
+	}
+}
+
+func TestCollectEvidenceDeletedPod(t *testing.T) {
+	client := fake.NewClientset(podEvent("e1", "Killing", time.Now()))
+	files, errs, err := CollectEvidence(context.Background(), client, "web", "api-7d9f-x2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files["events.yaml"] == nil {
+		t.Errorf("files = %v, want the Events only", files)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "pod: ") {
+		t.Errorf("errs = %v, want the missing pod reported", errs)
+	}
+}
+
+func TestCollectEvidenceNothing(t *testing.T) {
+	files, _, err := CollectEvidence(context.Background(), fake.NewClientset(), "web", "api-7d9f-x2")
+	if err == nil || files != nil {
+		t.Errorf("files = %v, err = %v, want an error", files, err)
+	}
+}
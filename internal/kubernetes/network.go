@@ -0,0 +1,316 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NetworkNode is a pod, or a namespace in the namespace-level graph
+type NetworkNode struct {
+	ID                  string   `json:"id"`
+	Namespace           string   `json:"namespace"`
+	Name                string   `json:"name,omitempty"`
+	IngressDefaultAllow bool     `json:"ingress_default_allow"`
+	EgressDefaultAllow  bool     `json:"egress_default_allow"`
+	IngressPolicies     []string `json:"ingress_policies,omitempty"`
+	EgressPolicies      []string `json:"egress_policies,omitempty"`
+	ReachableFrom       []string `json:"reachable_from,omitempty"`       // pod IDs allowed to connect
+	ReachableNamespaces []string `json:"reachable_namespaces,omitempty"` // namespaces with at least one such pod
+	ExternalIngress     []string `json:"external_ingress,omitempty"`     // CIDRs outside the cluster allowed in
+}
+
+// NetworkEdge means From may open connections to To
+type NetworkEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NetworkFinding is a namespace-level network isolation gap
+type NetworkFinding struct {
+	Namespace string `json:"namespace"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// NetworkGraph is the reachability graph of a cluster
+type NetworkGraph struct {
+	Nodes    []NetworkNode    `json:"nodes"`
+	Edges    []NetworkEdge    `json:"edges"`
+	Findings []NetworkFinding `json:"findings"`
+}
+
+// AnalyzeNetwork loads NetworkPolicies, Pods and Namespaces of a cluster and
+// computes the pod-level reachability graph
+func AnalyzeNetwork(ctx context.Context, client kubernetes.Interface) (*NetworkGraph, error) {
+	opts := metav1.ListOptions{}
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := client.NetworkingV1().NetworkPolicies("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return BuildNetworkGraph(namespaces.Items, pods.Items, policies.Items), nil
+}
+
+type podPolicies struct {
+	pod     *corev1.Pod
+	ingress []*networkingv1.NetworkPolicy
+	egress  []*networkingv1.NetworkPolicy
+}
+
+// BuildNetworkGraph computes reachability from already loaded objects. Ports
+// are not considered: an edge means some port is reachable.
+func BuildNetworkGraph(namespaces []corev1.Namespace, pods []corev1.Pod, policies []networkingv1.NetworkPolicy) *NetworkGraph {
+	nsLabels := make(map[string]labels.Set)
+	for _, ns := range namespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+
+	// Host-network and finished pods are not subject to NetworkPolicy.
+	var selected []*podPolicies
+	for i := range pods {
+		p := &pods[i]
+		if p.Spec.HostNetwork || p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		pp := &podPolicies{pod: p}
+		for j := range policies {
+			np := &policies[j]
+			if np.Namespace != p.Namespace || !selectorMatches(&np.Spec.PodSelector, p.Labels) {
+				continue
+			}
+			if hasPolicyType(np, networkingv1.PolicyTypeIngress) {
+				pp.ingress = append(pp.ingress, np)
+			}
+			if hasPolicyType(np, networkingv1.PolicyTypeEgress) {
+				pp.egress = append(pp.egress, np)
+			}
+		}
+		selected = append(selected, pp)
+	}
+
+	graph := &NetworkGraph{}
+	for _, dst := range selected {
+		node := NetworkNode{
+			ID:                  podID(dst.pod),
+			Namespace:           dst.pod.Namespace,
+			Name:                dst.pod.Name,
+			IngressDefaultAllow: len(dst.ingress) == 0,
+			EgressDefaultAllow:  len(dst.egress) == 0,
+			IngressPolicies:     policyNames(dst.ingress),
+			EgressPolicies:      policyNames(dst.egress),
+			ExternalIngress:     externalIngress(dst.ingress),
+		}
+		reachableNS := make(map[string]bool)
+		for _, src := range selected {
+			if src == dst {
+				continue
+			}
+			if ingressAllows(dst, src.pod, nsLabels) && egressAllows(src, dst.pod, nsLabels) {
+				node.ReachableFrom = append(node.ReachableFrom, podID(src.pod))
+				reachableNS[src.pod.Namespace] = true
+				graph.Edges = append(graph.Edges, NetworkEdge{From: podID(src.pod), To: node.ID})
+			}
+		}
+		node.ReachableNamespaces = sortedKeys(reachableNS)
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, ns := range namespaces {
+		var ingressDeny, egressDeny bool
+		for j := range policies {
+			np := &policies[j]
+			if np.Namespace != ns.Name || len(np.Spec.PodSelector.MatchLabels) > 0 || len(np.Spec.PodSelector.MatchExpressions) > 0 {
+				continue
+			}
+			if hasPolicyType(np, networkingv1.PolicyTypeIngress) && len(np.Spec.Ingress) == 0 {
+				ingressDeny = true
+			}
+			if hasPolicyType(np, networkingv1.PolicyTypeEgress) && len(np.Spec.Egress) == 0 {
+				egressDeny = true
+			}
+		}
+		if !ingressDeny {
+			graph.Findings = append(graph.Findings, NetworkFinding{Namespace: ns.Name, Severity: models.SeverityMedium,
+				Message: "namespace has no default-deny ingress NetworkPolicy"})
+		}
+		if !egressDeny {
+			graph.Findings = append(graph.Findings, NetworkFinding{Namespace: ns.Name, Severity: models.SeverityLow,
+				Message: "namespace has no default-deny egress NetworkPolicy"})
+		}
+	}
+	return graph
+}
+
+// NamespaceGraph collapses a pod-level graph into namespaces. A namespace is
+// default-allow when any of its pods is.
+func NamespaceGraph(g *NetworkGraph) *NetworkGraph {
+	nodes := make(map[string]*NetworkNode)
+	var order []string
+	podNS := make(map[string]string)
+	for _, n := range g.Nodes {
+		podNS[n.ID] = n.Namespace
+		ns, ok := nodes[n.Namespace]
+		if !ok {
+			ns = &NetworkNode{ID: n.Namespace, Namespace: n.Namespace}
+			nodes[n.Namespace] = ns
+			order = append(order, n.Namespace)
+		}
+		ns.IngressDefaultAllow = ns.IngressDefaultAllow || n.IngressDefaultAllow
+		ns.EgressDefaultAllow = ns.EgressDefaultAllow || n.EgressDefaultAllow
+	}
+
+	seen := make(map[NetworkEdge]bool)
+	reachable := make(map[string]map[string]bool)
+	out := &NetworkGraph{Findings: g.Findings}
+	for _, e := range g.Edges {
+		edge := NetworkEdge{From: podNS[e.From], To: podNS[e.To]}
+		if seen[edge] {
+			continue
+		}
+		seen[edge] = true
+		out.Edges = append(out.Edges, edge)
+		if reachable[edge.To] == nil {
+			reachable[edge.To] = make(map[string]bool)
+		}
+		reachable[edge.To][edge.From] = true
+	}
+	sort.Strings(order)
+	for _, ns := range order {
+		n := nodes[ns]
+		n.ReachableNamespaces = sortedKeys(reachable[ns])
+		out.Nodes = append(out.Nodes, *n)
+	}
+	return out
+}
+
+// ingressAllows reports whether the ingress policies of dst admit src.
+// An unisolated pod admits everything; otherwise any rule may allow it.
+func ingressAllows(dst *podPolicies, src *corev1.Pod, nsLabels map[string]labels.Set) bool {
+	if len(dst.ingress) == 0 {
+		return true
+	}
+	for _, np := range dst.ingress {
+		for _, rule := range np.Spec.Ingress {
+			if len(rule.From) == 0 || peersMatch(rule.From, np.Namespace, src, nsLabels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func egressAllows(src *podPolicies, dst *corev1.Pod, nsLabels map[string]labels.Set) bool {
+	if len(src.egress) == 0 {
+		return true
+	}
+	for _, np := range src.egress {
+		for _, rule := range np.Spec.Egress {
+			if len(rule.To) == 0 || peersMatch(rule.To, np.Namespace, dst, nsLabels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNS string, pod *corev1.Pod, nsLabels map[string]labels.Set) bool {
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			continue
+		}
+		if peer.NamespaceSelector == nil {
+			if pod.Namespace == policyNS && selectorMatches(peer.PodSelector, pod.Labels) {
+				return true
+			}
+			continue
+		}
+		if !selectorMatches(peer.NamespaceSelector, nsLabels[pod.Namespace]) {
+			continue
+		}
+		if peer.PodSelector == nil || selectorMatches(peer.PodSelector, pod.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func externalIngress(policies []*networkingv1.NetworkPolicy) []string {
+	if len(policies) == 0 {
+		return []string{"0.0.0.0/0"}
+	}
+	seen := make(map[string]bool)
+	for _, np := range policies {
+		for _, rule := range np.Spec.Ingress {
+			if len(rule.From) == 0 {
+				seen["0.0.0.0/0"] = true
+			}
+			for _, peer := range rule.From {
+				if peer.IPBlock != nil {
+					seen[peer.IPBlock.CIDR] = true
+				}
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func selectorMatches(sel *metav1.LabelSelector, set labels.Set) bool {
+	if sel == nil {
+		return true
+	}
+	s, err := metav1.LabelSelectorAsSelector(sel)
+	if err != nil {
+		return false
+	}
+	return s.Matches(set)
+}
+
+func hasPolicyType(np *networkingv1.NetworkPolicy, t networkingv1.PolicyType) bool {
+	// Without explicit policyTypes, Ingress always applies and Egress applies
+	// only when egress rules are present.
+	if len(np.Spec.PolicyTypes) == 0 {
+		return t == networkingv1.PolicyTypeIngress || len(np.Spec.Egress) > 0
+	}
+	for _, pt := range np.Spec.PolicyTypes {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func policyNames(policies []*networkingv1.NetworkPolicy) []string {
+	names := make([]string, len(policies))
+	for i, np := range policies {
+		names[i] = np.Name
+	}
+	return names
+}
+
+func podID(p *corev1.Pod) string {
+	return p.Namespace + "/" + p.Name
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func labelled(namespace, name, app string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": app}}}
+}
+
+func selector(app string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+}
+
+func teamSelector(team string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{"team": team}}
+}
+
+// testNetwork is a cluster where db denies everything but the API pods of
+// the web namespace and anything in monitoring, and web admits only its
+// frontend to the API
+func testNetwork() []runtime.Object {
+	ns := func(name, team string) *corev1.Namespace {
+		n := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if team != "" {
+			n.Labels = map[string]string{"team": team}
+		}
+		return n
+	}
+	host := labelled("web", "host-agent", "agent")
+	host.Spec.HostNetwork = true
+	done := labelled("web", "migrate", "api")
+	done.Status.Phase = corev1.PodSucceeded
+	both := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+
+	return []runtime.Object{
+		ns("web", "web"), ns("db", "db"), ns("monitoring", "ops"), ns("open", ""),
+		labelled("web", "frontend", "frontend"),
+		labelled("web", "api", "api"),
+		labelled("db", "postgres", "postgres"),
+		labelled("monitoring", "prom", "prom"),
+		labelled("open", "misc", "misc"),
+		host, done,
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "default-deny"},
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: both},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "allow-clients"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: *selector("postgres"),
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{
+					{NamespaceSelector: teamSelector("web"), PodSelector: selector("api")},
+					{NamespaceSelector: teamSelector("ops")},
+				}}},
+			},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default-deny-ingress"},
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "allow-frontend"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: *selector("api"),
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: selector("frontend")},
+					{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+				}}},
+			},
+		},
+	}
+}
+
+func TestAnalyzeNetwork(t *testing.T) {
+	graph, err := AnalyzeNetwork(context.Background(), fake.NewClientset(testNetwork()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		from            []string
+		ingress, egress bool // default allow
+		external        []string
+		ingressPolicies []string
+		namespaces      []string
+	}{
+		"web/frontend":    {nil, false, true, []string{}, []string{"default-deny-ingress"}, []string{}},
+		"web/api":         {[]string{"web/frontend"}, false, true, []string{"10.0.0.0/8"}, []string{"default-deny-ingress", "allow-frontend"}, []string{"web"}},
+		"db/postgres":     {[]string{"monitoring/prom", "web/api"}, false, false, []string{}, []string{"allow-clients", "default-deny"}, []string{"monitoring", "web"}},
+		"monitoring/prom": {[]string{"open/misc", "web/api", "web/frontend"}, true, true, []string{"0.0.0.0/0"}, []string{}, []string{"open", "web"}},
+		"open/misc":       {[]string{"monitoring/prom", "web/api", "web/frontend"}, true, true, []string{"0.0.0.0/0"}, []string{}, []string{"monitoring", "web"}},
+	}
+	if len(graph.Nodes) != len(want) {
+		t.Errorf("got %d nodes, want %d: host-network and finished pods are left out", len(graph.Nodes), len(want))
+	}
+	for _, n := range graph.Nodes {
+		w, ok := want[n.ID]
+		if !ok {
+			t.Errorf("unexpected node %s", n.ID)
+			continue
+		}
+		from := slices.Sorted(slices.Values(n.ReachableFrom))
+		if !slices.Equal(from, w.from) {
+			t.Errorf("%s reachable from %v, want %v", n.ID, from, w.from)
+		}
+		if n.IngressDefaultAllow != w.ingress || n.EgressDefaultAllow != w.egress {
+			t.Errorf("%s default allow ingress %v egress %v, want %v and %v", n.ID, n.IngressDefaultAllow, n.EgressDefaultAllow, w.ingress, w.egress)
+		}
+		if !slices.Equal(n.ExternalIngress, w.external) {
+			t.Errorf("%s external ingress %v, want %v", n.ID, n.ExternalIngress, w.external)
+		}
+		policies := slices.Sorted(slices.Values(n.IngressPolicies))
+		if !slices.Equal(policies, slices.Sorted(slices.Values(w.ingressPolicies))) {
+			t.Errorf("%s ingress policies %v, want %v", n.ID, policies, w.ingressPolicies)
+		}
+		if !slices.Equal(n.ReachableNamespaces, w.namespaces) {
+			t.Errorf("%s reachable from namespaces %v, want %v", n.ID, n.ReachableNamespaces, w.namespaces)
+		}
+	}
+
+	gaps := make(map[string]string)
+	for _, f := range graph.Findings {
+		gaps[f.Namespace] += f.Severity + " "
+	}
+	wantGaps := map[string]string{"web": "low ", "monitoring": "medium low ", "open": "medium low "}
+	if !reflect.DeepEqual(gaps, wantGaps) {
+		t.Errorf("findings by namespace = %q, want %q", gaps, wantGaps)
+	}
+}
+
+func TestNamespaceGraph(t *testing.T) {
+	graph, err := AnalyzeNetwork(context.Background(), fake.NewClientset(testNetwork()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := NamespaceGraph(graph)
+
+	want := map[string]struct {
+		ingress   bool
+		reachable []string
+	}{
+		"db":         {false, []string{"monitoring", "web"}},
+		"monitoring": {true, []string{"open", "web"}},
+		"open":       {true, []string{"monitoring", "web"}},
+		"web":        {false, []string{"web"}},
+	}
+	var ids []string
+	for _, n := range ns.Nodes {
+		ids = append(ids, n.ID)
+		w := want[n.ID]
+		if n.IngressDefaultAllow != w.ingress || !slices.Equal(n.ReachableNamespaces, w.reachable) {
+			t.Errorf("namespace %s = %+v, want default allow %v and reachable from %v", n.ID, n, w.ingress, w.reachable)
+		}
+	}
+	if !slices.Equal(ids, []string{"db", "monitoring", "open", "web"}) {
+		t.Errorf("namespace nodes %v, want them sorted", ids)
+	}
+	if len(ns.Edges) != 7 {
+		t.Errorf("got %d namespace edges, want 7: %v", len(ns.Edges), ns.Edges)
+	}
+	if len(ns.Findings) != len(graph.Findings) {
+		t.Error("namespace graph dropped the findings")
+	}
+}
+
+func TestPolicyTypesDefault(t *testing.T) {
+	// without policyTypes, egress applies only when egress rules are given
+	ingressOnly := &networkingv1.NetworkPolicy{}
+	withEgress := &networkingv1.NetworkPolicy{Spec: networkingv1.NetworkPolicySpec{Egress: []networkingv1.NetworkPolicyEgressRule{{}}}}
+	for _, tc := range []struct {
+		np              *networkingv1.NetworkPolicy
+		ingress, egress bool
+	}{
+		{ingressOnly, true, false},
+		{withEgress, true, true},
+		{&networkingv1.NetworkPolicy{Spec: networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}}}, false, true},
+	} {
+		if got := hasPolicyType(tc.np, networkingv1.PolicyTypeIngress); got != tc.ingress {
+			t.Errorf("%+v applies to ingress = %v", tc.np.Spec, got)
+		}
+		if got := hasPolicyType(tc.np, networkingv1.PolicyTypeEgress); got != tc.egress {
+			t.Errorf("%+v applies to egress = %v", tc.np.Spec, got)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Capabilities containers may add under the baseline profile
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true,
+	"KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true,
+	"SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// Sysctls allowed under the baseline profile
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced": true, "net.ipv4.ip_local_port_range": true,
+	"net.ipv4.ip_unprivileged_port_start": true, "net.ipv4.tcp_syncookies": true,
+	"net.ipv4.ping_group_range": true, "net.ipv4.ip_local_reserved_ports": true,
+	"net.ipv4.tcp_keepalive_time": true, "net.ipv4.tcp_fin_timeout": true,
+	"net.ipv4.tcp_keepalive_intvl": true, "net.ipv4.tcp_keepalive_probes": true,
+}
+
+// SELinux types allowed under the baseline profile
+var baselineSELinuxTypes = map[string]bool{
+	"": true, "container_t": true, "container_init_t": true, "container_kvm_t": true, "container_engine_t": true,
+}
+
+// PSSNamespaceSummary aggregates the findings of a namespace
+type PSSNamespaceSummary struct {
+	Namespace  string `json:"namespace"`
+	Workloads  int    `json:"workloads"`
+	Privileged int    `json:"privileged"`
+	Baseline   int    `json:"baseline"`
+	Restricted int    `json:"restricted"`
+	// Enforceable is the strictest profile that could be enforced on the
+	// namespace without rejecting any of its current workloads
+	Enforceable string `json:"enforceable"`
+}
+
+// ScanPodSecurity classifies every pod and pod-creating workload of a cluster
+// against the Pod Security Standards profiles
+func ScanPodSecurity(ctx context.Context, client kubernetes.Interface, clusterID string) ([]models.PSSFinding, error) {
+	now := time.Now()
+	var findings []models.PSSFinding
+	add := func(kind string, meta metav1.ObjectMeta, template *corev1.PodTemplateSpec, prefix string) {
+		violations := CheckPodTemplate(template, prefix)
+		findings = append(findings, models.PSSFinding{
+			ClusterID:  clusterID,
+			Kind:       kind,
+			Namespace:  meta.Namespace,
+			Name:       meta.Name,
+			Level:      pssLevel(violations),
+			Violations: violations,
+			ScannedAt:  now,
+		})
+	}
+
+	workloads, err := ListWorkloads(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	for _, wl := range workloads {
+		add(wl.Kind, wl.ObjectMeta, wl.Template, wl.Prefix)
+	}
+
+	return findings, nil
+}
+
+// SummarizePSS groups findings per namespace, sorted by namespace name
+func SummarizePSS(findings []models.PSSFinding) []PSSNamespaceSummary {
+	byNS := make(map[string]*PSSNamespaceSummary)
+	for _, f := range findings {
+		s, ok := byNS[f.Namespace]
+		if !ok {
+			s = &PSSNamespaceSummary{Namespace: f.Namespace, Enforceable: models.PSSRestricted}
+			byNS[f.Namespace] = s
+		}
+		s.Workloads++
+		switch f.Level {
+		case models.PSSPrivileged:
+			s.Privileged++
+			s.Enforceable = models.PSSPrivileged
+		case models.PSSBaseline:
+			s.Baseline++
+			if s.Enforceable == models.PSSRestricted {
+				s.Enforceable = models.PSSBaseline
+			}
+		default:
+			s.Restricted++
+		}
+	}
+
+	summaries := make([]PSSNamespaceSummary, 0, len(byNS))
+	for _, s := range byNS {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Namespace < summaries[j].Namespace })
+	return summaries
+}
+
+func pssLevel(violations []models.PSSViolation) string {
+	level := models.PSSRestricted
+	for _, v := range violations {
+		if v.Profile == models.PSSBaseline {
+			return models.PSSPrivileged
+		}
+		level = models.PSSBaseline
+	}
+	return level
+}
+
+// CheckPodTemplate lists every baseline and restricted control a pod fails.
+// The Profile of a violation is the profile whose requirement is broken.
+// Field paths are prefixed with prefix so they point into the owning object.
+func CheckPodTemplate(template *corev1.PodTemplateSpec, prefix string) []models.PSSViolation {
+	spec := &template.Spec
+	specField := prefix + "spec"
+	var out []models.PSSViolation
+	fail := func(profile, field, format string, args ...interface{}) {
+		out = append(out, models.PSSViolation{Profile: profile, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Baseline: host namespaces
+	if spec.HostNetwork {
+		fail(models.PSSBaseline, specField+".hostNetwork", "host network must not be used")
+	}
+	if spec.HostPID {
+		fail(models.PSSBaseline, specField+".hostPID", "host PID namespace must not be used")
+	}
+	if spec.HostIPC {
+		fail(models.PSSBaseline, specField+".hostIPC", "host IPC namespace must not be used")
+	}
+
+	// Baseline: pod-level security context
+	psc := spec.SecurityContext
+	if psc == nil {
+		psc = &corev1.PodSecurityContext{}
+	}
+	if psc.WindowsOptions != nil && psc.WindowsOptions.HostProcess != nil && *psc.WindowsOptions.HostProcess {
+		fail(models.PSSBaseline, specField+".securityContext.windowsOptions.hostProcess", "Windows host processes are not allowed")
+	}
+	checkSELinux(fail, specField+".securityContext.seLinuxOptions", psc.SELinuxOptions)
+	if psc.SeccompProfile != nil && psc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		fail(models.PSSBaseline, specField+".securityContext.seccompProfile.type", "seccomp profile must not be Unconfined")
+	}
+	if psc.AppArmorProfile != nil && psc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		fail(models.PSSBaseline, specField+".securityContext.appArmorProfile.type", "AppArmor profile must not be Unconfined")
+	}
+	for i, s := range psc.Sysctls {
+		if !safeSysctls[s.Name] {
+			fail(models.PSSBaseline, fmt.Sprintf("%s.securityContext.sysctls[%d].name", specField, i), "sysctl %s is not allowed", s.Name)
+		}
+	}
+	for key, value := range template.Annotations {
+		if strings.HasPrefix(key, "container.apparmor.security.beta.kubernetes.io/") && value == "unconfined" {
+			fail(models.PSSBaseline, prefix+"metadata.annotations["+key+"]", "AppArmor profile must not be unconfined")
+		}
+	}
+
+	// Baseline and restricted: volumes
+	restrictedVolume := func(v corev1.Volume) bool {
+		s := v.VolumeSource
+		return s.ConfigMap != nil || s.CSI != nil || s.DownwardAPI != nil || s.EmptyDir != nil ||
+			s.Ephemeral != nil || s.PersistentVolumeClaim != nil || s.Projected != nil || s.Secret != nil
+	}
+	for i, v := range spec.Volumes {
+		field := fmt.Sprintf("%s.volumes[%d]", specField, i)
+		if v.HostPath != nil {
+			fail(models.PSSBaseline, field+".hostPath", "hostPath volume %s is not allowed", v.Name)
+		} else if !restrictedVolume(v) {
+			fail(models.PSSRestricted, field, "volume %s uses a restricted volume type", v.Name)
+		}
+	}
+
+	// Restricted: pod-level defaults that containers may override
+	podRunAsNonRoot := psc.RunAsNonRoot != nil && *psc.RunAsNonRoot
+	podSeccomp := psc.SeccompProfile != nil
+	if psc.RunAsUser != nil && *psc.RunAsUser == 0 {
+		fail(models.PSSRestricted, specField+".securityContext.runAsUser", "containers must not run as UID 0")
+	}
+
+	type container struct {
+		field string
+		name  string
+		sc    *corev1.SecurityContext
+		ports []corev1.ContainerPort
+	}
+	var containers []container
+	for i, c := range spec.InitContainers {
+		containers = append(containers, container{fmt.Sprintf("%s.initContainers[%d]", specField, i), c.Name, c.SecurityContext, c.Ports})
+	}
+	for i, c := range spec.Containers {
+		containers = append(containers, container{fmt.Sprintf("%s.containers[%d]", specField, i), c.Name, c.SecurityContext, c.Ports})
+	}
+	for i, c := range spec.EphemeralContainers {
+		containers = append(containers, container{fmt.Sprintf("%s.ephemeralContainers[%d]", specField, i), c.Name, c.SecurityContext, c.Ports})
+	}
+
+	for _, c := range containers {
+		for i, p := range c.ports {
+			if p.HostPort != 0 {
+				fail(models.PSSBaseline, fmt.Sprintf("%s.ports[%d].hostPort", c.field, i), "container %s must not use host port %d", c.name, p.HostPort)
+			}
+		}
+
+		sc := c.sc
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		scField := c.field + ".securityContext"
+
+		// Baseline
+		if sc.Privileged != nil && *sc.Privileged {
+			fail(models.PSSBaseline, scField+".privileged", "container %s must not be privileged", c.name)
+		}
+		if sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			fail(models.PSSBaseline, scField+".windowsOptions.hostProcess", "container %s must not be a Windows host process", c.name)
+		}
+		checkSELinux(fail, scField+".seLinuxOptions", sc.SELinuxOptions)
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			fail(models.PSSBaseline, scField+".procMount", "container %s must use the default /proc mount", c.name)
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			fail(models.PSSBaseline, scField+".seccompProfile.type", "container %s seccomp profile must not be Unconfined", c.name)
+		}
+		if sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			fail(models.PSSBaseline, scField+".appArmorProfile.type", "container %s AppArmor profile must not be Unconfined", c.name)
+		}
+		if sc.Capabilities != nil {
+			for i, capability := range sc.Capabilities.Add {
+				field := fmt.Sprintf("%s.capabilities.add[%d]", scField, i)
+				if !baselineCapabilities[capability] {
+					fail(models.PSSBaseline, field, "container %s must not add capability %s", c.name, capability)
+				} else if capability != "NET_BIND_SERVICE" {
+					fail(models.PSSRestricted, field, "container %s may only add NET_BIND_SERVICE", c.name)
+				}
+			}
+		}
+
+		// Restricted
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			fail(models.PSSRestricted, scField+".allowPrivilegeEscalation", "container %s must set allowPrivilegeEscalation=false", c.name)
+		}
+		if (sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot) || (sc.RunAsNonRoot == nil && !podRunAsNonRoot) {
+			fail(models.PSSRestricted, scField+".runAsNonRoot", "container %s must set runAsNonRoot=true", c.name)
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			fail(models.PSSRestricted, scField+".runAsUser", "container %s must not run as UID 0", c.name)
+		}
+		if sc.SeccompProfile == nil && !podSeccomp {
+			fail(models.PSSRestricted, scField+".seccompProfile.type", "container %s must set a RuntimeDefault or Localhost seccomp profile", c.name)
+		}
+		if !dropsAll(sc.Capabilities) {
+			fail(models.PSSRestricted, scField+".capabilities.drop", "container %s must drop ALL capabilities", c.name)
+		}
+	}
+	return out
+}
+
+func checkSELinux(fail func(profile, field, format string, args ...interface{}), field string, opts *corev1.SELinuxOptions) {
+	if opts == nil {
+		return
+	}
+	if !baselineSELinuxTypes[opts.Type] {
+		fail(models.PSSBaseline, field+".type", "SELinux type %s is not allowed", opts.Type)
+	}
+	if opts.User != "" {
+		fail(models.PSSBaseline, field+".user", "custom SELinux user is not allowed")
+	}
+	if opts.Role != "" {
+		fail(models.PSSBaseline, field+".role", "custom SELinux role is not allowed")
+	}
+}
+
+func dropsAll(caps *corev1.Capabilities) bool {
+	if caps == nil {
+		return false
+	}
+	for _, c := range caps.Drop {
+		if c == "ALL" {
+			return true
+		}
+	}
+	return false
+}
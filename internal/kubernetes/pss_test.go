@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// restrictedTemplate returns a pod template that meets the restricted profile
+func restrictedTemplate() *corev1.PodTemplateSpec {
+	yes, no := true, false
+	return &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot:   &yes,
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "app:1",
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &no,
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			},
+		}},
+		Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}}},
+	}}
+}
+
+func TestCheckPodTemplateLevels(t *testing.T) {
+	yes, root := true, int64(0)
+	for _, tc := range []struct {
+		name   string
+		mutate func(*corev1.PodTemplateSpec)
+		level  string
+		field  string // field of the only violation; empty for none
+	}{
+		{"restricted", func(*corev1.PodTemplateSpec) {}, models.PSSRestricted, ""},
+		{"host network", func(p *corev1.PodTemplateSpec) { p.Spec.HostNetwork = true }, models.PSSPrivileged, "spec.hostNetwork"},
+		{"privileged container", func(p *corev1.PodTemplateSpec) { p.Spec.Containers[0].SecurityContext.Privileged = &yes }, models.PSSPrivileged,
+			"spec.containers[0].securityContext.privileged"},
+		{"hostPath volume", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Volumes[0].VolumeSource = corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}}
+		}, models.PSSPrivileged, "spec.volumes[0].hostPath"},
+		{"host port", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 80, HostPort: 80}}
+		}, models.PSSPrivileged, "spec.containers[0].ports[0].hostPort"},
+		{"unsafe sysctl", func(p *corev1.PodTemplateSpec) {
+			p.Spec.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "1"}}
+		}, models.PSSPrivileged, "spec.securityContext.sysctls[0].name"},
+		{"capability beyond baseline", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"SYS_ADMIN"}
+		}, models.PSSPrivileged, "spec.containers[0].securityContext.capabilities.add[0]"},
+		{"unconfined AppArmor annotation", func(p *corev1.PodTemplateSpec) {
+			p.Annotations = map[string]string{"container.apparmor.security.beta.kubernetes.io/app": "unconfined"}
+		}, models.PSSPrivileged, "metadata.annotations[container.apparmor.security.beta.kubernetes.io/app]"},
+		{"baseline capability", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"CHOWN"}
+		}, models.PSSBaseline, "spec.containers[0].securityContext.capabilities.add[0]"},
+		{"NET_BIND_SERVICE", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"NET_BIND_SERVICE"}
+		}, models.PSSRestricted, ""},
+		{"privilege escalation allowed", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation = nil
+		}, models.PSSBaseline, "spec.containers[0].securityContext.allowPrivilegeEscalation"},
+		{"root user", func(p *corev1.PodTemplateSpec) { p.Spec.Containers[0].SecurityContext.RunAsUser = &root }, models.PSSBaseline,
+			"spec.containers[0].securityContext.runAsUser"},
+		{"no pod seccomp", func(p *corev1.PodTemplateSpec) { p.Spec.SecurityContext.SeccompProfile = nil }, models.PSSBaseline,
+			"spec.containers[0].securityContext.seccompProfile.type"},
+		{"restricted volume type", func(p *corev1.PodTemplateSpec) {
+			p.Spec.Volumes[0].VolumeSource = corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/"}}
+		}, models.PSSBaseline, "spec.volumes[0]"},
+		{"init container", func(p *corev1.PodTemplateSpec) {
+			p.Spec.InitContainers = []corev1.Container{*p.Spec.Containers[0].DeepCopy()}
+			p.Spec.InitContainers[0].SecurityContext.Capabilities.Drop = nil
+		}, models.PSSBaseline, "spec.initContainers[0].securityContext.capabilities.drop"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			template := restrictedTemplate()
+			tc.mutate(template)
+			violations := CheckPodTemplate(template, "")
+			if level := pssLevel(violations); level != tc.level {
+				t.Errorf("level = %s, want %s (violations %+v)", level, tc.level, violations)
+			}
+			switch {
+			case tc.field == "" && len(violations) > 0:
+				t.Errorf("violations = %+v, want none", violations)
+			case tc.field != "" && (len(violations) != 1 || violations[0].Field != tc.field):
+				t.Errorf("violations = %+v, want one on %s", violations, tc.field)
+			}
+		})
+	}
+}
+
+func TestCheckPodTemplateDefaults(t *testing.T) {
+	// a pod without any security settings is baseline, failing each
+	// restricted control once per container
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "a"}, {Name: "b"}}}}
+	violations := CheckPodTemplate(template, "spec.template.")
+	if level := pssLevel(violations); level != models.PSSBaseline {
+		t.Errorf("level = %s, want baseline", level)
+	}
+	if len(violations) != 8 {
+		t.Errorf("got %d violations, want 8: %+v", len(violations), violations)
+	}
+	for _, v := range violations {
+		if v.Profile != models.PSSRestricted || !strings.HasPrefix(v.Field, "spec.template.spec.containers[") {
+			t.Errorf("violation %+v, want a restricted one inside the template", v)
+		}
+	}
+}
+
+func TestSummarizePSS(t *testing.T) {
+	summaries := SummarizePSS([]models.PSSFinding{
+		{Namespace: "web", Level: models.PSSRestricted},
+		{Namespace: "web", Level: models.PSSBaseline},
+		{Namespace: "kube-system", Level: models.PSSPrivileged},
+		{Namespace: "kube-system", Level: models.PSSBaseline},
+		{Namespace: "jobs", Level: models.PSSRestricted},
+	})
+	want := []PSSNamespaceSummary{
+		{Namespace: "jobs", Workloads: 1, Restricted: 1, Enforceable: models.PSSRestricted},
+		{Namespace: "kube-system", Workloads: 2, Privileged: 1, Baseline: 1, Enforceable: models.PSSPrivileged},
+		{Namespace: "web", Workloads: 2, Baseline: 1, Restricted: 1, Enforceable: models.PSSBaseline},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("summaries = %+v, want %+v", summaries, want)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("summary %d = %+v, want %+v", i, summaries[i], want[i])
+		}
+	}
+}
+
+func TestScanPodSecurity(t *testing.T) {
+	template := restrictedTemplate()
+	client := fake.NewClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "debug"}, Spec: corev1.PodSpec{HostPID: true, Containers: []corev1.Container{{Name: "sh"}}}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"}, Spec: appsv1.DeploymentSpec{Template: *template}},
+	)
+	findings, err := ScanPodSecurity(context.Background(), client, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := make(map[string]string)
+	for _, f := range findings {
+		if f.ClusterID != "c1" || f.ScannedAt.IsZero() {
+			t.Errorf("finding %+v lacks its cluster or scan time", f)
+		}
+		levels[f.Kind+" "+f.Namespace+"/"+f.Name] = f.Level
+	}
+	if levels["Pod web/debug"] != models.PSSPrivileged || levels["Deployment web/api"] != models.PSSRestricted || len(levels) != 2 {
+		t.Errorf("levels = %v, want the pod privileged and the deployment restricted", levels)
+	}
+}
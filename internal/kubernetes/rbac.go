@@ -0,0 +1,279 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RBACSubject identifies a user, group or service account
+type RBACSubject struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (s RBACSubject) String() string {
+	if s.Namespace != "" {
+		return s.Kind + " " + s.Namespace + "/" + s.Name
+	}
+	return s.Kind + " " + s.Name
+}
+
+// RBACPermission is one policy rule granted to a subject through a binding
+type RBACPermission struct {
+	Namespace     string   `json:"namespace,omitempty"` // empty for cluster-wide grants
+	APIGroups     []string `json:"api_groups,omitempty"`
+	Resources     []string `json:"resources,omitempty"`
+	ResourceNames []string `json:"resource_names,omitempty"`
+	NonResource   []string `json:"non_resource_urls,omitempty"`
+	Verbs         []string `json:"verbs"`
+	Via           string   `json:"via"`
+}
+
+// RBACRisk is a dangerous grant held by a subject
+type RBACRisk struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Via      string `json:"via"`
+}
+
+// SubjectAccess is the effective access of a single subject
+type SubjectAccess struct {
+	Subject     RBACSubject      `json:"subject"`
+	Permissions []RBACPermission `json:"permissions"`
+	Risks       []RBACRisk       `json:"risks"`
+}
+
+// AnalyzeRBAC loads all RBAC objects of a cluster and resolves the effective
+// permissions and risks of every bound subject
+func AnalyzeRBAC(ctx context.Context, client kubernetes.Interface) ([]SubjectAccess, error) {
+	opts := metav1.ListOptions{}
+	roles, err := client.RbacV1().Roles("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	roleBindings, err := client.RbacV1().RoleBindings("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBindings, err := client.RbacV1().ClusterRoleBindings().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveRBAC(roles.Items, clusterRoles.Items, roleBindings.Items, clusterRoleBindings.Items), nil
+}
+
+// ResolveRBAC computes subject access from already loaded RBAC objects
+func ResolveRBAC(roles []rbacv1.Role, clusterRoles []rbacv1.ClusterRole,
+	roleBindings []rbacv1.RoleBinding, clusterRoleBindings []rbacv1.ClusterRoleBinding) []SubjectAccess {
+
+	roleRules := make(map[string][]rbacv1.PolicyRule)
+	for _, r := range roles {
+		roleRules[r.Namespace+"/"+r.Name] = r.Rules
+	}
+	clusterRoleRules := aggregateClusterRoles(clusterRoles)
+
+	bySubject := make(map[RBACSubject]*SubjectAccess)
+	grant := func(subjects []rbacv1.Subject, namespace, via string, ref rbacv1.RoleRef, rules []rbacv1.PolicyRule) {
+		for _, s := range subjects {
+			subject := toRBACSubject(s, namespace)
+			access, ok := bySubject[subject]
+			if !ok {
+				access = &SubjectAccess{Subject: subject}
+				bySubject[subject] = access
+			}
+			for _, rule := range rules {
+				access.Permissions = append(access.Permissions, RBACPermission{
+					Namespace:     namespace,
+					APIGroups:     rule.APIGroups,
+					Resources:     rule.Resources,
+					ResourceNames: rule.ResourceNames,
+					NonResource:   rule.NonResourceURLs,
+					Verbs:         rule.Verbs,
+					Via:           via,
+				})
+			}
+			access.Risks = append(access.Risks, BindingRisks([]rbacv1.Subject{s}, via, ref, rules)...)
+		}
+	}
+
+	for _, b := range roleBindings {
+		var rules []rbacv1.PolicyRule
+		if b.RoleRef.Kind == "ClusterRole" {
+			rules = clusterRoleRules[b.RoleRef.Name]
+		} else {
+			rules = roleRules[b.Namespace+"/"+b.RoleRef.Name]
+		}
+		grant(b.Subjects, b.Namespace, bindingVia("RoleBinding", b.Namespace, b.Name, b.RoleRef), b.RoleRef, rules)
+	}
+	for _, b := range clusterRoleBindings {
+		rules := clusterRoleRules[b.RoleRef.Name]
+		grant(b.Subjects, "", bindingVia("ClusterRoleBinding", "", b.Name, b.RoleRef), b.RoleRef, rules)
+	}
+
+	result := make([]SubjectAccess, 0, len(bySubject))
+	for _, access := range bySubject {
+		result = append(result, *access)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Subject.String() < result[j].Subject.String() })
+	return result
+}
+
+// aggregateClusterRoles maps cluster roles to their rules. An aggregated role
+// gets the rules of the roles its selectors match, as the aggregation
+// controller would set them, so roles it has not filled in yet still count.
+func aggregateClusterRoles(clusterRoles []rbacv1.ClusterRole) map[string][]rbacv1.PolicyRule {
+	rules := make(map[string][]rbacv1.PolicyRule)
+	for _, r := range clusterRoles {
+		rules[r.Name] = r.Rules
+	}
+	for _, r := range clusterRoles {
+		if r.AggregationRule == nil {
+			continue
+		}
+		var aggregated []rbacv1.PolicyRule
+		for _, sel := range r.AggregationRule.ClusterRoleSelectors {
+			selector, err := metav1.LabelSelectorAsSelector(&sel)
+			if err != nil {
+				continue
+			}
+			for _, other := range clusterRoles {
+				if other.Name == r.Name || !selector.Matches(labels.Set(other.Labels)) {
+					continue
+				}
+				for _, rule := range other.Rules {
+					if !slices.ContainsFunc(aggregated, func(have rbacv1.PolicyRule) bool { return reflect.DeepEqual(have, rule) }) {
+						aggregated = append(aggregated, rule)
+					}
+				}
+			}
+		}
+		rules[r.Name] = aggregated
+	}
+	return rules
+}
+
+// BindingRisks lists the dangerous grants a binding of ref with the given
+// rules hands to its subjects
+func BindingRisks(subjects []rbacv1.Subject, via string, ref rbacv1.RoleRef, rules []rbacv1.PolicyRule) []RBACRisk {
+	var risks []RBACRisk
+	seen := make(map[string]bool)
+	add := func(rule, severity, message string) {
+		if seen[rule] {
+			return
+		}
+		seen[rule] = true
+		risks = append(risks, RBACRisk{Rule: rule, Severity: severity, Message: message, Via: via})
+	}
+
+	for _, s := range subjects {
+		if (s.Kind == rbacv1.UserKind && s.Name == "system:anonymous") ||
+			(s.Kind == rbacv1.GroupKind && s.Name == "system:unauthenticated") {
+			add("anonymous-binding", models.SeverityCritical, fmt.Sprintf("%s is bound to unauthenticated subject %s", ref.Name, s.Name))
+		}
+	}
+
+	if isClusterAdminRef(ref) {
+		add("cluster-admin", models.SeverityCritical, "grants cluster-admin")
+	}
+	for _, rule := range rules {
+		wildVerbs := hasAny(rule.Verbs, "*")
+		wildResources := hasAny(rule.Resources, "*")
+		switch {
+		case wildVerbs && wildResources:
+			add("wildcard-all", models.SeverityCritical, "grants all verbs on all resources")
+		case wildVerbs:
+			add("wildcard-verbs", models.SeverityHigh, "grants all verbs on "+strings.Join(rule.Resources, ", "))
+		case wildResources:
+			add("wildcard-resources", models.SeverityHigh, strings.Join(rule.Verbs, ", ")+" on all resources")
+		}
+		if (hasAny(rule.Resources, "secrets") || wildResources) && (hasAny(rule.Verbs, "get", "list", "watch") || wildVerbs) && len(rule.ResourceNames) == 0 {
+			add("secrets-read", models.SeverityHigh, "can read secrets")
+		}
+		if (hasAny(rule.Resources, "pods/exec", "pods/*") || wildResources) && (hasAny(rule.Verbs, "create", "get") || wildVerbs) {
+			add("pods-exec", models.SeverityHigh, "can exec into pods")
+		}
+		for _, verb := range []string{"escalate", "bind", "impersonate"} {
+			if hasAny(rule.Verbs, verb) {
+				add(verb, models.SeverityCritical, "has the "+verb+" verb")
+			}
+		}
+	}
+	return risks
+}
+
+// MaxSeverity returns the most severe level among risks
+func MaxSeverity(risks []RBACRisk) string {
+	max := models.SeverityInfo
+	for _, r := range risks {
+		if models.SeverityRank(r.Severity) > models.SeverityRank(max) {
+			max = r.Severity
+		}
+	}
+	return max
+}
+
+// FilterSubjects keeps the entries matching the non-empty kind, namespace and
+// name, and only risky subjects when riskyOnly is set
+func FilterSubjects(access []SubjectAccess, kind, namespace, name string, riskyOnly bool) []SubjectAccess {
+	out := make([]SubjectAccess, 0, len(access))
+	for _, a := range access {
+		if kind != "" && !strings.EqualFold(a.Subject.Kind, kind) {
+			continue
+		}
+		if namespace != "" && a.Subject.Namespace != namespace {
+			continue
+		}
+		if name != "" && a.Subject.Name != name {
+			continue
+		}
+		if riskyOnly && len(a.Risks) == 0 {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func toRBACSubject(s rbacv1.Subject, bindingNamespace string) RBACSubject {
+	subject := RBACSubject{Kind: s.Kind, Name: s.Name}
+	if s.Kind == rbacv1.ServiceAccountKind {
+		subject.Namespace = s.Namespace
+		if subject.Namespace == "" {
+			subject.Namespace = bindingNamespace
+		}
+	}
+	return subject
+}
+
+func bindingVia(kind, namespace, name string, ref rbacv1.RoleRef) string {
+	if namespace != "" {
+		name = namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s -> %s %s", kind, name, ref.Kind, ref.Name)
+}
+
+func hasAny(list []string, values ...string) bool {
+	for _, v := range values {
+		if contains(list, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func rule(verbs []string, resources ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{APIGroups: []string{""}, Resources: resources, Verbs: verbs}
+}
+
+func clusterRoleRef(name string) rbacv1.RoleRef {
+	return rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name}
+}
+
+// testRBAC is a cluster where a service account reads pods of its namespace,
+// alice reads secrets of one namespace through a cluster role, the ops group
+// views through an aggregated role, bob is cluster-admin and a binding refers
+// to a role that does not exist
+func testRBAC() []runtime.Object {
+	aggregated := map[string]string{"rbac.example.com/aggregate-to-view": "true"}
+	return []runtime.Object{
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "pod-reader"},
+			Rules:      []rbacv1.PolicyRule{rule([]string{"get", "list"}, "pods")},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader"},
+			Rules:      []rbacv1.PolicyRule{rule([]string{"get"}, "secrets")},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		// the aggregation controller does not run against a fake clientset,
+		// so view has no rules of its own
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "view"},
+			AggregationRule: &rbacv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+				{MatchLabels: aggregated},
+				{MatchLabels: map[string]string{"rbac.example.com/aggregate-to-edit": "true"}},
+			}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "view-deployments", Labels: aggregated},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "exec-pods", Labels: map[string]string{
+				"rbac.example.com/aggregate-to-view": "true", "rbac.example.com/aggregate-to-edit": "true"}},
+			Rules: []rbacv1.PolicyRule{rule([]string{"create"}, "pods/exec")},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "unlabelled"},
+			Rules:      []rbacv1.PolicyRule{rule([]string{"delete"}, "nodes")},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "app-reads-pods"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "app"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "pod-reader"},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "alice-reads-secrets"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			RoleRef:    clusterRoleRef("secret-reader"),
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "dangling"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "web", Name: "app"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "missing"},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops-view"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "ops"}},
+			RoleRef:    clusterRoleRef("view"),
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "bob-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+			RoleRef:    clusterRoleRef("cluster-admin"),
+		},
+	}
+}
+
+// describePermission renders a permission as namespace, resources, verbs and
+// the binding it came through
+func describePermission(p RBACPermission) string {
+	return p.Namespace + " " + strings.Join(p.Resources, ",") + " " + strings.Join(p.Verbs, ",") + " via " + p.Via
+}
+
+func TestAnalyzeRBAC(t *testing.T) {
+	access, err := AnalyzeRBAC(context.Background(), fake.NewSimpleClientset(testRBAC()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySubject := make(map[string]SubjectAccess)
+	for _, a := range access {
+		bySubject[a.Subject.String()] = a
+	}
+	if len(bySubject) != 4 {
+		t.Errorf("got access of %d subjects, want 4: %+v", len(bySubject), access)
+	}
+
+	for _, tc := range []struct {
+		subject     string
+		permissions []string
+		risks       []string
+	}{
+		{"ServiceAccount web/app", []string{"web pods get,list via RoleBinding web/app-reads-pods -> Role pod-reader"}, nil},
+		{"User alice", []string{"db secrets get via RoleBinding db/alice-reads-secrets -> ClusterRole secret-reader"}, []string{"secrets-read"}},
+		{"Group ops", []string{
+			" pods/exec create via ClusterRoleBinding ops-view -> ClusterRole view",
+			" deployments get,list via ClusterRoleBinding ops-view -> ClusterRole view",
+		}, []string{"pods-exec"}},
+		{"User bob", []string{" * * via ClusterRoleBinding bob-admin -> ClusterRole cluster-admin"},
+			[]string{"cluster-admin", "wildcard-all", "secrets-read", "pods-exec"}},
+	} {
+		t.Run(tc.subject, func(t *testing.T) {
+			a, ok := bySubject[tc.subject]
+			if !ok {
+				t.Fatalf("no access for %s", tc.subject)
+			}
+			var permissions, risks []string
+			for _, p := range a.Permissions {
+				permissions = append(permissions, describePermission(p))
+			}
+			for _, r := range a.Risks {
+				risks = append(risks, r.Rule)
+			}
+			if !reflect.DeepEqual(permissions, tc.permissions) {
+				t.Errorf("permissions = %q, want %q", permissions, tc.permissions)
+			}
+			if !reflect.DeepEqual(risks, tc.risks) {
+				t.Errorf("risks = %q, want %q", risks, tc.risks)
+			}
+		})
+	}
+}
+
+func TestBindingRisks(t *testing.T) {
+	sa := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "web", Name: "app"}}
+	for _, tc := range []struct {
+		name     string
+		subjects []rbacv1.Subject
+		ref      rbacv1.RoleRef
+		rules    []rbacv1.PolicyRule
+		want     []string
+		severity string
+	}{
+		{"harmless", sa, clusterRoleRef("view"), []rbacv1.PolicyRule{rule([]string{"get", "list"}, "pods", "services")}, nil, models.SeverityInfo},
+		{"cluster-admin", sa, clusterRoleRef("cluster-admin"), nil, []string{"cluster-admin"}, models.SeverityCritical},
+		{"wildcard verbs", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"*"}, "configmaps")}, []string{"wildcard-verbs"}, models.SeverityHigh},
+		{"wildcard resources", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"delete"}, "*")}, []string{"wildcard-resources"}, models.SeverityHigh},
+		{"secrets list", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"list"}, "secrets")}, []string{"secrets-read"}, models.SeverityHigh},
+		{"named secret", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{{Resources: []string{"secrets"}, ResourceNames: []string{"tls"}, Verbs: []string{"get"}}}, nil, models.SeverityInfo},
+		{"secrets create only", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"create"}, "secrets")}, nil, models.SeverityInfo},
+		{"pods exec", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"create"}, "pods/exec")}, []string{"pods-exec"}, models.SeverityHigh},
+		{"pod subresources", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"get"}, "pods/*")}, []string{"pods-exec"}, models.SeverityHigh},
+		{"escalate bind impersonate", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{
+			rule([]string{"escalate", "bind"}, "clusterroles"),
+			rule([]string{"impersonate"}, "users"),
+		}, []string{"escalate", "bind", "impersonate"}, models.SeverityCritical},
+		{"anonymous user", []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "system:anonymous"}}, clusterRoleRef("view"), nil,
+			[]string{"anonymous-binding"}, models.SeverityCritical},
+		{"unauthenticated group", []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:unauthenticated"}}, clusterRoleRef("view"), nil,
+			[]string{"anonymous-binding"}, models.SeverityCritical},
+		{"anonymous named group", []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:anonymous"}}, clusterRoleRef("view"), nil, nil, models.SeverityInfo},
+		{"each risk once", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{
+			rule([]string{"get"}, "secrets"),
+			rule([]string{"watch"}, "secrets"),
+		}, []string{"secrets-read"}, models.SeverityHigh},
+		{"everything", sa, clusterRoleRef("r"), []rbacv1.PolicyRule{rule([]string{"*"}, "*")},
+			[]string{"wildcard-all", "secrets-read", "pods-exec"}, models.SeverityCritical},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			risks := BindingRisks(tc.subjects, "via", tc.ref, tc.rules)
+			var got []string
+			for _, r := range risks {
+				got = append(got, r.Rule)
+				if r.Via != "via" {
+					t.Errorf("risk %s via %q", r.Rule, r.Via)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("risks = %q, want %q", got, tc.want)
+			}
+			if s := MaxSeverity(risks); s != tc.severity {
+				t.Errorf("MaxSeverity = %s, want %s", s, tc.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IsolationLabel marks a pod cut off by an isolate-pod action. The value is
+// the pod UID so each isolation policy selects exactly one pod.
+const IsolationLabel = "ksms.io/isolated"
+
+// ActionOutcome is what a response action did to the cluster. Before and
+// After are the JSON of the target object; After is empty when the object
+// was removed.
+type ActionOutcome struct {
+	Result string
+	Before string
+	After  string
+}
+
+// ExecuteAction applies a response action to a cluster. The outcome is
+// filled in as far as the action got, also when it fails part way.
+func ExecuteAction(ctx context.Context, client kubernetes.Interface, a models.ResponseAction) (ActionOutcome, error) {
+	switch a.Type {
+	case models.ActionDeletePod:
+		return deletePod(ctx, client, a.Namespace, a.Name)
+	case models.ActionIsolatePod:
+		return isolatePod(ctx, client, a.Namespace, a.Name)
+	case models.ActionDrainNode:
+		return drainNode(ctx, client, a.Name)
+	case models.ActionScaleToZero:
+		return scaleToZero(ctx, client, a.Kind, a.Namespace, a.Name)
+	case models.ActionRevokeRoleBinding:
+		return revokeRoleBinding(ctx, client, a.Namespace, a.Name)
+	}
+	return ActionOutcome{}, fmt.Errorf("unknown action type %q", a.Type)
+}
+
+func deletePod(ctx context.Context, client kubernetes.Interface, namespace, name string) (ActionOutcome, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ActionOutcome{}, err
+	}
+	out := ActionOutcome{Before: snapshot(pod)}
+	if err := client.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return out, err
+	}
+	out.Result = fmt.Sprintf("Deleted pod %s/%s", namespace, name)
+	return out, nil
+}
+
+// isolatePod labels the pod and applies a NetworkPolicy selecting only that
+// label with no ingress or egress rules, which denies all traffic
+func isolatePod(ctx context.Context, client kubernetes.Interface, namespace, name string) (ActionOutcome, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ActionOutcome{}, err
+	}
+	out := ActionOutcome{Before: snapshot(pod)}
+
+	labelled, err := labelPod(ctx, client, namespace, name, IsolationLabel, string(pod.UID))
+	if err != nil {
+		return out, err
+	}
+	out.After = snapshot(labelled)
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      isolationPolicyName(name),
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "ksms"},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{IsolationLabel: string(pod.UID)}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+	if _, err := client.NetworkingV1().NetworkPolicies(namespace).Create(ctx, np, metav1.CreateOptions{}); err != nil {
+		return out, fmt.Errorf("pod labelled but NetworkPolicy not created: %w", err)
+	}
+	out.Result = fmt.Sprintf("Isolated pod %s/%s with NetworkPolicy %s", namespace, name, np.Name)
+	return out, nil
+}
+
+// LabelPod sets a label on a pod, e.g. quarantine=true
+func LabelPod(ctx context.Context, client kubernetes.Interface, namespace, name, key, value string) (ActionOutcome, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ActionOutcome{}, err
+	}
+	out := ActionOutcome{Before: snapshot(pod)}
+	labelled, err := labelPod(ctx, client, namespace, name, key, value)
+	if err != nil {
+		return out, err
+	}
+	out.After = snapshot(labelled)
+	out.Result = fmt.Sprintf("Labelled pod %s/%s %s=%s", namespace, name, key, value)
+	return out, nil
+}
+
+func labelPod(ctx context.Context, client kubernetes.Interface, namespace, name, key, value string) (*corev1.Pod, error) {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]string{key: value}},
+	})
+	return client.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// PodLogs returns the last lines of every container of a pod, each under a
+// header naming the container
+func PodLogs(ctx context.Context, client kubernetes.Interface, namespace, name string, tailLines int64) (string, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range pod.Spec.Containers {
+		data, err := client.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{Container: c.Name, TailLines: &tailLines}).DoRaw(ctx)
+		if err != nil {
+			return b.String(), fmt.Errorf("logs of container %s: %w", c.Name, err)
+		}
+		fmt.Fprintf(&b, "==> %s <==\n%s\n", c.Name, data)
+	}
+	return b.String(), nil
+}
+
+// PodNode returns the name of the node a pod is scheduled on
+func PodNode(ctx context.Context, client kubernetes.Interface, namespace, name string) (string, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pod.Spec.NodeName == "" {
+		return "", fmt.Errorf("pod %s/%s is not scheduled", namespace, name)
+	}
+	return pod.Spec.NodeName, nil
+}
+
+func isolationPolicyName(pod string) string {
+	name := "ksms-isolate-" + pod
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+// drainNode cordons the node and evicts its pods through the eviction API so
+// PodDisruptionBudgets are honoured. DaemonSet and mirror pods are left
+// alone, as kubectl drain does.
+func drainNode(ctx context.Context, client kubernetes.Interface, name string) (ActionOutcome, error) {
+	node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ActionOutcome{}, err
+	}
+	out := ActionOutcome{Before: snapshot(node)}
+
+	cordoned, err := client.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(`{"spec":{"unschedulable":true}}`), metav1.PatchOptions{})
+	if err != nil {
+		return out, err
+	}
+	out.After = snapshot(cordoned)
+
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + name})
+	if err != nil {
+		out.Result = fmt.Sprintf("Cordoned node %s", name)
+		return out, fmt.Errorf("node cordoned but pods not listed: %w", err)
+	}
+	var evicted, skipped int
+	var failed []string
+	for _, pod := range pods.Items {
+		if daemonSetPod(&pod) || pod.Annotations[corev1.MirrorPodAnnotationKey] != "" ||
+			pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			skipped++
+			continue
+		}
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+		if err := client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction); err != nil {
+			failed = append(failed, pod.Namespace+"/"+pod.Name+": "+err.Error())
+			continue
+		}
+		evicted++
+	}
+	out.Result = fmt.Sprintf("Cordoned node %s, evicted %d pods, skipped %d", name, evicted, skipped)
+	if len(failed) > 0 {
+		return out, fmt.Errorf("%d pods not evicted: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return out, nil
+}
+
+func daemonSetPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+func scaleToZero(ctx context.Context, client kubernetes.Interface, kind, namespace, name string) (ActionOutcome, error) {
+	var out ActionOutcome
+	switch kind {
+	case "Deployment":
+		deployments := client.AppsV1().Deployments(namespace)
+		d, err := deployments.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return out, err
+		}
+		out.Before = snapshot(d)
+		scale, err := deployments.GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return out, err
+		}
+		scale.Spec.Replicas = 0
+		if _, err := deployments.UpdateScale(ctx, name, scale, metav1.UpdateOptions{}); err != nil {
+			return out, err
+		}
+		if d, err = deployments.Get(ctx, name, metav1.GetOptions{}); err == nil {
+			out.After = snapshot(d)
+		}
+	case "StatefulSet":
+		statefulSets := client.AppsV1().StatefulSets(namespace)
+		s, err := statefulSets.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return out, err
+		}
+		out.Before = snapshot(s)
+		scale, err := statefulSets.GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return out, err
+		}
+		scale.Spec.Replicas = 0
+		if _, err := statefulSets.UpdateScale(ctx, name, scale, metav1.UpdateOptions{}); err != nil {
+			return out, err
+		}
+		if s, err = statefulSets.Get(ctx, name, metav1.GetOptions{}); err == nil {
+			out.After = snapshot(s)
+		}
+	default:
+		return out, fmt.Errorf("cannot scale a %s", kind)
+	}
+	out.Result = fmt.Sprintf("Scaled %s %s/%s to zero replicas", kind, namespace, name)
+	return out, nil
+}
+
+func revokeRoleBinding(ctx context.Context, client kubernetes.Interface, namespace, name string) (ActionOutcome, error) {
+	rb, err := client.RbacV1().RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ActionOutcome{}, err
+	}
+	out := ActionOutcome{Before: snapshot(rb)}
+	if err := client.RbacV1().RoleBindings(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return out, err
+	}
+	out.Result = fmt.Sprintf("Revoked RoleBinding %s/%s to %s %s", namespace, name, rb.RoleRef.Kind, rb.RoleRef.Name)
+	return out, nil
+}
+
+// snapshot returns the JSON of an object without its managed fields, which
+// only add noise to a report
+func snapshot(obj metav1.Object) string {
+	obj.SetManagedFields(nil)
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,251 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// actionClient is a fake cluster that serves scale subresources and
+// evictions like an API server
+func actionClient(objects ...runtime.Object) *fake.Clientset {
+	fc := fake.NewClientset(objects...)
+	addScaleReactors(fc)
+	addEvictionReactor(fc)
+	return fc
+}
+
+// decode reads a Before or After snapshot
+func decode[T any](t *testing.T, snapshot string) *T {
+	t.Helper()
+	if snapshot == "" {
+		t.Fatal("empty snapshot")
+	}
+	obj := new(T)
+	if err := json.Unmarshal([]byte(snapshot), obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestExecuteDeletePod(t *testing.T) {
+	ctx := context.Background()
+	client := actionClient(privilegedPod("web", "debug"))
+	out, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionDeletePod, Namespace: "web", Name: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before := decode[corev1.Pod](t, out.Before); before.Name != "debug" || len(before.Spec.Containers) != 1 {
+		t.Errorf("before = %+v", before)
+	}
+	if out.After != "" || out.Result != "Deleted pod web/debug" {
+		t.Errorf("outcome = %+v", out)
+	}
+	if _, err := client.CoreV1().Pods("web").Get(ctx, "debug", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("pod still there: %v", err)
+	}
+
+	if _, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionDeletePod, Namespace: "web", Name: "debug"}); !apierrors.IsNotFound(err) {
+		t.Errorf("deleting a missing pod = %v, want not found", err)
+	}
+}
+
+func TestExecuteIsolatePod(t *testing.T) {
+	ctx := context.Background()
+	pod := privilegedPod("web", "debug")
+	pod.UID = "uid-1"
+	pod.Labels = map[string]string{"app": "debug"}
+	client := actionClient(pod)
+
+	out, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionIsolatePod, Namespace: "web", Name: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before := decode[corev1.Pod](t, out.Before); before.Labels[IsolationLabel] != "" {
+		t.Errorf("before already isolated: %v", before.Labels)
+	}
+	if after := decode[corev1.Pod](t, out.After); after.Labels[IsolationLabel] != "uid-1" || after.Labels["app"] != "debug" {
+		t.Errorf("after labels = %v, want the isolation label next to the others", after.Labels)
+	}
+
+	np, err := client.NetworkingV1().NetworkPolicies("web").Get(ctx, "ksms-isolate-debug", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := np.Spec
+	if !slices.Equal(spec.PolicyTypes, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}) ||
+		len(spec.Ingress) != 0 || len(spec.Egress) != 0 {
+		t.Errorf("policy spec = %+v, want deny-all ingress and egress", spec)
+	}
+	if len(spec.PodSelector.MatchLabels) != 1 || spec.PodSelector.MatchLabels[IsolationLabel] != "uid-1" || len(spec.PodSelector.MatchExpressions) != 0 {
+		t.Errorf("policy selects %+v, want only the isolated pod", spec.PodSelector)
+	}
+	if np.Labels["app.kubernetes.io/managed-by"] != "ksms" {
+		t.Errorf("policy labels = %v", np.Labels)
+	}
+	if !strings.Contains(out.Result, "NetworkPolicy ksms-isolate-debug") {
+		t.Errorf("result = %q", out.Result)
+	}
+
+	// a second isolation labels again but cannot create the policy twice
+	out, err = ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionIsolatePod, Namespace: "web", Name: "debug"})
+	if err == nil || out.Before == "" || out.After == "" || out.Result != "" {
+		t.Errorf("second isolation = %+v, %v; want snapshots and an error", out, err)
+	}
+}
+
+func TestExecuteDrainNode(t *testing.T) {
+	ctx := context.Background()
+	controller := true
+	daemon := nodePod("kube-system", "agent", "n1")
+	daemon.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "agent", UID: "ds", Controller: &controller}}
+	mirror := nodePod("kube-system", "etcd-n1", "n1")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+	done := nodePod("jobs", "migrate", "n1")
+	done.Status.Phase = corev1.PodSucceeded
+	// the fake clientset ignores field selectors, so every pod is on n1
+	client := actionClient(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}},
+		nodePod("web", "api", "n1"),
+		nodePod("web", "protected", "n1"),
+		daemon, mirror, done,
+	)
+	var evictions []string
+	client.PrependReactor("create", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		create := action.(clienttesting.CreateAction)
+		eviction, ok := create.GetObject().(*policyv1.Eviction)
+		if create.GetSubresource() != "eviction" || !ok {
+			return false, nil, nil
+		}
+		evictions = append(evictions, eviction.Namespace+"/"+eviction.Name)
+		if eviction.Name == "protected" {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		return false, nil, nil
+	})
+
+	out, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionDrainNode, Kind: "Node", Name: "n1"})
+	if err == nil || !strings.Contains(err.Error(), "1 pods not evicted: web/protected") {
+		t.Errorf("err = %v, want the pod its budget protects", err)
+	}
+	slices.Sort(evictions)
+	if want := []string{"web/api", "web/protected"}; !slices.Equal(evictions, want) {
+		t.Errorf("evicted %v, want %v", evictions, want)
+	}
+	if want := "Cordoned node n1, evicted 1 pods, skipped 3"; out.Result != want {
+		t.Errorf("result = %q, want %q", out.Result, want)
+	}
+	if before := decode[corev1.Node](t, out.Before); before.Spec.Unschedulable {
+		t.Error("before is already cordoned")
+	}
+	if after := decode[corev1.Node](t, out.After); !after.Spec.Unschedulable {
+		t.Error("after is not cordoned")
+	}
+	if n, _ := client.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{}); !n.Spec.Unschedulable {
+		t.Error("node not cordoned")
+	}
+	for _, p := range []struct {
+		namespace, name string
+		gone            bool
+	}{{"web", "api", true}, {"web", "protected", false}, {"kube-system", "agent", false}, {"kube-system", "etcd-n1", false}, {"jobs", "migrate", false}} {
+		_, err := client.CoreV1().Pods(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if gone := apierrors.IsNotFound(err); gone != p.gone {
+			t.Errorf("pod %s/%s evicted = %v, want %v", p.namespace, p.name, gone, p.gone)
+		}
+	}
+}
+
+func TestExecuteScaleToZero(t *testing.T) {
+	ctx := context.Background()
+	replicas := int32(3)
+	client := actionClient(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "db"}, Spec: appsv1.StatefulSetSpec{Replicas: &replicas}},
+	)
+
+	out, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionScaleToZero, Kind: "Deployment", Namespace: "web", Name: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before := decode[appsv1.Deployment](t, out.Before); *before.Spec.Replicas != 3 {
+		t.Errorf("before has %d replicas, want 3", *before.Spec.Replicas)
+	}
+	if after := decode[appsv1.Deployment](t, out.After); *after.Spec.Replicas != 0 {
+		t.Errorf("after has %d replicas, want 0", *after.Spec.Replicas)
+	}
+	if d, _ := client.AppsV1().Deployments("web").Get(ctx, "api", metav1.GetOptions{}); *d.Spec.Replicas != 0 {
+		t.Errorf("deployment has %d replicas, want 0", *d.Spec.Replicas)
+	}
+
+	out, err = ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionScaleToZero, Kind: "StatefulSet", Namespace: "web", Name: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before, after := decode[appsv1.StatefulSet](t, out.Before), decode[appsv1.StatefulSet](t, out.After); *before.Spec.Replicas != 3 || *after.Spec.Replicas != 0 {
+		t.Errorf("statefulset scaled from %d to %d, want 3 to 0", *before.Spec.Replicas, *after.Spec.Replicas)
+	}
+	if out.Result != "Scaled StatefulSet web/db to zero replicas" {
+		t.Errorf("result = %q", out.Result)
+	}
+
+	if _, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionScaleToZero, Kind: "DaemonSet", Namespace: "web", Name: "agent"}); err == nil {
+		t.Error("scaled a DaemonSet")
+	}
+}
+
+func TestExecuteRevokeRoleBinding(t *testing.T) {
+	ctx := context.Background()
+	client := actionClient(&rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "ci-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "web", Name: "ci"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin"},
+	})
+	out, err := ExecuteAction(ctx, client, models.ResponseAction{Type: models.ActionRevokeRoleBinding, Namespace: "web", Name: "ci-admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the snapshot keeps what is needed to recreate the binding
+	before := decode[rbacv1.RoleBinding](t, out.Before)
+	if before.RoleRef.Name != "admin" || len(before.Subjects) != 1 || before.Subjects[0].Name != "ci" {
+		t.Errorf("before = %+v", before)
+	}
+	if out.After != "" || out.Result != "Revoked RoleBinding web/ci-admin to ClusterRole admin" {
+		t.Errorf("outcome = %+v", out)
+	}
+	if _, err := client.RbacV1().RoleBindings("web").Get(ctx, "ci-admin", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("binding still there: %v", err)
+	}
+}
+
+func TestExecuteFailedDeleteKeepsBefore(t *testing.T) {
+	client := actionClient(privilegedPod("web", "debug"))
+	client.PrependReactor("delete", "pods", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "debug", nil)
+	})
+	out, err := ExecuteAction(context.Background(), client, models.ResponseAction{Type: models.ActionDeletePod, Namespace: "web", Name: "debug"})
+	if !apierrors.IsForbidden(err) || out.Before == "" || out.Result != "" {
+		t.Errorf("outcome = %+v, %v; want the snapshot and the error", out, err)
+	}
+}
+
+func TestExecuteUnknownAction(t *testing.T) {
+	if _, err := ExecuteAction(context.Background(), actionClient(), models.ResponseAction{Type: "reboot-cluster"}); err == nil {
+		t.Error("executed an unknown action")
+	}
+}
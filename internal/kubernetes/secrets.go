@@ -0,0 +1,251 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Secret audit check identifiers
+const (
+	CheckEnvSecret        = "env-secret"
+	CheckUnusedSecret     = "unused-secret"
+	CheckConfigMapSecret  = "configmap-credential"
+	CheckAutomountedToken = "automount-token"
+)
+
+var (
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY-----`)
+	awsAccessKey      = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+	awsSecretKey      = regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)
+	certificateBlock  = regexp.MustCompile(`(?s)-----BEGIN CERTIFICATE-----.*?-----END CERTIFICATE-----`)
+	tokenSeparators   = regexp.MustCompile(`[\s"'=:,;]+`)
+	minEntropyLength  = 24
+	minEntropyPerChar = 4.2
+)
+
+// AuditSecrets looks for secrets exposed through environment variables, unused
+// Secrets, credential-like ConfigMap values and needlessly mounted service
+// account tokens
+func AuditSecrets(ctx context.Context, client kubernetes.Interface, clusterID string) ([]models.SecretFinding, error) {
+	workloads, err := ListWorkloads(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	opts := metav1.ListOptions{}
+	secrets, err := client.CoreV1().Secrets("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	configMaps, err := client.CoreV1().ConfigMaps("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccounts, err := client.CoreV1().ServiceAccounts("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	ingresses, err := client.NetworkingV1().Ingresses("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	access, err := AnalyzeRBAC(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var findings []models.SecretFinding
+	add := func(check, severity, kind, namespace, name, reference, format string, args ...interface{}) {
+		findings = append(findings, models.SecretFinding{
+			ClusterID:  clusterID,
+			Check:      check,
+			Severity:   severity,
+			Kind:       kind,
+			Namespace:  namespace,
+			Name:       name,
+			Reference:  reference,
+			Message:    fmt.Sprintf(format, args...),
+			DetectedAt: now,
+		})
+	}
+
+	// Secret references come from every pod and template, including pods
+	// created by controllers, so nothing in use is reported as unused.
+	used := make(map[string]bool)
+	for _, wl := range workloads {
+		for name := range secretRefs(&wl.Template.Spec) {
+			used[wl.Namespace+"/"+name] = true
+		}
+	}
+	for _, ing := range ingresses.Items {
+		for _, tls := range ing.Spec.TLS {
+			used[ing.Namespace+"/"+tls.SecretName] = true
+		}
+	}
+	automount := make(map[string]bool)
+	for _, sa := range serviceAccounts.Items {
+		for _, ref := range sa.Secrets {
+			used[sa.Namespace+"/"+ref.Name] = true
+		}
+		for _, ref := range sa.ImagePullSecrets {
+			used[sa.Namespace+"/"+ref.Name] = true
+		}
+		automount[sa.Namespace+"/"+sa.Name] = sa.AutomountServiceAccountToken == nil || *sa.AutomountServiceAccountToken
+	}
+	hasRBAC := make(map[string]bool)
+	for _, a := range access {
+		if a.Subject.Kind == "ServiceAccount" && len(a.Permissions) > 0 {
+			hasRBAC[a.Subject.Namespace+"/"+a.Subject.Name] = true
+		}
+	}
+
+	// Findings about workloads are reported once, on the top-level object.
+	for _, wl := range workloads {
+		if wl.Controlled() {
+			continue
+		}
+		spec := &wl.Template.Spec
+		for _, c := range allContainers(spec) {
+			for _, env := range c.Env {
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					ref := env.ValueFrom.SecretKeyRef
+					add(CheckEnvSecret, models.SeverityMedium, wl.Kind, wl.Namespace, wl.Name,
+						fmt.Sprintf("secret %s key %s", ref.Name, ref.Key),
+						"container %s exposes secret %s as environment variable %s", c.Name, ref.Name, env.Name)
+				}
+			}
+			for _, from := range c.EnvFrom {
+				if from.SecretRef != nil {
+					add(CheckEnvSecret, models.SeverityMedium, wl.Kind, wl.Namespace, wl.Name,
+						"secret "+from.SecretRef.Name,
+						"container %s imports every key of secret %s as environment variables", c.Name, from.SecretRef.Name)
+				}
+			}
+		}
+
+		sa := spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+		mounted, known := automount[wl.Namespace+"/"+sa]
+		if spec.AutomountServiceAccountToken != nil {
+			mounted, known = *spec.AutomountServiceAccountToken, true
+		}
+		if (mounted || !known) && !hasRBAC[wl.Namespace+"/"+sa] {
+			add(CheckAutomountedToken, models.SeverityLow, wl.Kind, wl.Namespace, wl.Name, "serviceaccount "+sa,
+				"service account token of %s is mounted but the account has no RBAC permissions; set automountServiceAccountToken: false", sa)
+		}
+	}
+
+	for _, s := range secrets.Items {
+		if s.Type == corev1.SecretTypeServiceAccountToken || used[s.Namespace+"/"+s.Name] {
+			continue
+		}
+		add(CheckUnusedSecret, models.SeverityLow, "Secret", s.Namespace, s.Name, "secret "+s.Name,
+			"secret is not referenced by any workload or service account")
+	}
+
+	for _, cm := range configMaps.Items {
+		keys := make([]string, 0, len(cm.Data))
+		for k := range cm.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if reason := credentialReason(cm.Data[key]); reason != "" {
+				add(CheckConfigMapSecret, models.SeverityHigh, "ConfigMap", cm.Namespace, cm.Name, "configmap "+cm.Name+" key "+key,
+					"value of key %s looks like a credential (%s); move it to a Secret", key, reason)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// credentialReason explains why a value looks like a credential, or returns
+// an empty string. The value itself is never included.
+func credentialReason(value string) string {
+	switch {
+	case privateKeyPattern.MatchString(value):
+		return "private key header"
+	case awsAccessKey.MatchString(value):
+		return "AWS access key ID"
+	case awsSecretKey.MatchString(value):
+		return "AWS secret access key"
+	}
+	// Certificates are public and would otherwise trip the entropy check.
+	value = certificateBlock.ReplaceAllString(value, "")
+	for _, token := range tokenSeparators.Split(value, -1) {
+		if len(token) >= minEntropyLength && shannonEntropy(token) >= minEntropyPerChar {
+			return "high-entropy string"
+		}
+	}
+	return ""
+}
+
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretRefs collects the names of all Secrets a pod spec references
+func secretRefs(spec *corev1.PodSpec) map[string]bool {
+	refs := make(map[string]bool)
+	for _, ref := range spec.ImagePullSecrets {
+		refs[ref.Name] = true
+	}
+	for _, v := range spec.Volumes {
+		if v.Secret != nil {
+			refs[v.Secret.SecretName] = true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil {
+					refs[src.Secret.Name] = true
+				}
+			}
+		}
+		if v.CSI != nil && v.CSI.NodePublishSecretRef != nil {
+			refs[v.CSI.NodePublishSecretRef.Name] = true
+		}
+	}
+	for _, c := range allContainers(spec) {
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				refs[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+		for _, from := range c.EnvFrom {
+			if from.SecretRef != nil {
+				refs[from.SecretRef.Name] = true
+			}
+		}
+	}
+	return refs
+}
+
+// allContainers returns init and regular containers of a pod spec
+func allContainers(spec *corev1.PodSpec) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(spec.InitContainers)+len(spec.Containers))
+	containers = append(containers, spec.InitContainers...)
+	return append(containers, spec.Containers...)
+}
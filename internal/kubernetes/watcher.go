@@ -0,0 +1,337 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultResync is the resync period used by the shared informers
+const DefaultResync = 10 * time.Minute
+
+// AlertSink receives alerts raised by cluster watchers
+type AlertSink interface {
+	AddAlert(a models.Alert) error
+}
+
+var alertSeq uint64
+
+// NewAlertID returns a unique alert ID
+func NewAlertID() string {
+	return time.Now().Format("20060102150405") + "-" + strconv.FormatUint(atomic.AddUint64(&alertSeq, 1), 10)
+}
+
+// WatcherManager runs one ClusterWatcher per connected cluster
+type WatcherManager struct {
+	sink     AlertSink
+	watchers map[string]*ClusterWatcher
+	mu       sync.Mutex
+}
+
+func NewWatcherManager(sink AlertSink) *WatcherManager {
+	return &WatcherManager{
+		sink:     sink,
+		watchers: make(map[string]*ClusterWatcher),
+	}
+}
+
+// Start launches a watcher for the cluster unless one is already running
+func (m *WatcherManager) Start(clusterID string, client kubernetes.Interface) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.watchers[clusterID]; ok {
+		return
+	}
+	w := NewClusterWatcher(clusterID, client, m.sink)
+	w.Start()
+	m.watchers[clusterID] = w
+}
+
+// Stop shuts down the watcher of a cluster and waits for its informers to exit
+func (m *WatcherManager) Stop(clusterID string) {
+	m.mu.Lock()
+	w, ok := m.watchers[clusterID]
+	delete(m.watchers, clusterID)
+	m.mu.Unlock()
+	if ok {
+		w.Stop()
+	}
+}
+
+// StopAll shuts down every running watcher
+func (m *WatcherManager) StopAll() {
+	m.mu.Lock()
+	watchers := m.watchers
+	m.watchers = make(map[string]*ClusterWatcher)
+	m.mu.Unlock()
+	for _, w := range watchers {
+		w.Stop()
+	}
+}
+
+// Running reports whether a watcher is active for the cluster
+func (m *WatcherManager) Running(clusterID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.watchers[clusterID]
+	return ok
+}
+
+// ClusterWatcher turns informer events of a single cluster into alerts
+type ClusterWatcher struct {
+	clusterID    string
+	sink         AlertSink
+	factory      informers.SharedInformerFactory
+	roles        rbaclisters.RoleLister
+	clusterRoles rbaclisters.ClusterRoleLister
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+}
+
+func NewClusterWatcher(clusterID string, client kubernetes.Interface, sink AlertSink) *ClusterWatcher {
+	w := &ClusterWatcher{
+		clusterID: clusterID,
+		sink:      sink,
+		factory:   informers.NewSharedInformerFactory(client, DefaultResync),
+		stopCh:    make(chan struct{}),
+	}
+	w.roles = w.factory.Rbac().V1().Roles().Lister()
+	w.clusterRoles = w.factory.Rbac().V1().ClusterRoles().Lister()
+
+	w.factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc:    w.onPodAdd,
+		UpdateFunc: w.onPodUpdate,
+	})
+	w.factory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: w.onEventAdd,
+	})
+	w.factory.Rbac().V1().RoleBindings().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: w.onRoleBindingAdd,
+	})
+	w.factory.Rbac().V1().ClusterRoleBindings().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: w.onClusterRoleBindingAdd,
+	})
+	w.factory.Networking().V1().NetworkPolicies().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		DeleteFunc: w.onNetworkPolicyDelete,
+	})
+	return w
+}
+
+// Start runs the informers in the background
+func (w *ClusterWatcher) Start() {
+	w.factory.Start(w.stopCh)
+	log.Printf("Started watcher for cluster %s", w.clusterID)
+}
+
+// WaitForCacheSync blocks until the initial list of every informer is processed
+func (w *ClusterWatcher) WaitForCacheSync() bool {
+	for _, ok := range w.factory.WaitForCacheSync(w.stopCh) {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Stop terminates the informers and waits for their goroutines to finish
+func (w *ClusterWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.factory.Shutdown()
+		log.Printf("Stopped watcher for cluster %s", w.clusterID)
+	})
+}
+
+// Watcher alert rules
+const (
+	RulePrivilegedPod   = "privileged-pod"
+	RuleCrashLoop       = "crashloop"
+	RuleAdmissionDenied = "admission-denied"
+	RuleRiskyBinding    = "risky-binding"
+	RulePolicyDeleted   = "networkpolicy-deleted"
+)
+
+func (w *ClusterWatcher) raise(rule, namespace, resource, severity, message string) {
+	a := models.Alert{
+		ID:        NewAlertID(),
+		ClusterID: w.clusterID,
+		Rule:      rule,
+		Namespace: namespace,
+		Resource:  resource,
+		Severity:  severity,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	if err := w.sink.AddAlert(a); err != nil {
+		log.Printf("Alert %s of cluster %s not stored: %v", a.ID, w.clusterID, err)
+	}
+}
+
+// Objects present in the initial list already existed before the watcher
+// started, so only changes observed afterwards raise alerts.
+func (w *ClusterWatcher) onPodAdd(obj interface{}, isInInitialList bool) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || isInInitialList {
+		return
+	}
+	if names := privilegedContainers(pod); len(names) > 0 {
+		w.raise(RulePrivilegedPod, pod.Namespace, "Pod "+pod.Namespace+"/"+pod.Name, models.SeverityHigh, fmt.Sprintf("Privileged pod created: %s/%s (containers: %s)",
+			pod.Namespace, pod.Name, strings.Join(names, ", ")))
+	}
+}
+
+func (w *ClusterWatcher) onPodUpdate(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	before := crashLoopingContainers(oldPod)
+	for _, name := range crashLoopingContainers(newPod) {
+		if !contains(before, name) {
+			resource := "Pod " + newPod.Namespace + "/" + newPod.Name + " container " + name
+			w.raise(RuleCrashLoop, newPod.Namespace, resource, models.SeverityMedium, fmt.Sprintf("Container %s in pod %s/%s is in CrashLoopBackOff",
+				name, newPod.Namespace, newPod.Name))
+		}
+	}
+}
+
+func (w *ClusterWatcher) onEventAdd(obj interface{}, isInInitialList bool) {
+	ev, ok := obj.(*corev1.Event)
+	if !ok || isInInitialList || ev.Type != corev1.EventTypeWarning {
+		return
+	}
+	// Admission rejections (PodSecurity, webhooks, quotas) surface as FailedCreate
+	// warnings whose message carries the "forbidden" status reason.
+	if ev.Reason == "FailedCreate" && strings.Contains(ev.Message, "forbidden") {
+		obj := ev.InvolvedObject
+		resource := obj.Kind + " " + obj.Namespace + "/" + obj.Name
+		w.raise(RuleAdmissionDenied, obj.Namespace, resource, models.SeverityMedium, fmt.Sprintf("Admission denied for %s: %s",
+			resource, ev.Message))
+	}
+}
+
+func (w *ClusterWatcher) onRoleBindingAdd(obj interface{}, isInInitialList bool) {
+	rb, ok := obj.(*rbacv1.RoleBinding)
+	if !ok || isInInitialList {
+		return
+	}
+	var rules []rbacv1.PolicyRule
+	if rb.RoleRef.Kind == "ClusterRole" {
+		if role, err := w.clusterRoles.Get(rb.RoleRef.Name); err == nil {
+			rules = role.Rules
+		}
+	} else if role, err := w.roles.Roles(rb.Namespace).Get(rb.RoleRef.Name); err == nil {
+		rules = role.Rules
+	}
+	w.raiseBindingRisks(rb.Namespace, bindingVia("RoleBinding", rb.Namespace, rb.Name, rb.RoleRef), rb.Subjects, rb.RoleRef, rules)
+}
+
+func (w *ClusterWatcher) onClusterRoleBindingAdd(obj interface{}, isInInitialList bool) {
+	crb, ok := obj.(*rbacv1.ClusterRoleBinding)
+	if !ok || isInInitialList {
+		return
+	}
+	var rules []rbacv1.PolicyRule
+	if role, err := w.clusterRoles.Get(crb.RoleRef.Name); err == nil {
+		rules = role.Rules
+	}
+	w.raiseBindingRisks("", bindingVia("ClusterRoleBinding", "", crb.Name, crb.RoleRef), crb.Subjects, crb.RoleRef, rules)
+}
+
+func (w *ClusterWatcher) raiseBindingRisks(namespace, via string, subjects []rbacv1.Subject, ref rbacv1.RoleRef, rules []rbacv1.PolicyRule) {
+	risks := BindingRisks(subjects, via, ref, rules)
+	if len(risks) == 0 {
+		return
+	}
+	msgs := make([]string, len(risks))
+	for i, r := range risks {
+		msgs[i] = r.Message
+	}
+	w.raise(RuleRiskyBinding, namespace, via, MaxSeverity(risks), fmt.Sprintf("New risky binding %s for %s: %s",
+		via, formatSubjects(subjects), strings.Join(msgs, "; ")))
+}
+
+func (w *ClusterWatcher) onNetworkPolicyDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	np, ok := obj.(*networkingv1.NetworkPolicy)
+	if !ok {
+		return
+	}
+	w.raise(RulePolicyDeleted, np.Namespace, "NetworkPolicy "+np.Namespace+"/"+np.Name, models.SeverityMedium, fmt.Sprintf("NetworkPolicy %s/%s was deleted", np.Namespace, np.Name))
+}
+
+func privilegedContainers(pod *corev1.Pod) []string {
+	var names []string
+	check := func(name string, sc *corev1.SecurityContext) {
+		if sc != nil && sc.Privileged != nil && *sc.Privileged {
+			names = append(names, name)
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		check(c.Name, c.SecurityContext)
+	}
+	for _, c := range pod.Spec.Containers {
+		check(c.Name, c.SecurityContext)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		check(c.Name, c.SecurityContext)
+	}
+	return names
+}
+
+func crashLoopingContainers(pod *corev1.Pod) []string {
+	var names []string
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, st := range statuses {
+			if st.State.Waiting != nil && st.State.Waiting.Reason == "CrashLoopBackOff" {
+				names = append(names, st.Name)
+			}
+		}
+	}
+	return names
+}
+
+func isClusterAdminRef(ref rbacv1.RoleRef) bool {
+	return ref.Kind == "ClusterRole" && ref.Name == "cluster-admin"
+}
+
+func formatSubjects(subjects []rbacv1.Subject) string {
+	parts := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		if s.Namespace != "" {
+			parts = append(parts, s.Kind+" "+s.Namespace+"/"+s.Name)
+		} else {
+			parts = append(parts, s.Kind+" "+s.Name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordingSink keeps the alerts handed to it
+type recordingSink struct {
+	mu     sync.Mutex
+	alerts []models.Alert
+}
+
+func (s *recordingSink) AddAlert(a models.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, a)
+	return nil
+}
+
+func (s *recordingSink) rules() map[string]models.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := make(map[string]models.Alert)
+	for _, a := range s.alerts {
+		rules[a.Rule] = a
+	}
+	return rules
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+// waitFor polls until cond holds or a few seconds passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func privilegedPod(namespace, name string) *corev1.Pod {
+	privileged := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app", Image: "nginx:1.27", SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+		}}},
+	}
+}
+
+func forbiddenEvent(name string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "web", Name: name},
+		InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Namespace: "web", Name: "api-5d8f"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "FailedCreate",
+		Message:        `pods "api-5d8f-x" is forbidden: violates PodSecurity "restricted:latest"`,
+	}
+}
+
+func startWatcher(t *testing.T, objects ...runtime.Object) (*fake.Clientset, *ClusterWatcher, *recordingSink) {
+	t.Helper()
+	client := fake.NewClientset(objects...)
+	sink := &recordingSink{}
+	w := NewClusterWatcher("c1", client, sink)
+	w.Start()
+	t.Cleanup(w.Stop)
+	if !w.WaitForCacheSync() {
+		t.Fatal("informer caches did not sync")
+	}
+	return client, w, sink
+}
+
+func TestClusterWatcherRaisesAlerts(t *testing.T) {
+	ctx := context.Background()
+	secretReader := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "secret-reader"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}}},
+	}
+	deny := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "default-deny"}}
+	web := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "api:1"}}},
+	}
+	client, _, sink := startWatcher(t, secretReader, deny, web)
+
+	if _, err := client.CoreV1().Pods("web").Create(ctx, privilegedPod("web", "debug"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	crashing := web.DeepCopy()
+	crashing.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name: "app", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	if _, err := client.CoreV1().Pods("web").UpdateStatus(ctx, crashing, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Events("web").Create(ctx, forbiddenEvent("api.denied"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "ci-secrets"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "web", Name: "ci"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secret-reader"},
+	}
+	if _, err := client.RbacV1().RoleBindings("web").Create(ctx, binding, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.NetworkingV1().NetworkPolicies("web").Delete(ctx, "default-deny", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct{ severity, resource string }{
+		RulePrivilegedPod:   {models.SeverityHigh, "Pod web/debug"},
+		RuleCrashLoop:       {models.SeverityMedium, "Pod web/api container app"},
+		RuleAdmissionDenied: {models.SeverityMedium, "ReplicaSet web/api-5d8f"},
+		RuleRiskyBinding:    {models.SeverityHigh, "RoleBinding web/ci-secrets -> Role secret-reader"},
+		RulePolicyDeleted:   {models.SeverityMedium, "NetworkPolicy web/default-deny"},
+	}
+	waitFor(t, "an alert per rule", func() bool { return len(sink.rules()) == len(want) })
+	for rule, a := range sink.rules() {
+		w, ok := want[rule]
+		if !ok {
+			t.Errorf("unexpected alert %+v", a)
+			continue
+		}
+		if a.ClusterID != "c1" || a.Namespace != "web" || a.Severity != w.severity || a.Resource != w.resource || a.Message == "" {
+			t.Errorf("%s alert = %+v, want severity %s on %s", rule, a, w.severity, w.resource)
+		}
+	}
+	if n := sink.count(); n != len(want) {
+		t.Errorf("got %d alerts, want %d", n, len(want))
+	}
+}
+
+func TestClusterWatcherIgnoresInitialList(t *testing.T) {
+	clusterAdmin := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "ops-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "ops"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+	}
+	client, _, sink := startWatcher(t, privilegedPod("web", "old"), forbiddenEvent("old.denied"), clusterAdmin)
+
+	// an alert raised after the initial list shows that the handlers ran
+	if _, err := client.CoreV1().Pods("web").Create(context.Background(), privilegedPod("web", "new"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the new pod's alert", func() bool { return sink.count() > 0 })
+	time.Sleep(50 * time.Millisecond)
+	if n := sink.count(); n != 1 || sink.rules()[RulePrivilegedPod].Resource != "Pod web/new" {
+		t.Errorf("alerts = %+v, want only the one for pod web/new", sink.alerts)
+	}
+}
+
+func TestWatcherManagerStop(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{}
+	m := NewWatcherManager(sink)
+	clients := map[string]*fake.Clientset{"c1": fake.NewClientset(), "c2": fake.NewClientset(), "c3": fake.NewClientset()}
+	for id, client := range clients {
+		m.Start(id, client)
+	}
+	for id := range clients {
+		if !m.Running(id) {
+			t.Fatalf("watcher of %s is not running", id)
+		}
+	}
+
+	// Stop and StopAll return once the informers have exited, after which
+	// changes raise nothing
+	m.Stop("c1")
+	if m.Running("c1") || !m.Running("c2") {
+		t.Fatal("Stop stopped the wrong watcher")
+	}
+	m.StopAll()
+	for id := range clients {
+		if m.Running(id) {
+			t.Errorf("watcher of %s still running after StopAll", id)
+		}
+	}
+	for id, client := range clients {
+		if _, err := client.CoreV1().Pods("web").Create(ctx, privilegedPod("web", "late-"+id), metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := sink.count(); n != 0 {
+		t.Errorf("stopped watchers raised %d alerts", n)
+	}
+}
@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload is a pod or a controller together with the pod template it runs
+type Workload struct {
+	Kind string
+	metav1.ObjectMeta
+	Template *corev1.PodTemplateSpec
+	Prefix   string // field path of the template inside the object, e.g. "spec.template."
+}
+
+// Controlled reports whether the workload is managed by another controller,
+// such as a pod of a ReplicaSet or a Job of a CronJob
+func (w Workload) Controlled() bool {
+	return metav1.GetControllerOf(&w.ObjectMeta) != nil
+}
+
+// ListWorkloads returns every Pod, Deployment, StatefulSet, DaemonSet, Job and
+// CronJob of a cluster
+func ListWorkloads(ctx context.Context, client kubernetes.Interface) ([]Workload, error) {
+	var workloads []Workload
+	opts := metav1.ListOptions{}
+
+	pods, err := client.CoreV1().Pods("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		workloads = append(workloads, Workload{"Pod", p.ObjectMeta, &corev1.PodTemplateSpec{ObjectMeta: p.ObjectMeta, Spec: p.Spec}, ""})
+	}
+
+	deployments, err := client.AppsV1().Deployments("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		workloads = append(workloads, Workload{"Deployment", d.ObjectMeta, &d.Spec.Template, "spec.template."})
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		workloads = append(workloads, Workload{"StatefulSet", s.ObjectMeta, &s.Spec.Template, "spec.template."})
+	}
+
+	daemonSets, err := client.AppsV1().DaemonSets("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		d := &daemonSets.Items[i]
+		workloads = append(workloads, Workload{"DaemonSet", d.ObjectMeta, &d.Spec.Template, "spec.template."})
+	}
+
+	jobs, err := client.BatchV1().Jobs("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		j := &jobs.Items[i]
+		workloads = append(workloads, Workload{"Job", j.ObjectMeta, &j.Spec.Template, "spec.template."})
+	}
+
+	cronJobs, err := client.BatchV1().CronJobs("").List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		c := &cronJobs.Items[i]
+		workloads = append(workloads, Workload{"CronJob", c.ObjectMeta, &c.Spec.JobTemplate.Spec.Template, "spec.jobTemplate.spec.template."})
+	}
+
+	return workloads, nil
+}
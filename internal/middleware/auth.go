@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
 	"strings"
 
@@ -11,10 +10,6 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type contextKey string
-
-const UserContextKey contextKey = "user"
-
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("token")
@@ -39,8 +34,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		})
 
 		if err == nil && token.Valid {
-			ctx := context.WithValue(r.Context(), UserContextKey, claims)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(handlers.WithClaims(r.Context(), claims)))
 		} else {
 			next.ServeHTTP(w, r)
 		}
@@ -50,7 +44,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value(UserContextKey).(*handlers.Claims)
+			claims, ok := handlers.ClaimsFromContext(r.Context())
 			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
@@ -1,6 +1,15 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
 
 type Role string
 
@@ -26,12 +35,22 @@ type User struct {
 type Cluster struct {
 	ID         string    `json:"id"`
 	Name       string    `json:"name"`
-	KubeConfig string    `json:"kube_config"` // Base64 or path
+	KubeConfig string    `json:"kube_config,omitempty"` // raw kubeconfig; accepted on create, never returned
 	Status     string    `json:"status"`
 	Metrics    Metrics   `json:"metrics"`
+	AuditToken string    `json:"audit_token,omitempty"` // bearer token of the audit webhook; only returned when issued
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// MarshalJSON leaves out the cluster's credentials so they never leave the
+// server once stored
+func (c Cluster) MarshalJSON() ([]byte, error) {
+	type cluster Cluster
+	redacted := cluster(c)
+	redacted.KubeConfig, redacted.AuditToken = "", ""
+	return json.Marshal(redacted)
+}
+
 type Metrics struct {
 	CPUUsage    float64 `json:"cpu_usage"`
 	MemoryUsage float64 `json:"memory_usage"`
@@ -39,26 +58,1015 @@ type Metrics struct {
 }
 
 type Policy struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	Rules             []string  `json:"rules"`
+	Namespace         string    `json:"namespace"`
+	AllowedRegistries []string  `json:"allowed_registries,omitempty"` // registries or repository prefixes; empty allows any
+	Severity          string    `json:"severity,omitempty"`           // severity of violation alerts; medium when empty
+	Responses         []string  `json:"responses,omitempty"`          // response action types requested for each violation
+	AutoRespond       bool      `json:"auto_respond"`                 // run the responses without waiting for approval
+	Playbooks         []string  `json:"playbooks,omitempty"`          // IDs of playbooks run for each violation
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank orders severities from info (0) to critical (4)
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return 0
+}
+
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+	AlertSuppressed   = "suppressed"
+)
+
+// alertTransitions lists the statuses each status may move to
+var alertTransitions = map[string][]string{
+	AlertOpen:         {AlertAcknowledged, AlertResolved, AlertSuppressed},
+	AlertAcknowledged: {AlertOpen, AlertResolved, AlertSuppressed},
+	AlertResolved:     {AlertOpen},
+	AlertSuppressed:   {AlertOpen},
+}
+
+// IncidentWindow is how long after the last alert of an incident a related
+// alert still joins it
+const IncidentWindow = 30 * time.Minute
+
+type Alert struct {
+	ID          string            `json:"id"`
+	ClusterID   string            `json:"cluster_id"`
+	PolicyID    string            `json:"policy_id,omitempty"`
+	Rule        string            `json:"rule,omitempty"` // detection that raised the alert, e.g. "crashloop"
+	Namespace   string            `json:"namespace,omitempty"`
+	Resource    string            `json:"resource,omitempty"` // e.g. "Pod default/web-0"
+	Severity    string            `json:"severity"`
+	Message     string            `json:"message"`
+	Actor       string            `json:"actor,omitempty"` // user recorded in the audit event, if any
+	Verb        string            `json:"verb,omitempty"`
+	Status      string            `json:"status"`
+	Assignee    string            `json:"assignee,omitempty"`
+	Resolution  string            `json:"resolution,omitempty"`
+	History     []AlertTransition `json:"history,omitempty"`
+	Fingerprint string            `json:"fingerprint"`
+	Count       int               `json:"count"`
+	FirstSeen   time.Time         `json:"first_seen"`
+	LastSeen    time.Time         `json:"last_seen"`
+	IncidentID  string            `json:"incident_id,omitempty"`
+	SilenceID   string            `json:"silence_id,omitempty"` // silence that suppressed the alert when raised
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// Prepare fills the defaults of a newly raised alert: open status, a single
+// occurrence seen at its timestamp and its fingerprint
+func (a *Alert) Prepare() {
+	if a.Status == "" {
+		a.Status = AlertOpen
+	}
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now()
+	}
+	if a.Count == 0 {
+		a.Count = 1
+		a.FirstSeen = a.Timestamp
+		a.LastSeen = a.Timestamp
+	}
+	if a.Fingerprint == "" {
+		// Alerts without a rule and resource only fold when the message repeats.
+		key := a.Resource
+		if a.Rule == "" && a.Resource == "" {
+			key = a.Message
+		}
+		sum := sha256.Sum256([]byte(a.ClusterID + "\x00" + a.Rule + "\x00" + key))
+		a.Fingerprint = hex.EncodeToString(sum[:16])
+	}
+}
+
+// Folds reports whether a repeat is counted on the alert rather than raised
+// anew. Resolved alerts are not folded into, so a recurrence opens a new
+// alert, and silenced and unsilenced occurrences are kept apart.
+func (a Alert) Folds(repeat Alert) bool {
+	return a.Fingerprint == repeat.Fingerprint && a.Status != AlertResolved && a.SilenceID == repeat.SilenceID
+}
+
+// Fold counts a repeat occurrence. The latest message is kept and the
+// severity only ever goes up.
+func (a *Alert) Fold(repeat Alert) {
+	a.Count++
+	a.LastSeen = repeat.Timestamp
+	a.Message = repeat.Message
+	if SeverityRank(repeat.Severity) > SeverityRank(a.Severity) {
+		a.Severity = repeat.Severity
+	}
+}
+
+// GroupKey identifies the alerts that belong to the same incident. Alerts
+// without a rule are never grouped.
+func (a Alert) GroupKey() string {
+	if a.Rule == "" {
+		return ""
+	}
+	return a.ClusterID + "/" + a.Namespace + "/" + a.Rule
+}
+
+// Pod returns the pod an alert implicates, taken from a resource of the form
+// "Pod <namespace>/<name>" optionally followed by details
+func (a Alert) Pod() (namespace, name string, ok bool) {
+	ref, found := strings.CutPrefix(a.Resource, "Pod ")
+	if !found {
+		return "", "", false
+	}
+	ref, _, _ = strings.Cut(ref, " ")
+	namespace, name, ok = strings.Cut(ref, "/")
+	return namespace, name, ok && namespace != "" && name != ""
+}
+
+const (
+	IncidentOpen          = "open"
+	IncidentInvestigating = "investigating"
+	IncidentMitigated     = "mitigated"
+	IncidentClosed        = "closed"
+)
+
+// incidentTransitions lists the statuses each incident status may move to
+var incidentTransitions = map[string][]string{
+	IncidentOpen:          {IncidentInvestigating, IncidentMitigated, IncidentClosed},
+	IncidentInvestigating: {IncidentOpen, IncidentMitigated, IncidentClosed},
+	IncidentMitigated:     {IncidentInvestigating, IncidentClosed},
+	IncidentClosed:        {IncidentOpen},
+}
+
+// Incident is a case grouping related alerts. Alerts of one rule in one
+// namespace raised within IncidentWindow of each other are grouped
+// automatically; incidents can also be opened by hand from any alerts.
+type Incident struct {
+	ID          string          `json:"id"`
+	Title       string          `json:"title,omitempty"`
+	ClusterID   string          `json:"cluster_id"`
+	Namespace   string          `json:"namespace,omitempty"`
+	Rule        string          `json:"rule"` // empty for incidents opened by hand, which are not grouped into
+	Severity    string          `json:"severity"`
+	Status      string          `json:"status"`
+	Owner       string          `json:"owner,omitempty"`
+	AlertCount  int             `json:"alert_count"`
+	EvidenceIDs []string        `json:"evidence_ids,omitempty"`
+	Timeline    []TimelineEntry `json:"timeline,omitempty"` // comments, status, owner and evidence changes
+	PostMortem  *PostMortem     `json:"post_mortem,omitempty"`
+	CreatedBy   string          `json:"created_by,omitempty"`
+	FirstSeen   time.Time       `json:"first_seen"`
+	LastSeen    time.Time       `json:"last_seen"`
+	ClosedAt    *time.Time      `json:"closed_at,omitempty"`
+}
+
+// Incident timeline entry types. Alert and action entries are derived from
+// the incident's alerts when it is read; the others are recorded.
+const (
+	TimelineAlert      = "alert"
+	TimelineAction     = "action"
+	TimelineEvidence   = "evidence"
+	TimelineComment    = "comment"
+	TimelineStatus     = "status"
+	TimelineOwner      = "owner"
+	TimelineSeverity   = "severity"
+	TimelinePostMortem = "post-mortem"
+)
+
+// TimelineEntry is one event in the history of an incident
+type TimelineEntry struct {
+	Type       string    `json:"type"`
+	Text       string    `json:"text,omitempty"`
+	From       string    `json:"from,omitempty"`
+	To         string    `json:"to,omitempty"`
+	AlertID    string    `json:"alert_id,omitempty"`
+	ActionID   string    `json:"action_id,omitempty"`
+	EvidenceID string    `json:"evidence_id,omitempty"`
+	By         string    `json:"by,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// PostMortem is the write-up of an incident, usually completed when it is
+// closed
+type PostMortem struct {
+	Summary     string    `json:"summary"`
+	Impact      string    `json:"impact,omitempty"`
+	RootCause   string    `json:"root_cause,omitempty"`
+	Resolution  string    `json:"resolution,omitempty"`
+	Lessons     string    `json:"lessons,omitempty"`
+	ActionItems []string  `json:"action_items,omitempty"`
+	Author      string    `json:"author"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewIncident starts an incident with its first alert
+func NewIncident(a Alert) Incident {
+	return Incident{
+		ID:         "inc-" + a.ID,
+		ClusterID:  a.ClusterID,
+		Namespace:  a.Namespace,
+		Rule:       a.Rule,
+		Severity:   a.Severity,
+		Status:     IncidentOpen,
+		AlertCount: 1,
+		FirstSeen:  a.Timestamp,
+		LastSeen:   a.Timestamp,
+	}
+}
+
+// OpenIncident opens an incident by hand for the given alerts. The cluster and
+// namespace are only set when all alerts share them.
+func OpenIncident(id, title, by string, alerts []Alert) Incident {
+	inc := Incident{ID: id, Title: title, Status: IncidentOpen, CreatedBy: by, AlertCount: len(alerts)}
+	for i, a := range alerts {
+		if i == 0 {
+			inc.ClusterID, inc.Namespace = a.ClusterID, a.Namespace
+			inc.FirstSeen, inc.LastSeen = a.FirstSeen, a.LastSeen
+		}
+		if a.ClusterID != inc.ClusterID {
+			inc.ClusterID = ""
+		}
+		if a.Namespace != inc.Namespace {
+			inc.Namespace = ""
+		}
+		if a.FirstSeen.Before(inc.FirstSeen) {
+			inc.FirstSeen = a.FirstSeen
+		}
+		if a.LastSeen.After(inc.LastSeen) {
+			inc.LastSeen = a.LastSeen
+		}
+		if SeverityRank(a.Severity) > SeverityRank(inc.Severity) {
+			inc.Severity = a.Severity
+		}
+	}
+	return inc
+}
+
+// Accepts reports whether an alert raised at t still joins the incident.
+// Closed incidents take no more alerts.
+func (i Incident) Accepts(t time.Time) bool {
+	return i.Status != IncidentClosed && t.Sub(i.LastSeen) <= IncidentWindow
+}
+
+// Touch records activity of an alert of the incident; added is true for a new
+// alert and false for a folded repeat
+func (i *Incident) Touch(a Alert, added bool) {
+	if added {
+		i.AlertCount++
+	}
+	if a.LastSeen.After(i.LastSeen) {
+		i.LastSeen = a.LastSeen
+	}
+	if SeverityRank(a.Severity) > SeverityRank(i.Severity) {
+		i.Severity = a.Severity
+	}
+}
+
+// IncidentUpdate is a change to an incident made by a user. Set fields are
+// applied and each is recorded on the timeline.
+type IncidentUpdate struct {
+	Status     string      `json:"status,omitempty"`
+	Owner      string      `json:"owner,omitempty"`
+	Severity   string      `json:"severity,omitempty"`
+	Comment    string      `json:"comment,omitempty"`
+	EvidenceID string      `json:"evidence_id,omitempty"`
+	PostMortem *PostMortem `json:"post_mortem,omitempty"`
+	By         string      `json:"-"`
+	At         time.Time   `json:"-"`
+}
+
+// Apply validates an update against the incident and records it. Closing
+// sets ClosedAt; reopening clears it.
+func (i *Incident) Apply(u IncidentUpdate) error {
+	if i.Status == "" {
+		i.Status = IncidentOpen
+	}
+	if u.Owner == "" && u.Severity == "" && u.Comment == "" && u.EvidenceID == "" && u.PostMortem == nil {
+		if u.Status == "" {
+			return fmt.Errorf("nothing to update")
+		}
+		if u.Status == i.Status {
+			return fmt.Errorf("incident is already %s", i.Status)
+		}
+	}
+	if u.Status != "" && u.Status != i.Status {
+		allowed := false
+		for _, s := range incidentTransitions[i.Status] {
+			if s == u.Status {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("incident cannot move from %s to %s", i.Status, u.Status)
+		}
+	}
+	if u.Severity != "" && SeverityRank(u.Severity) == 0 && u.Severity != SeverityInfo {
+		return fmt.Errorf("unknown severity %q", u.Severity)
+	}
+	if u.PostMortem != nil && u.PostMortem.Summary == "" {
+		return fmt.Errorf("post_mortem requires a summary")
+	}
+
+	timeline := i.Timeline[:len(i.Timeline):len(i.Timeline)]
+	entry := TimelineEntry{By: u.By, At: u.At}
+	if u.Comment != "" {
+		e := entry
+		e.Type, e.Text = TimelineComment, u.Comment
+		timeline = append(timeline, e)
+	}
+	if u.EvidenceID != "" && !containsString(i.EvidenceIDs, u.EvidenceID) {
+		e := entry
+		e.Type, e.EvidenceID = TimelineEvidence, u.EvidenceID
+		timeline = append(timeline, e)
+		i.EvidenceIDs = append(i.EvidenceIDs[:len(i.EvidenceIDs):len(i.EvidenceIDs)], u.EvidenceID)
+	}
+	if u.Owner != "" && u.Owner != i.Owner {
+		e := entry
+		e.Type, e.From, e.To = TimelineOwner, i.Owner, u.Owner
+		timeline = append(timeline, e)
+		i.Owner = u.Owner
+	}
+	if u.Severity != "" && u.Severity != i.Severity {
+		e := entry
+		e.Type, e.From, e.To = TimelineSeverity, i.Severity, u.Severity
+		timeline = append(timeline, e)
+		i.Severity = u.Severity
+	}
+	if u.PostMortem != nil {
+		pm := *u.PostMortem
+		pm.Author, pm.UpdatedAt = u.By, u.At
+		e := entry
+		e.Type = TimelinePostMortem
+		timeline = append(timeline, e)
+		i.PostMortem = &pm
+	}
+	if u.Status != "" && u.Status != i.Status {
+		e := entry
+		e.Type, e.From, e.To = TimelineStatus, i.Status, u.Status
+		timeline = append(timeline, e)
+		i.Status = u.Status
+		if u.Status == IncidentClosed {
+			at := u.At
+			i.ClosedAt = &at
+		} else {
+			i.ClosedAt = nil
+		}
+	}
+	i.Timeline = timeline
+	return nil
+}
+
+// AlertTransition records a status or assignee change of an alert. From and
+// To are equal when only the assignee changed.
+type AlertTransition struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Assignee string    `json:"assignee,omitempty"`
+	Note     string    `json:"note,omitempty"`
+	By       string    `json:"by"`
+	At       time.Time `json:"at"`
+}
+
+// Apply validates a transition against the current status and records it.
+// Resolving stores the note as the resolution; reopening clears it.
+func (a *Alert) Apply(t AlertTransition) error {
+	if a.Status == "" {
+		a.Status = AlertOpen
+	}
+	t.From = a.Status
+	if t.To == "" {
+		t.To = a.Status
+	}
+	if t.To != a.Status {
+		allowed := false
+		for _, s := range alertTransitions[a.Status] {
+			if s == t.To {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("alert cannot move from %s to %s", a.Status, t.To)
+		}
+	} else if t.Assignee == "" || t.Assignee == a.Assignee {
+		return fmt.Errorf("alert is already %s", a.Status)
+	}
+
+	switch t.To {
+	case AlertResolved:
+		a.Resolution = t.Note
+	case AlertOpen:
+		a.Resolution = ""
+	}
+	if t.Assignee != "" {
+		a.Assignee = t.Assignee
+	}
+	a.Status = t.To
+	a.History = append(a.History[:len(a.History):len(a.History)], t)
+	return nil
+}
+
+// Silence suppresses the alerts raised between StartsAt and EndsAt that match
+// all of its non-empty matchers
+type Silence struct {
+	ID        string    `json:"id"`
+	ClusterID string    `json:"cluster_id,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Rule      string    `json:"rule,omitempty"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Silence states
+const (
+	SilencePending = "pending"
+	SilenceActive  = "active"
+	SilenceExpired = "expired"
+)
+
+// Validate checks that the silence has a matcher and a non-empty time range
+func (s Silence) Validate() error {
+	if s.ClusterID == "" && s.Namespace == "" && s.Severity == "" && s.Rule == "" {
+		return fmt.Errorf("silence needs at least one matcher")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+	return nil
+}
+
+// State reports whether the silence is pending, active or expired at t
+func (s Silence) State(t time.Time) string {
+	switch {
+	case t.Before(s.StartsAt):
+		return SilencePending
+	case t.Before(s.EndsAt):
+		return SilenceActive
+	}
+	return SilenceExpired
+}
+
+// Matches reports whether the silence is active at the alert's time and all
+// of its matchers match
+func (s Silence) Matches(a Alert) bool {
+	return s.State(a.Timestamp) == SilenceActive &&
+		(s.ClusterID == "" || s.ClusterID == a.ClusterID) &&
+		(s.Namespace == "" || s.Namespace == a.Namespace) &&
+		(s.Severity == "" || s.Severity == a.Severity) &&
+		(s.Rule == "" || s.Rule == a.Rule)
+}
+
+// Notification channel types
+const (
+	ChannelWebhook = "webhook"
+	ChannelSMTP    = "smtp"
+	ChannelSlack   = "slack" // Slack or Mattermost incoming webhook
+)
+
+// Channel is an outbound notification target. Alerts are routed to it when
+// they reach MinSeverity and come from one of Clusters (any when empty).
+type Channel struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Rules       []string  `json:"rules"`
-	Namespace   string    `json:"namespace"`
+	Type        string    `json:"type"`
+	URL         string    `json:"url,omitempty"`    // webhook and slack
+	Secret      string    `json:"secret,omitempty"` // HMAC key signing webhook bodies
+	SMTPAddr    string    `json:"smtp_addr,omitempty"`
+	Username    string    `json:"username,omitempty"`
+	Password    string    `json:"password,omitempty"`
+	From        string    `json:"from,omitempty"`
+	To          []string  `json:"to,omitempty"`
+	MinSeverity string    `json:"min_severity,omitempty"`
+	Clusters    []string  `json:"clusters,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-type Alert struct {
-	ID        string    `json:"id"`
-	ClusterID string    `json:"cluster_id"`
-	Severity  string    `json:"severity"`
-	Message   string    `json:"message"`
-	Timestamp time.Time `json:"timestamp"`
+// Validate checks that the channel has the settings its type needs
+func (c Channel) Validate() error {
+	switch c.Type {
+	case ChannelWebhook, ChannelSlack:
+		if c.URL == "" {
+			return fmt.Errorf("%s channel needs a url", c.Type)
+		}
+	case ChannelSMTP:
+		if c.SMTPAddr == "" || c.From == "" || len(c.To) == 0 {
+			return fmt.Errorf("smtp channel needs smtp_addr, from and to")
+		}
+	default:
+		return fmt.Errorf("unknown channel type %q", c.Type)
+	}
+	return nil
 }
 
-type IncidentReport struct {
+// Routes reports whether an alert should be sent to the channel
+func (c Channel) Routes(a Alert) bool {
+	if SeverityRank(a.Severity) < SeverityRank(c.MinSeverity) {
+		return false
+	}
+	if len(c.Clusters) == 0 {
+		return true
+	}
+	for _, id := range c.Clusters {
+		if id == a.ClusterID {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetter records a notification that could not be delivered after all
+// retries
+type DeadLetter struct {
 	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
 	AlertID   string    `json:"alert_id"`
-	Details   string    `json:"details"`
-	Action    string    `json:"action_taken"`
-	Timestamp time.Time `json:"timestamp"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// Schedule is a weekly on-call rotation. The rotation hands over every
+// RotationStart plus a multiple of a week; overrides take precedence.
+type Schedule struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Rotation      []string           `json:"rotation"` // user IDs in hand-over order
+	RotationStart time.Time          `json:"rotation_start"`
+	Overrides     []ScheduleOverride `json:"overrides,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+// ScheduleOverride puts a user on call between Start and End
+type ScheduleOverride struct {
+	UserID string    `json:"user_id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+const rotationLength = 7 * 24 * time.Hour
+
+// Validate checks that the schedule has someone to put on call
+func (s Schedule) Validate() error {
+	if len(s.Rotation) == 0 && len(s.Overrides) == 0 {
+		return fmt.Errorf("schedule needs a rotation or an override")
+	}
+	for _, o := range s.Overrides {
+		if o.UserID == "" || !o.End.After(o.Start) {
+			return fmt.Errorf("override needs a user_id and must end after it starts")
+		}
+	}
+	return nil
+}
+
+// OnCall returns the ID of the user on call at t, or an empty string
+func (s Schedule) OnCall(t time.Time) string {
+	for _, o := range s.Overrides {
+		if !t.Before(o.Start) && t.Before(o.End) {
+			return o.UserID
+		}
+	}
+	if len(s.Rotation) == 0 || t.Before(s.RotationStart) {
+		return ""
+	}
+	week := int(t.Sub(s.RotationStart) / rotationLength)
+	return s.Rotation[week%len(s.Rotation)]
+}
+
+// EscalationPolicy pages its tiers in turn while a matching alert stays open
+type EscalationPolicy struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	MinSeverity string           `json:"min_severity"`
+	Clusters    []string         `json:"clusters,omitempty"`
+	Tiers       []EscalationTier `json:"tiers"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// EscalationTier is paged when the alert is still open AfterMinutes after it
+// was raised. Recipients are the on-call user of the schedule, the listed
+// users and every user with one of the roles.
+type EscalationTier struct {
+	AfterMinutes int      `json:"after_minutes"`
+	ChannelID    string   `json:"channel_id"`
+	ScheduleID   string   `json:"schedule_id,omitempty"`
+	UserIDs      []string `json:"user_ids,omitempty"`
+	Roles        []Role   `json:"roles,omitempty"`
+}
+
+// Validate checks that every tier has a channel and that tiers are ordered
+func (p EscalationPolicy) Validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("escalation policy needs at least one tier")
+	}
+	for i, t := range p.Tiers {
+		if t.ChannelID == "" {
+			return fmt.Errorf("tier %d needs a channel_id", i+1)
+		}
+		if t.AfterMinutes < 0 || (i > 0 && t.AfterMinutes < p.Tiers[i-1].AfterMinutes) {
+			return fmt.Errorf("tier %d must not fire before tier %d", i+1, i)
+		}
+	}
+	return nil
+}
+
+// Applies reports whether the policy covers an alert
+func (p EscalationPolicy) Applies(a Alert) bool {
+	return len(p.Tiers) > 0 && Channel{MinSeverity: p.MinSeverity, Clusters: p.Clusters}.Routes(a)
+}
+
+// Escalation is the pending escalation of one alert: NextTier is paged at
+// DueAt unless the alert has been acknowledged
+type Escalation struct {
+	AlertID  string    `json:"alert_id"`
+	PolicyID string    `json:"policy_id"`
+	NextTier int       `json:"next_tier"`
+	DueAt    time.Time `json:"due_at"`
+}
+
+var idSeq atomic.Uint64
+
+// NewID returns a unique ID of the given prefix, e.g. "rep-". The counter
+// keeps IDs made within the same microsecond apart.
+func NewID(prefix string) string {
+	return prefix + time.Now().Format("20060102150405.000000") + "-" + strconv.FormatUint(idSeq.Add(1), 10)
+}
+
+type IncidentReport struct {
+	ID         string    `json:"id"`
+	AlertID    string    `json:"alert_id"`
+	ActionID   string    `json:"action_id,omitempty"`
+	EvidenceID string    `json:"evidence_id,omitempty"` // ID of the evidence bundle
+	Details    string    `json:"details"`
+	Action     string    `json:"action_taken"`
+	Result     string    `json:"result,omitempty"`
+	Before     string    `json:"before,omitempty"` // JSON of the object before the action
+	After      string    `json:"after,omitempty"`  // JSON of the object after the action; empty when it was removed
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// EvidenceFile is one file of an evidence bundle. The content is stored
+// once under its SHA-256, however many bundles contain it.
+type EvidenceFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// EvidenceBundle is the forensic evidence captured for a pod. The ID is the
+// SHA-256 of the alert ID, the capture time and the manifest, the files
+// sorted by path with their hashes, so it can be checked against the bundle
+// while repeated captures of the same evidence are kept apart.
+type EvidenceBundle struct {
+	ID          string         `json:"id"`
+	ClusterID   string         `json:"cluster_id"`
+	AlertID     string         `json:"alert_id,omitempty"`
+	Namespace   string         `json:"namespace"`
+	Pod         string         `json:"pod"`
+	Files       []EvidenceFile `json:"files"`
+	Errors      []string       `json:"errors,omitempty"` // parts that could not be collected
+	CollectedAt time.Time      `json:"collected_at"`
+}
+
+// Response action types
+const (
+	ActionDeletePod         = "delete-pod"
+	ActionIsolatePod        = "isolate-pod"
+	ActionDrainNode         = "drain-node"
+	ActionScaleToZero       = "scale-to-zero"
+	ActionRevokeRoleBinding = "revoke-rolebinding"
+)
+
+// Response action statuses
+const (
+	ActionPending   = "pending"
+	ActionRunning   = "running" // approved, or automatic, and being executed
+	ActionRejected  = "rejected"
+	ActionSucceeded = "succeeded"
+	ActionFailed    = "failed"
+)
+
+// actionKinds lists the object kinds each action type can target
+var actionKinds = map[string][]string{
+	ActionDeletePod:         {"Pod"},
+	ActionIsolatePod:        {"Pod"},
+	ActionDrainNode:         {"Node"},
+	ActionScaleToZero:       {"Deployment", "StatefulSet"},
+	ActionRevokeRoleBinding: {"RoleBinding"},
+}
+
+// DefaultActionKind is the kind an action type targets when none is given
+func DefaultActionKind(typ string) string {
+	if kinds := actionKinds[typ]; len(kinds) > 0 {
+		return kinds[0]
+	}
+	return ""
+}
+
+// ActionTargets reports whether an action type can act on objects of kind
+func ActionTargets(typ, kind string) bool {
+	for _, k := range actionKinds[typ] {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseAction is a change made to a cluster in response to an alert.
+// Actions that are not Auto stay pending until an Administrator approves
+// or rejects them.
+type ResponseAction struct {
+	ID          string    `json:"id"`
+	ClusterID   string    `json:"cluster_id"`
+	AlertID     string    `json:"alert_id,omitempty"`
+	PolicyID    string    `json:"policy_id,omitempty"`
+	Type        string    `json:"type"`
+	Kind        string    `json:"kind"`
+	Namespace   string    `json:"namespace,omitempty"` // empty for nodes
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Auto        bool      `json:"auto"`
+	RequestedBy string    `json:"requested_by"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	ReportID    string    `json:"report_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	DecidedAt   time.Time `json:"decided_at"`
+	ExecutedAt  time.Time `json:"executed_at"`
+}
+
+// Validate checks that the action type is known and fits the target
+func (a ResponseAction) Validate() error {
+	if _, ok := actionKinds[a.Type]; !ok {
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+	if !ActionTargets(a.Type, a.Kind) {
+		return fmt.Errorf("%s cannot act on a %s", a.Type, a.Kind)
+	}
+	if a.ClusterID == "" || a.Name == "" {
+		return fmt.Errorf("action needs a cluster_id and a name")
+	}
+	if a.Kind != "Node" && a.Namespace == "" {
+		return fmt.Errorf("%s needs a namespace", a.Kind)
+	}
+	return nil
+}
+
+// Playbook step actions, besides the response action types
+const (
+	StepSnapshotLogs = "snapshot-logs"
+	StepLabelPod     = "label-pod"
+	StepNotify       = "notify"
+)
+
+// Playbook run and step statuses
+const (
+	PlaybookRunning   = "running"
+	PlaybookSucceeded = "succeeded"
+	PlaybookFailed    = "failed"
+	StepSkipped       = "skipped"
+	StepTimedOut      = "timed-out"
+)
+
+// DefaultStepTimeout bounds a step that sets no timeout_seconds
+const DefaultStepTimeout = time.Minute
+
+// Playbook is an ordered list of response steps. It runs for a violation of a
+// policy that links it when the trigger matches the alert.
+type Playbook struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Trigger     PlaybookCondition `json:"trigger"`
+	Steps       []PlaybookStep    `json:"steps"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// PlaybookCondition matches the alert a playbook runs for and the kind of
+// the violating object. Empty fields match anything.
+type PlaybookCondition struct {
+	Namespaces  []string `json:"namespaces,omitempty"`
+	MinSeverity string   `json:"min_severity,omitempty"`
+	Kinds       []string `json:"kinds,omitempty"`
+}
+
+// Matches reports whether an alert about an object of kind meets the condition
+func (c PlaybookCondition) Matches(namespace, severity, kind string) bool {
+	if SeverityRank(severity) < SeverityRank(c.MinSeverity) {
+		return false
+	}
+	if len(c.Namespaces) > 0 && !containsString(c.Namespaces, namespace) {
+		return false
+	}
+	return len(c.Kinds) == 0 || containsString(c.Kinds, kind)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PlaybookStep is one action of a playbook. A step whose When condition does
+// not match is skipped. A failed or timed out step runs its OnFailure steps
+// and then stops the playbook unless ContinueOnFailure is set.
+type PlaybookStep struct {
+	Name              string             `json:"name"`
+	Action            string             `json:"action"` // a step action or a response action type
+	Params            map[string]string  `json:"params,omitempty"`
+	When              *PlaybookCondition `json:"when,omitempty"`
+	TimeoutSeconds    int                `json:"timeout_seconds,omitempty"`
+	ContinueOnFailure bool               `json:"continue_on_failure,omitempty"`
+	OnFailure         []PlaybookStep     `json:"on_failure,omitempty"`
+}
+
+// Timeout returns how long the step may run
+func (st PlaybookStep) Timeout() time.Duration {
+	if st.TimeoutSeconds <= 0 {
+		return DefaultStepTimeout
+	}
+	return time.Duration(st.TimeoutSeconds) * time.Second
+}
+
+// Validate checks that the playbook has steps and that each is runnable
+func (pb Playbook) Validate() error {
+	if pb.Name == "" {
+		return fmt.Errorf("playbook needs a name")
+	}
+	if len(pb.Steps) == 0 {
+		return fmt.Errorf("playbook needs at least one step")
+	}
+	return validateSteps(pb.Steps)
+}
+
+func validateSteps(steps []PlaybookStep) error {
+	for i, st := range steps {
+		if st.Name == "" {
+			return fmt.Errorf("step %d needs a name", i+1)
+		}
+		switch st.Action {
+		case StepSnapshotLogs:
+		case StepLabelPod:
+			if st.Params["key"] == "" {
+				return fmt.Errorf("step %q needs a key param", st.Name)
+			}
+		case StepNotify:
+			if st.Params["channel_id"] == "" {
+				return fmt.Errorf("step %q needs a channel_id param", st.Name)
+			}
+		default:
+			if DefaultActionKind(st.Action) == "" {
+				return fmt.Errorf("step %q has unknown action %q", st.Name, st.Action)
+			}
+		}
+		if st.TimeoutSeconds < 0 {
+			return fmt.Errorf("step %q has a negative timeout", st.Name)
+		}
+		if err := validateSteps(st.OnFailure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlaybookRun is one execution of a playbook against an object. Dry runs act
+// on a fake clientset seeded with copies of the objects.
+type PlaybookRun struct {
+	ID         string               `json:"id"`
+	PlaybookID string               `json:"playbook_id"`
+	PolicyID   string               `json:"policy_id,omitempty"`
+	AlertID    string               `json:"alert_id,omitempty"`
+	ClusterID  string               `json:"cluster_id"`
+	Kind       string               `json:"kind"`
+	Namespace  string               `json:"namespace,omitempty"`
+	Name       string               `json:"name"`
+	Severity   string               `json:"severity,omitempty"`
+	DryRun     bool                 `json:"dry_run"`
+	Status     string               `json:"status"`
+	Timeline   []PlaybookStepResult `json:"timeline"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at"`
+}
+
+// PlaybookStepResult is one entry of a run's timeline. Branch names the step
+// whose on_failure steps it belongs to.
+type PlaybookStepResult struct {
+	Step       string    `json:"step"`
+	Action     string    `json:"action"`
+	Branch     string    `json:"branch,omitempty"`
+	Status     string    `json:"status"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Before     string    `json:"before,omitempty"`
+	After      string    `json:"after,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+const (
+	PSSPrivileged = "privileged"
+	PSSBaseline   = "baseline"
+	PSSRestricted = "restricted"
+)
+
+// PSSViolation is a single field that breaks a Pod Security Standards profile
+type PSSViolation struct {
+	Profile string `json:"profile"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Kinds of stored scans, whose last run is recorded per cluster so that a scan
+// that found nothing is told apart from none
+const (
+	ScanPSS     = "pss"
+	ScanImages  = "images"
+	ScanSecrets = "secrets"
+)
+
+// PSSFinding is the Pod Security Standards classification of one workload
+type PSSFinding struct {
+	ClusterID  string         `json:"cluster_id"`
+	Kind       string         `json:"kind"`
+	Namespace  string         `json:"namespace"`
+	Name       string         `json:"name"`
+	Level      string         `json:"level"` // strictest profile the workload satisfies
+	Violations []PSSViolation `json:"violations"`
+	ScannedAt  time.Time      `json:"scanned_at"`
+}
+
+// ImageFinding is an image hygiene problem of a single container
+type ImageFinding struct {
+	ClusterID  string    `json:"cluster_id"`
+	PolicyID   string    `json:"policy_id,omitempty"`
+	Namespace  string    `json:"namespace"`
+	Pod        string    `json:"pod"`
+	Container  string    `json:"container"`
+	Image      string    `json:"image"`
+	Check      string    `json:"check"`
+	Severity   string    `json:"severity"`
+	Message    string    `json:"message"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// SecretFinding is a secret hygiene problem. It only ever references secrets
+// by name and key and never carries their values.
+type SecretFinding struct {
+	ClusterID  string    `json:"cluster_id"`
+	Check      string    `json:"check"`
+	Severity   string    `json:"severity"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	Reference  string    `json:"reference,omitempty"` // e.g. "secret db-creds key password"
+	Message    string    `json:"message"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+const (
+	CISPass   = "pass"
+	CISFail   = "fail"
+	CISManual = "manual"
+)
+
+// CISControl is the result of one CIS Kubernetes Benchmark control
+type CISControl struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Evidence []string `json:"evidence"`
+}
+
+// CISRun is a stored benchmark run. Version numbers increase per cluster.
+type CISRun struct {
+	ID        string       `json:"id"`
+	ClusterID string       `json:"cluster_id"`
+	Version   int          `json:"version"`
+	Benchmark string       `json:"benchmark"`
+	StartedAt time.Time    `json:"started_at"`
+	Controls  []CISControl `json:"controls"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleOnCall(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) // a Monday
+	week := 7 * 24 * time.Hour
+	s := Schedule{
+		Rotation:      []string{"u1", "u2", "u3"},
+		RotationStart: start,
+		Overrides: []ScheduleOverride{
+			{UserID: "u9", Start: start.Add(week + 24*time.Hour), End: start.Add(week + 48*time.Hour)},
+		},
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"before the rotation starts", start.Add(-time.Minute), ""},
+		{"first week", start, "u1"},
+		{"last moment of the first week", start.Add(week - time.Nanosecond), "u1"},
+		{"second week", start.Add(week), "u2"},
+		{"override", start.Add(week + 24*time.Hour), "u9"},
+		{"override ended", start.Add(week + 48*time.Hour), "u2"},
+		{"third week", start.Add(2*week + time.Hour), "u3"},
+		{"rotation wraps around", start.Add(3 * week), "u1"},
+		{"months later", start.Add(10*week + 3*24*time.Hour), "u2"},
+	}
+	for _, tt := range tests {
+		if got := s.OnCall(tt.at); got != tt.want {
+			t.Errorf("%s: OnCall = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	overridesOnly := Schedule{Overrides: s.Overrides}
+	if got := overridesOnly.OnCall(start.Add(week + 36*time.Hour)); got != "u9" {
+		t.Errorf("overrides only, during the override: OnCall = %q, want u9", got)
+	}
+	if got := overridesOnly.OnCall(start); got != "" {
+		t.Errorf("overrides only, outside the override: OnCall = %q, want nobody", got)
+	}
+}
@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body, keyed with the
+// channel secret, as "sha256=<hex>"
+const SignatureHeader = "X-KSMS-Signature"
+
+// Sender delivers an alert over one type of channel
+type Sender interface {
+	Send(ctx context.Context, ch models.Channel, a models.Alert) error
+}
+
+// WebhookSender posts the alert as JSON, signed when the channel has a secret
+type WebhookSender struct {
+	Client *http.Client
+}
+
+func (s WebhookSender) Send(ctx context.Context, ch models.Channel, a models.Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if ch.Secret != "" {
+		header.Set(SignatureHeader, "sha256="+Sign(ch.Secret, body))
+	}
+	return post(ctx, s.Client, ch.URL, header, body)
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SlackSender posts a text message to a Slack or Mattermost incoming webhook
+type SlackSender struct {
+	Client *http.Client
+}
+
+func (s SlackSender) Send(ctx context.Context, ch models.Channel, a models.Alert) error {
+	body, err := json.Marshal(map[string]string{"text": Summary(a)})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, ch.URL, http.Header{"Content-Type": {"application/json"}}, body)
+}
+
+// SMTPSender mails the alert. Authentication is only used when the channel
+// has a username.
+type SMTPSender struct{}
+
+func (SMTPSender) Send(ctx context.Context, ch models.Channel, a models.Alert) error {
+	var auth smtp.Auth
+	if ch.Username != "" {
+		auth = smtp.PlainAuth("", ch.Username, ch.Password, smtpHost(ch.SMTPAddr))
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", ch.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(ch.To, ", "))
+	// the summary carries the alert message, which an attacker can shape, so
+	// it is encoded rather than written raw where a CRLF would end the header
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Summary(a)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nCluster: %s\r\nSeverity: %s\r\nRule: %s\r\nResource: %s\r\nRaised: %s\r\nAlert ID: %s\r\n",
+		a.Message, a.ClusterID, a.Severity, a.Rule, a.Resource, a.Timestamp.Format(time.RFC3339), a.ID)
+
+	// the connection is closed when the context ends, so the send never
+	// outlives the attempt
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", ch.SMTPAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := sendMail(conn, smtpHost(ch.SMTPAddr), auth, ch.From, ch.To, msg.Bytes()); err != nil {
+		// the connection's deadline is the context's, so a timeout means the
+		// context is ending, though it may not report so yet
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			<-ctx.Done()
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// sendMail is smtp.SendMail over an established connection
+func sendMail(conn net.Conn, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// smtpHost strips the port from an SMTP address
+func smtpHost(addr string) string {
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		return addr[:i]
+	}
+	return addr
+}
+
+// Summary is the one line form of an alert used in chat messages and subjects
+func Summary(a models.Alert) string {
+	return fmt.Sprintf("[%s] cluster %s: %s", strings.ToUpper(a.Severity), a.ClusterID, a.Message)
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
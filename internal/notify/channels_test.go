@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+// capture serves one request and hands back its header and body
+func capture(t *testing.T) (*httptest.Server, <-chan *http.Request, <-chan []byte) {
+	reqs, bodies := make(chan *http.Request, 1), make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- body
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs, bodies
+}
+
+func TestWebhookSignsBody(t *testing.T) {
+	for _, secret := range []string{"s3cret", ""} {
+		srv, reqs, bodies := capture(t)
+		ch := models.Channel{Type: models.ChannelWebhook, URL: srv.URL, Secret: secret}
+		if err := (WebhookSender{Client: srv.Client()}).Send(context.Background(), ch, testAlert()); err != nil {
+			t.Fatal(err)
+		}
+		r, body := <-reqs, <-bodies
+
+		var got models.Alert
+		if err := json.Unmarshal(body, &got); err != nil || got.ID != testAlert().ID {
+			t.Errorf("body = %s, want the alert as JSON", body)
+		}
+		signature := r.Header.Get(SignatureHeader)
+		if secret == "" {
+			if signature != "" {
+				t.Errorf("unsigned channel sent %s %s", SignatureHeader, signature)
+			}
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+			t.Errorf("%s = %q, want %q", SignatureHeader, signature, want)
+		}
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv, reqs, bodies := capture(t)
+	ch := models.Channel{Type: models.ChannelSlack, URL: srv.URL}
+	if err := (SlackSender{Client: srv.Client()}).Send(context.Background(), ch, testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	r, body := <-reqs, <-bodies
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := "[HIGH] cluster c1: Privileged pod web/debug created"
+	if len(payload) != 1 || payload["text"] != want {
+		t.Errorf("payload = %s, want only text %q", body, want)
+	}
+}
+
+// smtpServer is a minimal SMTP server that accepts one message and sends it
+// on the returned channel
+func smtpServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	msgs := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				msgs <- strings.Join(envelope, "\n") + "\n" + string(data)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), msgs
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, msgs := smtpServer(t)
+	ch := models.Channel{Type: models.ChannelSMTP, SMTPAddr: addr, From: "ksms@example.com", To: []string{"a@example.com", "b@example.com"}}
+	if err := (SMTPSender{}).Send(context.Background(), ch, testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-msgs
+	for _, want := range []string{
+		"MAIL FROM:<ksms@example.com>",
+		"RCPT TO:<a@example.com>",
+		"RCPT TO:<b@example.com>",
+		"To: a@example.com, b@example.com",
+		"Subject: [HIGH] cluster c1: Privileged pod web/debug created",
+		"Alert ID: 20260101120000-1",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSMTPSenderEncodesSubject(t *testing.T) {
+	addr, msgs := smtpServer(t)
+	ch := models.Channel{Type: models.ChannelSMTP, SMTPAddr: addr, From: "ksms@example.com", To: []string{"a@example.com"}}
+	a := testAlert()
+	a.Message = "Pod café/debug created\r\nBcc: attacker@example.com\r\n\r\nforged body"
+	if err := (SMTPSender{}).Send(context.Background(), ch, a); err != nil {
+		t.Fatal(err)
+	}
+	raw := <-msgs
+	data := raw[strings.Index(raw, "From: "):]
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcc := m.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("message has an injected Bcc header %q", bcc)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != Summary(a) {
+		t.Errorf("subject = %q, want %q", subject, Summary(a))
+	}
+}
+
+func TestSMTPSenderHonoursContext(t *testing.T) {
+	// a server that accepts connections and never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, bufio.NewReader(conn))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ch := models.Channel{Type: models.ChannelSMTP, SMTPAddr: l.Addr().String(), From: "ksms@example.com", To: []string{"a@example.com"}}
+	done := make(chan error, 1)
+	go func() { done <- (SMTPSender{}).Send(ctx, ch, testAlert()) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Send = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send did not return after the context ended")
+	}
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/alerts"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// Escalator pages the tiers of escalation policies while alerts stay open.
+// Pending escalations live in storage, so with DatabaseStorage they are
+// picked up again after a restart.
+type Escalator struct {
+	Storage  storage.Storage
+	Notifier *Notifier
+}
+
+func NewEscalator(store storage.Storage, notifier *Notifier) *Escalator {
+	return &Escalator{Storage: store, Notifier: notifier}
+}
+
+// Run starts escalations for new alerts and pages due tiers every interval
+// until the context is cancelled
+func (e *Escalator) Run(ctx context.Context, bus *alerts.Bus, interval time.Duration) {
+	go alerts.Follow(ctx, bus, func(ev alerts.Event) {
+		switch ev.Type {
+		case alerts.EventAlert:
+			e.Start(ev.Alert)
+		case alerts.EventTransition:
+			if ev.Alert.Status != models.AlertOpen {
+				e.Storage.DeleteEscalation(ev.Alert.ID)
+			}
+		}
+	})
+	for {
+		e.EscalateDue(ctx, time.Now())
+		if !sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// Start schedules the first tier of the first policy that applies to the alert
+func (e *Escalator) Start(a models.Alert) {
+	for _, p := range e.Storage.GetEscalationPolicies() {
+		if p.Applies(a) {
+			e.Storage.SaveEscalation(models.Escalation{
+				AlertID:  a.ID,
+				PolicyID: p.ID,
+				DueAt:    a.Timestamp.Add(time.Duration(p.Tiers[0].AfterMinutes) * time.Minute),
+			})
+			return
+		}
+	}
+}
+
+// EscalateDue pages every tier due at now whose alert is still open and
+// schedules the following tier. A tier is claimed in storage before it is
+// paged, so an escalation stopped by an acknowledgement in the meantime stays
+// stopped and concurrent runs page each tier once.
+func (e *Escalator) EscalateDue(ctx context.Context, now time.Time) {
+	for _, esc := range e.Storage.GetEscalations() {
+		if esc.DueAt.After(now) {
+			continue
+		}
+		a, err := e.Storage.GetAlert(esc.AlertID)
+		if err != nil || a.Status != models.AlertOpen {
+			e.Storage.DeleteEscalation(esc.AlertID)
+			continue
+		}
+		p, err := e.Storage.GetEscalationPolicy(esc.PolicyID)
+		if err != nil || esc.NextTier >= len(p.Tiers) {
+			e.Storage.DeleteEscalation(esc.AlertID)
+			continue
+		}
+
+		tier := esc.NextTier
+		esc.NextTier++
+		if esc.NextTier < len(p.Tiers) {
+			esc.DueAt = a.Timestamp.Add(time.Duration(p.Tiers[esc.NextTier].AfterMinutes) * time.Minute)
+		}
+		if err := e.Storage.AdvanceEscalation(esc); errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrConflict) {
+			continue
+		} else if err != nil {
+			log.Printf("Escalation of alert %s not advanced: %v", a.ID, err)
+			continue
+		}
+		e.page(ctx, p, tier, a, now)
+		if esc.NextTier == len(p.Tiers) {
+			e.Storage.DeleteEscalation(esc.AlertID)
+		}
+	}
+}
+
+func (e *Escalator) page(ctx context.Context, p models.EscalationPolicy, tier int, a models.Alert, now time.Time) {
+	t := p.Tiers[tier]
+	ch, err := e.Storage.GetChannel(t.ChannelID)
+	if err != nil {
+		log.Printf("Escalation of alert %s skipped tier %d: channel %s: %v", a.ID, tier+1, t.ChannelID, err)
+		return
+	}
+	users := e.Recipients(ctx, t, now)
+	names := make([]string, 0, len(users))
+	var emails []string
+	for _, u := range users {
+		names = append(names, strings.TrimSpace(u.FirstName+" "+u.LastName))
+		emails = append(emails, u.Email)
+	}
+	if ch.Type == models.ChannelSMTP && len(emails) > 0 {
+		ch.To = emails
+	}
+	a.Message = fmt.Sprintf("Escalation %q tier %d (%s): alert not acknowledged: %s",
+		p.Name, tier+1, strings.Join(names, ", "), a.Message)
+	e.Notifier.Deliver(ctx, ch, a)
+}
+
+// Recipients resolves the users paged by a tier at t
+func (e *Escalator) Recipients(ctx context.Context, t models.EscalationTier, at time.Time) []models.User {
+	ids := append([]string(nil), t.UserIDs...)
+	if t.ScheduleID != "" {
+		if sc, err := e.Storage.GetSchedule(t.ScheduleID); err == nil {
+			if id := sc.OnCall(at); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	var users []models.User
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		if u, err := e.Storage.GetUser(id); err == nil {
+			seen[id] = true
+			users = append(users, u)
+		}
+	}
+	if len(t.Roles) > 0 {
+		all, err := storage.All(ctx, e.Storage.GetAllUsers, storage.ListOptions{})
+		if err != nil {
+			log.Printf("Escalation cannot list users by role: %v", err)
+		}
+		for _, u := range all {
+			for _, r := range t.Roles {
+				if u.Role == r && !seen[u.ID] {
+					seen[u.ID] = true
+					users = append(users, u)
+				}
+			}
+		}
+	}
+	return users
+}
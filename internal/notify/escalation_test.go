@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// recordingSender records the messages sent instead of sending them
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []sent
+}
+
+type sent struct {
+	to      []string
+	message string
+}
+
+func (s *recordingSender) Send(ctx context.Context, ch models.Channel, a models.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, sent{to: ch.To, message: a.Message})
+	return nil
+}
+
+func (s *recordingSender) take() []sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	taken := s.sent
+	s.sent = nil
+	return taken
+}
+
+// testEscalator has a three tier policy: u1 at once, the on-call user after
+// 10 minutes and every Administrator after 30
+func testEscalator(t *testing.T, store storage.Storage) (*Escalator, *recordingSender, models.Alert) {
+	t.Helper()
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, u := range []models.User{
+		{ID: "u1", Email: "u1@example.com", FirstName: "Una", Role: models.RoleSecurityAnalyst},
+		{ID: "u2", Email: "u2@example.com", FirstName: "Ugo", Role: models.RoleSecurityAnalyst},
+		{ID: "u3", Email: "u3@example.com", FirstName: "Uma", Role: models.RoleSecurityAnalyst},
+		{ID: "admin", Email: "admin@example.com", FirstName: "Ada", Role: models.RoleAdmin},
+	} {
+		must(t, store.AddUser(u))
+	}
+	must(t, store.AddChannel(models.Channel{ID: "mail", Name: "mail", Type: models.ChannelSMTP, To: []string{"soc@example.com"}}))
+	must(t, store.AddSchedule(models.Schedule{ID: "sched", Rotation: []string{"u2", "u3"}, RotationStart: start.Add(-7 * 24 * time.Hour)}))
+	must(t, store.AddEscalationPolicy(models.EscalationPolicy{ID: "ep1", Name: "soc", MinSeverity: models.SeverityHigh, Tiers: []models.EscalationTier{
+		{AfterMinutes: 0, ChannelID: "mail", UserIDs: []string{"u1"}},
+		{AfterMinutes: 10, ChannelID: "mail", ScheduleID: "sched"},
+		{AfterMinutes: 30, ChannelID: "mail", Roles: []models.Role{models.RoleAdmin}, UserIDs: []string{"u1"}},
+	}}))
+	a, _, err := store.UpsertAlert(models.Alert{ID: "a1", ClusterID: "c1", Severity: models.SeverityCritical, Message: "shell in pod", Timestamp: start})
+	must(t, err)
+
+	sender := &recordingSender{}
+	n := NewNotifier(store)
+	n.Senders = map[string]Sender{models.ChannelSMTP: sender}
+	return NewEscalator(store, n), sender, a
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// escalate runs one escalation pass and waits for its pages to be sent
+func escalate(e *Escalator, at time.Time) {
+	e.EscalateDue(context.Background(), at)
+	e.Notifier.wg.Wait()
+}
+
+func TestEscalatorTiers(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	e, sender, a := testEscalator(t, store)
+	e.Start(a)
+	e.Start(models.Alert{ID: "low", Severity: models.SeverityLow, Timestamp: a.Timestamp})
+
+	steps := []struct {
+		after time.Duration
+		to    []string // recipients paged, none when the tier is not due yet
+		tier  string
+	}{
+		{0, []string{"u1@example.com"}, "tier 1 (Una)"},
+		{9 * time.Minute, nil, ""},
+		{10 * time.Minute, []string{"u3@example.com"}, "tier 2 (Uma)"}, // second week of the rotation
+		{10 * time.Minute, nil, ""},                                    // a tier is paged once
+		{45 * time.Minute, []string{"u1@example.com", "admin@example.com"}, "tier 3 (Una, Ada)"},
+		{2 * time.Hour, nil, ""},
+	}
+	for _, step := range steps {
+		escalate(e, a.Timestamp.Add(step.after))
+		got := sender.take()
+		if step.to == nil {
+			if len(got) != 0 {
+				t.Errorf("after %s: paged %+v, want nothing", step.after, got)
+			}
+			continue
+		}
+		if len(got) != 1 || !slices.Equal(got[0].to, step.to) || !strings.Contains(got[0].message, step.tier) {
+			t.Errorf("after %s: paged %+v, want %s to %v", step.after, got, step.tier, step.to)
+		}
+	}
+	if escalations := store.GetEscalations(); len(escalations) != 0 {
+		t.Errorf("escalations after the last tier = %+v", escalations)
+	}
+}
+
+func TestEscalatorStopsOnAcknowledgement(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	e, sender, a := testEscalator(t, store)
+	e.Start(a)
+	escalate(e, a.Timestamp)
+	sender.take()
+
+	_, err := store.TransitionAlert(a.ID, models.AlertTransition{To: models.AlertAcknowledged, By: "u1", At: a.Timestamp.Add(time.Minute)})
+	must(t, err)
+	escalate(e, a.Timestamp.Add(time.Hour))
+	if got := sender.take(); len(got) != 0 {
+		t.Errorf("paged %+v after the alert was acknowledged", got)
+	}
+	if escalations := store.GetEscalations(); len(escalations) != 0 {
+		t.Errorf("escalations after the acknowledgement = %+v", escalations)
+	}
+}
+
+// ackingStorage acknowledges the alert as soon as the escalator has read it,
+// the way the stream follower would delete the escalation on a concurrent
+// acknowledgement
+type ackingStorage struct {
+	storage.Storage
+}
+
+func (s ackingStorage) GetAlert(id string) (models.Alert, error) {
+	a, err := s.Storage.GetAlert(id)
+	if err == nil {
+		s.Storage.DeleteEscalation(id)
+	}
+	return a, err
+}
+
+func TestEscalatorDoesNotReviveStoppedEscalation(t *testing.T) {
+	mem := storage.NewMemoryStorage()
+	e, sender, a := testEscalator(t, mem)
+	e.Start(a)
+	e.Storage = ackingStorage{mem}
+
+	escalate(e, a.Timestamp)
+	if got := sender.take(); len(got) != 0 {
+		t.Errorf("paged %+v for an escalation stopped meanwhile", got)
+	}
+	if escalations := mem.GetEscalations(); len(escalations) != 0 {
+		t.Errorf("escalations = %+v, want the stopped escalation to stay deleted", escalations)
+	}
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/alerts"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// Notifier sends new alerts to the channels whose routing rules match. Failed
+// deliveries are retried with exponential backoff and recorded as dead
+// letters once all attempts are used up.
+type Notifier struct {
+	Storage  storage.Storage
+	Senders  map[string]Sender
+	Attempts int
+	Backoff  time.Duration // delay before the first retry, doubled for each further one
+	Timeout  time.Duration // per attempt
+	wg       sync.WaitGroup
+}
+
+func NewNotifier(store storage.Storage) *Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &Notifier{
+		Storage: store,
+		Senders: map[string]Sender{
+			models.ChannelWebhook: WebhookSender{Client: client},
+			models.ChannelSlack:   SlackSender{Client: client},
+			models.ChannelSMTP:    SMTPSender{},
+		},
+		Attempts: 5,
+		Backoff:  time.Second,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// Run notifies about every new alert published on the bus until the context
+// is cancelled
+func (n *Notifier) Run(ctx context.Context, bus *alerts.Bus) {
+	alerts.Follow(ctx, bus, func(ev alerts.Event) {
+		if ev.Type == alerts.EventAlert {
+			n.Notify(ctx, ev.Alert)
+		}
+	})
+	n.wg.Wait()
+}
+
+// Notify delivers an alert to every matching channel in the background
+func (n *Notifier) Notify(ctx context.Context, a models.Alert) {
+	channels, err := storage.All(ctx, n.Storage.GetChannels, storage.ListOptions{})
+	if err != nil {
+		log.Printf("Notification of alert %s may miss channels: %v", a.ID, err)
+	}
+	for _, ch := range channels {
+		if !ch.Routes(a) {
+			continue
+		}
+		n.Deliver(ctx, ch, a)
+	}
+}
+
+// Deliver sends an alert to a channel in the background with the usual
+// retries and dead-lettering
+func (n *Notifier) Deliver(ctx context.Context, ch models.Channel, a models.Alert) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(ctx, ch, a)
+	}()
+}
+
+// Test sends a test notification to a channel once, without retries
+func (n *Notifier) Test(ctx context.Context, ch models.Channel) error {
+	now := time.Now()
+	return n.send(ctx, ch, models.Alert{
+		ID:        "test-" + now.Format("20060102150405"),
+		ClusterID: "test",
+		Rule:      "test-notification",
+		Severity:  models.SeverityInfo,
+		Message:   fmt.Sprintf("Test notification for channel %q", ch.Name),
+		Status:    models.AlertOpen,
+		Timestamp: now,
+	})
+}
+
+func (n *Notifier) deliver(ctx context.Context, ch models.Channel, a models.Alert) {
+	var err error
+	delay := n.Backoff
+	attempt := 1
+	for ; ; attempt++ {
+		if err = n.send(ctx, ch, a); err == nil {
+			return
+		}
+		if attempt >= n.Attempts {
+			break
+		}
+		if !sleep(ctx, delay) {
+			err = ctx.Err()
+			break
+		}
+		delay *= 2
+	}
+	log.Printf("Notification of alert %s to channel %s failed after %d attempts: %v", a.ID, ch.ID, attempt, err)
+	// the same alert can fail on a channel more than once, e.g. when an
+	// escalation pages it again, so every failure gets its own dead letter
+	if err := n.Storage.AddDeadLetter(models.DeadLetter{
+		ID:        models.NewID("dl-"),
+		ChannelID: ch.ID,
+		AlertID:   a.ID,
+		Attempts:  attempt,
+		Error:     err.Error(),
+		FailedAt:  time.Now(),
+	}); err != nil {
+		log.Printf("Dead letter for alert %s on channel %s could not be stored: %v", a.ID, ch.ID, err)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, ch models.Channel, a models.Alert) error {
+	sender, ok := n.Senders[ch.Type]
+	if !ok {
+		return fmt.Errorf("no sender for channel type %q", ch.Type)
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.Timeout)
+	defer cancel()
+	return sender.Send(ctx, ch, a)
+}
+
+// sleep waits for d and reports false when the context ends first
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// failingServer answers the first failures requests with 503 and the rest
+// with 204, recording when each request came in
+type failingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	times    []time.Time
+}
+
+func newFailingServer(t *testing.T, failures int) *failingServer {
+	s := &failingServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.times = append(s.times, time.Now())
+		if len(s.times) <= s.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *failingServer) requests() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.times...)
+}
+
+func testNotifier(attempts int, backoff time.Duration) (*Notifier, *storage.MemoryStorage) {
+	store := storage.NewMemoryStorage()
+	n := NewNotifier(store)
+	n.Attempts, n.Backoff, n.Timeout = attempts, backoff, time.Second
+	return n, store
+}
+
+func deadLetters(t *testing.T, store storage.Storage) []models.DeadLetter {
+	t.Helper()
+	dead, err := storage.All(context.Background(), store.GetDeadLetters, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dead
+}
+
+func testAlert() models.Alert {
+	return models.Alert{
+		ID: "20260101120000-1", ClusterID: "c1", Rule: "privileged-pod", Severity: models.SeverityHigh,
+		Resource: "Pod web/debug", Message: "Privileged pod web/debug created", Status: models.AlertOpen,
+		Timestamp: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	srv := newFailingServer(t, 2)
+	n, store := testNotifier(5, 20*time.Millisecond)
+	ch := models.Channel{ID: "ch1", Type: models.ChannelWebhook, URL: srv.URL}
+
+	n.deliver(context.Background(), ch, testAlert())
+
+	times := srv.requests()
+	if len(times) != 3 {
+		t.Fatalf("got %d requests, want 3", len(times))
+	}
+	if first, second := times[1].Sub(times[0]), times[2].Sub(times[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("retried after %v and %v, want at least 20ms and then 40ms", first, second)
+	}
+	if dead := deadLetters(t, store); len(dead) != 0 {
+		t.Errorf("dead letters = %+v, want none after a successful retry", dead)
+	}
+}
+
+func TestDeliverRecordsDeadLetter(t *testing.T) {
+	srv := newFailingServer(t, 100)
+	n, store := testNotifier(3, time.Millisecond)
+	ch := models.Channel{ID: "ch1", Type: models.ChannelWebhook, URL: srv.URL}
+	a := testAlert()
+
+	// an escalation pages the same alert to the same channel again
+	n.deliver(context.Background(), ch, a)
+	n.deliver(context.Background(), ch, a)
+
+	if got := len(srv.requests()); got != 6 {
+		t.Errorf("got %d requests, want 6", got)
+	}
+	dead := deadLetters(t, store)
+	if len(dead) != 2 {
+		t.Fatalf("got %d dead letters, want one per failed delivery", len(dead))
+	}
+	if dead[0].ID == dead[1].ID || !strings.HasPrefix(dead[0].ID, "dl-") {
+		t.Errorf("dead letter IDs %s and %s", dead[0].ID, dead[1].ID)
+	}
+	for _, d := range dead {
+		if d.ChannelID != ch.ID || d.AlertID != a.ID || d.Attempts != 3 || !strings.Contains(d.Error, "503") {
+			t.Errorf("dead letter = %+v, want 3 attempts of alert %s to %s failing with 503", d, a.ID, ch.ID)
+		}
+	}
+}
+
+func TestDeliverStopsWhenCancelled(t *testing.T) {
+	srv := newFailingServer(t, 100)
+	n, store := testNotifier(5, time.Hour)
+	ch := models.Channel{ID: "ch1", Type: models.ChannelWebhook, URL: srv.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	n.deliver(ctx, ch, testAlert())
+
+	dead := deadLetters(t, store)
+	if len(dead) != 1 || dead[0].Attempts != 1 || dead[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("dead letters = %+v, want one after the first attempt with the context's error", dead)
+	}
+}
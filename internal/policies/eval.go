@@ -0,0 +1,169 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Matches reports whether an object, in its unstructured JSON form, satisfies
+// the rule. A rule that does not match is a violation.
+func (r *Rule) Matches(obj map[string]interface{}) bool {
+	return eval(r.Expr, obj)
+}
+
+func eval(e Expr, obj map[string]interface{}) bool {
+	switch e := e.(type) {
+	case *BinaryExpr:
+		if e.Op == "&&" {
+			return eval(e.Left, obj) && eval(e.Right, obj)
+		}
+		return eval(e.Left, obj) || eval(e.Right, obj)
+	case *NotExpr:
+		return !eval(e.X, obj)
+	case *Comparison:
+		// A wildcard path must hold for every element it expands to.
+		for _, v := range resolve(obj, e.Path.Steps) {
+			if !e.compare(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// resolve walks a path and returns every value it reaches. Missing fields
+// resolve to nil; wildcards over missing or empty lists resolve to nothing.
+func resolve(v interface{}, steps []Step) []interface{} {
+	if len(steps) == 0 {
+		return []interface{}{v}
+	}
+	step := steps[0]
+	switch {
+	case step.Wildcard:
+		list, _ := v.([]interface{})
+		var out []interface{}
+		for _, item := range list {
+			out = append(out, resolve(item, steps[1:])...)
+		}
+		return out
+	case step.IsIndex:
+		list, _ := v.([]interface{})
+		if step.Index >= len(list) {
+			return resolve(nil, steps[1:])
+		}
+		return resolve(list[step.Index], steps[1:])
+	default:
+		m, _ := v.(map[string]interface{})
+		return resolve(m[step.Field], steps[1:])
+	}
+}
+
+func (c *Comparison) compare(v interface{}) bool {
+	switch c.Op {
+	case "in":
+		for _, lit := range c.Value.([]interface{}) {
+			if equal(normalize(v, lit), lit) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		return contains(v, c.Value)
+	}
+
+	v = normalize(v, c.Value)
+	switch c.Op {
+	case "==":
+		return equal(v, c.Value)
+	case "!=":
+		return !equal(v, c.Value)
+	case "=~", "!~":
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+		return c.re.MatchString(s) == (c.Op == "=~")
+	}
+
+	n, ok := v.(float64)
+	if !ok {
+		return false
+	}
+	lit := c.Value.(float64)
+	switch c.Op {
+	case "<":
+		return n < lit
+	case "<=":
+		return n <= lit
+	case ">":
+		return n > lit
+	case ">=":
+		return n >= lit
+	}
+	return false
+}
+
+// normalize prepares a value for comparison with a literal. Kubernetes
+// serializes zero values with omitempty, so an absent field is compared as
+// the zero value of the literal's type.
+func normalize(v, lit interface{}) interface{} {
+	if v == nil && lit != nil {
+		v = reflect.Zero(reflect.TypeOf(lit)).Interface()
+	}
+	if n, ok := toFloat(v); ok {
+		return n
+	}
+	return v
+}
+
+// contains reports whether a string has the literal as a substring, a list
+// has it as an element or a map has it as a key. Absent fields contain
+// nothing.
+func contains(v, lit interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		s, ok := lit.(string)
+		return ok && strings.Contains(v, s)
+	case []interface{}:
+		for _, item := range v {
+			if item != nil && equal(normalize(item, lit), lit) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		s, ok := lit.(string)
+		if !ok {
+			return false
+		}
+		_, found := v[s]
+		return found
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	// Maps and lists are never equal to a literal.
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,80 @@
+package policies
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testPod = `{
+	"metadata": {"name": "api", "namespace": "web", "labels": {"app": "api", "tier": "backend"}},
+	"spec": {
+		"hostNetwork": true,
+		"priority": 100,
+		"containers": [
+			{"name": "app", "image": "api:1.2", "args": ["--port", "8080"], "securityContext": {"privileged": false}},
+			{"name": "sidecar", "image": "envoy:latest"}
+		]
+	}
+}`
+
+func TestMatches(t *testing.T) {
+	var pod map[string]interface{}
+	if err := json.Unmarshal([]byte(testPod), &pod); err != nil {
+		t.Fatal(err)
+	}
+	// unstructured objects hold integers as int64
+	pod["spec"].(map[string]interface{})["priority"] = int64(100)
+
+	for _, tc := range []struct {
+		rule string
+		want bool
+	}{
+		{`pod.spec.hostNetwork == true`, true},
+		{`pod.spec.priority == 100`, true},
+		{`pod.spec.priority >= 100`, true},
+		{`pod.spec.priority > 100`, false},
+		{`labels["tier"] == "backend"`, true},
+		{`pod.spec.containers[0].image =~ "^api:"`, true},
+		{`image !~ ":latest$"`, false},
+		{`pod.spec.containers == "x"`, false},
+
+		// missing fields compare as the literal's zero value
+		{`pod.spec.hostPID == false`, true},
+		{`pod.spec.hostPID != true`, true},
+		{`pod.spec.hostPID == null`, true},
+		{`pod.spec.replicas < 1`, true},
+		{`pod.spec.containers[5].name == ""`, true},
+		{`pod.spec.containers[*].securityContext.privileged == false`, true},
+		{`pod.spec.containers[*].securityContext.privileged == true`, false},
+
+		// a wildcard over a missing list holds vacuously
+		{`pod.spec.initContainers[*].image == "never"`, true},
+
+		{`name in ["web", "api"]`, true},
+		{`namespace in ["prod", "staging"]`, false},
+		{`pod.spec.priority in [1, 100]`, true},
+		{`pod.spec.hostPID in [false]`, true},
+		{`pod.spec.hostPID in [true, "false"]`, false},
+
+		{`labels contains "app"`, true},
+		{`labels contains "team"`, false},
+		{`pod.spec.containers[0].args contains "--port"`, true},
+		{`pod.spec.containers[0].args contains 8080`, false},
+		{`pod.spec.containers[0].image contains ":1."`, true},
+		{`pod.spec.containers[1].args contains "--port"`, false},
+		{`!(pod.spec.containers[1].args contains "--port")`, true},
+
+		{`pod.spec.hostNetwork == false && name == "x" || name == "api"`, true},
+		{`pod.spec.hostNetwork == false && (name == "x" || name == "api")`, false},
+		{`!pod.spec.hostNetwork == true || namespace == "web"`, true},
+	} {
+		r, err := Parse(tc.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.rule, err)
+			continue
+		}
+		if got := r.Matches(pod); got != tc.want {
+			t.Errorf("%s = %v, want %v", tc.rule, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/response"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Violation is an object that fails a policy rule
+type Violation struct {
+	PolicyID  string `json:"policy_id"`
+	Rule      string `json:"rule"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (v Violation) key(clusterID string) string {
+	return clusterID + "/" + v.PolicyID + "/" + v.Rule + "/" + v.Kind + "/" + v.Namespace + "/" + v.Name
+}
+
+// Evaluate checks every rule of a policy against the live objects of a
+// cluster, limited to the policy namespace when one is set
+func Evaluate(ctx context.Context, client kubernetes.Interface, p models.Policy) ([]Violation, error) {
+	rules, err := ParseRules(p.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string][]map[string]interface{})
+	var violations []Violation
+	for _, r := range rules {
+		objs, ok := objects[r.Kind]
+		if !ok {
+			objs, err = listObjects(ctx, client, r.Kind, p.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			objects[r.Kind] = objs
+		}
+		for _, obj := range objs {
+			if r.Matches(obj) {
+				continue
+			}
+			meta, _ := obj["metadata"].(map[string]interface{})
+			ns, _ := meta["namespace"].(string)
+			name, _ := meta["name"].(string)
+			violations = append(violations, Violation{
+				PolicyID:  p.ID,
+				Rule:      r.Source,
+				Kind:      Kinds[r.Kind],
+				Namespace: ns,
+				Name:      name,
+			})
+		}
+	}
+	return violations, nil
+}
+
+func listObjects(ctx context.Context, client kubernetes.Interface, kind, namespace string) ([]map[string]interface{}, error) {
+	opts := metav1.ListOptions{}
+	var items []interface{}
+	switch kind {
+	case "pod":
+		list, err := client.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			items = append(items, &list.Items[i])
+		}
+	case "deployment":
+		list, err := client.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			items = append(items, &list.Items[i])
+		}
+	case "statefulset":
+		list, err := client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			items = append(items, &list.Items[i])
+		}
+	case "daemonset":
+		list, err := client.AppsV1().DaemonSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			items = append(items, &list.Items[i])
+		}
+	case "service":
+		list, err := client.CoreV1().Services(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			items = append(items, &list.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", kind)
+	}
+
+	objs := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// Evaluator periodically evaluates every stored policy against every
+// connected cluster and raises an alert for each new violation. Policies
+// with responses also request a response action for it, and policies with
+// playbooks start the playbooks whose trigger matches.
+type Evaluator struct {
+	Storage   storage.Storage
+	K8s       *k8s.ClusterManager
+	Responder *response.Responder
+	Playbooks *response.PlaybookRunner
+	active    map[string]bool
+	mu        sync.Mutex
+}
+
+func NewEvaluator(store storage.Storage, mgr *k8s.ClusterManager, responder *response.Responder, playbooks *response.PlaybookRunner) *Evaluator {
+	return &Evaluator{
+		Storage:   store,
+		K8s:       mgr,
+		Responder: responder,
+		Playbooks: playbooks,
+		active:    make(map[string]bool),
+	}
+}
+
+// Run evaluates all policies every interval until the context is cancelled
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.EvaluateAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateAll runs one evaluation pass. Violations that persist across passes
+// are only reported once; a violation that clears and reappears is reported
+// again.
+func (e *Evaluator) EvaluateAll(ctx context.Context) {
+	policies, err := storage.All(ctx, e.Storage.GetPolicies, storage.ListOptions{})
+	if err != nil {
+		log.Printf("Policy evaluation skipped: %v", err)
+		return
+	}
+	clusters, err := storage.All(ctx, e.Storage.GetClusters, storage.ListOptions{})
+	if err != nil {
+		log.Printf("Policy evaluation skipped: %v", err)
+		return
+	}
+	current := make(map[string]bool)
+
+	for _, c := range clusters {
+		client, err := e.K8s.GetClient(c.ID, c.KubeConfig)
+		if err != nil {
+			log.Printf("Policy evaluation skipped for cluster %s: %v", c.ID, err)
+			e.carryOver(current, c.ID+"/")
+			continue
+		}
+		for _, p := range policies {
+			violations, err := Evaluate(ctx, client, p)
+			if err != nil {
+				log.Printf("Policy %s evaluation failed on cluster %s: %v", p.ID, c.ID, err)
+				e.carryOver(current, c.ID+"/"+p.ID+"/")
+				continue
+			}
+			for _, v := range violations {
+				key := v.key(c.ID)
+				current[key] = true
+				if e.wasActive(key) {
+					continue
+				}
+				a, created, err := e.Storage.UpsertAlert(models.Alert{
+					ID:        k8s.NewAlertID(),
+					ClusterID: c.ID,
+					PolicyID:  p.ID,
+					Rule:      "policy:" + p.ID,
+					Namespace: v.Namespace,
+					Resource:  v.Kind + " " + v.Namespace + "/" + v.Name,
+					Severity:  severity(p),
+					Message: fmt.Sprintf("Policy %q violated by %s %s/%s: %s",
+						p.Name, v.Kind, v.Namespace, v.Name, v.Rule),
+					Timestamp: time.Now(),
+				})
+				if err != nil {
+					log.Printf("Policy %s violation on cluster %s not stored: %v", p.ID, c.ID, err)
+					continue
+				}
+				// a violation still open from before a restart, or deduplicated
+				// into an open alert, already had its responses
+				if !created {
+					continue
+				}
+				e.respond(ctx, p, v, a)
+				e.startPlaybooks(ctx, p, v, a)
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.active = current
+	e.mu.Unlock()
+}
+
+// respond requests the policy responses that can act on the violating
+// object. Silenced alerts are left alone, as during maintenance.
+func (e *Evaluator) respond(ctx context.Context, p models.Policy, v Violation, a models.Alert) {
+	if e.Responder == nil || a.SilenceID != "" {
+		return
+	}
+	for _, typ := range p.Responses {
+		if !models.ActionTargets(typ, v.Kind) {
+			continue
+		}
+		action, err := e.Responder.Request(ctx, models.ResponseAction{
+			ClusterID:   a.ClusterID,
+			AlertID:     a.ID,
+			PolicyID:    p.ID,
+			Type:        typ,
+			Kind:        v.Kind,
+			Namespace:   v.Namespace,
+			Name:        v.Name,
+			Auto:        p.AutoRespond,
+			RequestedBy: "policy:" + p.ID,
+		})
+		if err != nil {
+			log.Printf("Response %s for policy %s not requested: %v", typ, p.ID, err)
+		} else if action.Status == models.ActionFailed {
+			log.Printf("Response action %s failed: %s", action.ID, action.Result)
+		}
+	}
+}
+
+// startPlaybooks runs the policy's playbooks whose trigger matches the
+// violation in the background
+func (e *Evaluator) startPlaybooks(ctx context.Context, p models.Policy, v Violation, a models.Alert) {
+	if e.Playbooks == nil || a.SilenceID != "" {
+		return
+	}
+	for _, id := range p.Playbooks {
+		pb, err := e.Storage.GetPlaybook(id)
+		if err != nil {
+			log.Printf("Playbook %s of policy %s not found: %v", id, p.ID, err)
+			continue
+		}
+		if !pb.Trigger.Matches(v.Namespace, a.Severity, v.Kind) {
+			continue
+		}
+		if _, err := e.Playbooks.Start(ctx, pb, models.PlaybookRun{
+			PolicyID:  p.ID,
+			AlertID:   a.ID,
+			ClusterID: a.ClusterID,
+			Kind:      v.Kind,
+			Namespace: v.Namespace,
+			Name:      v.Name,
+			Severity:  a.Severity,
+		}); err != nil {
+			log.Printf("Playbook %s for policy %s not started: %v", pb.ID, p.ID, err)
+		}
+	}
+}
+
+func severity(p models.Policy) string {
+	if p.Severity == "" {
+		return models.SeverityMedium
+	}
+	return p.Severity
+}
+
+// carryOver keeps the previous violations under a prefix when they could not
+// be re-evaluated, so a transient error does not re-raise them later
+func (e *Evaluator) carryOver(current map[string]bool, prefix string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key := range e.active {
+		if strings.HasPrefix(key, prefix) {
+			current[key] = true
+		}
+	}
+}
+
+func (e *Evaluator) wasActive(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active[key]
+}
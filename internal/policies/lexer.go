@@ -0,0 +1,225 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDot
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+	tokStar
+	tokComma
+	tokNot
+	tokAnd
+	tokOr
+	tokEq
+	tokNeq
+	tokMatch
+	tokNotMatch
+	tokLt
+	tokLte
+	tokGt
+	tokGte
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:      "end of rule",
+	tokIdent:    "identifier",
+	tokString:   "string",
+	tokNumber:   "number",
+	tokDot:      `"."`,
+	tokLBracket: `"["`,
+	tokRBracket: `"]"`,
+	tokLParen:   `"("`,
+	tokRParen:   `")"`,
+	tokStar:     `"*"`,
+	tokComma:    `","`,
+	tokNot:      `"!"`,
+	tokAnd:      `"&&"`,
+	tokOr:       `"||"`,
+	tokEq:       `"=="`,
+	tokNeq:      `"!="`,
+	tokMatch:    `"=~"`,
+	tokNotMatch: `"!~"`,
+	tokLt:       `"<"`,
+	tokLte:      `"<="`,
+	tokGt:       `">"`,
+	tokGte:      `">="`,
+}
+
+func (k tokenKind) String() string {
+	return tokenNames[k]
+}
+
+// Pos is a 1-based line/column position inside a rule
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+type lexer struct {
+	src  []rune
+	off  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: []rune(src), line: 1, col: 1}
+}
+
+func (l *lexer) peekRune(n int) rune {
+	if l.off+n < len(l.src) {
+		return l.src[l.off+n]
+	}
+	return 0
+}
+
+func (l *lexer) advance() rune {
+	r := l.src[l.off]
+	l.off++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) next() (token, error) {
+	for l.off < len(l.src) && unicode.IsSpace(l.src[l.off]) {
+		l.advance()
+	}
+	pos := Pos{Line: l.line, Column: l.col}
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+
+	r := l.peekRune(0)
+	two := string([]rune{r, l.peekRune(1)})
+	switch two {
+	case "&&", "||", "==", "!=", "=~", "!~", "<=", ">=":
+		l.advance()
+		l.advance()
+		kinds := map[string]tokenKind{"&&": tokAnd, "||": tokOr, "==": tokEq, "!=": tokNeq,
+			"=~": tokMatch, "!~": tokNotMatch, "<=": tokLte, ">=": tokGte}
+		return token{kind: kinds[two], text: two, pos: pos}, nil
+	}
+
+	switch r {
+	case '.':
+		l.advance()
+		return token{kind: tokDot, text: ".", pos: pos}, nil
+	case '[':
+		l.advance()
+		return token{kind: tokLBracket, text: "[", pos: pos}, nil
+	case ']':
+		l.advance()
+		return token{kind: tokRBracket, text: "]", pos: pos}, nil
+	case '(':
+		l.advance()
+		return token{kind: tokLParen, text: "(", pos: pos}, nil
+	case ')':
+		l.advance()
+		return token{kind: tokRParen, text: ")", pos: pos}, nil
+	case '*':
+		l.advance()
+		return token{kind: tokStar, text: "*", pos: pos}, nil
+	case ',':
+		l.advance()
+		return token{kind: tokComma, text: ",", pos: pos}, nil
+	case '!':
+		l.advance()
+		return token{kind: tokNot, text: "!", pos: pos}, nil
+	case '<':
+		l.advance()
+		return token{kind: tokLt, text: "<", pos: pos}, nil
+	case '>':
+		l.advance()
+		return token{kind: tokGt, text: ">", pos: pos}, nil
+	case '"':
+		return l.lexString(pos)
+	}
+
+	if r == '-' || unicode.IsDigit(r) {
+		return l.lexNumber(pos)
+	}
+	if r == '_' || unicode.IsLetter(r) {
+		var b strings.Builder
+		for l.off < len(l.src) {
+			c := l.peekRune(0)
+			if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				break
+			}
+			b.WriteRune(l.advance())
+		}
+		return token{kind: tokIdent, text: b.String(), pos: pos}, nil
+	}
+	return token{}, &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
+
+func (l *lexer) lexString(pos Pos) (token, error) {
+	l.advance() // opening quote
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) || l.peekRune(0) == '\n' {
+			return token{}, &Error{Pos: pos, Msg: "unterminated string"}
+		}
+		c := l.advance()
+		switch c {
+		case '"':
+			return token{kind: tokString, text: b.String(), pos: pos}, nil
+		case '\\':
+			if l.off >= len(l.src) {
+				return token{}, &Error{Pos: pos, Msg: "unterminated string"}
+			}
+			escPos := Pos{Line: l.line, Column: l.col - 1}
+			switch e := l.advance(); e {
+			case '"', '\\':
+				b.WriteRune(e)
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				return token{}, &Error{Pos: escPos, Msg: fmt.Sprintf("unknown escape sequence \\%c", e)}
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+func (l *lexer) lexNumber(pos Pos) (token, error) {
+	var b strings.Builder
+	if l.peekRune(0) == '-' {
+		b.WriteRune(l.advance())
+	}
+	digits := 0
+	for l.off < len(l.src) && (unicode.IsDigit(l.peekRune(0)) || l.peekRune(0) == '.') {
+		b.WriteRune(l.advance())
+		digits++
+	}
+	if digits == 0 {
+		return token{}, &Error{Pos: pos, Msg: "expected number after \"-\""}
+	}
+	return token{kind: tokNumber, text: b.String(), pos: pos}, nil
+}
@@ -0,0 +1,433 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds lists the object kinds a rule path may start with
+var Kinds = map[string]string{
+	"pod":         "Pod",
+	"deployment":  "Deployment",
+	"statefulset": "StatefulSet",
+	"daemonset":   "DaemonSet",
+	"service":     "Service",
+}
+
+// aliases are shorthand roots that expand to a pod path prefix
+var aliases = map[string]string{
+	"image":          "pod.spec.containers[*].image",
+	"name":           "pod.metadata.name",
+	"namespace":      "pod.metadata.namespace",
+	"labels":         "pod.metadata.labels",
+	"serviceAccount": "pod.spec.serviceAccountName",
+}
+
+// Error is a rule syntax or validation error
+type Error struct {
+	Pos
+	Msg string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ErrorList collects the errors of every rule in a policy
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Expr is a parsed rule expression
+type Expr interface {
+	String() string
+}
+
+// BinaryExpr combines two expressions with && or ||
+type BinaryExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+func (e *BinaryExpr) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+// NotExpr negates an expression
+type NotExpr struct {
+	X Expr
+}
+
+func (e *NotExpr) String() string {
+	return "!" + e.X.String()
+}
+
+// Step is one element of a path: a field name, a list index or a wildcard
+type Step struct {
+	Field    string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// Path addresses a field of an object of a given kind
+type Path struct {
+	Kind  string
+	Steps []Step
+	Raw   string
+}
+
+func (p Path) String() string {
+	return p.Raw
+}
+
+// Comparison tests the values at a path against a literal, or for "in"
+// against a list of literals
+type Comparison struct {
+	Path  Path
+	Op    string
+	Value interface{}
+	re    *regexp.Regexp
+}
+
+func (c *Comparison) String() string {
+	return c.Path.String() + " " + c.Op + " " + literalString(c.Value)
+}
+
+func literalString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case nil:
+		return "null"
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = literalString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// Rule is a single compiled policy rule
+type Rule struct {
+	Source string
+	Kind   string
+	Expr   Expr
+}
+
+// Parse compiles a single rule
+func Parse(src string) (*Rule, error) {
+	p := &parser{lex: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok.kind)
+	}
+	return &Rule{Source: src, Kind: p.kind, Expr: expr}, nil
+}
+
+// ParseRules compiles every rule of a policy. Line numbers are reported as if
+// the rules were joined with newlines, so rule N starts on line N for
+// single-line rules.
+func ParseRules(rules []string) ([]*Rule, error) {
+	var errs ErrorList
+	compiled := make([]*Rule, 0, len(rules))
+	line := 0
+	for _, src := range rules {
+		r, err := Parse(src)
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) {
+				e = &Error{Pos: Pos{Line: 1, Column: 1}, Msg: err.Error()}
+			}
+			e.Line += line
+			errs = append(errs, e)
+		} else {
+			compiled = append(compiled, r)
+		}
+		line += strings.Count(src, "\n") + 1
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return compiled, nil
+}
+
+type parser struct {
+	lex  *lexer
+	tok  token
+	kind string
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &Error{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.tok
+	if tok.kind != kind {
+		return tok, p.errorf("expected %s, found %s", kind, tok.kind)
+	}
+	return tok, p.advance()
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch p.tok.kind {
+	case tokNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{X: x}, nil
+	case tokLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.tok
+	switch {
+	case opTok.kind == tokIdent && (opTok.text == "in" || opTok.text == "contains"):
+	case opTok.kind == tokEq, opTok.kind == tokNeq, opTok.kind == tokMatch, opTok.kind == tokNotMatch,
+		opTok.kind == tokLt, opTok.kind == tokLte, opTok.kind == tokGt, opTok.kind == tokGte:
+	default:
+		return nil, p.errorf("expected comparison operator, found %s", opTok.kind)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	litTok := p.tok
+	var value interface{}
+	if opTok.text == "in" {
+		value, err = p.parseList()
+	} else {
+		value, err = p.parseLiteral()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Comparison{Path: path, Op: opTok.text, Value: value}
+	switch opTok.kind {
+	case tokMatch, tokNotMatch:
+		s, ok := value.(string)
+		if !ok {
+			return nil, &Error{Pos: litTok.pos, Msg: fmt.Sprintf("operator %s requires a string pattern", opTok.text)}
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, &Error{Pos: litTok.pos, Msg: "invalid regular expression: " + err.Error()}
+		}
+		c.re = re
+	case tokLt, tokLte, tokGt, tokGte:
+		if _, ok := value.(float64); !ok {
+			return nil, &Error{Pos: litTok.pos, Msg: fmt.Sprintf("operator %s requires a number", opTok.text)}
+		}
+	}
+	return c, nil
+}
+
+func (p *parser) parseLiteral() (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokString:
+		return tok.text, p.advance()
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		return f, p.advance()
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return true, p.advance()
+		case "false":
+			return false, p.advance()
+		case "null":
+			return nil, p.advance()
+		}
+	}
+	return nil, p.errorf("expected literal, found %s", tok.kind)
+}
+
+// parseList parses a non-empty list of literals such as ["a", "b"]
+func (p *parser) parseList() ([]interface{}, error) {
+	if _, err := p.expect(tokLBracket); err != nil {
+		return nil, err
+	}
+	var list []interface{}
+	for {
+		v, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		if p.tok.kind != tokComma {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(tokRBracket); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (p *parser) parsePath() (Path, error) {
+	root, err := p.expect(tokIdent)
+	if err != nil {
+		return Path{}, err
+	}
+
+	var path Path
+	var raw strings.Builder
+	if expansion, ok := aliases[root.text]; ok {
+		aliased, err := Parse(expansion + " == null")
+		if err != nil {
+			return Path{}, err
+		}
+		path = aliased.Expr.(*Comparison).Path
+		path.Steps = append([]Step(nil), path.Steps...)
+	} else if _, ok := Kinds[root.text]; ok {
+		path.Kind = root.text
+	} else {
+		return Path{}, &Error{Pos: root.pos, Msg: fmt.Sprintf("unknown object kind %q", root.text)}
+	}
+	raw.WriteString(root.text)
+
+	if p.kind == "" {
+		p.kind = path.Kind
+	} else if p.kind != path.Kind {
+		return Path{}, &Error{Pos: root.pos, Msg: fmt.Sprintf("rule mixes object kinds %q and %q", p.kind, path.Kind)}
+	}
+
+	for {
+		switch p.tok.kind {
+		case tokDot:
+			if err := p.advance(); err != nil {
+				return Path{}, err
+			}
+			field, err := p.expect(tokIdent)
+			if err != nil {
+				return Path{}, err
+			}
+			path.Steps = append(path.Steps, Step{Field: field.text})
+			raw.WriteString("." + field.text)
+		case tokLBracket:
+			if err := p.advance(); err != nil {
+				return Path{}, err
+			}
+			switch p.tok.kind {
+			case tokStar:
+				path.Steps = append(path.Steps, Step{Wildcard: true})
+				raw.WriteString("[*]")
+			case tokNumber:
+				i, err := strconv.Atoi(p.tok.text)
+				if err != nil || i < 0 {
+					return Path{}, p.errorf("invalid index %q", p.tok.text)
+				}
+				path.Steps = append(path.Steps, Step{Index: i, IsIndex: true})
+				raw.WriteString("[" + p.tok.text + "]")
+			case tokString:
+				path.Steps = append(path.Steps, Step{Field: p.tok.text})
+				raw.WriteString("[" + strconv.Quote(p.tok.text) + "]")
+			default:
+				return Path{}, p.errorf("expected index, \"*\" or string key, found %s", p.tok.kind)
+			}
+			if err := p.advance(); err != nil {
+				return Path{}, err
+			}
+			if _, err := p.expect(tokRBracket); err != nil {
+				return Path{}, err
+			}
+		default:
+			path.Raw = raw.String()
+			return path, nil
+		}
+	}
+}
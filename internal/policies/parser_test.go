@@ -0,0 +1,140 @@
+package policies
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePrecedence(t *testing.T) {
+	for _, tc := range []struct{ src, want string }{
+		{`pod.a == 1 || pod.b == 2 && pod.c == 3`, `(pod.a == 1 || (pod.b == 2 && pod.c == 3))`},
+		{`pod.a == 1 && pod.b == 2 || pod.c == 3`, `((pod.a == 1 && pod.b == 2) || pod.c == 3)`},
+		{`(pod.a == 1 || pod.b == 2) && pod.c == 3`, `((pod.a == 1 || pod.b == 2) && pod.c == 3)`},
+		{`!pod.a == 1 && pod.b == 2`, `(!pod.a == 1 && pod.b == 2)`},
+		{`!(pod.a == 1 && pod.b == 2)`, `!(pod.a == 1 && pod.b == 2)`},
+		{`pod.a == 1 || pod.b == 2 || pod.c == 3`, `((pod.a == 1 || pod.b == 2) || pod.c == 3)`},
+		{`name in ["a", 1, true, null]`, `name in ["a", 1, true, null]`},
+	} {
+		r, err := Parse(tc.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.src, err)
+			continue
+		}
+		if got := r.Expr.String(); got != tc.want {
+			t.Errorf("Parse(%q) = %s, want %s", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestParseLiterals(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want interface{}
+	}{
+		{`pod.a == "plain"`, "plain"},
+		{`pod.a == "say \"hi\"\n\t\\"`, "say \"hi\"\n\t\\"},
+		{`pod.a == ""`, ""},
+		{`pod.a == 42`, 42.0},
+		{`pod.a == -1.5`, -1.5},
+		{`pod.a == true`, true},
+		{`pod.a == false`, false},
+		{`pod.a == null`, nil},
+		{`pod.a contains "x"`, "x"},
+		{`pod.a in ["x", 2, false]`, []interface{}{"x", 2.0, false}},
+	} {
+		r, err := Parse(tc.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.src, err)
+			continue
+		}
+		if got := r.Expr.(*Comparison).Value; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Parse(%q) literal = %#v, want %#v", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	r, err := Parse(`labels["app.kubernetes.io/name"] != "" && pod.spec.containers[0].ports[*].hostPort == 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kind != "pod" {
+		t.Errorf("kind = %q, want pod", r.Kind)
+	}
+	and := r.Expr.(*BinaryExpr)
+	labels := and.Left.(*Comparison).Path
+	want := []Step{{Field: "metadata"}, {Field: "labels"}, {Field: "app.kubernetes.io/name"}}
+	if !reflect.DeepEqual(labels.Steps, want) {
+		t.Errorf("labels steps = %+v, want %+v", labels.Steps, want)
+	}
+	ports := and.Right.(*Comparison).Path
+	want = []Step{{Field: "spec"}, {Field: "containers"}, {Index: 0, IsIndex: true}, {Field: "ports"}, {Wildcard: true}, {Field: "hostPort"}}
+	if !reflect.DeepEqual(ports.Steps, want) {
+		t.Errorf("ports steps = %+v, want %+v", ports.Steps, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src          string
+		line, column int
+		msg          string
+	}{
+		{`pod.a ==`, 1, 9, "expected literal, found end of rule"},
+		{`pod.a = 1`, 1, 7, `unexpected character '='`},
+		{`foo.a == 1`, 1, 1, `unknown object kind "foo"`},
+		{`pod.a == 1 && deployment.b == 1`, 1, 15, `rule mixes object kinds "pod" and "deployment"`},
+		{`pod.a =~ "("`, 1, 10, "invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		{`pod.a < "x"`, 1, 9, "operator < requires a number"},
+		{`pod.a == "abc`, 1, 10, "unterminated string"},
+		{`pod.a == "a\qb"`, 1, 12, `unknown escape sequence \q`},
+		{`pod.a == 1)`, 1, 11, `unexpected ")"`},
+		{`(pod.a == 1`, 1, 12, `expected ")", found end of rule`},
+		{`pod.a[x] == 1`, 1, 7, `expected index, "*" or string key, found identifier`},
+		{`pod.a in "x"`, 1, 10, `expected "[", found string`},
+		{`pod.a in []`, 1, 11, `expected literal, found "]"`},
+		{`pod.a in ["x" "y"]`, 1, 15, `expected "]", found string`},
+		{`pod.a like "x"`, 1, 7, "expected comparison operator, found identifier"},
+		{"pod.a == 1 &&\n  pod.b ==", 2, 11, "expected literal, found end of rule"},
+	} {
+		_, err := Parse(tc.src)
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("Parse(%q) = %v, want a syntax error", tc.src, err)
+			continue
+		}
+		if e.Line != tc.line || e.Column != tc.column || e.Msg != tc.msg {
+			t.Errorf("Parse(%q) = %d:%d %s, want %d:%d %s", tc.src, e.Line, e.Column, e.Msg, tc.line, tc.column, tc.msg)
+		}
+	}
+}
+
+func TestParseRulesLineOffsets(t *testing.T) {
+	rules := []string{
+		`pod.a == 1`,
+		"pod.a ==\n  2 &&\n  pod.b",
+		`pod.c = 1`,
+		`foo == 1`,
+		`pod.d == true`,
+	}
+	_, err := ParseRules(rules)
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("ParseRules = %v, want an error list", err)
+	}
+	want := []Pos{{Line: 4, Column: 8}, {Line: 5, Column: 7}, {Line: 6, Column: 1}}
+	if len(list) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(list), len(want), list)
+	}
+	for i, e := range list {
+		if e.Pos != want[i] {
+			t.Errorf("error %d at %d:%d, want %d:%d (%s)", i, e.Line, e.Column, want[i].Line, want[i].Column, e.Msg)
+		}
+	}
+
+	compiled, err := ParseRules([]string{rules[0], rules[4]})
+	if err != nil || len(compiled) != 2 {
+		t.Errorf("ParseRules of valid rules = %v, %v", compiled, err)
+	}
+}
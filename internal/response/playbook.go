@@ -0,0 +1,254 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/notify"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxLogBytes caps the logs a snapshot-logs step keeps in the timeline
+const maxLogBytes = 64 * 1024
+
+// PlaybookRunner executes playbooks step by step against the cluster clients
+// and records a timeline of each run. Live runs write an incident report for
+// every step that changed the cluster.
+type PlaybookRunner struct {
+	Storage  storage.Storage
+	K8s      *k8s.ClusterManager
+	Notifier *notify.Notifier
+}
+
+func NewPlaybookRunner(store storage.Storage, mgr *k8s.ClusterManager, notifier *notify.Notifier) *PlaybookRunner {
+	return &PlaybookRunner{Storage: store, K8s: mgr, Notifier: notifier}
+}
+
+// Start stores a new run and executes it in the background. The run must
+// name the playbook and its target; the rest is filled in.
+func (p *PlaybookRunner) Start(ctx context.Context, pb models.Playbook, run models.PlaybookRun) (models.PlaybookRun, error) {
+	run, err := p.begin(pb, run)
+	if err != nil {
+		return run, err
+	}
+	go p.execute(ctx, pb, run)
+	return run, nil
+}
+
+// Run stores a new run, executes it and returns it finished
+func (p *PlaybookRunner) Run(ctx context.Context, pb models.Playbook, run models.PlaybookRun) (models.PlaybookRun, error) {
+	run, err := p.begin(pb, run)
+	if err != nil {
+		return run, err
+	}
+	return p.execute(ctx, pb, run), nil
+}
+
+func (p *PlaybookRunner) begin(pb models.Playbook, run models.PlaybookRun) (models.PlaybookRun, error) {
+	run.PlaybookID = pb.ID
+	run.StartedAt = time.Now()
+	run.ID = models.NewID("run-")
+	run.Status = models.PlaybookRunning
+	run.Timeline = []models.PlaybookStepResult{}
+	if run.Severity == "" && run.AlertID != "" {
+		if a, err := p.Storage.GetAlert(run.AlertID); err == nil {
+			run.Severity = a.Severity
+		}
+	}
+	return run, p.Storage.AddPlaybookRun(run)
+}
+
+func (p *PlaybookRunner) execute(ctx context.Context, pb models.Playbook, run models.PlaybookRun) models.PlaybookRun {
+	client, err := p.client(ctx, run)
+	if err != nil {
+		now := time.Now()
+		run.Timeline = append(run.Timeline, models.PlaybookStepResult{
+			Step: "connect", Status: models.PlaybookFailed, Error: err.Error(), StartedAt: now, FinishedAt: now,
+		})
+		run.Status = models.PlaybookFailed
+	} else if p.runSteps(ctx, client, pb.Steps, &run, "") {
+		run.Status = models.PlaybookSucceeded
+	} else {
+		run.Status = models.PlaybookFailed
+	}
+	run.FinishedAt = time.Now()
+	p.save(run)
+	return run
+}
+
+// client returns the cluster client, or for a dry run a fake clientset
+// seeded with copies of the target objects
+func (p *PlaybookRunner) client(ctx context.Context, run models.PlaybookRun) (kubernetes.Interface, error) {
+	c, err := p.Storage.GetCluster(run.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.K8s.GetClient(c.ID, c.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !run.DryRun {
+		return client, nil
+	}
+	return k8s.NewDryRunClient(ctx, client, run.Kind, run.Namespace, run.Name)
+}
+
+// runSteps runs steps in order and reports whether none failed without
+// continue_on_failure. branch names the step whose on_failure steps these are.
+func (p *PlaybookRunner) runSteps(ctx context.Context, client kubernetes.Interface, steps []models.PlaybookStep, run *models.PlaybookRun, branch string) bool {
+	for _, st := range steps {
+		res := models.PlaybookStepResult{Step: st.Name, Action: st.Action, Branch: branch, StartedAt: time.Now()}
+		if st.When != nil && !st.When.Matches(run.Namespace, run.Severity, run.Kind) {
+			res.Status, res.FinishedAt = models.StepSkipped, res.StartedAt
+			run.Timeline = append(run.Timeline, res)
+			continue
+		}
+
+		stepCtx, cancel := context.WithTimeout(ctx, st.Timeout())
+		out, err := p.runStep(stepCtx, ctx, client, st, *run)
+		timedOut := errors.Is(stepCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		res.Output, res.Before, res.After = out.Result, out.Before, out.After
+		res.FinishedAt = time.Now()
+		switch {
+		case err != nil && timedOut:
+			res.Status, res.Error = models.StepTimedOut, fmt.Sprintf("timed out after %s: %v", st.Timeout(), err)
+		case err != nil:
+			res.Status, res.Error = models.PlaybookFailed, err.Error()
+		default:
+			res.Status = models.PlaybookSucceeded
+		}
+		run.Timeline = append(run.Timeline, res)
+		p.report(*run, res)
+		p.save(*run)
+
+		if res.Status == models.PlaybookSucceeded {
+			continue
+		}
+		if len(st.OnFailure) > 0 {
+			p.runSteps(ctx, client, st.OnFailure, run, st.Name)
+		}
+		if !st.ContinueOnFailure {
+			return false
+		}
+	}
+	return true
+}
+
+// runStep performs one step. runCtx outlives the step and is used for work
+// it hands off, such as notifications.
+func (p *PlaybookRunner) runStep(ctx, runCtx context.Context, client kubernetes.Interface, st models.PlaybookStep, run models.PlaybookRun) (k8s.ActionOutcome, error) {
+	switch st.Action {
+	case models.StepSnapshotLogs:
+		if run.Kind != "Pod" {
+			return k8s.ActionOutcome{}, fmt.Errorf("snapshot-logs needs a Pod, not a %s", run.Kind)
+		}
+		tail := int64(200)
+		if v, err := strconv.ParseInt(st.Params["tail_lines"], 10, 64); err == nil && v > 0 {
+			tail = v
+		}
+		logs, err := k8s.PodLogs(ctx, client, run.Namespace, run.Name, tail)
+		if len(logs) > maxLogBytes {
+			logs = logs[len(logs)-maxLogBytes:]
+		}
+		return k8s.ActionOutcome{Result: logs}, err
+
+	case models.StepLabelPod:
+		if run.Kind != "Pod" {
+			return k8s.ActionOutcome{}, fmt.Errorf("label-pod needs a Pod, not a %s", run.Kind)
+		}
+		value := st.Params["value"]
+		if value == "" {
+			value = "true"
+		}
+		return k8s.LabelPod(ctx, client, run.Namespace, run.Name, st.Params["key"], value)
+
+	case models.StepNotify:
+		return p.notify(runCtx, st, run)
+	}
+
+	a := models.ResponseAction{ClusterID: run.ClusterID, Type: st.Action, Kind: run.Kind, Namespace: run.Namespace, Name: run.Name}
+	if st.Action == models.ActionDrainNode && run.Kind == "Pod" {
+		node, err := k8s.PodNode(ctx, client, run.Namespace, run.Name)
+		if err != nil {
+			return k8s.ActionOutcome{}, err
+		}
+		a.Kind, a.Namespace, a.Name = "Node", "", node
+	}
+	if err := a.Validate(); err != nil {
+		return k8s.ActionOutcome{}, err
+	}
+	return k8s.ExecuteAction(ctx, client, a)
+}
+
+// notify sends the run's alert to a channel, or says it would on a dry run.
+// Delivery is retried in the background like any other notification.
+func (p *PlaybookRunner) notify(ctx context.Context, st models.PlaybookStep, run models.PlaybookRun) (k8s.ActionOutcome, error) {
+	ch, err := p.Storage.GetChannel(st.Params["channel_id"])
+	if err != nil {
+		return k8s.ActionOutcome{}, err
+	}
+	if run.DryRun {
+		return k8s.ActionOutcome{Result: fmt.Sprintf("Would notify channel %q", ch.Name)}, nil
+	}
+	a, err := p.Storage.GetAlert(run.AlertID)
+	if err != nil {
+		a = models.Alert{
+			ID:        "playbook-" + run.ID,
+			ClusterID: run.ClusterID,
+			Rule:      "playbook:" + run.PlaybookID,
+			Namespace: run.Namespace,
+			Resource:  run.Kind + " " + run.Namespace + "/" + run.Name,
+			Severity:  run.Severity,
+			Status:    models.AlertOpen,
+			Timestamp: time.Now(),
+		}
+	}
+	if msg := st.Params["message"]; msg != "" {
+		a.Message = msg
+	} else if a.Message == "" {
+		a.Message = fmt.Sprintf("Playbook %s ran against %s", run.PlaybookID, a.Resource)
+	}
+	p.Notifier.Deliver(ctx, ch, a)
+	return k8s.ActionOutcome{Result: fmt.Sprintf("Notified channel %q", ch.Name)}, nil
+}
+
+// report writes an incident report for a live step that changed the cluster
+func (p *PlaybookRunner) report(run models.PlaybookRun, res models.PlaybookStepResult) {
+	if run.DryRun || (res.Before == "" && res.After == "") {
+		return
+	}
+	result := res.Output
+	if res.Error != "" {
+		result = res.Error
+	}
+	err := p.Storage.AddReport(models.IncidentReport{
+		ID:      models.NewID("rep-"),
+		AlertID: run.AlertID,
+		Details: fmt.Sprintf("Step %q of playbook %s (run %s) on %s %s in cluster %s",
+			res.Step, run.PlaybookID, run.ID, run.Kind, run.Name, run.ClusterID),
+		Action:    res.Action,
+		Result:    res.Status + ": " + result,
+		Before:    res.Before,
+		After:     res.After,
+		Timestamp: res.FinishedAt,
+	})
+	if err != nil {
+		log.Printf("Report of step %q of playbook run %s not stored: %v", res.Step, run.ID, err)
+	}
+}
+
+func (p *PlaybookRunner) save(run models.PlaybookRun) {
+	if err := p.Storage.UpdatePlaybookRun(run); err != nil {
+		log.Printf("Playbook run %s could not be saved: %v", run.ID, err)
+	}
+}
@@ -0,0 +1,158 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func testCluster() *fake.Clientset {
+	pod := func(namespace, name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{NodeName: "n1", Containers: []corev1.Container{{Name: "app", Image: "app:1"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	return fake.NewClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, pod("web", "api"), pod("jobs", "worker"))
+}
+
+func testRunner(t *testing.T, run *models.PlaybookRun) (*PlaybookRunner, *storage.MemoryStorage) {
+	store := storage.NewMemoryStorage()
+	run.ID, run.PlaybookID, run.ClusterID = "run-1", "pb-1", "c1"
+	run.Kind, run.Namespace, run.Name = "Pod", "web", "api"
+	if err := store.AddPlaybookRun(*run); err != nil {
+		t.Fatal(err)
+	}
+	return &PlaybookRunner{Storage: store}, store
+}
+
+// statuses lists the timeline as step:status, with the branch for on_failure
+// steps
+func statuses(run models.PlaybookRun) string {
+	var s []string
+	for _, res := range run.Timeline {
+		step := res.Step + ":" + res.Status
+		if res.Branch != "" {
+			step = res.Branch + "/" + step
+		}
+		s = append(s, step)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestDryRunRecordsStepsAndLeavesClusterUnchanged(t *testing.T) {
+	ctx := context.Background()
+	real := testCluster()
+	run := models.PlaybookRun{DryRun: true, Severity: models.SeverityHigh}
+	p, store := testRunner(t, &run)
+	dry, err := k8s.NewDryRunClient(ctx, real, "Pod", "web", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []models.PlaybookStep{
+		{Name: "logs", Action: models.StepSnapshotLogs},
+		{Name: "label", Action: models.StepLabelPod, Params: map[string]string{"key": "quarantine"}},
+		{Name: "isolate", Action: models.ActionIsolatePod},
+		{Name: "only-prod", Action: models.ActionDeletePod, When: &models.PlaybookCondition{Namespaces: []string{"prod"}}},
+		{Name: "drain", Action: models.ActionDrainNode},
+	}
+	if !p.runSteps(ctx, dry, steps, &run, "") {
+		t.Fatalf("dry run failed: %+v", run.Timeline)
+	}
+	if got, want := statuses(run), "logs:succeeded label:succeeded isolate:succeeded only-prod:skipped drain:succeeded"; got != want {
+		t.Errorf("timeline = %s, want %s", got, want)
+	}
+	for _, res := range run.Timeline[1:3] {
+		if res.Before == "" || res.After == "" {
+			t.Errorf("step %s recorded no before and after", res.Step)
+		}
+	}
+	if stored, _ := store.GetPlaybookRun(run.ID); len(stored.Timeline) != len(steps) {
+		t.Errorf("stored timeline has %d steps, want %d", len(stored.Timeline), len(steps))
+	}
+	if reports, _ := storage.All(ctx, store.GetReports, storage.ListOptions{}); len(reports) != 0 {
+		t.Errorf("dry run wrote %d reports", len(reports))
+	}
+
+	pod, err := real.CoreV1().Pods("web").Get(ctx, "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("dry run drained the cluster's pod: %v", err)
+	}
+	if _, ok := pod.Labels["quarantine"]; ok {
+		t.Error("dry run labelled the cluster's pod")
+	}
+	if policies, _ := real.NetworkingV1().NetworkPolicies("web").List(ctx, metav1.ListOptions{}); len(policies.Items) != 0 {
+		t.Error("dry run created an isolation policy in the cluster")
+	}
+	if node, _ := real.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{}); node.Spec.Unschedulable {
+		t.Error("dry run cordoned the cluster's node")
+	}
+}
+
+func TestOnFailure(t *testing.T) {
+	failing := func(continueOnFailure bool) []models.PlaybookStep {
+		return []models.PlaybookStep{
+			{Name: "label", Action: models.StepLabelPod, Params: map[string]string{"key": "seen"}},
+			{
+				Name: "scale", Action: models.ActionScaleToZero, ContinueOnFailure: continueOnFailure,
+				OnFailure: []models.PlaybookStep{{Name: "isolate", Action: models.ActionIsolatePod}},
+			},
+			{Name: "delete", Action: models.ActionDeletePod},
+		}
+	}
+	for _, tc := range []struct {
+		name              string
+		continueOnFailure bool
+		ok                bool
+		timeline          string
+	}{
+		{"abort", false, false, "label:succeeded scale:failed scale/isolate:succeeded"},
+		{"continue", true, true, "label:succeeded scale:failed scale/isolate:succeeded delete:succeeded"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := models.PlaybookRun{}
+			p, _ := testRunner(t, &run)
+			// scale-to-zero cannot target a Pod, so the second step fails
+			if ok := p.runSteps(context.Background(), testCluster(), failing(tc.continueOnFailure), &run, ""); ok != tc.ok {
+				t.Errorf("runSteps = %v, want %v", ok, tc.ok)
+			}
+			if got := statuses(run); got != tc.timeline {
+				t.Errorf("timeline = %s, want %s", got, tc.timeline)
+			}
+		})
+	}
+}
+
+func TestStepTimeout(t *testing.T) {
+	client := testCluster()
+	// the API server answers after the step's timeout
+	client.PrependReactor("get", "pods", func(clienttesting.Action) (bool, runtime.Object, error) {
+		time.Sleep(1100 * time.Millisecond)
+		return true, nil, errors.New("context deadline exceeded")
+	})
+	run := models.PlaybookRun{}
+	p, _ := testRunner(t, &run)
+	steps := []models.PlaybookStep{{Name: "label", Action: models.StepLabelPod, Params: map[string]string{"key": "seen"}, TimeoutSeconds: 1}}
+
+	if p.runSteps(context.Background(), client, steps, &run, "") {
+		t.Fatal("timed out step did not fail the run")
+	}
+	res := run.Timeline[0]
+	if res.Status != models.StepTimedOut || !strings.HasPrefix(res.Error, "timed out after 1s") {
+		t.Errorf("step = %+v, want timed out after 1s", res)
+	}
+}
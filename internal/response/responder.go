@@ -0,0 +1,165 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	k8s "KubernetesSecurityMonitoringSystem/internal/kubernetes"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// ErrNotPending is returned when deciding on an action that already ran or
+// was rejected
+var ErrNotPending = errors.New("response action is not pending")
+
+// actionTimeout bounds one execution; draining a node evicts every pod on it
+const actionTimeout = 2 * time.Minute
+
+// Responder runs response actions against clusters. Auto actions run as soon
+// as they are requested, the others wait for an Administrator's approval.
+// Every execution, successful or not, is written up as an incident report.
+// Decisions take effect through storage only while the action is pending, so
+// an action runs at most once however many approvals race for it.
+type Responder struct {
+	Storage storage.Storage
+	K8s     *k8s.ClusterManager
+}
+
+func NewResponder(store storage.Storage, mgr *k8s.ClusterManager) *Responder {
+	return &Responder{Storage: store, K8s: mgr}
+}
+
+// Request validates and stores a new action, running it right away when it
+// is Auto
+func (r *Responder) Request(ctx context.Context, a models.ResponseAction) (models.ResponseAction, error) {
+	if a.Kind == "" {
+		a.Kind = models.DefaultActionKind(a.Type)
+	}
+	if err := a.Validate(); err != nil {
+		return a, err
+	}
+	a.CreatedAt = time.Now()
+	a.ID = models.NewID("act-")
+	a.Status = models.ActionPending
+	if a.Auto {
+		a.Status = models.ActionRunning
+	}
+	if err := r.Storage.AddResponseAction(a); err != nil {
+		return a, err
+	}
+	if !a.Auto {
+		return a, nil
+	}
+	return r.execute(ctx, a), nil
+}
+
+// Approve records the decision and runs a pending action
+func (r *Responder) Approve(ctx context.Context, id, user string) (models.ResponseAction, error) {
+	a, err := r.decide(id, func(a *models.ResponseAction) {
+		a.Status = models.ActionRunning
+		a.DecidedBy, a.DecidedAt = user, time.Now()
+	})
+	if err != nil {
+		return a, err
+	}
+	return r.execute(ctx, a), nil
+}
+
+// Reject records the decision; the action is never run
+func (r *Responder) Reject(id, user, reason string) (models.ResponseAction, error) {
+	return r.decide(id, func(a *models.ResponseAction) {
+		a.Status = models.ActionRejected
+		a.DecidedBy, a.DecidedAt = user, time.Now()
+		a.Result = reason
+	})
+}
+
+// decide applies a decision to a pending action. It fails with ErrNotPending
+// when the action was decided before, or concurrently by another approver.
+func (r *Responder) decide(id string, decision func(*models.ResponseAction)) (models.ResponseAction, error) {
+	a, err := r.Storage.GetResponseAction(id)
+	if err != nil {
+		return a, err
+	}
+	if a.Status != models.ActionPending {
+		return a, ErrNotPending
+	}
+	decision(&a)
+	if err := r.Storage.DecideResponseAction(a); errors.Is(err, storage.ErrConflict) {
+		return a, ErrNotPending
+	} else if err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// execute runs an action claimed by its caller, stores its outcome and
+// writes the incident report
+func (r *Responder) execute(ctx context.Context, a models.ResponseAction) models.ResponseAction {
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+
+	var out k8s.ActionOutcome
+	c, err := r.Storage.GetCluster(a.ClusterID)
+	if err == nil {
+		client, cerr := r.K8s.GetClient(c.ID, c.KubeConfig)
+		if cerr != nil {
+			err = cerr
+		} else {
+			out, err = k8s.ExecuteAction(ctx, client, a)
+		}
+	}
+
+	a.ExecutedAt = time.Now()
+	a.Status, a.Result = models.ActionSucceeded, out.Result
+	if err != nil {
+		a.Status = models.ActionFailed
+		if out.Result != "" {
+			a.Result = out.Result + ": " + err.Error()
+		} else {
+			a.Result = err.Error()
+		}
+	}
+
+	report := models.IncidentReport{
+		ID:        models.NewID("rep-"),
+		AlertID:   a.AlertID,
+		ActionID:  a.ID,
+		Details:   describe(a),
+		Action:    a.Type,
+		Result:    a.Status + ": " + a.Result,
+		Before:    out.Before,
+		After:     out.After,
+		Timestamp: a.ExecutedAt,
+	}
+	if err := r.Storage.AddReport(report); err != nil {
+		log.Printf("Report of response action %s not stored: %v", a.ID, err)
+	} else {
+		a.ReportID = report.ID
+	}
+
+	if err := r.Storage.UpdateResponseAction(a); err != nil {
+		log.Printf("Response action %s ran but could not be updated: %v", a.ID, err)
+	}
+	return a
+}
+
+// describe says what the action targeted and who let it run
+func describe(a models.ResponseAction) string {
+	target := a.Kind + " " + a.Name
+	if a.Namespace != "" {
+		target = a.Kind + " " + a.Namespace + "/" + a.Name
+	}
+	by := "approved by " + a.DecidedBy
+	if a.Auto {
+		by = "run automatically"
+		if a.PolicyID != "" {
+			by += " by policy " + a.PolicyID
+		}
+	}
+	return fmt.Sprintf("%s on %s in cluster %s, requested by %s, %s", a.Type, target, a.ClusterID, a.RequestedBy, by)
+}
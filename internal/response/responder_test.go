@@ -0,0 +1,57 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+func TestApproveRunsActionOnce(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	r := NewResponder(store, nil)
+	// the cluster is unknown, so the execution fails without a client but is
+	// still recorded and reported
+	a, err := r.Request(ctx, models.ResponseAction{ClusterID: "c1", Type: models.ActionDeletePod, Namespace: "web", Name: "api", RequestedBy: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 10
+	var ran, refused atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Approve(ctx, a.ID, "admin")
+			switch {
+			case err == nil:
+				ran.Add(1)
+			case errors.Is(err, ErrNotPending):
+				refused.Add(1)
+			default:
+				t.Errorf("Approve: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ran.Load() != 1 || refused.Load() != n-1 {
+		t.Errorf("%d approvals ran and %d were refused, want 1 and %d", ran.Load(), refused.Load(), n-1)
+	}
+	if got, _ := store.GetResponseAction(a.ID); got.Status != models.ActionFailed || got.DecidedBy != "admin" || got.ReportID == "" {
+		t.Errorf("action = %+v, want failed, decided by admin and reported", got)
+	}
+	if reports, _ := storage.All(ctx, store.GetReports, storage.ListOptions{}); len(reports) != 1 {
+		t.Errorf("got %d reports, want 1", len(reports))
+	}
+	if _, err := r.Reject(a.ID, "admin", "too late"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Reject of an executed action = %v, want %v", err, ErrNotPending)
+	}
+}
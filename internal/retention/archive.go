@@ -0,0 +1,153 @@
+package retention
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNoArchive is returned for an archive name that is not in the archive
+// directory
+var ErrNoArchive = errors.New("no such archive")
+
+// archiveExt is the extension of archive files, which hold one JSON record
+// per line, gzip-compressed
+const archiveExt = ".jsonl.gz"
+
+// Archive is an archive file of expired records
+type Archive struct {
+	Name      string    `json:"name"`
+	Entity    string    `json:"entity"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// archiveWriter writes an archive to a temporary file that only takes the
+// archive's name once it is complete, so a crash never leaves a partial one
+type archiveWriter struct {
+	f       *os.File
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	path    string
+	records int
+	done    bool
+}
+
+func createArchive(dir, entity string, at time.Time) (*archiveWriter, error) {
+	name := entity + "-" + at.UTC().Format("20060102T150405.000Z") + archiveExt
+	f, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &archiveWriter{f: f, gz: gz, enc: json.NewEncoder(gz), path: filepath.Join(dir, name)}, nil
+}
+
+// write adds a record as one line
+func (w *archiveWriter) write(record any) error {
+	w.records++
+	return w.enc.Encode(record)
+}
+
+// commit completes the archive and gives it its name
+func (w *archiveWriter) commit() (string, error) {
+	if err := w.gz.Close(); err != nil {
+		return "", err
+	}
+	if err := w.f.Sync(); err != nil {
+		return "", err
+	}
+	if err := w.f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(w.f.Name(), w.path); err != nil {
+		return "", err
+	}
+	w.done = true
+	return filepath.Base(w.path), nil
+}
+
+// abort removes an archive that was not committed
+func (w *archiveWriter) abort() {
+	if !w.done {
+		w.f.Close()
+		os.Remove(w.f.Name())
+	}
+}
+
+// archiveEntity returns the entity of an archive file name
+func archiveEntity(name string) (string, bool) {
+	if !strings.HasSuffix(name, archiveExt) || filepath.Base(name) != name {
+		return "", false
+	}
+	entity, _, _ := strings.Cut(name, "-")
+	return entity, entity == EntityAlerts || entity == EntityReports
+}
+
+// listArchives returns the archives in dir, oldest first
+func listArchives(dir string) ([]Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	archives := []Archive{}
+	for _, e := range entries {
+		entity, ok := archiveEntity(e.Name())
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		archives = append(archives, Archive{Name: e.Name(), Entity: entity, Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	slices.SortFunc(archives, func(a, b Archive) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return archives, nil
+}
+
+// readArchive decodes the records of an archive and hands them to put in
+// batches of up to size
+func readArchive[T any](path string, size int, put func([]T) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoArchive
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	batch := make([]T, 0, size)
+	for line := 1; ; line++ {
+		var record T
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+		if batch = append(batch, record); len(batch) == size {
+			if err := put(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return put(batch)
+}
@@ -0,0 +1,329 @@
+// Package retention enforces how long alerts and reports are kept. Expired
+// records are exported to gzip-compressed JSONL archives before they are
+// deleted, and archives can be re-imported on demand.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// maxPerPass bounds how many records of an entity one pass archives, so that
+// the first pass over a large backlog does not hold all their IDs at once;
+// the next pass continues where it stopped
+const maxPerPass = 100000
+
+// DefaultHold is how long restored records are kept from compaction when the
+// restore does not say
+const DefaultHold = 7 * 24 * time.Hour
+
+// holdsFile keeps the holds on restored records in the archive directory
+const holdsFile = "holds.json"
+
+// Pass is the outcome of a compaction pass
+type Pass struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Alerts     int       `json:"alerts"`  // alerts archived and deleted
+	Reports    int       `json:"reports"` // reports archived and deleted
+	Archives   []string  `json:"archives,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is the state of retention: the policy, the last pass since startup,
+// the archives and how many restored records are held from compaction
+type Status struct {
+	Policy   Policy    `json:"policy"`
+	Dir      string    `json:"dir"`
+	LastPass *Pass     `json:"last_pass"`
+	Archives []Archive `json:"archives"`
+	Held     int       `json:"held"`
+}
+
+// Compactor enforces a retention policy. Each pass exports the alerts and
+// reports that outlived their period to a new archive per entity and deletes
+// them once the archive is complete. A crash in between leaves the records
+// stored, so they are archived again by the next pass; restoring skips the
+// duplicates.
+type Compactor struct {
+	Storage storage.Storage
+	Policy  Policy
+	Dir     string
+
+	running sync.Mutex           // one pass or restore at a time
+	mu      sync.Mutex           // guards holds and last
+	holds   map[string]time.Time // restored records by entity/ID, held until the time
+	last    *Pass
+}
+
+// NewCompactor creates the archive directory if needed and loads the holds
+// on restored records kept there
+func NewCompactor(store storage.Storage, policy Policy, dir string) (*Compactor, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	c := &Compactor{Storage: store, Policy: policy, Dir: dir, holds: make(map[string]time.Time)}
+	data, err := os.ReadFile(filepath.Join(dir, holdsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.holds); err != nil {
+		return nil, fmt.Errorf("%s: %w", holdsFile, err)
+	}
+	return c, nil
+}
+
+// Run compacts every interval until the context is cancelled
+func (c *Compactor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pass, err := c.Compact(ctx, time.Now())
+		if err != nil {
+			log.Printf("Retention pass failed: %v", err)
+		} else if pass.Alerts > 0 || pass.Reports > 0 {
+			log.Printf("Archived %d alerts and %d reports to %v", pass.Alerts, pass.Reports, pass.Archives)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep finds expired records of an entity: the list options select the
+// candidates and expired decides on each
+type sweep[T any] struct {
+	opts    storage.ListOptions
+	expired func(T) bool
+}
+
+// Compact runs one pass as of now
+func (c *Compactor) Compact(ctx context.Context, now time.Time) (Pass, error) {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	pass := Pass{StartedAt: now}
+	err := c.releaseHolds(now)
+	if err == nil {
+		err = c.compactAlerts(ctx, now, &pass)
+	}
+	if err == nil {
+		err = c.compactReports(ctx, now, &pass)
+	}
+	pass.FinishedAt = time.Now()
+	if err != nil {
+		pass.Error = err.Error()
+	}
+	c.mu.Lock()
+	c.last = &pass
+	c.mu.Unlock()
+	return pass, err
+}
+
+// compactAlerts archives alerts raised and last seen before the period of
+// their severity
+func (c *Compactor) compactAlerts(ctx context.Context, now time.Time, pass *Pass) error {
+	var sweeps []sweep[models.Alert]
+	for severity, period := range c.Policy.Severities {
+		if period > 0 {
+			cutoff := now.Add(-period)
+			sweeps = append(sweeps, sweep[models.Alert]{
+				opts:    storage.ListOptions{Severity: severity, Until: cutoff},
+				expired: func(a models.Alert) bool { return a.LastSeen.Before(cutoff) },
+			})
+		}
+	}
+	if c.Policy.Alerts > 0 {
+		cutoff := now.Add(-c.Policy.Alerts)
+		sweeps = append(sweeps, sweep[models.Alert]{
+			opts: storage.ListOptions{Until: cutoff},
+			expired: func(a models.Alert) bool {
+				_, own := c.Policy.Severities[a.Severity]
+				return !own && a.LastSeen.Before(cutoff)
+			},
+		})
+	}
+	name, n, err := compact(ctx, c, EntityAlerts, now, c.Storage.GetAlerts, sweeps,
+		func(a models.Alert) string { return a.ID }, c.Storage.DeleteAlerts)
+	pass.Alerts += n
+	if name != "" {
+		pass.Archives = append(pass.Archives, name)
+	}
+	return err
+}
+
+// compactReports archives reports written before the reports period
+func (c *Compactor) compactReports(ctx context.Context, now time.Time, pass *Pass) error {
+	var sweeps []sweep[models.IncidentReport]
+	if c.Policy.Reports > 0 {
+		sweeps = append(sweeps, sweep[models.IncidentReport]{
+			opts:    storage.ListOptions{Until: now.Add(-c.Policy.Reports)},
+			expired: func(models.IncidentReport) bool { return true },
+		})
+	}
+	name, n, err := compact(ctx, c, EntityReports, now, c.Storage.GetReports, sweeps,
+		func(r models.IncidentReport) string { return r.ID }, c.Storage.DeleteReports)
+	pass.Reports += n
+	if name != "" {
+		pass.Archives = append(pass.Archives, name)
+	}
+	return err
+}
+
+// compact writes the records the sweeps find expired to an archive and, once
+// it is complete, deletes them. It returns the archive's name, empty when
+// nothing expired, and how many records were deleted.
+func compact[T any](ctx context.Context, c *Compactor, entity string, now time.Time,
+	list func(context.Context, storage.ListOptions) (storage.Page[T], error), sweeps []sweep[T],
+	id func(T) string, del func(context.Context, []string) (int, error)) (string, int, error) {
+	if len(sweeps) == 0 {
+		return "", 0, nil
+	}
+	w, err := createArchive(c.Dir, entity, now)
+	if err != nil {
+		return "", 0, err
+	}
+	defer w.abort()
+
+	var ids []string
+	for _, s := range sweeps {
+		opts := s.opts
+		opts.Order, opts.Limit = storage.Oldest, storage.MaxLimit
+		for len(ids) < maxPerPass {
+			page, err := list(ctx, opts)
+			if err != nil {
+				return "", 0, err
+			}
+			for _, r := range page.Items {
+				if len(ids) == maxPerPass || !s.expired(r) || c.held(entity, id(r)) {
+					continue
+				}
+				if err := w.write(r); err != nil {
+					return "", 0, err
+				}
+				ids = append(ids, id(r))
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+	if len(ids) == 0 {
+		return "", 0, nil
+	}
+	name, err := w.commit()
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := del(ctx, ids)
+	return name, n, err
+}
+
+// Restore re-imports the records of an archive that are no longer stored and
+// holds all of its records from compaction until the given time. It returns
+// how many records it stored.
+func (c *Compactor) Restore(ctx context.Context, name string, until time.Time) (int, error) {
+	entity, ok := archiveEntity(name)
+	if !ok {
+		return 0, ErrNoArchive
+	}
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	path := filepath.Join(c.Dir, name)
+	var n int
+	var err error
+	switch entity {
+	case EntityAlerts:
+		n, err = restore(ctx, c, path, entity, until, func(a models.Alert) string { return a.ID }, c.Storage.RestoreAlerts)
+	case EntityReports:
+		n, err = restore(ctx, c, path, entity, until, func(r models.IncidentReport) string { return r.ID }, c.Storage.RestoreReports)
+	}
+	if saveErr := c.saveHolds(); err == nil {
+		err = saveErr
+	}
+	return n, err
+}
+
+func restore[T any](ctx context.Context, c *Compactor, path, entity string, until time.Time,
+	id func(T) string, put func(context.Context, []T) (int, error)) (int, error) {
+	n := 0
+	err := readArchive(path, storage.MaxLimit, func(batch []T) error {
+		c.mu.Lock()
+		for _, r := range batch {
+			c.holds[entity+"/"+id(r)] = until
+		}
+		c.mu.Unlock()
+		stored, err := put(ctx, batch)
+		n += stored
+		return err
+	})
+	return n, err
+}
+
+// Status returns the state of retention
+func (c *Compactor) Status() (Status, error) {
+	archives, err := listArchives(c.Dir)
+	if err != nil {
+		return Status{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Status{Policy: c.Policy, Dir: c.Dir, LastPass: c.last, Archives: archives, Held: len(c.holds)}, nil
+}
+
+func (c *Compactor) held(entity, id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.holds[entity+"/"+id]
+	return ok
+}
+
+// releaseHolds drops the holds that ended before now
+func (c *Compactor) releaseHolds(now time.Time) error {
+	c.mu.Lock()
+	released := false
+	for key, until := range c.holds {
+		if until.Before(now) {
+			delete(c.holds, key)
+			released = true
+		}
+	}
+	c.mu.Unlock()
+	if !released {
+		return nil
+	}
+	return c.saveHolds()
+}
+
+// saveHolds writes the holds to the archive directory, replacing the file in
+// one step
+func (c *Compactor) saveHolds() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.holds)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.Dir, holdsFile)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
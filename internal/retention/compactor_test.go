@@ -0,0 +1,254 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testStore holds alerts of every severity at various ages and two reports
+func testStore(t *testing.T) *storage.MemoryStorage {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	alert := func(id, severity string, age time.Duration) models.Alert {
+		return models.Alert{
+			ID: id, ClusterID: "c1", Rule: "test", Resource: id, Severity: severity,
+			Message: "alert " + id, Timestamp: now.Add(-age),
+		}
+	}
+	refreshed := alert("high-refreshed", models.SeverityHigh, 40*day)
+	refreshed.Count, refreshed.FirstSeen, refreshed.LastSeen = 3, refreshed.Timestamp, now.Add(-day)
+	for _, a := range []models.Alert{
+		alert("info-old", models.SeverityInfo, 10*day),
+		alert("info-new", models.SeverityInfo, 3*day),
+		alert("high-old", models.SeverityHigh, 40*day),
+		alert("high-new", models.SeverityHigh, 20*day),
+		alert("critical-ancient", models.SeverityCritical, 400*day),
+		refreshed,
+	} {
+		if err := store.AddAlert(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []models.IncidentReport{
+		{ID: "rep-old", Action: "isolate", Timestamp: now.Add(-100 * day)},
+		{ID: "rep-new", Action: "isolate", Timestamp: now.Add(-10 * day)},
+	} {
+		if err := store.AddReport(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func testCompactor(t *testing.T, store storage.Storage, dir string) *Compactor {
+	t.Helper()
+	policy, err := ParsePolicy("alerts=30d,alerts.info=7d,alerts.critical=0,reports=90d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCompactor(store, policy, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func storedIDs(t *testing.T, store storage.Storage) (alerts, reports []string) {
+	t.Helper()
+	ctx := context.Background()
+	as, err := storage.All(ctx, store.GetAlerts, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range as {
+		alerts = append(alerts, a.ID)
+	}
+	rs, err := storage.All(ctx, store.GetReports, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rs {
+		reports = append(reports, r.ID)
+	}
+	slices.Sort(alerts)
+	slices.Sort(reports)
+	return alerts, reports
+}
+
+func compactAt(t *testing.T, c *Compactor, at time.Time, alerts, reports int) Pass {
+	t.Helper()
+	pass, err := c.Compact(context.Background(), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pass.Alerts != alerts || pass.Reports != reports {
+		t.Fatalf("pass at %s archived %d alerts and %d reports, want %d and %d", at, pass.Alerts, pass.Reports, alerts, reports)
+	}
+	return pass
+}
+
+// readLines decodes every line of a gzip JSONL archive
+func readLines[T any](t *testing.T, path string) []T {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []T
+	lines := bufio.NewScanner(gz)
+	for lines.Scan() {
+		var r T
+		if err := json.Unmarshal(lines.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCompactCutoffs(t *testing.T) {
+	store := testStore(t)
+	dir := t.TempDir()
+	c := testCompactor(t, store, dir)
+
+	pass := compactAt(t, c, now, 2, 1)
+	alerts, reports := storedIDs(t, store)
+	// info expires after 7 days, critical never and everything else after 30
+	// days since it was last seen
+	if want := []string{"critical-ancient", "high-new", "high-refreshed", "info-new"}; !slices.Equal(alerts, want) {
+		t.Errorf("kept alerts %v, want %v", alerts, want)
+	}
+	if want := []string{"rep-new"}; !slices.Equal(reports, want) {
+		t.Errorf("kept reports %v, want %v", reports, want)
+	}
+
+	if len(pass.Archives) != 2 || !strings.HasPrefix(pass.Archives[0], "alerts-") || !strings.HasPrefix(pass.Archives[1], "reports-") {
+		t.Fatalf("archives = %v", pass.Archives)
+	}
+	var archived []string
+	for _, a := range readLines[models.Alert](t, filepath.Join(dir, pass.Archives[0])) {
+		archived = append(archived, a.ID)
+	}
+	slices.Sort(archived)
+	if want := []string{"high-old", "info-old"}; !slices.Equal(archived, want) {
+		t.Errorf("alert archive holds %v, want %v", archived, want)
+	}
+	if rs := readLines[models.IncidentReport](t, filepath.Join(dir, pass.Archives[1])); len(rs) != 1 || rs[0].ID != "rep-old" {
+		t.Errorf("report archive holds %+v, want rep-old", rs)
+	}
+
+	// a pass with nothing to do writes no archive, complete or partial
+	pass = compactAt(t, c, now, 0, 0)
+	if len(pass.Archives) != 0 {
+		t.Errorf("empty pass wrote %v", pass.Archives)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("archive directory holds %d files, want the 2 archives", len(entries))
+	}
+}
+
+func TestCompactKeepsEverythingWithoutPolicy(t *testing.T) {
+	store := testStore(t)
+	c, err := NewCompactor(store, Policy{}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	compactAt(t, c, now.Add(1000*day), 0, 0)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+	dir := t.TempDir()
+	c := testCompactor(t, store, dir)
+	before, err := storage.All(ctx, store.GetAlerts, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAlerts, wantReports := storedIDs(t, store)
+
+	pass := compactAt(t, c, now, 2, 1)
+	hold := now.Add(day)
+	for i, name := range pass.Archives {
+		want := []int{2, 1}[i]
+		if n, err := c.Restore(ctx, name, hold); err != nil || n != want {
+			t.Fatalf("Restore(%s) = %d, %v; want %d records stored", name, n, err, want)
+		}
+		// restoring again skips what is stored
+		if n, err := c.Restore(ctx, name, hold); err != nil || n != 0 {
+			t.Errorf("second Restore(%s) = %d, %v; want nothing stored", name, n, err)
+		}
+	}
+	alerts, reports := storedIDs(t, store)
+	if !slices.Equal(alerts, wantAlerts) || !slices.Equal(reports, wantReports) {
+		t.Errorf("after restore stored %v and %v, want %v and %v", alerts, reports, wantAlerts, wantReports)
+	}
+	after, err := storage.All(ctx, store.GetAlerts, storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range before {
+		i := slices.IndexFunc(after, func(a models.Alert) bool { return a.ID == b.ID })
+		a := after[i]
+		if a.Severity != b.Severity || a.Message != b.Message || !a.Timestamp.Equal(b.Timestamp) || !a.LastSeen.Equal(b.LastSeen) || a.Fingerprint != b.Fingerprint {
+			t.Errorf("restored %+v, archived %+v", a, b)
+		}
+	}
+
+	// restored records are held from compaction until the hold ends, also
+	// across restarts
+	compactAt(t, c, now.Add(time.Hour), 0, 0)
+	c = testCompactor(t, store, dir)
+	status, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Held != 3 || len(status.Archives) != 2 {
+		t.Errorf("status = %+v, want 3 held records and 2 archives", status)
+	}
+	compactAt(t, c, hold.Add(time.Hour), 2, 1)
+	if status, _ := c.Status(); status.Held != 0 {
+		t.Errorf("%d records still held after the hold ended", status.Held)
+	}
+}
+
+func TestRestoreUnknownArchive(t *testing.T) {
+	c := testCompactor(t, storage.NewMemoryStorage(), t.TempDir())
+	for _, name := range []string{
+		"alerts-20260601T120000.000Z.jsonl.gz", // not there
+		"events-20260601T120000.000Z.jsonl.gz",
+		"alerts-20260601T120000.000Z.json",
+		"../alerts-20260601T120000.000Z.jsonl.gz",
+		holdsFile,
+	} {
+		if _, err := c.Restore(context.Background(), name, now); !errors.Is(err, ErrNoArchive) {
+			t.Errorf("Restore(%q) = %v, want ErrNoArchive", name, err)
+		}
+	}
+}
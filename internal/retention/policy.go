@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// Entities that retention applies to
+const (
+	EntityAlerts  = "alerts"
+	EntityReports = "reports"
+)
+
+// Policy is how long alerts and reports are kept. An alert is kept for the
+// period of its severity, or for Alerts when its severity has none. A zero
+// period keeps records forever.
+type Policy struct {
+	Alerts     time.Duration
+	Severities map[string]time.Duration
+	Reports    time.Duration
+}
+
+// AlertPeriod returns how long alerts of a severity are kept
+func (p Policy) AlertPeriod(severity string) time.Duration {
+	if d, ok := p.Severities[severity]; ok {
+		return d
+	}
+	return p.Alerts
+}
+
+// ParsePolicy reads a comma separated policy such as
+// "alerts=90d,alerts.info=7d,alerts.critical=365d,reports=180d". Periods are
+// whole days with a d suffix or Go durations; 0 keeps records forever.
+func ParsePolicy(s string) (Policy, error) {
+	p := Policy{Severities: make(map[string]time.Duration)}
+	for _, rule := range strings.Split(s, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(rule, "=")
+		if !ok {
+			return Policy{}, fmt.Errorf("retention rule %q: want entity=period", rule)
+		}
+		period, err := parsePeriod(strings.TrimSpace(value))
+		if err != nil {
+			return Policy{}, fmt.Errorf("retention rule %q: %w", rule, err)
+		}
+		entity, severity, _ := strings.Cut(strings.TrimSpace(key), ".")
+		switch {
+		case entity == EntityAlerts && severity == "":
+			p.Alerts = period
+		case entity == EntityAlerts:
+			if severity != models.SeverityInfo && models.SeverityRank(severity) == 0 {
+				return Policy{}, fmt.Errorf("retention rule %q: unknown severity %s", rule, severity)
+			}
+			p.Severities[severity] = period
+		case entity == EntityReports && severity == "":
+			p.Reports = period
+		default:
+			return Policy{}, fmt.Errorf("retention rule %q: unknown entity %s", rule, key)
+		}
+	}
+	return p, nil
+}
+
+// LoadCompactor creates a compactor for the policy in KSMS_RETENTION, which
+// keeps everything forever when unset, that archives to the directory named
+// by KSMS_ARCHIVE_DIR, "archive" by default
+func LoadCompactor(store storage.Storage) (*Compactor, error) {
+	p, err := ParsePolicy(os.Getenv("KSMS_RETENTION"))
+	if err != nil {
+		return nil, fmt.Errorf("KSMS_RETENTION: %w", err)
+	}
+	dir := os.Getenv("KSMS_ARCHIVE_DIR")
+	if dir == "" {
+		dir = "archive"
+	}
+	return NewCompactor(store, p, dir)
+}
+
+func parsePeriod(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid period %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid period %s", s)
+	}
+	return d, nil
+}
+
+func formatPeriod(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	}
+	return d.String()
+}
+
+// MarshalJSON writes the policy as rules in the syntax of ParsePolicy, e.g.
+// {"alerts": "90d", "alerts.info": "7d"}. Entities kept forever are left out.
+func (p Policy) MarshalJSON() ([]byte, error) {
+	rules := make(map[string]string)
+	if p.Alerts > 0 {
+		rules[EntityAlerts] = formatPeriod(p.Alerts)
+	}
+	for severity, d := range p.Severities {
+		rules[EntityAlerts+"."+severity] = formatPeriod(d)
+	}
+	if p.Reports > 0 {
+		rules[EntityReports] = formatPeriod(p.Reports)
+	}
+	return json.Marshal(rules)
+}
@@ -0,0 +1,67 @@
+package retention
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+)
+
+const day = 24 * time.Hour
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy(" alerts=90d, alerts.info=7d,alerts.critical=0,reports=36h,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Alerts != 90*day || p.Reports != 36*time.Hour {
+		t.Errorf("policy = %+v", p)
+	}
+	for severity, want := range map[string]time.Duration{
+		models.SeverityInfo:     7 * day,
+		models.SeverityCritical: 0,
+		models.SeverityHigh:     90 * day,
+	} {
+		if got := p.AlertPeriod(severity); got != want {
+			t.Errorf("AlertPeriod(%s) = %s, want %s", severity, got, want)
+		}
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rules map[string]string
+	if err := json.Unmarshal(data, &rules); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"alerts": "90d", "alerts.info": "7d", "alerts.critical": "0d", "reports": "36h0m0s"}
+	if !maps.Equal(rules, want) {
+		t.Errorf("MarshalJSON = %s, want %v", data, want)
+	}
+
+	if p, err := ParsePolicy(""); err != nil || p.Alerts != 0 || p.Reports != 0 || len(p.Severities) != 0 {
+		t.Errorf("empty policy = %+v, %v; want everything kept forever", p, err)
+	}
+}
+
+func TestParsePolicyRejectsMalformed(t *testing.T) {
+	for _, s := range []string{
+		"alerts",
+		"alerts=",
+		"alerts=90",
+		"alerts=ninety days",
+		"alerts=-1d",
+		"alerts=-5h",
+		"alerts.urgent=7d",
+		"events=7d",
+		"reports.high=7d",
+		"alerts=90d,reports=1y",
+	} {
+		if p, err := ParsePolicy(s); err == nil {
+			t.Errorf("ParsePolicy(%q) = %+v, want an error", s, p)
+		}
+	}
+}
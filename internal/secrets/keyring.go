@@ -0,0 +1,205 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// sealedPrefix marks values sealed by a Keyring. Values without it are
+// legacy plain text, readable until the next rotation encrypts them.
+const sealedPrefix = "ksms:v1:"
+
+// KeySize is the size of master and data keys (AES-256)
+const KeySize = 32
+
+// Keyring does envelope encryption: every value gets a fresh data key that
+// encrypts it with AES-GCM, and the data key is stored next to it wrapped by
+// the current master key. Previous master keys are kept to open values sealed
+// before a rotation.
+type Keyring struct {
+	current string            // ID of the master key new values are sealed with
+	keys    map[string][]byte // master keys by ID
+}
+
+// NewKeyring creates a keyring sealing with current and also opening values
+// sealed with any of the previous keys
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for i, key := range append([][]byte{current}, previous...) {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %d is %d bytes, want %d", i, len(key), KeySize)
+		}
+		k.keys[keyID(key)] = key
+	}
+	k.current = keyID(current)
+	return k, nil
+}
+
+// LoadKeyring reads the master key from KSMS_MASTER_KEY (base64) or from the
+// file named by KSMS_MASTER_KEY_FILE, "master.key" by default. A missing
+// default file is created with a new random key; a missing file named by
+// KSMS_MASTER_KEY_FILE is an error, as a new key could not open the values
+// sealed with the configured one. Keys being rotated out are listed, comma
+// separated, in KSMS_PREVIOUS_MASTER_KEYS.
+func LoadKeyring() (*Keyring, error) {
+	current, err := loadMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	var previous [][]byte
+	for _, s := range strings.Split(os.Getenv("KSMS_PREVIOUS_MASTER_KEYS"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("KSMS_PREVIOUS_MASTER_KEYS: %w", err)
+		}
+		previous = append(previous, key)
+	}
+	return NewKeyring(current, previous...)
+}
+
+func loadMasterKey() ([]byte, error) {
+	if s := os.Getenv("KSMS_MASTER_KEY"); s != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("KSMS_MASTER_KEY: %w", err)
+		}
+		return key, nil
+	}
+
+	path := os.Getenv("KSMS_MASTER_KEY_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = "master.key"
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && explicit {
+		return nil, fmt.Errorf("KSMS_MASTER_KEY_FILE %s does not exist; refusing to generate a new master key", path)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("Generated a new master key in %s; back it up, stored kubeconfigs cannot be read without it", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// Seal encrypts a value. aad binds the result to its owner, e.g. a cluster
+// ID, so it cannot be opened when copied to another row.
+func (k *Keyring) Seal(plaintext, aad string) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + k.current + ":" + base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a value sealed with any key of the keyring. Legacy plain text
+// values are returned as they are.
+func (k *Keyring) Open(sealed, aad string) (string, error) {
+	rest, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return sealed, nil
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed sealed value")
+	}
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("value is sealed with unknown master key %s", parts[0])
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := open(masterKey, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("unwrapping data key: %w", err)
+	}
+	plaintext, err := open(dataKey, ciphertext, []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Current reports whether a value is sealed with the current master key, so
+// rotation can skip it
+func (k *Keyring) Current(sealed string) bool {
+	return strings.HasPrefix(sealed, sealedPrefix+k.current+":")
+}
+
+// keyID names a master key without revealing it
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// seal encrypts with AES-GCM, prefixing the random nonce
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
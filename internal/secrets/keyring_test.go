@@ -0,0 +1,242 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+const kubeConfig = "apiVersion: v1\nkind: Config\nusers:\n- name: admin\n  user:\n    token: s3cr3t\n"
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func testKeyring(t *testing.T, current []byte, previous ...[]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(current, previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSealOpen(t *testing.T) {
+	k := testKeyring(t, testKey(1))
+	sealed, err := k.Seal(kubeConfig, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "s3cr3t") || !k.Current(sealed) {
+		t.Errorf("sealed value = %q", sealed)
+	}
+	again, err := k.Seal(kubeConfig, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same value; data keys and nonces must be fresh")
+	}
+	if got, err := k.Open(sealed, "c1"); err != nil || got != kubeConfig {
+		t.Errorf("Open = %q, %v; want the kubeconfig", got, err)
+	}
+
+	// a value copied to another cluster's row does not open
+	if _, err := k.Open(sealed, "c2"); err == nil {
+		t.Error("opened a value with the wrong cluster ID")
+	}
+	for _, tampered := range []string{
+		sealed[:len(sealed)-4] + "AAA=",
+		strings.TrimSuffix(sealed, sealed[strings.LastIndex(sealed, ":"):]),
+		sealedPrefix + "x:y:z",
+	} {
+		if _, err := k.Open(tampered, "c1"); err == nil {
+			t.Errorf("opened tampered value %q", tampered)
+		}
+	}
+}
+
+func TestOpenUnknownMasterKey(t *testing.T) {
+	old := testKeyring(t, testKey(1))
+	sealed, err := old.Seal(kubeConfig, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := testKeyring(t, testKey(2))
+	if _, err := k.Open(sealed, "c1"); err == nil || !strings.Contains(err.Error(), "unknown master key "+keyID(testKey(1))) {
+		t.Errorf("Open under another key = %v, want an unknown master key error", err)
+	}
+	if k.Current(sealed) {
+		t.Error("a value sealed with another key counts as current")
+	}
+	// listing the old key as previous opens it again
+	k = testKeyring(t, testKey(2), testKey(1))
+	if got, err := k.Open(sealed, "c1"); err != nil || got != kubeConfig {
+		t.Errorf("Open with the previous key = %q, %v", got, err)
+	}
+}
+
+func TestNewKeyringRejectsShortKeys(t *testing.T) {
+	if _, err := NewKeyring(testKey(1)[:16]); err == nil {
+		t.Error("accepted a 16 byte master key")
+	}
+	if _, err := NewKeyring(testKey(1), []byte("short")); err == nil {
+		t.Error("accepted a short previous master key")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("KSMS_MASTER_KEY", "")
+	t.Setenv("KSMS_MASTER_KEY_FILE", "")
+	t.Setenv("KSMS_PREVIOUS_MASTER_KEYS", "")
+
+	// without configuration a key is generated once and then reused
+	first, err := LoadKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "master.key")); err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.current != second.current {
+		t.Error("the generated master key was not reused")
+	}
+
+	// a configured key file that is missing is an error, not a new key
+	missing := filepath.Join(dir, "missing.key")
+	t.Setenv("KSMS_MASTER_KEY_FILE", missing)
+	if _, err := LoadKeyring(); err == nil {
+		t.Error("generated a key for a missing KSMS_MASTER_KEY_FILE")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("created %s", missing)
+	}
+
+	t.Setenv("KSMS_MASTER_KEY", base64.StdEncoding.EncodeToString(testKey(2)))
+	t.Setenv("KSMS_PREVIOUS_MASTER_KEYS", " "+base64.StdEncoding.EncodeToString(testKey(1))+", ")
+	k, err := LoadKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.current != keyID(testKey(2)) || len(k.keys) != 2 {
+		t.Errorf("keyring = current %s with %d keys", k.current, len(k.keys))
+	}
+	t.Setenv("KSMS_PREVIOUS_MASTER_KEYS", "not base64!")
+	if _, err := LoadKeyring(); err == nil {
+		t.Error("accepted a malformed previous key")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	old := testKeyring(t, testKey(1))
+	sealed, err := old.Seal(kubeConfig, "sealed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []models.Cluster{
+		{ID: "legacy", Name: "stored before encryption", KubeConfig: kubeConfig},
+		{ID: "sealed", Name: "sealed with the old key", KubeConfig: sealed},
+	} {
+		if err := store.AddCluster(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// legacy plain text is readable before it is rotated
+	if c, err := WithEncryption(store, old).GetCluster("legacy"); err != nil || c.KubeConfig != kubeConfig {
+		t.Errorf("legacy cluster = %q, %v", c.KubeConfig, err)
+	}
+
+	k := testKeyring(t, testKey(2), testKey(1))
+	if n, err := Rotate(ctx, store, k); err != nil || n != 2 {
+		t.Fatalf("Rotate = %d, %v; want 2 rows rewritten", n, err)
+	}
+	if n, err := Rotate(ctx, store, k); err != nil || n != 0 {
+		t.Errorf("second Rotate = %d, %v; want nothing to do", n, err)
+	}
+
+	// the rows are sealed with the new key alone and bound to their cluster
+	current := testKeyring(t, testKey(2))
+	encrypted := WithEncryption(store, current)
+	for _, id := range []string{"legacy", "sealed"} {
+		raw, err := store.GetCluster(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !current.Current(raw.KubeConfig) {
+			t.Errorf("%s stored as %q, want it sealed with the current key", id, raw.KubeConfig)
+		}
+		if c, err := encrypted.GetCluster(id); err != nil || c.KubeConfig != kubeConfig {
+			t.Errorf("%s = %q, %v", id, c.KubeConfig, err)
+		}
+	}
+
+	// a row under a key that is no longer configured stops the rotation
+	if err := store.SetKubeConfig("sealed", sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rotate(ctx, store, current); err == nil {
+		t.Error("rotated a value sealed with an unknown key")
+	}
+}
+
+func TestEncryptingStorage(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	k := testKeyring(t, testKey(1))
+	encrypted := WithEncryption(store, k)
+	if err := encrypted.AddCluster(models.Cluster{ID: "c1", KubeConfig: kubeConfig}); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := store.GetCluster("c1"); !k.Current(raw.KubeConfig) {
+		t.Errorf("stored kubeconfig = %q, want it sealed", raw.KubeConfig)
+	}
+	page, err := encrypted.GetClusters(context.Background(), storage.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].KubeConfig != kubeConfig {
+		t.Errorf("GetClusters = %+v", page.Items)
+	}
+	if err := encrypted.SetKubeConfig("c1", "replaced"); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := encrypted.GetCluster("c1"); err != nil || c.KubeConfig != "replaced" {
+		t.Errorf("after SetKubeConfig = %q, %v", c.KubeConfig, err)
+	}
+}
+
+func TestClusterMarshalJSONRedacts(t *testing.T) {
+	data, err := json.Marshal(models.Cluster{ID: "c1", Name: "prod", KubeConfig: kubeConfig, AuditToken: "audit-s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["kube_config"]; ok || bytes.Contains(data, []byte("s3cr3t")) {
+		t.Errorf("cluster JSON %s reveals its credentials", data)
+	}
+	if _, ok := fields["audit_token"]; ok {
+		t.Errorf("cluster JSON %s has an audit token", data)
+	}
+	if fields["id"] != "c1" || fields["name"] != "prod" {
+		t.Errorf("cluster JSON %s lost its other fields", data)
+	}
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+type encryptingStorage struct {
+	storage.Storage
+	keys *Keyring
+}
+
+// WithEncryption wraps a storage so that cluster kubeconfigs are sealed before
+// they are stored and opened when read back
+func WithEncryption(s storage.Storage, keys *Keyring) storage.Storage {
+	return &encryptingStorage{Storage: s, keys: keys}
+}
+
+func (s *encryptingStorage) AddCluster(c models.Cluster) error {
+	sealed, err := s.keys.Seal(c.KubeConfig, c.ID)
+	if err != nil {
+		return err
+	}
+	c.KubeConfig = sealed
+	return s.Storage.AddCluster(c)
+}
+
+// GetClusters opens every kubeconfig of the page. A cluster whose kubeconfig
+// cannot be opened, e.g. because its master key is gone, is returned without one.
+func (s *encryptingStorage) GetClusters(ctx context.Context, opts storage.ListOptions) (storage.Page[models.Cluster], error) {
+	page, err := s.Storage.GetClusters(ctx, opts)
+	for i, c := range page.Items {
+		kubeConfig, err := s.keys.Open(c.KubeConfig, c.ID)
+		if err != nil {
+			log.Printf("Cannot decrypt kubeconfig of cluster %s: %v", c.ID, err)
+		}
+		page.Items[i].KubeConfig = kubeConfig
+	}
+	return page, err
+}
+
+func (s *encryptingStorage) GetCluster(id string) (models.Cluster, error) {
+	c, err := s.Storage.GetCluster(id)
+	if err != nil {
+		return c, err
+	}
+	if c.KubeConfig, err = s.keys.Open(c.KubeConfig, c.ID); err != nil {
+		return models.Cluster{}, err
+	}
+	return c, nil
+}
+
+func (s *encryptingStorage) SetKubeConfig(clusterID, kubeConfig string) error {
+	sealed, err := s.keys.Seal(kubeConfig, clusterID)
+	if err != nil {
+		return err
+	}
+	return s.Storage.SetKubeConfig(clusterID, sealed)
+}
+
+// Rotate re-encrypts every kubeconfig of an unwrapped storage that is not
+// sealed with the current master key, including legacy plain text ones. It is
+// safe to repeat after a failure; it returns how many rows it rewrote.
+func Rotate(ctx context.Context, s storage.Storage, keys *Keyring) (int, error) {
+	clusters, err := storage.All(ctx, s.GetClusters, storage.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	rotated := 0
+	for _, c := range clusters {
+		if keys.Current(c.KubeConfig) {
+			continue
+		}
+		kubeConfig, err := keys.Open(c.KubeConfig, c.ID)
+		if err != nil {
+			return rotated, fmt.Errorf("cluster %s: %w", c.ID, err)
+		}
+		sealed, err := keys.Seal(kubeConfig, c.ID)
+		if err != nil {
+			return rotated, err
+		}
+		if err := s.SetKubeConfig(c.ID, sealed); err != nil {
+			return rotated, fmt.Errorf("cluster %s: %w", c.ID, err)
+		}
+		rotated++
+	}
+	return rotated, nil
+}
@@ -1,22 +1,73 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"KubernetesSecurityMonitoringSystem/internal/models"
 
 	_ "github.com/lib/pq"
 )
 
+// DatabaseStorage keeps everything in PostgreSQL or, when opened on a SQLite
+// database, in a single SQLite file. Both share the migrations and the SQL.
 type DatabaseStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	sqlite bool
 }
 
+// NewDatabaseStorage opens the database selected by OpenDatabase and applies
+// pending migrations
 func NewDatabaseStorage() (*DatabaseStorage, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	return newDatabaseStorage(db)
+}
+
+func newDatabaseStorage(db *sql.DB) (*DatabaseStorage, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied schema migrations %v", applied)
+	}
+
+	return &DatabaseStorage{db: db, sqlite: isSQLite(db)}, nil
+}
+
+// Close closes the database connections
+func (s *DatabaseStorage) Close() error {
+	return s.db.Close()
+}
+
+// OpenDatabase connects to the database configured by the environment: the
+// SQLite file at DB_PATH when DB_DRIVER is "sqlite", otherwise the PostgreSQL
+// database described by the other DB_* variables
+func OpenDatabase() (*sql.DB, error) {
+	switch driver := getEnv("DB_DRIVER", "postgres"); driver {
+	case "sqlite":
+		return OpenSQLite(getEnv("DB_PATH", "ksms.db"))
+	case "postgres":
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, want postgres or sqlite", driver)
+	}
+
 	host := getEnv("DB_HOST", "localhost")
 	port := getEnv("DB_PORT", "5432")
 	user := getEnv("DB_USER", "postgres")
@@ -32,67 +83,30 @@ func NewDatabaseStorage() (*DatabaseStorage, error) {
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
+	return db, nil
+}
 
-	s := &DatabaseStorage{db: db}
-	if err := s.initSchema(); err != nil {
-		return nil, err
+// forUpdate is the clause locking selected rows until the transaction ends.
+// SQLite has no row locks and needs none: its only connection already
+// serializes transactions.
+func (s *DatabaseStorage) forUpdate() string {
+	if s.sqlite {
+		return ""
 	}
+	return " FOR UPDATE"
+}
 
-	return s, nil
-}
-
-func (s *DatabaseStorage) initSchema() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			email TEXT UNIQUE NOT NULL,
-			password TEXT NOT NULL,
-			first_name TEXT,
-			last_name TEXT,
-			role TEXT,
-			token_keys JSONB,
-			created_at TIMESTAMP WITH TIME ZONE
-		)`,
-		`CREATE TABLE IF NOT EXISTS clusters (
-			id TEXT PRIMARY KEY,
-			name TEXT,
-			kube_config TEXT,
-			status TEXT,
-			metrics JSONB,
-			created_at TIMESTAMP WITH TIME ZONE
-		)`,
-		`CREATE TABLE IF NOT EXISTS policies (
-			id TEXT PRIMARY KEY,
-			name TEXT,
-			description TEXT,
-			rules JSONB,
-			namespace TEXT,
-			created_at TIMESTAMP WITH TIME ZONE
-		)`,
-		`CREATE TABLE IF NOT EXISTS alerts (
-			id TEXT PRIMARY KEY,
-			cluster_id TEXT,
-			severity TEXT,
-			message TEXT,
-			timestamp TIMESTAMP WITH TIME ZONE
-		)`,
-		`CREATE TABLE IF NOT EXISTS reports (
-			id TEXT PRIMARY KEY,
-			alert_id TEXT,
-			details TEXT,
-			action_taken TEXT,
-			timestamp TIMESTAMP WITH TIME ZONE
-		)`,
-	}
-
-	for _, q := range queries {
-		if _, err := s.db.Exec(q); err != nil {
-			return err
-		}
+// lock takes a transaction scoped advisory lock on key, for serializing
+// writers of rows that may not exist yet
+func (s *DatabaseStorage) lock(tx *sql.Tx, key string) error {
+	if s.sqlite {
+		return nil
 	}
-	return nil
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", key)
+	return err
 }
 
 // User methods
@@ -100,7 +114,7 @@ func (s *DatabaseStorage) AddUser(u models.User) error {
 	tokenKeys, _ := json.Marshal(u.TokenKeys)
 	_, err := s.db.Exec("INSERT INTO users (id, email, password, first_name, last_name, role, token_keys, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
 		u.ID, u.Email, u.Password, u.FirstName, u.LastName, u.Role, tokenKeys, u.CreatedAt)
-	return err
+	return dbError(err, "user")
 }
 
 func (s *DatabaseStorage) GetUser(id string) (models.User, error) {
@@ -109,7 +123,7 @@ func (s *DatabaseStorage) GetUser(id string) (models.User, error) {
 	err := s.db.QueryRow("SELECT id, email, password, first_name, last_name, role, token_keys, created_at FROM users WHERE id = $1", id).
 		Scan(&u.ID, &u.Email, &u.Password, &u.FirstName, &u.LastName, &u.Role, &tokenKeys, &u.CreatedAt)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, dbError(err, "user")
 	}
 	json.Unmarshal(tokenKeys, &u.TokenKeys)
 	return u, nil
@@ -121,180 +135,1190 @@ func (s *DatabaseStorage) GetUserByEmail(email string) (models.User, error) {
 	err := s.db.QueryRow("SELECT id, email, password, first_name, last_name, role, token_keys, created_at FROM users WHERE email = $1", email).
 		Scan(&u.ID, &u.Email, &u.Password, &u.FirstName, &u.LastName, &u.Role, &tokenKeys, &u.CreatedAt)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, dbError(err, "user")
 	}
 	json.Unmarshal(tokenKeys, &u.TokenKeys)
 	return u, nil
 }
 
-func (s *DatabaseStorage) GetAllUsers() []models.User {
-	rows, err := s.db.Query("SELECT id, email, password, first_name, last_name, role, token_keys, created_at FROM users")
+func (s *DatabaseStorage) GetAllUsers(ctx context.Context, opts ListOptions) (Page[models.User], error) {
+	q, err := opts.resolve(Oldest)
 	if err != nil {
-		log.Println("Error querying users:", err)
-		return nil
+		return Page[models.User]{}, err
 	}
-	defer rows.Close()
-
-	var users []models.User
-	for rows.Next() {
+	l := q.sql("created_at", "id")
+	l.contains("email", "first_name", "last_name")
+	return listRows(ctx, s.db, "SELECT id, email, password, first_name, last_name, role, token_keys, created_at FROM users", l, func(rows *sql.Rows) (models.User, error) {
 		var u models.User
 		var tokenKeys []byte
 		if err := rows.Scan(&u.ID, &u.Email, &u.Password, &u.FirstName, &u.LastName, &u.Role, &tokenKeys, &u.CreatedAt); err != nil {
-			continue
+			return u, err
 		}
 		json.Unmarshal(tokenKeys, &u.TokenKeys)
-		users = append(users, u)
-	}
-	return users
+		return u, nil
+	}, userKey)
 }
 
 func (s *DatabaseStorage) UpdateUser(u models.User) error {
 	tokenKeys, _ := json.Marshal(u.TokenKeys)
-	_, err := s.db.Exec("UPDATE users SET email=$1, password=$2, first_name=$3, last_name=$4, role=$5, token_keys=$6 WHERE id=$7",
+	res, err := s.db.Exec("UPDATE users SET email=$1, password=$2, first_name=$3, last_name=$4, role=$5, token_keys=$6 WHERE id=$7",
 		u.Email, u.Password, u.FirstName, u.LastName, u.Role, tokenKeys, u.ID)
-	return err
+	return affected(res, err, "user")
 }
 
 func (s *DatabaseStorage) DeleteUser(id string) error {
-	_, err := s.db.Exec("DELETE FROM users WHERE id=$1", id)
-	return err
+	res, err := s.db.Exec("DELETE FROM users WHERE id=$1", id)
+	return affected(res, err, "user")
 }
 
 // Cluster methods
 func (s *DatabaseStorage) AddCluster(c models.Cluster) error {
 	metrics, _ := json.Marshal(c.Metrics)
-	_, err := s.db.Exec("INSERT INTO clusters (id, name, kube_config, status, metrics, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
-		c.ID, c.Name, c.KubeConfig, c.Status, metrics, c.CreatedAt)
-	return err
+	_, err := s.db.Exec("INSERT INTO clusters (id, name, kube_config, status, metrics, audit_token, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		c.ID, c.Name, c.KubeConfig, c.Status, metrics, c.AuditToken, c.CreatedAt)
+	return dbError(err, "cluster")
 }
 
-func (s *DatabaseStorage) GetClusters() []models.Cluster {
-	rows, err := s.db.Query("SELECT id, name, kube_config, status, metrics, created_at FROM clusters")
+func (s *DatabaseStorage) GetClusters(ctx context.Context, opts ListOptions) (Page[models.Cluster], error) {
+	q, err := opts.resolve(Oldest)
 	if err != nil {
-		return nil
+		return Page[models.Cluster]{}, err
 	}
-	defer rows.Close()
-
-	var clusters []models.Cluster
-	for rows.Next() {
+	l := q.sql("created_at", "id")
+	l.contains("name")
+	return listRows(ctx, s.db, "SELECT id, name, kube_config, status, metrics, COALESCE(audit_token, ''), created_at FROM clusters", l, func(rows *sql.Rows) (models.Cluster, error) {
 		var c models.Cluster
 		var metrics []byte
-		if err := rows.Scan(&c.ID, &c.Name, &c.KubeConfig, &c.Status, &metrics, &c.CreatedAt); err != nil {
-			continue
+		if err := rows.Scan(&c.ID, &c.Name, &c.KubeConfig, &c.Status, &metrics, &c.AuditToken, &c.CreatedAt); err != nil {
+			return c, err
 		}
 		json.Unmarshal(metrics, &c.Metrics)
-		clusters = append(clusters, c)
-	}
-	return clusters
+		return c, nil
+	}, clusterKey)
 }
 
 func (s *DatabaseStorage) GetCluster(id string) (models.Cluster, error) {
 	var c models.Cluster
 	var metrics []byte
-	err := s.db.QueryRow("SELECT id, name, kube_config, status, metrics, created_at FROM clusters WHERE id = $1", id).
-		Scan(&c.ID, &c.Name, &c.KubeConfig, &c.Status, &metrics, &c.CreatedAt)
+	err := s.db.QueryRow("SELECT id, name, kube_config, status, metrics, COALESCE(audit_token, ''), created_at FROM clusters WHERE id = $1", id).
+		Scan(&c.ID, &c.Name, &c.KubeConfig, &c.Status, &metrics, &c.AuditToken, &c.CreatedAt)
 	if err != nil {
-		return models.Cluster{}, err
+		return models.Cluster{}, dbError(err, "cluster")
 	}
 	json.Unmarshal(metrics, &c.Metrics)
 	return c, nil
 }
 
+func (s *DatabaseStorage) SetAuditToken(clusterID, token string) error {
+	res, err := s.db.Exec("UPDATE clusters SET audit_token=$2 WHERE id=$1", clusterID, token)
+	return affected(res, err, "cluster")
+}
+
+func (s *DatabaseStorage) SetKubeConfig(clusterID, kubeConfig string) error {
+	res, err := s.db.Exec("UPDATE clusters SET kube_config=$2 WHERE id=$1", clusterID, kubeConfig)
+	return affected(res, err, "cluster")
+}
+
 func (s *DatabaseStorage) DeleteCluster(id string) error {
-	_, err := s.db.Exec("DELETE FROM clusters WHERE id=$1", id)
-	return err
+	res, err := s.db.Exec("DELETE FROM clusters WHERE id=$1", id)
+	return affected(res, err, "cluster")
 }
 
 // Policy methods
 func (s *DatabaseStorage) AddPolicy(p models.Policy) error {
 	rules, _ := json.Marshal(p.Rules)
-	_, err := s.db.Exec("INSERT INTO policies (id, name, description, rules, namespace, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
-		p.ID, p.Name, p.Description, rules, p.Namespace, p.CreatedAt)
+	registries, _ := json.Marshal(p.AllowedRegistries)
+	responses, _ := json.Marshal(p.Responses)
+	playbooks, _ := json.Marshal(p.Playbooks)
+	_, err := s.db.Exec("INSERT INTO policies (id, name, description, rules, namespace, allowed_registries, severity, responses, auto_respond, playbooks, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		p.ID, p.Name, p.Description, rules, p.Namespace, registries, p.Severity, responses, p.AutoRespond, playbooks, p.CreatedAt)
+	return dbError(err, "policy")
+}
+
+func (s *DatabaseStorage) GetPolicies(ctx context.Context, opts ListOptions) (Page[models.Policy], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Policy]{}, err
+	}
+	l := q.sql("created_at", "id")
+	l.eq("COALESCE(severity, '')", q.Severity)
+	l.eq("namespace", q.Namespace)
+	l.contains("name", "description")
+	return listRows(ctx, s.db, "SELECT id, name, description, rules, namespace, allowed_registries, COALESCE(severity, ''), responses, auto_respond, playbooks, created_at FROM policies", l,
+		func(rows *sql.Rows) (models.Policy, error) { return scanPolicy(rows) }, policyKey)
+}
+
+func (s *DatabaseStorage) GetPolicy(id string) (models.Policy, error) {
+	p, err := scanPolicy(s.db.QueryRow("SELECT id, name, description, rules, namespace, allowed_registries, COALESCE(severity, ''), responses, auto_respond, playbooks, created_at FROM policies WHERE id = $1", id))
+	return p, dbError(err, "policy")
+}
+
+func scanPolicy(row interface{ Scan(...interface{}) error }) (models.Policy, error) {
+	var p models.Policy
+	var rules, registries, responses, playbooks []byte
+	if err := row.Scan(&p.ID, &p.Name, &p.Description, &rules, &p.Namespace, &registries, &p.Severity, &responses, &p.AutoRespond, &playbooks, &p.CreatedAt); err != nil {
+		return models.Policy{}, err
+	}
+	json.Unmarshal(rules, &p.Rules)
+	json.Unmarshal(registries, &p.AllowedRegistries)
+	json.Unmarshal(responses, &p.Responses)
+	json.Unmarshal(playbooks, &p.Playbooks)
+	return p, nil
+}
+
+func (s *DatabaseStorage) DeletePolicy(id string) error {
+	res, err := s.db.Exec("DELETE FROM policies WHERE id=$1", id)
+	return affected(res, err, "policy")
+}
+
+// Alert and Report methods
+const alertColumns = "id, cluster_id, COALESCE(policy_id, ''), severity, message, COALESCE(actor, ''), COALESCE(verb, ''), status, COALESCE(assignee, ''), COALESCE(resolution, ''), history, " +
+	"COALESCE(rule, ''), COALESCE(namespace, ''), COALESCE(resource, ''), COALESCE(fingerprint, ''), count, first_seen, last_seen, COALESCE(incident_id, ''), COALESCE(silence_id, ''), timestamp"
+
+const insertAlert = "INSERT INTO alerts (id, cluster_id, policy_id, severity, message, actor, verb, status, assignee, resolution, history, rule, namespace, resource, fingerprint, count, first_seen, last_seen, incident_id, silence_id, timestamp) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)"
+
+const incidentColumns = "id, cluster_id, namespace, rule, severity, alert_count, first_seen, last_seen, " +
+	"COALESCE(title, ''), status, COALESCE(owner, ''), COALESCE(created_by, ''), timeline, evidence_ids, post_mortem, closed_at"
+
+// AddAlert upserts an alert when the caller has no use for the result
+func (s *DatabaseStorage) AddAlert(a models.Alert) error {
+	_, _, err := s.UpsertAlert(a)
 	return err
 }
 
-func (s *DatabaseStorage) GetPolicies() []models.Policy {
-	rows, err := s.db.Query("SELECT id, name, description, rules, namespace, created_at FROM policies")
+// UpsertAlert folds the alert into the latest open alert with the same
+// fingerprint, or stores it and attaches it to an incident. It reports
+// whether a new alert was stored. Advisory locks on the fingerprint and the
+// group key serialize concurrent upserts of the same alert.
+func (s *DatabaseStorage) UpsertAlert(a models.Alert) (models.Alert, bool, error) {
+	a.Prepare()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Alert{}, false, err
+	}
+	defer tx.Rollback()
+
+	if err := s.lock(tx, a.Fingerprint); err != nil {
+		return models.Alert{}, false, err
+	}
+	existing, err := scanAlert(tx.QueryRow("SELECT "+alertColumns+" FROM alerts WHERE fingerprint=$1 AND status <> $2 AND COALESCE(silence_id, '') = $3 ORDER BY timestamp DESC LIMIT 1"+s.forUpdate(),
+		a.Fingerprint, models.AlertResolved, a.SilenceID))
+	switch {
+	case err == nil:
+		existing.Fold(a)
+		if _, err := tx.Exec("UPDATE alerts SET severity=$2, message=$3, count=$4, last_seen=$5 WHERE id=$1",
+			existing.ID, existing.Severity, existing.Message, existing.Count, existing.LastSeen); err != nil {
+			return models.Alert{}, false, err
+		}
+		if existing.IncidentID != "" {
+			inc, err := scanIncident(tx.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id=$1"+s.forUpdate(), existing.IncidentID))
+			if err == nil {
+				inc.Touch(existing, false)
+				if err := saveIncident(tx, inc); err != nil {
+					return models.Alert{}, false, err
+				}
+			}
+		}
+		return existing, false, tx.Commit()
+	case err != sql.ErrNoRows:
+		return models.Alert{}, false, err
+	}
+
+	if key := a.GroupKey(); key != "" {
+		if err := s.lock(tx, key); err != nil {
+			return models.Alert{}, false, err
+		}
+		inc, err := scanIncident(tx.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE cluster_id=$1 AND namespace=$2 AND rule=$3 ORDER BY last_seen DESC LIMIT 1"+s.forUpdate(),
+			a.ClusterID, a.Namespace, a.Rule))
+		if err == nil && inc.Accepts(a.Timestamp) {
+			inc.Touch(a, true)
+		} else if err == nil || err == sql.ErrNoRows {
+			inc = models.NewIncident(a)
+		} else {
+			return models.Alert{}, false, err
+		}
+		if err := saveIncident(tx, inc); err != nil {
+			return models.Alert{}, false, err
+		}
+		a.IncidentID = inc.ID
+	}
+
+	history, _ := json.Marshal(a.History)
+	if _, err := tx.Exec(insertAlert,
+		a.ID, a.ClusterID, a.PolicyID, a.Severity, a.Message, a.Actor, a.Verb, a.Status, a.Assignee, a.Resolution, history,
+		a.Rule, a.Namespace, a.Resource, a.Fingerprint, a.Count, a.FirstSeen, a.LastSeen, a.IncidentID, a.SilenceID, a.Timestamp); err != nil {
+		return models.Alert{}, false, err
+	}
+	return a, true, tx.Commit()
+}
+
+func (s *DatabaseStorage) GetAlerts(ctx context.Context, opts ListOptions) (Page[models.Alert], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Alert]{}, err
+	}
+	l := q.sql("timestamp", "id")
+	l.eq("cluster_id", q.ClusterID)
+	l.eq("severity", q.Severity)
+	l.eq("COALESCE(namespace, '')", q.Namespace)
+	l.eq("COALESCE(incident_id, '')", q.IncidentID)
+	l.contains("message", "resource", "rule", "actor")
+	return listRows(ctx, s.db, "SELECT "+alertColumns+" FROM alerts", l,
+		func(rows *sql.Rows) (models.Alert, error) { return scanAlert(rows) }, alertKey)
+}
+
+// DeleteAlerts deletes the alerts with the given IDs and returns how many it
+// found
+func (s *DatabaseStorage) DeleteAlerts(ctx context.Context, ids []string) (int, error) {
+	return s.deleteIDs(ctx, "alerts", ids)
+}
+
+// RestoreAlerts stores archived alerts as they were, skipping IDs that are
+// taken, and returns how many it stored
+func (s *DatabaseStorage) RestoreAlerts(ctx context.Context, alerts []models.Alert) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n := 0
+	for _, a := range alerts {
+		history, _ := json.Marshal(a.History)
+		res, err := tx.ExecContext(ctx, insertAlert+" ON CONFLICT (id) DO NOTHING",
+			a.ID, a.ClusterID, a.PolicyID, a.Severity, a.Message, a.Actor, a.Verb, a.Status, a.Assignee, a.Resolution, history,
+			a.Rule, a.Namespace, a.Resource, a.Fingerprint, a.Count, a.FirstSeen, a.LastSeen, a.IncidentID, a.SilenceID, a.Timestamp)
+		if err != nil {
+			return 0, err
+		}
+		if stored, _ := res.RowsAffected(); stored > 0 {
+			n++
+		}
+	}
+	return n, tx.Commit()
+}
+
+func (s *DatabaseStorage) GetAlert(id string) (models.Alert, error) {
+	a, err := scanAlert(s.db.QueryRow("SELECT "+alertColumns+" FROM alerts WHERE id=$1", id))
+	return a, dbError(err, "alert")
+}
+
+// TransitionAlert applies a status or assignee change to an alert. The row is
+// locked so concurrent transitions are validated against the latest status.
+func (s *DatabaseStorage) TransitionAlert(id string, t models.AlertTransition) (models.Alert, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Alert{}, err
+	}
+	defer tx.Rollback()
+
+	a, err := scanAlert(tx.QueryRow("SELECT "+alertColumns+" FROM alerts WHERE id=$1"+s.forUpdate(), id))
+	if err != nil {
+		return models.Alert{}, dbError(err, "alert")
+	}
+	if err := a.Apply(t); err != nil {
+		return models.Alert{}, conflict(err)
+	}
+	history, _ := json.Marshal(a.History)
+	if _, err := tx.Exec("UPDATE alerts SET status=$2, assignee=$3, resolution=$4, history=$5 WHERE id=$1",
+		a.ID, a.Status, a.Assignee, a.Resolution, history); err != nil {
+		return models.Alert{}, err
+	}
+	return a, tx.Commit()
+}
+
+// scanAlert reads a row of alertColumns. Alerts stored before grouping
+// existed have no first and last seen times and get their timestamp instead.
+func scanAlert(row interface{ Scan(...interface{}) error }) (models.Alert, error) {
+	var a models.Alert
+	var history []byte
+	var firstSeen, lastSeen sql.NullTime
+	if err := row.Scan(&a.ID, &a.ClusterID, &a.PolicyID, &a.Severity, &a.Message, &a.Actor, &a.Verb,
+		&a.Status, &a.Assignee, &a.Resolution, &history, &a.Rule, &a.Namespace, &a.Resource, &a.Fingerprint, &a.Count,
+		&firstSeen, &lastSeen, &a.IncidentID, &a.SilenceID, &a.Timestamp); err != nil {
+		return models.Alert{}, err
+	}
+	json.Unmarshal(history, &a.History)
+	a.FirstSeen, a.LastSeen = a.Timestamp, a.Timestamp
+	if firstSeen.Valid {
+		a.FirstSeen = firstSeen.Time
+	}
+	if lastSeen.Valid {
+		a.LastSeen = lastSeen.Time
+	}
+	return a, nil
+}
+
+func (s *DatabaseStorage) GetIncidents(ctx context.Context, opts ListOptions) (Page[models.Incident], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Incident]{}, err
+	}
+	l := q.sql("last_seen", "id")
+	l.eq("cluster_id", q.ClusterID)
+	l.eq("severity", q.Severity)
+	l.eq("namespace", q.Namespace)
+	l.eq("status", q.Status)
+	l.eq("owner", q.Owner)
+	l.contains("title", "rule")
+	return listRows(ctx, s.db, "SELECT "+incidentColumns+" FROM incidents", l,
+		func(rows *sql.Rows) (models.Incident, error) { return scanIncident(rows) }, incidentKey)
+}
+
+func (s *DatabaseStorage) GetIncident(id string) (models.Incident, error) {
+	inc, err := scanIncident(s.db.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id=$1", id))
+	return inc, dbError(err, "incident")
+}
+
+// CreateIncident stores an incident opened by hand and moves the given alerts
+// into it. The alert counts of the incidents they leave are recounted.
+func (s *DatabaseStorage) CreateIncident(inc models.Incident, alertIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	left := make(map[string]bool)
+	for _, id := range alertIDs {
+		var incidentID string
+		if err := tx.QueryRow("SELECT COALESCE(incident_id, '') FROM alerts WHERE id=$1"+s.forUpdate(), id).Scan(&incidentID); err != nil {
+			return dbError(err, "alert")
+		}
+		if incidentID != "" {
+			left[incidentID] = true
+		}
+	}
+	timeline, _ := json.Marshal(inc.Timeline)
+	evidence, _ := json.Marshal(inc.EvidenceIDs)
+	if _, err := tx.Exec(`INSERT INTO incidents (id, cluster_id, namespace, rule, severity, alert_count, first_seen, last_seen, title, status, owner, created_by, timeline, evidence_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		inc.ID, inc.ClusterID, inc.Namespace, inc.Rule, inc.Severity, inc.AlertCount, inc.FirstSeen, inc.LastSeen,
+		inc.Title, inc.Status, inc.Owner, inc.CreatedBy, timeline, evidence); err != nil {
+		return dbError(err, "incident")
+	}
+	for _, id := range alertIDs {
+		if _, err := tx.Exec("UPDATE alerts SET incident_id=$2 WHERE id=$1", id, inc.ID); err != nil {
+			return err
+		}
+	}
+	for id := range left {
+		if _, err := tx.Exec("UPDATE incidents SET alert_count=(SELECT count(*) FROM alerts WHERE incident_id=$1) WHERE id=$1", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateIncident applies a user's change to an incident. The row is locked so
+// concurrent changes are validated against the latest status.
+func (s *DatabaseStorage) UpdateIncident(id string, u models.IncidentUpdate) (models.Incident, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Incident{}, err
+	}
+	defer tx.Rollback()
+
+	inc, err := scanIncident(tx.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id=$1"+s.forUpdate(), id))
+	if err != nil {
+		return models.Incident{}, dbError(err, "incident")
+	}
+	if err := inc.Apply(u); err != nil {
+		return models.Incident{}, conflict(err)
+	}
+	timeline, _ := json.Marshal(inc.Timeline)
+	evidence, _ := json.Marshal(inc.EvidenceIDs)
+	var postMortem []byte
+	if inc.PostMortem != nil {
+		postMortem, _ = json.Marshal(inc.PostMortem)
+	}
+	if _, err := tx.Exec("UPDATE incidents SET severity=$2, status=$3, owner=$4, timeline=$5, evidence_ids=$6, post_mortem=$7, closed_at=$8 WHERE id=$1",
+		inc.ID, inc.Severity, inc.Status, inc.Owner, timeline, evidence, postMortem, inc.ClosedAt); err != nil {
+		return models.Incident{}, err
+	}
+	return inc, tx.Commit()
+}
+
+func scanIncident(row interface{ Scan(...interface{}) error }) (models.Incident, error) {
+	var inc models.Incident
+	var timeline, evidence, postMortem []byte
+	if err := row.Scan(&inc.ID, &inc.ClusterID, &inc.Namespace, &inc.Rule, &inc.Severity, &inc.AlertCount, &inc.FirstSeen, &inc.LastSeen,
+		&inc.Title, &inc.Status, &inc.Owner, &inc.CreatedBy, &timeline, &evidence, &postMortem, &inc.ClosedAt); err != nil {
+		return models.Incident{}, err
+	}
+	json.Unmarshal(timeline, &inc.Timeline)
+	json.Unmarshal(evidence, &inc.EvidenceIDs)
+	if len(postMortem) > 0 {
+		inc.PostMortem = new(models.PostMortem)
+		json.Unmarshal(postMortem, inc.PostMortem)
+	}
+	return inc, nil
+}
+
+func saveIncident(tx *sql.Tx, inc models.Incident) error {
+	_, err := tx.Exec(`INSERT INTO incidents (id, cluster_id, namespace, rule, severity, alert_count, first_seen, last_seen, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET severity=EXCLUDED.severity, alert_count=EXCLUDED.alert_count, last_seen=EXCLUDED.last_seen`,
+		inc.ID, inc.ClusterID, inc.Namespace, inc.Rule, inc.Severity, inc.AlertCount, inc.FirstSeen, inc.LastSeen, inc.Status)
+	return err
+}
+
+// Silence methods
+const silenceColumns = "id, COALESCE(cluster_id, ''), COALESCE(namespace, ''), COALESCE(severity, ''), COALESCE(rule, ''), starts_at, ends_at, created_by, comment, created_at"
+
+func (s *DatabaseStorage) AddSilence(sl models.Silence) error {
+	_, err := s.db.Exec("INSERT INTO silences (id, cluster_id, namespace, severity, rule, starts_at, ends_at, created_by, comment, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		sl.ID, sl.ClusterID, sl.Namespace, sl.Severity, sl.Rule, sl.StartsAt, sl.EndsAt, sl.CreatedBy, sl.Comment, sl.CreatedAt)
+	return dbError(err, "silence")
+}
+
+// GetSilences returns a page of silences; the status filter selects them by
+// their state now
+func (s *DatabaseStorage) GetSilences(ctx context.Context, opts ListOptions) (Page[models.Silence], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Silence]{}, err
+	}
+	if err := checkSilenceState(q.Status); err != nil {
+		return Page[models.Silence]{}, err
+	}
+	l := q.sql("starts_at", "id")
+	l.eq("cluster_id", q.ClusterID)
+	l.eq("severity", q.Severity)
+	l.eq("namespace", q.Namespace)
+	switch now := time.Now(); q.Status {
+	case models.SilencePending:
+		l.where("starts_at > ?", now)
+	case models.SilenceActive:
+		l.where("starts_at <= ? AND ends_at > ?", now, now)
+	case models.SilenceExpired:
+		l.where("ends_at <= ?", now)
+	}
+	l.contains("comment")
+	return listRows(ctx, s.db, "SELECT "+silenceColumns+" FROM silences", l,
+		func(rows *sql.Rows) (models.Silence, error) { return scanSilence(rows) }, silenceKey)
+}
+
+func (s *DatabaseStorage) GetSilence(id string) (models.Silence, error) {
+	sl, err := scanSilence(s.db.QueryRow("SELECT "+silenceColumns+" FROM silences WHERE id=$1", id))
+	return sl, dbError(err, "silence")
+}
+
+func (s *DatabaseStorage) UpdateSilence(sl models.Silence) error {
+	res, err := s.db.Exec("UPDATE silences SET cluster_id=$2, namespace=$3, severity=$4, rule=$5, starts_at=$6, ends_at=$7, comment=$8 WHERE id=$1",
+		sl.ID, sl.ClusterID, sl.Namespace, sl.Severity, sl.Rule, sl.StartsAt, sl.EndsAt, sl.Comment)
+	return affected(res, err, "silence")
+}
+
+func (s *DatabaseStorage) DeleteSilence(id string) error {
+	res, err := s.db.Exec("DELETE FROM silences WHERE id=$1", id)
+	return affected(res, err, "silence")
+}
+
+func scanSilence(row interface{ Scan(...interface{}) error }) (models.Silence, error) {
+	var sl models.Silence
+	err := row.Scan(&sl.ID, &sl.ClusterID, &sl.Namespace, &sl.Severity, &sl.Rule, &sl.StartsAt, &sl.EndsAt, &sl.CreatedBy, &sl.Comment, &sl.CreatedAt)
+	return sl, err
+}
+
+// Notification channel methods. The type specific settings are kept as JSON.
+func (s *DatabaseStorage) AddChannel(c models.Channel) error {
+	config, _ := json.Marshal(c)
+	_, err := s.db.Exec("INSERT INTO channels (id, name, type, config, created_at) VALUES ($1, $2, $3, $4, $5)",
+		c.ID, c.Name, c.Type, config, c.CreatedAt)
+	return dbError(err, "channel")
+}
+
+func (s *DatabaseStorage) GetChannels(ctx context.Context, opts ListOptions) (Page[models.Channel], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Channel]{}, err
+	}
+	l := q.sql("created_at", "id")
+	l.contains("name")
+	return listRows(ctx, s.db, "SELECT config FROM channels", l, scanConfig[models.Channel], channelKey)
+}
+
+func (s *DatabaseStorage) GetChannel(id string) (models.Channel, error) {
+	var config []byte
+	var c models.Channel
+	if err := s.db.QueryRow("SELECT config FROM channels WHERE id=$1", id).Scan(&config); err != nil {
+		return models.Channel{}, dbError(err, "channel")
+	}
+	err := json.Unmarshal(config, &c)
+	return c, err
+}
+
+func (s *DatabaseStorage) DeleteChannel(id string) error {
+	res, err := s.db.Exec("DELETE FROM channels WHERE id=$1", id)
+	return affected(res, err, "channel")
+}
+
+func (s *DatabaseStorage) AddDeadLetter(d models.DeadLetter) error {
+	_, err := s.db.Exec("INSERT INTO dead_letters (id, channel_id, alert_id, attempts, error, failed_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		d.ID, d.ChannelID, d.AlertID, d.Attempts, d.Error, d.FailedAt)
+	return dbError(err, "dead letter")
+}
+
+func (s *DatabaseStorage) GetDeadLetters(ctx context.Context, opts ListOptions) (Page[models.DeadLetter], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.DeadLetter]{}, err
+	}
+	l := q.sql("failed_at", "id")
+	l.eq("alert_id", q.AlertID)
+	l.contains("error")
+	return listRows(ctx, s.db, "SELECT id, channel_id, alert_id, attempts, error, failed_at FROM dead_letters", l,
+		func(rows *sql.Rows) (models.DeadLetter, error) {
+			var d models.DeadLetter
+			err := rows.Scan(&d.ID, &d.ChannelID, &d.AlertID, &d.Attempts, &d.Error, &d.FailedAt)
+			return d, err
+		}, deadLetterKey)
+}
+
+// On-call schedule methods. Rotations and overrides are kept as JSON.
+func (s *DatabaseStorage) AddSchedule(sc models.Schedule) error {
+	config, _ := json.Marshal(sc)
+	_, err := s.db.Exec("INSERT INTO schedules (id, name, config, created_at) VALUES ($1, $2, $3, $4)",
+		sc.ID, sc.Name, config, sc.CreatedAt)
+	return dbError(err, "schedule")
+}
+
+func (s *DatabaseStorage) GetSchedules(ctx context.Context, opts ListOptions) (Page[models.Schedule], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Schedule]{}, err
+	}
+	l := q.sql("created_at", "id")
+	l.contains("name")
+	return listRows(ctx, s.db, "SELECT config FROM schedules", l, scanConfig[models.Schedule], scheduleKey)
+}
+
+func (s *DatabaseStorage) GetSchedule(id string) (models.Schedule, error) {
+	var config []byte
+	var sc models.Schedule
+	if err := s.db.QueryRow("SELECT config FROM schedules WHERE id=$1", id).Scan(&config); err != nil {
+		return models.Schedule{}, dbError(err, "schedule")
+	}
+	err := json.Unmarshal(config, &sc)
+	return sc, err
+}
+
+func (s *DatabaseStorage) UpdateSchedule(sc models.Schedule) error {
+	config, _ := json.Marshal(sc)
+	res, err := s.db.Exec("UPDATE schedules SET name=$2, config=$3 WHERE id=$1", sc.ID, sc.Name, config)
+	return affected(res, err, "schedule")
+}
+
+func (s *DatabaseStorage) DeleteSchedule(id string) error {
+	res, err := s.db.Exec("DELETE FROM schedules WHERE id=$1", id)
+	return affected(res, err, "schedule")
+}
+
+// Escalation methods
+func (s *DatabaseStorage) AddEscalationPolicy(p models.EscalationPolicy) error {
+	config, _ := json.Marshal(p)
+	_, err := s.db.Exec("INSERT INTO escalation_policies (id, name, config, created_at) VALUES ($1, $2, $3, $4)",
+		p.ID, p.Name, config, p.CreatedAt)
+	return dbError(err, "escalation policy")
+}
+
+func (s *DatabaseStorage) GetEscalationPolicies() []models.EscalationPolicy {
+	rows, err := s.db.Query("SELECT config FROM escalation_policies ORDER BY created_at")
 	if err != nil {
 		return nil
 	}
 	defer rows.Close()
 
-	var policies []models.Policy
+	var policies []models.EscalationPolicy
 	for rows.Next() {
-		var p models.Policy
-		var rules []byte
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &rules, &p.Namespace, &p.CreatedAt); err != nil {
+		var config []byte
+		var p models.EscalationPolicy
+		if err := rows.Scan(&config); err != nil || json.Unmarshal(config, &p) != nil {
 			continue
 		}
-		json.Unmarshal(rules, &p.Rules)
 		policies = append(policies, p)
 	}
 	return policies
 }
 
-func (s *DatabaseStorage) GetPolicy(id string) (models.Policy, error) {
-	var p models.Policy
-	var rules []byte
-	err := s.db.QueryRow("SELECT id, name, description, rules, namespace, created_at FROM policies WHERE id = $1", id).
-		Scan(&p.ID, &p.Name, &p.Description, &rules, &p.Namespace, &p.CreatedAt)
+func (s *DatabaseStorage) GetEscalationPolicy(id string) (models.EscalationPolicy, error) {
+	var config []byte
+	var p models.EscalationPolicy
+	if err := s.db.QueryRow("SELECT config FROM escalation_policies WHERE id=$1", id).Scan(&config); err != nil {
+		return models.EscalationPolicy{}, dbError(err, "escalation policy")
+	}
+	err := json.Unmarshal(config, &p)
+	return p, err
+}
+
+func (s *DatabaseStorage) DeleteEscalationPolicy(id string) error {
+	res, err := s.db.Exec("DELETE FROM escalation_policies WHERE id=$1", id)
+	return affected(res, err, "escalation policy")
+}
+
+func (s *DatabaseStorage) SaveEscalation(e models.Escalation) error {
+	_, err := s.db.Exec(`INSERT INTO escalations (alert_id, policy_id, next_tier, due_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (alert_id) DO UPDATE SET policy_id=EXCLUDED.policy_id, next_tier=EXCLUDED.next_tier, due_at=EXCLUDED.due_at`,
+		e.AlertID, e.PolicyID, e.NextTier, e.DueAt)
+	return err
+}
+
+// AdvanceEscalation stores an escalation moved on to e.NextTier only while it
+// still waits for the tier before, so that an escalation deleted when its
+// alert was acknowledged is not brought back and a tier is paged once
+func (s *DatabaseStorage) AdvanceEscalation(e models.Escalation) error {
+	res, err := s.db.Exec("UPDATE escalations SET next_tier=$2, due_at=$3 WHERE alert_id=$1 AND policy_id=$4 AND next_tier=$5",
+		e.AlertID, e.NextTier, e.DueAt, e.PolicyID, e.NextTier-1)
+	if err := affected(res, err, "escalation"); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM escalations WHERE alert_id=$1", e.AlertID).Scan(&n); err != nil {
+		return dbError(err, "escalation")
+	}
+	if n == 0 {
+		return notFound("escalation")
+	}
+	return changed("escalation")
+}
+
+func (s *DatabaseStorage) GetEscalations() []models.Escalation {
+	rows, err := s.db.Query("SELECT alert_id, policy_id, next_tier, due_at FROM escalations ORDER BY due_at")
 	if err != nil {
-		return models.Policy{}, err
+		return nil
 	}
-	json.Unmarshal(rules, &p.Rules)
-	return p, nil
+	defer rows.Close()
+
+	var escalations []models.Escalation
+	for rows.Next() {
+		var e models.Escalation
+		if err := rows.Scan(&e.AlertID, &e.PolicyID, &e.NextTier, &e.DueAt); err != nil {
+			continue
+		}
+		escalations = append(escalations, e)
+	}
+	return escalations
 }
 
-func (s *DatabaseStorage) DeletePolicy(id string) error {
-	_, err := s.db.Exec("DELETE FROM policies WHERE id=$1", id)
+func (s *DatabaseStorage) DeleteEscalation(alertID string) error {
+	_, err := s.db.Exec("DELETE FROM escalations WHERE alert_id=$1", alertID)
 	return err
 }
 
-// Alert and Report methods
-func (s *DatabaseStorage) AddAlert(a models.Alert) {
-	s.db.Exec("INSERT INTO alerts (id, cluster_id, severity, message, timestamp) VALUES ($1, $2, $3, $4, $5)",
-		a.ID, a.ClusterID, a.Severity, a.Message, a.Timestamp)
+const insertReport = "INSERT INTO reports (id, alert_id, action_id, evidence_id, details, action_taken, result, before_state, after_state, timestamp) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+
+func (s *DatabaseStorage) AddReport(r models.IncidentReport) error {
+	_, err := s.db.Exec(insertReport,
+		r.ID, r.AlertID, r.ActionID, r.EvidenceID, r.Details, r.Action, r.Result, r.Before, r.After, r.Timestamp)
+	return dbError(err, "report")
 }
 
-func (s *DatabaseStorage) GetAlerts() []models.Alert {
-	rows, err := s.db.Query("SELECT id, cluster_id, severity, message, timestamp FROM alerts ORDER BY timestamp DESC")
+func (s *DatabaseStorage) GetReports(ctx context.Context, opts ListOptions) (Page[models.IncidentReport], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.IncidentReport]{}, err
+	}
+	l := q.sql("timestamp", "id")
+	l.contains("details", "action_taken", "result")
+	return listRows(ctx, s.db, "SELECT id, alert_id, COALESCE(action_id, ''), COALESCE(evidence_id, ''), details, action_taken, COALESCE(result, ''), COALESCE(before_state, ''), COALESCE(after_state, ''), timestamp FROM reports", l,
+		func(rows *sql.Rows) (models.IncidentReport, error) {
+			var r models.IncidentReport
+			err := rows.Scan(&r.ID, &r.AlertID, &r.ActionID, &r.EvidenceID, &r.Details, &r.Action, &r.Result, &r.Before, &r.After, &r.Timestamp)
+			return r, err
+		}, reportKey)
+}
+
+// DeleteReports deletes the reports with the given IDs and returns how many
+// it found
+func (s *DatabaseStorage) DeleteReports(ctx context.Context, ids []string) (int, error) {
+	return s.deleteIDs(ctx, "reports", ids)
+}
+
+// RestoreReports stores archived reports as they were, skipping IDs that are
+// taken, and returns how many it stored
+func (s *DatabaseStorage) RestoreReports(ctx context.Context, reports []models.IncidentReport) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n := 0
+	for _, r := range reports {
+		res, err := tx.ExecContext(ctx, insertReport+" ON CONFLICT (id) DO NOTHING",
+			r.ID, r.AlertID, r.ActionID, r.EvidenceID, r.Details, r.Action, r.Result, r.Before, r.After, r.Timestamp)
+		if err != nil {
+			return 0, err
+		}
+		if stored, _ := res.RowsAffected(); stored > 0 {
+			n++
+		}
+	}
+	return n, tx.Commit()
+}
+
+// Response action methods. Targets and outcomes are kept as JSON.
+func (s *DatabaseStorage) AddResponseAction(a models.ResponseAction) error {
+	config, _ := json.Marshal(a)
+	_, err := s.db.Exec("INSERT INTO response_actions (id, cluster_id, status, config, created_at) VALUES ($1, $2, $3, $4, $5)",
+		a.ID, a.ClusterID, a.Status, config, a.CreatedAt)
+	return dbError(err, "response action")
+}
+
+func (s *DatabaseStorage) GetResponseActions(ctx context.Context, opts ListOptions) (Page[models.ResponseAction], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.ResponseAction]{}, err
+	}
+	l := q.sql("created_at", "id")
+	l.eq("cluster_id", q.ClusterID)
+	l.eq("status", q.Status)
+	return listRows(ctx, s.db, "SELECT config FROM response_actions", l, scanConfig[models.ResponseAction], actionKey)
+}
+
+func (s *DatabaseStorage) GetResponseAction(id string) (models.ResponseAction, error) {
+	var config []byte
+	var a models.ResponseAction
+	if err := s.db.QueryRow("SELECT config FROM response_actions WHERE id=$1", id).Scan(&config); err != nil {
+		return models.ResponseAction{}, dbError(err, "response action")
+	}
+	err := json.Unmarshal(config, &a)
+	return a, err
+}
+
+func (s *DatabaseStorage) UpdateResponseAction(a models.ResponseAction) error {
+	config, _ := json.Marshal(a)
+	res, err := s.db.Exec("UPDATE response_actions SET status=$2, config=$3 WHERE id=$1", a.ID, a.Status, config)
+	return affected(res, err, "response action")
+}
+
+// DecideResponseAction stores a decision on an action only while it is still
+// pending, so that concurrent decisions cannot both take effect
+func (s *DatabaseStorage) DecideResponseAction(a models.ResponseAction) error {
+	config, _ := json.Marshal(a)
+	res, err := s.db.Exec("UPDATE response_actions SET status=$2, config=$3 WHERE id=$1 AND status=$4",
+		a.ID, a.Status, config, models.ActionPending)
+	if err := affected(res, err, "response action"); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, err := s.GetResponseAction(a.ID); err != nil {
+		return err
+	}
+	return changed("response action")
+}
+
+// Playbook methods. Steps and run timelines are kept as JSON.
+func (s *DatabaseStorage) AddPlaybook(pb models.Playbook) error {
+	config, _ := json.Marshal(pb)
+	_, err := s.db.Exec("INSERT INTO playbooks (id, name, config, created_at) VALUES ($1, $2, $3, $4)",
+		pb.ID, pb.Name, config, pb.CreatedAt)
+	return dbError(err, "playbook")
+}
+
+func (s *DatabaseStorage) GetPlaybooks(ctx context.Context, opts ListOptions) (Page[models.Playbook], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Playbook]{}, err
+	}
+	l := q.sql("created_at", "id")
+	l.contains("name")
+	return listRows(ctx, s.db, "SELECT config FROM playbooks", l, scanConfig[models.Playbook], playbookKey)
+}
+
+func (s *DatabaseStorage) GetPlaybook(id string) (models.Playbook, error) {
+	var config []byte
+	var pb models.Playbook
+	if err := s.db.QueryRow("SELECT config FROM playbooks WHERE id=$1", id).Scan(&config); err != nil {
+		return models.Playbook{}, dbError(err, "playbook")
+	}
+	err := json.Unmarshal(config, &pb)
+	return pb, err
+}
+
+func (s *DatabaseStorage) UpdatePlaybook(pb models.Playbook) error {
+	config, _ := json.Marshal(pb)
+	res, err := s.db.Exec("UPDATE playbooks SET name=$2, config=$3 WHERE id=$1", pb.ID, pb.Name, config)
+	return affected(res, err, "playbook")
+}
+
+func (s *DatabaseStorage) DeletePlaybook(id string) error {
+	res, err := s.db.Exec("DELETE FROM playbooks WHERE id=$1", id)
+	return affected(res, err, "playbook")
+}
+
+func (s *DatabaseStorage) AddPlaybookRun(run models.PlaybookRun) error {
+	config, _ := json.Marshal(run)
+	_, err := s.db.Exec("INSERT INTO playbook_runs (id, playbook_id, status, config, started_at) VALUES ($1, $2, $3, $4, $5)",
+		run.ID, run.PlaybookID, run.Status, config, run.StartedAt)
+	return dbError(err, "playbook run")
+}
+
+func (s *DatabaseStorage) UpdatePlaybookRun(run models.PlaybookRun) error {
+	config, _ := json.Marshal(run)
+	res, err := s.db.Exec("UPDATE playbook_runs SET status=$2, config=$3 WHERE id=$1", run.ID, run.Status, config)
+	return affected(res, err, "playbook run")
+}
+
+// GetPlaybookRuns returns the runs of a playbook, newest first
+func (s *DatabaseStorage) GetPlaybookRuns(playbookID string) []models.PlaybookRun {
+	rows, err := s.db.Query("SELECT config FROM playbook_runs WHERE playbook_id=$1 ORDER BY started_at DESC", playbookID)
 	if err != nil {
 		return nil
 	}
 	defer rows.Close()
 
-	var alerts []models.Alert
+	var runs []models.PlaybookRun
 	for rows.Next() {
-		var a models.Alert
-		if err := rows.Scan(&a.ID, &a.ClusterID, &a.Severity, &a.Message, &a.Timestamp); err != nil {
+		var config []byte
+		var run models.PlaybookRun
+		if err := rows.Scan(&config); err != nil || json.Unmarshal(config, &run) != nil {
 			continue
 		}
-		alerts = append(alerts, a)
+		runs = append(runs, run)
 	}
-	return alerts
+	return runs
 }
 
-func (s *DatabaseStorage) AddReport(r models.IncidentReport) {
-	s.db.Exec("INSERT INTO reports (id, alert_id, details, action_taken, timestamp) VALUES ($1, $2, $3, $4, $5)",
-		r.ID, r.AlertID, r.Details, r.Action, r.Timestamp)
+func (s *DatabaseStorage) GetPlaybookRun(id string) (models.PlaybookRun, error) {
+	var config []byte
+	var run models.PlaybookRun
+	if err := s.db.QueryRow("SELECT config FROM playbook_runs WHERE id=$1", id).Scan(&config); err != nil {
+		return models.PlaybookRun{}, dbError(err, "playbook run")
+	}
+	err := json.Unmarshal(config, &run)
+	return run, err
 }
 
-func (s *DatabaseStorage) GetReports() []models.IncidentReport {
-	rows, err := s.db.Query("SELECT id, alert_id, details, action_taken, timestamp FROM reports ORDER BY timestamp DESC")
+// Evidence methods. File contents are stored once per SHA-256 in
+// evidence_blobs; bundles keep their manifest as JSON.
+func (s *DatabaseStorage) SaveEvidence(b models.EvidenceBundle, blobs map[string][]byte) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for sum, data := range blobs {
+		if _, err := tx.Exec("INSERT INTO evidence_blobs (sha256, data) VALUES ($1, $2) ON CONFLICT (sha256) DO NOTHING", sum, data); err != nil {
+			return err
+		}
+	}
+	config, _ := json.Marshal(b)
+	if _, err := tx.Exec("INSERT INTO evidence_bundles (id, alert_id, config, collected_at) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING",
+		b.ID, b.AlertID, config, b.CollectedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseStorage) GetEvidenceBundles(ctx context.Context, opts ListOptions) (Page[models.EvidenceBundle], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.EvidenceBundle]{}, err
+	}
+	l := q.sql("collected_at", "id")
+	l.eq("alert_id", q.AlertID)
+	return listRows(ctx, s.db, "SELECT config FROM evidence_bundles", l, scanConfig[models.EvidenceBundle], evidenceKey)
+}
+
+func (s *DatabaseStorage) GetEvidenceBundle(id string) (models.EvidenceBundle, error) {
+	var config []byte
+	var b models.EvidenceBundle
+	if err := s.db.QueryRow("SELECT config FROM evidence_bundles WHERE id=$1", id).Scan(&config); err != nil {
+		return models.EvidenceBundle{}, dbError(err, "evidence bundle")
+	}
+	err := json.Unmarshal(config, &b)
+	return b, err
+}
+
+func (s *DatabaseStorage) GetEvidenceBlob(sha256 string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT data FROM evidence_blobs WHERE sha256=$1", sha256).Scan(&data)
+	return data, dbError(err, "evidence blob")
+}
+
+// Pod Security Standards methods
+func (s *DatabaseStorage) SavePSSFindings(clusterID string, findings []models.PSSFinding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM pss_findings WHERE cluster_id=$1", clusterID); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		violations, _ := json.Marshal(f.Violations)
+		if _, err := tx.Exec("INSERT INTO pss_findings (cluster_id, kind, namespace, name, level, violations, scanned_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			clusterID, f.Kind, f.Namespace, f.Name, f.Level, violations, f.ScannedAt); err != nil {
+			return err
+		}
+	}
+	if err := recordScan(tx, clusterID, models.ScanPSS); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseStorage) GetPSSFindings(clusterID string) []models.PSSFinding {
+	rows, err := s.db.Query("SELECT cluster_id, kind, namespace, name, level, violations, scanned_at FROM pss_findings WHERE cluster_id=$1 ORDER BY namespace, kind, name", clusterID)
 	if err != nil {
 		return nil
 	}
 	defer rows.Close()
 
-	var reports []models.IncidentReport
+	var findings []models.PSSFinding
 	for rows.Next() {
-		var r models.IncidentReport
-		if err := rows.Scan(&r.ID, &r.AlertID, &r.Details, &r.Action, &r.Timestamp); err != nil {
+		var f models.PSSFinding
+		var violations []byte
+		if err := rows.Scan(&f.ClusterID, &f.Kind, &f.Namespace, &f.Name, &f.Level, &violations, &f.ScannedAt); err != nil {
 			continue
 		}
-		reports = append(reports, r)
+		json.Unmarshal(violations, &f.Violations)
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// Image finding methods
+func (s *DatabaseStorage) SaveImageFindings(clusterID string, findings []models.ImageFinding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM image_findings WHERE cluster_id=$1", clusterID); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		if _, err := tx.Exec("INSERT INTO image_findings (cluster_id, policy_id, namespace, pod, container, image, check_id, severity, message, detected_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			clusterID, f.PolicyID, f.Namespace, f.Pod, f.Container, f.Image, f.Check, f.Severity, f.Message, f.DetectedAt); err != nil {
+			return err
+		}
+	}
+	if err := recordScan(tx, clusterID, models.ScanImages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseStorage) GetImageFindings(clusterID string) []models.ImageFinding {
+	rows, err := s.db.Query("SELECT cluster_id, policy_id, namespace, pod, container, image, check_id, severity, message, detected_at FROM image_findings WHERE cluster_id=$1 ORDER BY namespace, pod, container", clusterID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var findings []models.ImageFinding
+	for rows.Next() {
+		var f models.ImageFinding
+		if err := rows.Scan(&f.ClusterID, &f.PolicyID, &f.Namespace, &f.Pod, &f.Container, &f.Image, &f.Check, &f.Severity, &f.Message, &f.DetectedAt); err != nil {
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// Secret audit methods
+func (s *DatabaseStorage) SaveSecretFindings(clusterID string, findings []models.SecretFinding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM secret_findings WHERE cluster_id=$1", clusterID); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		if _, err := tx.Exec("INSERT INTO secret_findings (cluster_id, check_id, severity, kind, namespace, name, reference, message, detected_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+			clusterID, f.Check, f.Severity, f.Kind, f.Namespace, f.Name, f.Reference, f.Message, f.DetectedAt); err != nil {
+			return err
+		}
+	}
+	if err := recordScan(tx, clusterID, models.ScanSecrets); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseStorage) GetSecretFindings(clusterID string) []models.SecretFinding {
+	rows, err := s.db.Query("SELECT cluster_id, check_id, severity, kind, namespace, name, reference, message, detected_at FROM secret_findings WHERE cluster_id=$1 ORDER BY namespace, kind, name", clusterID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var findings []models.SecretFinding
+	for rows.Next() {
+		var f models.SecretFinding
+		if err := rows.Scan(&f.ClusterID, &f.Check, &f.Severity, &f.Kind, &f.Namespace, &f.Name, &f.Reference, &f.Message, &f.DetectedAt); err != nil {
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// recordScan notes, with the findings it saves, when a cluster was last
+// scanned for a kind of findings
+func recordScan(tx *sql.Tx, clusterID, kind string) error {
+	_, err := tx.Exec(`INSERT INTO scans (cluster_id, kind, scanned_at) VALUES ($1, $2, $3)
+		ON CONFLICT (cluster_id, kind) DO UPDATE SET scanned_at=EXCLUDED.scanned_at`, clusterID, kind, time.Now())
+	return err
+}
+
+func (s *DatabaseStorage) LastScan(clusterID, kind string) (time.Time, error) {
+	var t time.Time
+	err := s.db.QueryRow("SELECT scanned_at FROM scans WHERE cluster_id=$1 AND kind=$2", clusterID, kind).Scan(&t)
+	return t, dbError(err, "scan")
+}
+
+// DeleteScans deletes the findings of every kind of a cluster and the record
+// of its scans in one transaction
+func (s *DatabaseStorage) DeleteScans(clusterID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"pss_findings", "image_findings", "secret_findings", "scans"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE cluster_id=$1", clusterID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CIS benchmark methods
+
+// AddCISRun stores a run under the next version number of its cluster. An
+// advisory lock on the cluster keeps concurrent runs from taking the same one.
+func (s *DatabaseStorage) AddCISRun(run models.CISRun) (models.CISRun, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.CISRun{}, err
+	}
+	defer tx.Rollback()
+
+	if err := s.lock(tx, "cis:"+run.ClusterID); err != nil {
+		return models.CISRun{}, err
+	}
+	controls, _ := json.Marshal(run.Controls)
+	err = tx.QueryRow(`INSERT INTO cis_runs (id, cluster_id, version, benchmark, controls, started_at)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5 FROM cis_runs WHERE cluster_id=$2
+		RETURNING version`,
+		run.ID, run.ClusterID, run.Benchmark, controls, run.StartedAt).Scan(&run.Version)
+	if err != nil {
+		return models.CISRun{}, err
+	}
+	return run, tx.Commit()
+}
+
+func (s *DatabaseStorage) GetCISRuns(clusterID string) []models.CISRun {
+	rows, err := s.db.Query("SELECT id, cluster_id, version, benchmark, controls, started_at FROM cis_runs WHERE cluster_id=$1 ORDER BY version", clusterID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var runs []models.CISRun
+	for rows.Next() {
+		var run models.CISRun
+		var controls []byte
+		if err := rows.Scan(&run.ID, &run.ClusterID, &run.Version, &run.Benchmark, &controls, &run.StartedAt); err != nil {
+			continue
+		}
+		json.Unmarshal(controls, &run.Controls)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func (s *DatabaseStorage) GetCISRun(clusterID string, version int) (models.CISRun, error) {
+	var run models.CISRun
+	var controls []byte
+	err := s.db.QueryRow("SELECT id, cluster_id, version, benchmark, controls, started_at FROM cis_runs WHERE cluster_id=$1 AND version=$2", clusterID, version).
+		Scan(&run.ID, &run.ClusterID, &run.Version, &run.Benchmark, &controls, &run.StartedAt)
+	if err != nil {
+		return models.CISRun{}, dbError(err, "benchmark run")
+	}
+	json.Unmarshal(controls, &run.Controls)
+	return run, nil
+}
+
+func (s *DatabaseStorage) DeleteCISRuns(clusterID string) error {
+	_, err := s.db.Exec("DELETE FROM cis_runs WHERE cluster_id=$1", clusterID)
+	return err
+}
+
+// deleteBatch is how many rows deleteIDs deletes per statement, well under
+// the bind parameter limits of PostgreSQL and SQLite
+const deleteBatch = 500
+
+// deleteIDs deletes the rows of a table with the given IDs and returns how
+// many it found
+func (s *DatabaseStorage) deleteIDs(ctx context.Context, table string, ids []string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n := 0
+	for batch := range slices.Chunk(ids, deleteBatch) {
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			placeholders[i] = "$" + strconv.Itoa(i+1)
+			args[i] = id
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE id IN ("+strings.Join(placeholders, ", ")+")", args...)
+		if err != nil {
+			return 0, err
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		n += int(deleted)
+	}
+	return n, tx.Commit()
+}
+
+// listRows runs a list query and reads a page of its rows
+func listRows[T any](ctx context.Context, db *sql.DB, query string, l *sqlList, scan func(*sql.Rows) (T, error), key func(T) (time.Time, string)) (Page[T], error) {
+	rows, err := db.QueryContext(ctx, query+l.String(), l.args...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[T]{}, err
+	}
+	return paginate(l.q, items, key), nil
+}
+
+// scanConfig reads a record kept as JSON in a config column
+func scanConfig[T any](rows *sql.Rows) (T, error) {
+	var v T
+	var config []byte
+	if err := rows.Scan(&config); err != nil {
+		return v, err
 	}
-	return reports
+	return v, json.Unmarshal(config, &v)
 }
 
 func getEnv(key, fallback string) string {
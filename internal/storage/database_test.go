@@ -0,0 +1,39 @@
+package storage_test
+
+import (
+	"os"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+	"KubernetesSecurityMonitoringSystem/internal/storage/storagetest"
+)
+
+// TestDatabaseStorage runs the suite against the PostgreSQL database
+// configured by the DB_* variables. It empties every table, so it only runs
+// when KSMS_TEST_POSTGRES is set.
+func TestDatabaseStorage(t *testing.T) {
+	if os.Getenv("KSMS_TEST_POSTGRES") == "" {
+		t.Skip("set KSMS_TEST_POSTGRES to run against PostgreSQL")
+	}
+	t.Setenv("DB_DRIVER", "postgres")
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		db, err := storage.OpenDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if _, err := db.Exec(`DO $$ DECLARE t text; BEGIN
+			FOR t IN SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations' LOOP
+				EXECUTE 'TRUNCATE TABLE ' || quote_ident(t) || ' CASCADE';
+			END LOOP; END $$`); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := storage.NewDatabaseStorage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
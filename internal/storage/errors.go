@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Errors every Storage reports, wrapped with what was missing or clashed, so
+// callers can tell them apart with errors.Is
+var (
+	// ErrNotFound is returned when the record to read, change or delete does
+	// not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a record with the same ID or unique value
+	// already exists, or a change is not allowed in the record's current state
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidQuery is returned for list options that cannot be used, such
+	// as a malformed cursor
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// storageError keeps the message of the sentinel it wraps out of its own, so
+// an ErrConflict still reads e.g. "user already exists"
+type storageError struct {
+	msg  string
+	kind error
+}
+
+func (e *storageError) Error() string { return e.msg }
+func (e *storageError) Unwrap() error { return e.kind }
+
+// notFound reports a missing record, e.g. notFound("user")
+func notFound(what string) error {
+	return &storageError{msg: what + " not found", kind: ErrNotFound}
+}
+
+// exists reports a record whose ID or unique value is taken
+func exists(what string) error {
+	return &storageError{msg: what + " already exists", kind: ErrConflict}
+}
+
+// conflict reports a change refused in the record's current state, such as an
+// invalid status transition
+func conflict(err error) error {
+	return &storageError{msg: err.Error(), kind: ErrConflict}
+}
+
+// changed reports a conditional update refused because the record is no
+// longer in the state it was read in, e.g. changed("response action")
+func changed(what string) error {
+	return &storageError{msg: what + " changed since it was read", kind: ErrConflict}
+}
+
+// invalid reports an unusable list option, e.g. invalid("cursor")
+func invalid(what string) error {
+	return &storageError{msg: "invalid " + what, kind: ErrInvalidQuery}
+}
+
+// dbError translates the database errors that have a sentinel: no rows and
+// unique constraint violations. Other errors are returned as they are.
+func dbError(err error, what string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return notFound(what)
+	case isUniqueViolation(err):
+		return exists(what)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return isSQLiteUniqueViolation(err)
+}
+
+// affected turns an update or delete that matched no row into notFound
+func affected(res sql.Result, err error, what string) error {
+	if err != nil {
+		return dbError(err, what)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound(what)
+	}
+	return nil
+}
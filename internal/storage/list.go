@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Order is the direction a list is sorted in by its time field
+type Order string
+
+const (
+	Newest Order = "desc"
+	Oldest Order = "asc"
+)
+
+// Page sizes of list queries
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// ListOptions narrows, sorts and pages a list. Lists ignore the filters they
+// have no field for: alerts take the time range, cluster, severity,
+// namespace, incident and text; policies the time range, severity, namespace
+// and text; incidents the time range, cluster, severity, namespace, status,
+// owner and text; silences the time range, cluster, severity, namespace,
+// status and text; response actions the time range, cluster and status;
+// evidence bundles the time range and alert; dead letters the time range,
+// alert and text; and reports, users, clusters, channels, schedules and
+// playbooks the time range and text.
+type ListOptions struct {
+	Since      time.Time // only records at or after Since
+	Until      time.Time // only records before Until
+	ClusterID  string
+	Severity   string
+	Namespace  string
+	IncidentID string
+	AlertID    string
+	Status     string // for silences their state at the time of the query
+	Owner      string
+	Text       string // case-insensitive substring of the list's text fields
+	Order      Order  // Newest or Oldest; empty uses the list's own order
+	Cursor     string // NextCursor of the previous page
+	Limit      int    // page size; DefaultLimit when zero, at most MaxLimit
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// All collects every page of a list. It is meant for small lists that are
+// processed as a whole, such as the clusters or policies.
+func All[T any](ctx context.Context, list func(context.Context, ListOptions) (Page[T], error), opts ListOptions) ([]T, error) {
+	var all []T
+	opts.Limit = MaxLimit
+	for {
+		page, err := list(ctx, opts)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page.Items...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// listQuery is ListOptions checked and resolved for one list
+type listQuery struct {
+	ListOptions
+	after    bool // a cursor was given; only records past cursorAt and cursorID
+	cursorAt time.Time
+	cursorID string
+}
+
+func (o ListOptions) resolve(order Order) (listQuery, error) {
+	q := listQuery{ListOptions: o}
+	switch q.Order {
+	case "":
+		q.Order = order
+	case Newest, Oldest:
+	default:
+		return q, invalid("order " + string(q.Order))
+	}
+	if q.Limit < 0 {
+		return q, invalid("limit")
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	q.Limit = min(q.Limit, MaxLimit)
+	q.Text = strings.ToLower(q.Text)
+	if q.Cursor != "" {
+		var err error
+		if q.cursorAt, q.cursorID, err = decodeCursor(q.Cursor); err != nil {
+			return q, invalid("cursor")
+		}
+		q.after = true
+	}
+	return q, nil
+}
+
+// sorted reports whether a record at t with id comes before one at u with
+// other in the query's order. Records at the same time are ordered by ID so
+// that pages never skip or repeat them.
+func (q listQuery) sorted(t time.Time, id string, u time.Time, other string) bool {
+	if q.Order == Oldest {
+		return t.Before(u) || t.Equal(u) && id < other
+	}
+	return t.After(u) || t.Equal(u) && id > other
+}
+
+// matches applies the time range and cursor to a record at t with id
+func (q listQuery) matches(t time.Time, id string) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return !q.after || q.sorted(q.cursorAt, q.cursorID, t, id)
+}
+
+// eq applies an equality filter, which is off when empty
+func eq(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+// contains reports whether any of fields contains the query's text
+func (q listQuery) contains(fields ...string) bool {
+	if q.Text == "" {
+		return true
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), q.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate cuts a sorted list that may hold one record more than the limit
+// into a page, with a cursor after its last record when there is more
+func paginate[T any](q listQuery, items []T, key func(T) (time.Time, string)) Page[T] {
+	if len(items) <= q.Limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:q.Limit]
+	return Page[T]{Items: items, NextCursor: encodeCursor(key(items[len(items)-1]))}
+}
+
+func encodeCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + " " + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	at, id, _ := strings.Cut(string(b), " ")
+	t, err := time.Parse(time.RFC3339Nano, at)
+	return t, id, err
+}
+
+// sqlList renders a list query as the WHERE, ORDER BY and LIMIT clauses of a
+// statement over a table with the given time and ID columns
+type sqlList struct {
+	q       listQuery
+	timeCol string
+	idCol   string
+	conds   []string
+	args    []interface{}
+}
+
+func (q listQuery) sql(timeCol, idCol string) *sqlList {
+	l := &sqlList{q: q, timeCol: timeCol, idCol: idCol}
+	if !q.Since.IsZero() {
+		l.where(timeCol+" >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		l.where(timeCol+" < ?", q.Until)
+	}
+	if q.after {
+		cmp := "<"
+		if q.Order == Oldest {
+			cmp = ">"
+		}
+		l.where(fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", timeCol, idCol, cmp), q.cursorAt, q.cursorAt, q.cursorID)
+	}
+	return l
+}
+
+// where adds a condition whose ? placeholders take args in turn
+func (l *sqlList) where(cond string, args ...interface{}) {
+	var b strings.Builder
+	for _, part := range strings.SplitAfter(cond, "?") {
+		if strings.HasSuffix(part, "?") {
+			l.args = append(l.args, args[0])
+			args = args[1:]
+			part = strings.TrimSuffix(part, "?") + "$" + strconv.Itoa(len(l.args))
+		}
+		b.WriteString(part)
+	}
+	l.conds = append(l.conds, b.String())
+}
+
+// eq adds an equality filter on a column unless it is off
+func (l *sqlList) eq(col, filter string) {
+	if filter != "" {
+		l.where(col+" = ?", filter)
+	}
+}
+
+// contains adds the text filter over the given columns
+func (l *sqlList) contains(cols ...string) {
+	if l.q.Text == "" {
+		return
+	}
+	pattern := "%" + likeEscaper.Replace(l.q.Text) + "%"
+	var ors []string
+	args := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		ors = append(ors, "LOWER(COALESCE("+col+", '')) LIKE ? ESCAPE '\\'")
+		args = append(args, pattern)
+	}
+	l.where("("+strings.Join(ors, " OR ")+")", args...)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// String returns the clauses, fetching one row more than the page so that
+// paginate knows whether there is a next one
+func (l *sqlList) String() string {
+	var b strings.Builder
+	if len(l.conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(l.conds, " AND "))
+	}
+	dir := "DESC"
+	if l.q.Order == Oldest {
+		dir = "ASC"
+	}
+	fmt.Fprintf(&b, " ORDER BY %[1]s %[3]s, %[2]s %[3]s LIMIT %[4]d", l.timeCol, l.idCol, dir, l.q.Limit+1)
+	return b.String()
+}
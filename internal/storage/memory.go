@@ -1,8 +1,13 @@
 package storage
 
 import (
-	"errors"
+	"context"
+	"iter"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"KubernetesSecurityMonitoringSystem/internal/models"
 )
@@ -11,42 +16,157 @@ type Storage interface {
 	AddUser(u models.User) error
 	GetUser(id string) (models.User, error)
 	GetUserByEmail(email string) (models.User, error)
-	GetAllUsers() []models.User
+	GetAllUsers(ctx context.Context, opts ListOptions) (Page[models.User], error)
 	UpdateUser(u models.User) error
 	DeleteUser(id string) error
 
 	AddCluster(c models.Cluster) error
-	GetClusters() []models.Cluster
+	GetClusters(ctx context.Context, opts ListOptions) (Page[models.Cluster], error)
 	GetCluster(id string) (models.Cluster, error)
+	SetAuditToken(clusterID, token string) error
+	SetKubeConfig(clusterID, kubeConfig string) error
 	DeleteCluster(id string) error
 
 	AddPolicy(p models.Policy) error
-	GetPolicies() []models.Policy
+	GetPolicies(ctx context.Context, opts ListOptions) (Page[models.Policy], error)
 	GetPolicy(id string) (models.Policy, error)
 	DeletePolicy(id string) error
 
-	AddAlert(a models.Alert)
-	GetAlerts() []models.Alert
-	AddReport(r models.IncidentReport)
-	GetReports() []models.IncidentReport
+	AddAlert(a models.Alert) error
+	UpsertAlert(a models.Alert) (models.Alert, bool, error)
+	GetAlerts(ctx context.Context, opts ListOptions) (Page[models.Alert], error)
+	GetAlert(id string) (models.Alert, error)
+	TransitionAlert(id string, t models.AlertTransition) (models.Alert, error)
+	DeleteAlerts(ctx context.Context, ids []string) (int, error)
+	RestoreAlerts(ctx context.Context, alerts []models.Alert) (int, error)
+	GetIncidents(ctx context.Context, opts ListOptions) (Page[models.Incident], error)
+	GetIncident(id string) (models.Incident, error)
+	CreateIncident(inc models.Incident, alertIDs []string) error
+	UpdateIncident(id string, u models.IncidentUpdate) (models.Incident, error)
+
+	AddSilence(sl models.Silence) error
+	GetSilences(ctx context.Context, opts ListOptions) (Page[models.Silence], error)
+	GetSilence(id string) (models.Silence, error)
+	UpdateSilence(sl models.Silence) error
+	DeleteSilence(id string) error
+
+	AddChannel(c models.Channel) error
+	GetChannels(ctx context.Context, opts ListOptions) (Page[models.Channel], error)
+	GetChannel(id string) (models.Channel, error)
+	DeleteChannel(id string) error
+	AddDeadLetter(d models.DeadLetter) error
+	GetDeadLetters(ctx context.Context, opts ListOptions) (Page[models.DeadLetter], error)
+
+	AddSchedule(sc models.Schedule) error
+	GetSchedules(ctx context.Context, opts ListOptions) (Page[models.Schedule], error)
+	GetSchedule(id string) (models.Schedule, error)
+	UpdateSchedule(sc models.Schedule) error
+	DeleteSchedule(id string) error
+
+	AddEscalationPolicy(p models.EscalationPolicy) error
+	GetEscalationPolicies() []models.EscalationPolicy
+	GetEscalationPolicy(id string) (models.EscalationPolicy, error)
+	DeleteEscalationPolicy(id string) error
+
+	SaveEscalation(e models.Escalation) error
+	AdvanceEscalation(e models.Escalation) error
+	GetEscalations() []models.Escalation
+	DeleteEscalation(alertID string) error
+	AddReport(r models.IncidentReport) error
+	GetReports(ctx context.Context, opts ListOptions) (Page[models.IncidentReport], error)
+	DeleteReports(ctx context.Context, ids []string) (int, error)
+	RestoreReports(ctx context.Context, reports []models.IncidentReport) (int, error)
+
+	AddResponseAction(a models.ResponseAction) error
+	GetResponseActions(ctx context.Context, opts ListOptions) (Page[models.ResponseAction], error)
+	GetResponseAction(id string) (models.ResponseAction, error)
+	UpdateResponseAction(a models.ResponseAction) error
+	DecideResponseAction(a models.ResponseAction) error
+
+	AddPlaybook(pb models.Playbook) error
+	GetPlaybooks(ctx context.Context, opts ListOptions) (Page[models.Playbook], error)
+	GetPlaybook(id string) (models.Playbook, error)
+	UpdatePlaybook(pb models.Playbook) error
+	DeletePlaybook(id string) error
+	AddPlaybookRun(run models.PlaybookRun) error
+	UpdatePlaybookRun(run models.PlaybookRun) error
+	GetPlaybookRuns(playbookID string) []models.PlaybookRun
+	GetPlaybookRun(id string) (models.PlaybookRun, error)
+
+	SaveEvidence(b models.EvidenceBundle, blobs map[string][]byte) error
+	GetEvidenceBundles(ctx context.Context, opts ListOptions) (Page[models.EvidenceBundle], error)
+	GetEvidenceBundle(id string) (models.EvidenceBundle, error)
+	GetEvidenceBlob(sha256 string) ([]byte, error)
+
+	SavePSSFindings(clusterID string, findings []models.PSSFinding) error
+	GetPSSFindings(clusterID string) []models.PSSFinding
+
+	SaveImageFindings(clusterID string, findings []models.ImageFinding) error
+	GetImageFindings(clusterID string) []models.ImageFinding
+
+	SaveSecretFindings(clusterID string, findings []models.SecretFinding) error
+	GetSecretFindings(clusterID string) []models.SecretFinding
+
+	LastScan(clusterID, kind string) (time.Time, error)
+	DeleteScans(clusterID string) error
+
+	AddCISRun(run models.CISRun) (models.CISRun, error)
+	GetCISRuns(clusterID string) []models.CISRun
+	GetCISRun(clusterID string, version int) (models.CISRun, error)
+	DeleteCISRuns(clusterID string) error
 }
 
 type MemoryStorage struct {
-	users    map[string]models.User
-	clusters map[string]models.Cluster
-	policies map[string]models.Policy
-	alerts   []models.Alert
-	reports  []models.IncidentReport
-	mu       sync.RWMutex
+	users       map[string]models.User
+	clusters    map[string]models.Cluster
+	policies    map[string]models.Policy
+	alerts      []models.Alert
+	incidents   map[string]models.Incident
+	groups      map[string]string // alert group key to latest incident ID
+	silences    map[string]models.Silence
+	channels    map[string]models.Channel
+	dead        []models.DeadLetter
+	schedules   map[string]models.Schedule
+	escPolicies map[string]models.EscalationPolicy
+	escalations map[string]models.Escalation
+	reports     []models.IncidentReport
+	actions     map[string]models.ResponseAction
+	playbooks   map[string]models.Playbook
+	runs        map[string]models.PlaybookRun
+	evidence    map[string]models.EvidenceBundle
+	blobs       map[string][]byte // evidence file contents by SHA-256
+	pss         map[string][]models.PSSFinding
+	images      map[string][]models.ImageFinding
+	secrets     map[string][]models.SecretFinding
+	scans       map[string]time.Time // last scan by cluster ID and scan kind
+	cis         map[string][]models.CISRun
+	mu          sync.RWMutex
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		users:    make(map[string]models.User),
-		clusters: make(map[string]models.Cluster),
-		policies: make(map[string]models.Policy),
-		alerts:   make([]models.Alert, 0),
-		reports:  make([]models.IncidentReport, 0),
+		users:       make(map[string]models.User),
+		clusters:    make(map[string]models.Cluster),
+		policies:    make(map[string]models.Policy),
+		alerts:      make([]models.Alert, 0),
+		incidents:   make(map[string]models.Incident),
+		groups:      make(map[string]string),
+		silences:    make(map[string]models.Silence),
+		channels:    make(map[string]models.Channel),
+		schedules:   make(map[string]models.Schedule),
+		escPolicies: make(map[string]models.EscalationPolicy),
+		escalations: make(map[string]models.Escalation),
+		reports:     make([]models.IncidentReport, 0),
+		actions:     make(map[string]models.ResponseAction),
+		playbooks:   make(map[string]models.Playbook),
+		runs:        make(map[string]models.PlaybookRun),
+		evidence:    make(map[string]models.EvidenceBundle),
+		blobs:       make(map[string][]byte),
+		pss:         make(map[string][]models.PSSFinding),
+		images:      make(map[string][]models.ImageFinding),
+		secrets:     make(map[string][]models.SecretFinding),
+		scans:       make(map[string]time.Time),
+		cis:         make(map[string][]models.CISRun),
 	}
 }
 
@@ -54,8 +174,8 @@ func NewMemoryStorage() *MemoryStorage {
 func (s *MemoryStorage) AddUser(u models.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.users[u.ID]; ok {
-		return errors.New("user already exists")
+	if _, ok := s.users[u.ID]; ok || s.emailTaken(u.Email, "") {
+		return exists("user")
 	}
 	s.users[u.ID] = u
 	return nil
@@ -66,7 +186,7 @@ func (s *MemoryStorage) GetUser(id string) (models.User, error) {
 	defer s.mu.RUnlock()
 	u, ok := s.users[id]
 	if !ok {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, notFound("user")
 	}
 	return u, nil
 }
@@ -79,32 +199,54 @@ func (s *MemoryStorage) GetUserByEmail(email string) (models.User, error) {
 			return u, nil
 		}
 	}
-	return models.User{}, errors.New("user not found")
+	return models.User{}, notFound("user")
 }
 
-func (s *MemoryStorage) GetAllUsers() []models.User {
+// GetAllUsers returns a page of users, oldest first by default
+func (s *MemoryStorage) GetAllUsers(ctx context.Context, opts ListOptions) (Page[models.User], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.User]{}, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	users := make([]models.User, 0, len(s.users))
-	for _, u := range s.users {
-		users = append(users, u)
-	}
-	return users
+	return list(ctx, q, maps.Values(s.users), userKey, func(u models.User) bool {
+		return q.contains(u.Email, u.FirstName, u.LastName)
+	})
 }
 
+func userKey(u models.User) (time.Time, string) { return u.CreatedAt, u.ID }
+
 func (s *MemoryStorage) UpdateUser(u models.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.users[u.ID]; !ok {
-		return errors.New("user not found")
+		return notFound("user")
+	}
+	if s.emailTaken(u.Email, u.ID) {
+		return exists("user")
 	}
 	s.users[u.ID] = u
 	return nil
 }
 
+// emailTaken reports whether a user other than the one with ID except has the
+// email, which is unique like in the users table
+func (s *MemoryStorage) emailTaken(email, except string) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *MemoryStorage) DeleteUser(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return notFound("user")
+	}
 	delete(s.users, id)
 	return nil
 }
@@ -113,33 +255,68 @@ func (s *MemoryStorage) DeleteUser(id string) error {
 func (s *MemoryStorage) AddCluster(c models.Cluster) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.clusters[c.ID]; ok {
+		return exists("cluster")
+	}
 	s.clusters[c.ID] = c
 	return nil
 }
 
-func (s *MemoryStorage) GetClusters() []models.Cluster {
+// GetClusters returns a page of clusters, oldest first by default
+func (s *MemoryStorage) GetClusters(ctx context.Context, opts ListOptions) (Page[models.Cluster], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Cluster]{}, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	clusters := make([]models.Cluster, 0, len(s.clusters))
-	for _, c := range s.clusters {
-		clusters = append(clusters, c)
-	}
-	return clusters
+	return list(ctx, q, maps.Values(s.clusters), clusterKey, func(c models.Cluster) bool {
+		return q.contains(c.Name)
+	})
 }
 
+func clusterKey(c models.Cluster) (time.Time, string) { return c.CreatedAt, c.ID }
+
 func (s *MemoryStorage) GetCluster(id string) (models.Cluster, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	c, ok := s.clusters[id]
 	if !ok {
-		return models.Cluster{}, errors.New("cluster not found")
+		return models.Cluster{}, notFound("cluster")
 	}
 	return c, nil
 }
 
+func (s *MemoryStorage) SetAuditToken(clusterID, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clusters[clusterID]
+	if !ok {
+		return notFound("cluster")
+	}
+	c.AuditToken = token
+	s.clusters[clusterID] = c
+	return nil
+}
+
+func (s *MemoryStorage) SetKubeConfig(clusterID, kubeConfig string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clusters[clusterID]
+	if !ok {
+		return notFound("cluster")
+	}
+	c.KubeConfig = kubeConfig
+	s.clusters[clusterID] = c
+	return nil
+}
+
 func (s *MemoryStorage) DeleteCluster(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.clusters[id]; !ok {
+		return notFound("cluster")
+	}
 	delete(s.clusters, id)
 	return nil
 }
@@ -148,26 +325,34 @@ func (s *MemoryStorage) DeleteCluster(id string) error {
 func (s *MemoryStorage) AddPolicy(p models.Policy) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.policies[p.ID]; ok {
+		return exists("policy")
+	}
 	s.policies[p.ID] = p
 	return nil
 }
 
-func (s *MemoryStorage) GetPolicies() []models.Policy {
+// GetPolicies returns a page of policies, oldest first by default
+func (s *MemoryStorage) GetPolicies(ctx context.Context, opts ListOptions) (Page[models.Policy], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Policy]{}, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	policies := make([]models.Policy, 0, len(s.policies))
-	for _, p := range s.policies {
-		policies = append(policies, p)
-	}
-	return policies
+	return list(ctx, q, maps.Values(s.policies), policyKey, func(p models.Policy) bool {
+		return eq(q.Severity, p.Severity) && eq(q.Namespace, p.Namespace) && q.contains(p.Name, p.Description)
+	})
 }
 
+func policyKey(p models.Policy) (time.Time, string) { return p.CreatedAt, p.ID }
+
 func (s *MemoryStorage) GetPolicy(id string) (models.Policy, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	p, ok := s.policies[id]
 	if !ok {
-		return models.Policy{}, errors.New("policy not found")
+		return models.Policy{}, notFound("policy")
 	}
 	return p, nil
 }
@@ -175,31 +360,952 @@ func (s *MemoryStorage) GetPolicy(id string) (models.Policy, error) {
 func (s *MemoryStorage) DeletePolicy(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.policies[id]; !ok {
+		return notFound("policy")
+	}
 	delete(s.policies, id)
 	return nil
 }
 
 // Alert and Report methods
-func (s *MemoryStorage) AddAlert(a models.Alert) {
+// AddAlert upserts an alert when the caller has no use for the result
+func (s *MemoryStorage) AddAlert(a models.Alert) error {
+	_, _, err := s.UpsertAlert(a)
+	return err
+}
+
+// UpsertAlert folds the alert into the latest open alert with the same
+// fingerprint, or stores it and attaches it to an incident. It reports
+// whether a new alert was stored.
+func (s *MemoryStorage) UpsertAlert(a models.Alert) (models.Alert, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	a.Prepare()
+	for i := len(s.alerts) - 1; i >= 0; i-- {
+		existing := s.alerts[i]
+		if !existing.Folds(a) {
+			continue
+		}
+		existing.Fold(a)
+		s.alerts[i] = existing
+		if inc, ok := s.incidents[existing.IncidentID]; ok {
+			inc.Touch(existing, false)
+			s.incidents[inc.ID] = inc
+		}
+		return existing, false, nil
+	}
+
+	if key := a.GroupKey(); key != "" {
+		inc, ok := s.incidents[s.groups[key]]
+		if ok && inc.Accepts(a.Timestamp) {
+			inc.Touch(a, true)
+		} else {
+			inc = models.NewIncident(a)
+			s.groups[key] = inc.ID
+		}
+		s.incidents[inc.ID] = inc
+		a.IncidentID = inc.ID
+	}
 	s.alerts = append(s.alerts, a)
+	return a, true, nil
+}
+
+// GetAlerts returns a page of alerts, newest first by default
+func (s *MemoryStorage) GetAlerts(ctx context.Context, opts ListOptions) (Page[models.Alert], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Alert]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, slices.Values(s.alerts), alertKey, func(a models.Alert) bool {
+		return eq(q.ClusterID, a.ClusterID) && eq(q.Severity, a.Severity) && eq(q.Namespace, a.Namespace) &&
+			eq(q.IncidentID, a.IncidentID) && q.contains(a.Message, a.Resource, a.Rule, a.Actor)
+	})
+}
+
+func alertKey(a models.Alert) (time.Time, string) { return a.Timestamp, a.ID }
+
+func (s *MemoryStorage) GetAlert(id string) (models.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.alerts {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return models.Alert{}, notFound("alert")
+}
+
+// TransitionAlert applies a status or assignee change to an alert
+func (s *MemoryStorage) TransitionAlert(id string, t models.AlertTransition) (models.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.alerts {
+		if s.alerts[i].ID != id {
+			continue
+		}
+		a := s.alerts[i]
+		if err := a.Apply(t); err != nil {
+			return models.Alert{}, conflict(err)
+		}
+		s.alerts[i] = a
+		return a, nil
+	}
+	return models.Alert{}, notFound("alert")
+}
+
+// DeleteAlerts deletes the alerts with the given IDs and returns how many it
+// found
+func (s *MemoryStorage) DeleteAlerts(ctx context.Context, ids []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	s.alerts, n = deleteIDs(s.alerts, ids, alertKey)
+	return n, nil
+}
+
+// RestoreAlerts stores archived alerts as they were, skipping IDs that are
+// taken, and returns how many it stored
+func (s *MemoryStorage) RestoreAlerts(ctx context.Context, alerts []models.Alert) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	s.alerts, n = restoreIDs(s.alerts, alerts, alertKey)
+	return n, nil
+}
+
+// GetIncidents returns a page of incidents, most recently active first by
+// default
+func (s *MemoryStorage) GetIncidents(ctx context.Context, opts ListOptions) (Page[models.Incident], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Incident]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.incidents), incidentKey, func(inc models.Incident) bool {
+		return eq(q.ClusterID, inc.ClusterID) && eq(q.Severity, inc.Severity) && eq(q.Namespace, inc.Namespace) &&
+			eq(q.Status, inc.Status) && eq(q.Owner, inc.Owner) && q.contains(inc.Title, inc.Rule)
+	})
+}
+
+func incidentKey(inc models.Incident) (time.Time, string) { return inc.LastSeen, inc.ID }
+
+func (s *MemoryStorage) GetIncident(id string) (models.Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	inc, ok := s.incidents[id]
+	if !ok {
+		return models.Incident{}, notFound("incident")
+	}
+	return inc, nil
+}
+
+// CreateIncident stores an incident opened by hand and moves the given alerts
+// into it from the incidents they were grouped into
+func (s *MemoryStorage) CreateIncident(inc models.Incident, alertIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.incidents[inc.ID]; ok {
+		return exists("incident")
+	}
+	index := make(map[string]int, len(s.alerts))
+	for i, a := range s.alerts {
+		index[a.ID] = i
+	}
+	for _, id := range alertIDs {
+		if _, ok := index[id]; !ok {
+			return notFound("alert")
+		}
+	}
+	for _, id := range alertIDs {
+		a := &s.alerts[index[id]]
+		if old, ok := s.incidents[a.IncidentID]; ok && old.ID != inc.ID {
+			old.AlertCount--
+			s.incidents[old.ID] = old
+		}
+		a.IncidentID = inc.ID
+	}
+	s.incidents[inc.ID] = inc
+	return nil
+}
+
+// UpdateIncident applies a user's change to an incident
+func (s *MemoryStorage) UpdateIncident(id string, u models.IncidentUpdate) (models.Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inc, ok := s.incidents[id]
+	if !ok {
+		return models.Incident{}, notFound("incident")
+	}
+	if err := inc.Apply(u); err != nil {
+		return models.Incident{}, conflict(err)
+	}
+	s.incidents[id] = inc
+	return inc, nil
+}
+
+// Silence methods
+func (s *MemoryStorage) AddSilence(sl models.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.silences[sl.ID]; ok {
+		return exists("silence")
+	}
+	s.silences[sl.ID] = sl
+	return nil
+}
+
+// GetSilences returns a page of silences, latest start first by default. The
+// status filter selects silences by their state now.
+func (s *MemoryStorage) GetSilences(ctx context.Context, opts ListOptions) (Page[models.Silence], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.Silence]{}, err
+	}
+	if err := checkSilenceState(q.Status); err != nil {
+		return Page[models.Silence]{}, err
+	}
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.silences), silenceKey, func(sl models.Silence) bool {
+		return eq(q.ClusterID, sl.ClusterID) && eq(q.Severity, sl.Severity) && eq(q.Namespace, sl.Namespace) &&
+			eq(q.Status, sl.State(now)) && q.contains(sl.Comment)
+	})
+}
+
+func silenceKey(sl models.Silence) (time.Time, string) { return sl.StartsAt, sl.ID }
+
+// checkSilenceState rejects a status filter that is not a silence state
+func checkSilenceState(state string) error {
+	switch state {
+	case "", models.SilencePending, models.SilenceActive, models.SilenceExpired:
+		return nil
+	}
+	return invalid("silence state " + state)
+}
+
+func (s *MemoryStorage) GetSilence(id string) (models.Silence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sl, ok := s.silences[id]
+	if !ok {
+		return models.Silence{}, notFound("silence")
+	}
+	return sl, nil
+}
+
+func (s *MemoryStorage) UpdateSilence(sl models.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.silences[sl.ID]; !ok {
+		return notFound("silence")
+	}
+	s.silences[sl.ID] = sl
+	return nil
+}
+
+func (s *MemoryStorage) DeleteSilence(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.silences[id]; !ok {
+		return notFound("silence")
+	}
+	delete(s.silences, id)
+	return nil
+}
+
+// Notification channel methods
+func (s *MemoryStorage) AddChannel(c models.Channel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.channels[c.ID]; ok {
+		return exists("channel")
+	}
+	s.channels[c.ID] = c
+	return nil
+}
+
+// GetChannels returns a page of channels, oldest first by default
+func (s *MemoryStorage) GetChannels(ctx context.Context, opts ListOptions) (Page[models.Channel], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Channel]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.channels), channelKey, func(c models.Channel) bool {
+		return q.contains(c.Name)
+	})
+}
+
+func channelKey(c models.Channel) (time.Time, string) { return c.CreatedAt, c.ID }
+
+func (s *MemoryStorage) GetChannel(id string) (models.Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.channels[id]
+	if !ok {
+		return models.Channel{}, notFound("channel")
+	}
+	return c, nil
+}
+
+func (s *MemoryStorage) DeleteChannel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.channels[id]; !ok {
+		return notFound("channel")
+	}
+	delete(s.channels, id)
+	return nil
+}
+
+func (s *MemoryStorage) AddDeadLetter(d models.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.dead {
+		if existing.ID == d.ID {
+			return exists("dead letter")
+		}
+	}
+	s.dead = append(s.dead, d)
+	return nil
+}
+
+// GetDeadLetters returns a page of dead letters, most recent failure first
+// by default
+func (s *MemoryStorage) GetDeadLetters(ctx context.Context, opts ListOptions) (Page[models.DeadLetter], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.DeadLetter]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, slices.Values(s.dead), deadLetterKey, func(d models.DeadLetter) bool {
+		return eq(q.AlertID, d.AlertID) && q.contains(d.Error)
+	})
+}
+
+func deadLetterKey(d models.DeadLetter) (time.Time, string) { return d.FailedAt, d.ID }
+
+// On-call schedule methods
+func (s *MemoryStorage) AddSchedule(sc models.Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[sc.ID]; ok {
+		return exists("schedule")
+	}
+	s.schedules[sc.ID] = sc
+	return nil
+}
+
+// GetSchedules returns a page of schedules, oldest first by default
+func (s *MemoryStorage) GetSchedules(ctx context.Context, opts ListOptions) (Page[models.Schedule], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Schedule]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.schedules), scheduleKey, func(sc models.Schedule) bool {
+		return q.contains(sc.Name)
+	})
+}
+
+func scheduleKey(sc models.Schedule) (time.Time, string) { return sc.CreatedAt, sc.ID }
+
+func (s *MemoryStorage) GetSchedule(id string) (models.Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sc, ok := s.schedules[id]
+	if !ok {
+		return models.Schedule{}, notFound("schedule")
+	}
+	return sc, nil
+}
+
+func (s *MemoryStorage) UpdateSchedule(sc models.Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[sc.ID]; !ok {
+		return notFound("schedule")
+	}
+	s.schedules[sc.ID] = sc
+	return nil
+}
+
+func (s *MemoryStorage) DeleteSchedule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[id]; !ok {
+		return notFound("schedule")
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+// Escalation methods
+func (s *MemoryStorage) AddEscalationPolicy(p models.EscalationPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.escPolicies[p.ID]; ok {
+		return exists("escalation policy")
+	}
+	s.escPolicies[p.ID] = p
+	return nil
+}
+
+func (s *MemoryStorage) GetEscalationPolicies() []models.EscalationPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	policies := make([]models.EscalationPolicy, 0, len(s.escPolicies))
+	for _, p := range s.escPolicies {
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].CreatedAt.Before(policies[j].CreatedAt) })
+	return policies
+}
+
+func (s *MemoryStorage) GetEscalationPolicy(id string) (models.EscalationPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.escPolicies[id]
+	if !ok {
+		return models.EscalationPolicy{}, notFound("escalation policy")
+	}
+	return p, nil
+}
+
+func (s *MemoryStorage) DeleteEscalationPolicy(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.escPolicies[id]; !ok {
+		return notFound("escalation policy")
+	}
+	delete(s.escPolicies, id)
+	return nil
+}
+
+func (s *MemoryStorage) SaveEscalation(e models.Escalation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.escalations[e.AlertID] = e
+	return nil
 }
 
-func (s *MemoryStorage) GetAlerts() []models.Alert {
+// AdvanceEscalation stores an escalation moved on to e.NextTier only while it
+// still waits for the tier before, so that an escalation deleted when its
+// alert was acknowledged is not brought back and a tier is paged once
+func (s *MemoryStorage) AdvanceEscalation(e models.Escalation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.escalations[e.AlertID]
+	if !ok {
+		return notFound("escalation")
+	}
+	if stored.PolicyID != e.PolicyID || stored.NextTier != e.NextTier-1 {
+		return changed("escalation")
+	}
+	s.escalations[e.AlertID] = e
+	return nil
+}
+
+func (s *MemoryStorage) GetEscalations() []models.Escalation {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.alerts
+	escalations := make([]models.Escalation, 0, len(s.escalations))
+	for _, e := range s.escalations {
+		escalations = append(escalations, e)
+	}
+	sort.Slice(escalations, func(i, j int) bool { return escalations[i].DueAt.Before(escalations[j].DueAt) })
+	return escalations
 }
 
-func (s *MemoryStorage) AddReport(r models.IncidentReport) {
+func (s *MemoryStorage) DeleteEscalation(alertID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	delete(s.escalations, alertID)
+	return nil
+}
+
+func (s *MemoryStorage) AddReport(r models.IncidentReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.reports {
+		if existing.ID == r.ID {
+			return exists("report")
+		}
+	}
 	s.reports = append(s.reports, r)
+	return nil
+}
+
+// GetReports returns a page of reports, newest first by default
+func (s *MemoryStorage) GetReports(ctx context.Context, opts ListOptions) (Page[models.IncidentReport], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.IncidentReport]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, slices.Values(s.reports), reportKey, func(r models.IncidentReport) bool {
+		return q.contains(r.Details, r.Action, r.Result)
+	})
+}
+
+func reportKey(r models.IncidentReport) (time.Time, string) { return r.Timestamp, r.ID }
+
+// DeleteReports deletes the reports with the given IDs and returns how many
+// it found
+func (s *MemoryStorage) DeleteReports(ctx context.Context, ids []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	s.reports, n = deleteIDs(s.reports, ids, reportKey)
+	return n, nil
+}
+
+// RestoreReports stores archived reports as they were, skipping IDs that are
+// taken, and returns how many it stored
+func (s *MemoryStorage) RestoreReports(ctx context.Context, reports []models.IncidentReport) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	s.reports, n = restoreIDs(s.reports, reports, reportKey)
+	return n, nil
+}
+
+// Response action methods
+func (s *MemoryStorage) AddResponseAction(a models.ResponseAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.actions[a.ID]; ok {
+		return exists("response action")
+	}
+	s.actions[a.ID] = a
+	return nil
+}
+
+// GetResponseActions returns a page of response actions, newest first by
+// default
+func (s *MemoryStorage) GetResponseActions(ctx context.Context, opts ListOptions) (Page[models.ResponseAction], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.ResponseAction]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.actions), actionKey, func(a models.ResponseAction) bool {
+		return eq(q.ClusterID, a.ClusterID) && eq(q.Status, a.Status)
+	})
 }
 
-func (s *MemoryStorage) GetReports() []models.IncidentReport {
+func actionKey(a models.ResponseAction) (time.Time, string) { return a.CreatedAt, a.ID }
+
+func (s *MemoryStorage) GetResponseAction(id string) (models.ResponseAction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.actions[id]
+	if !ok {
+		return models.ResponseAction{}, notFound("response action")
+	}
+	return a, nil
+}
+
+func (s *MemoryStorage) UpdateResponseAction(a models.ResponseAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.actions[a.ID]; !ok {
+		return notFound("response action")
+	}
+	s.actions[a.ID] = a
+	return nil
+}
+
+// DecideResponseAction stores a decision on an action only while it is still
+// pending, so that concurrent decisions cannot both take effect
+func (s *MemoryStorage) DecideResponseAction(a models.ResponseAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.actions[a.ID]
+	if !ok {
+		return notFound("response action")
+	}
+	if stored.Status != models.ActionPending {
+		return changed("response action")
+	}
+	s.actions[a.ID] = a
+	return nil
+}
+
+// Playbook methods
+func (s *MemoryStorage) AddPlaybook(pb models.Playbook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.playbooks[pb.ID]; ok {
+		return exists("playbook")
+	}
+	s.playbooks[pb.ID] = pb
+	return nil
+}
+
+// GetPlaybooks returns a page of playbooks, oldest first by default
+func (s *MemoryStorage) GetPlaybooks(ctx context.Context, opts ListOptions) (Page[models.Playbook], error) {
+	q, err := opts.resolve(Oldest)
+	if err != nil {
+		return Page[models.Playbook]{}, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.reports
+	return list(ctx, q, maps.Values(s.playbooks), playbookKey, func(pb models.Playbook) bool {
+		return q.contains(pb.Name)
+	})
+}
+
+func playbookKey(pb models.Playbook) (time.Time, string) { return pb.CreatedAt, pb.ID }
+
+func (s *MemoryStorage) GetPlaybook(id string) (models.Playbook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pb, ok := s.playbooks[id]
+	if !ok {
+		return models.Playbook{}, notFound("playbook")
+	}
+	return pb, nil
+}
+
+func (s *MemoryStorage) UpdatePlaybook(pb models.Playbook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.playbooks[pb.ID]; !ok {
+		return notFound("playbook")
+	}
+	s.playbooks[pb.ID] = pb
+	return nil
+}
+
+func (s *MemoryStorage) DeletePlaybook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.playbooks[id]; !ok {
+		return notFound("playbook")
+	}
+	delete(s.playbooks, id)
+	return nil
+}
+
+func (s *MemoryStorage) AddPlaybookRun(run models.PlaybookRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[run.ID]; ok {
+		return exists("playbook run")
+	}
+	s.runs[run.ID] = run
+	return nil
+}
+
+func (s *MemoryStorage) UpdatePlaybookRun(run models.PlaybookRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[run.ID]; !ok {
+		return notFound("playbook run")
+	}
+	s.runs[run.ID] = run
+	return nil
+}
+
+// GetPlaybookRuns returns the runs of a playbook, newest first
+func (s *MemoryStorage) GetPlaybookRuns(playbookID string) []models.PlaybookRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var runs []models.PlaybookRun
+	for _, run := range s.runs {
+		if run.PlaybookID == playbookID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs
+}
+
+func (s *MemoryStorage) GetPlaybookRun(id string) (models.PlaybookRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return models.PlaybookRun{}, notFound("playbook run")
+	}
+	return run, nil
+}
+
+// Evidence methods. A bundle and its blobs are stored together; blobs
+// already present are shared.
+func (s *MemoryStorage) SaveEvidence(b models.EvidenceBundle, blobs map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sum, data := range blobs {
+		if _, ok := s.blobs[sum]; !ok {
+			s.blobs[sum] = data
+		}
+	}
+	if _, ok := s.evidence[b.ID]; !ok {
+		s.evidence[b.ID] = b
+	}
+	return nil
+}
+
+// GetEvidenceBundles returns a page of evidence bundles, latest capture
+// first by default
+func (s *MemoryStorage) GetEvidenceBundles(ctx context.Context, opts ListOptions) (Page[models.EvidenceBundle], error) {
+	q, err := opts.resolve(Newest)
+	if err != nil {
+		return Page[models.EvidenceBundle]{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return list(ctx, q, maps.Values(s.evidence), evidenceKey, func(b models.EvidenceBundle) bool {
+		return eq(q.AlertID, b.AlertID)
+	})
+}
+
+func evidenceKey(b models.EvidenceBundle) (time.Time, string) { return b.CollectedAt, b.ID }
+
+func (s *MemoryStorage) GetEvidenceBundle(id string) (models.EvidenceBundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.evidence[id]
+	if !ok {
+		return models.EvidenceBundle{}, notFound("evidence bundle")
+	}
+	return b, nil
+}
+
+func (s *MemoryStorage) GetEvidenceBlob(sha256 string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[sha256]
+	if !ok {
+		return nil, notFound("evidence blob")
+	}
+	return data, nil
+}
+
+// Pod Security Standards methods
+func (s *MemoryStorage) SavePSSFindings(clusterID string, findings []models.PSSFinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pss[clusterID] = append([]models.PSSFinding(nil), findings...)
+	s.scans[clusterID+"/"+models.ScanPSS] = time.Now()
+	return nil
+}
+
+// GetPSSFindings returns the findings of a cluster ordered by namespace, kind
+// and name
+func (s *MemoryStorage) GetPSSFindings(clusterID string) []models.PSSFinding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	findings := append([]models.PSSFinding(nil), s.pss[clusterID]...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return findings
+}
+
+// Image finding methods
+func (s *MemoryStorage) SaveImageFindings(clusterID string, findings []models.ImageFinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[clusterID] = append([]models.ImageFinding(nil), findings...)
+	s.scans[clusterID+"/"+models.ScanImages] = time.Now()
+	return nil
+}
+
+// GetImageFindings returns the findings of a cluster ordered by namespace,
+// pod and container
+func (s *MemoryStorage) GetImageFindings(clusterID string) []models.ImageFinding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	findings := append([]models.ImageFinding(nil), s.images[clusterID]...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+	return findings
+}
+
+// Secret audit methods
+func (s *MemoryStorage) SaveSecretFindings(clusterID string, findings []models.SecretFinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[clusterID] = append([]models.SecretFinding(nil), findings...)
+	s.scans[clusterID+"/"+models.ScanSecrets] = time.Now()
+	return nil
+}
+
+// GetSecretFindings returns the findings of a cluster ordered by namespace,
+// kind and name
+func (s *MemoryStorage) GetSecretFindings(clusterID string) []models.SecretFinding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	findings := append([]models.SecretFinding(nil), s.secrets[clusterID]...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return findings
+}
+
+// LastScan returns when a cluster was last scanned for a kind of findings
+func (s *MemoryStorage) LastScan(clusterID, kind string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.scans[clusterID+"/"+kind]
+	if !ok {
+		return time.Time{}, notFound("scan")
+	}
+	return t, nil
+}
+
+// DeleteScans deletes the findings of every kind of a cluster and the record
+// of its scans
+func (s *MemoryStorage) DeleteScans(clusterID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pss, clusterID)
+	delete(s.images, clusterID)
+	delete(s.secrets, clusterID)
+	for _, kind := range []string{models.ScanPSS, models.ScanImages, models.ScanSecrets} {
+		delete(s.scans, clusterID+"/"+kind)
+	}
+	return nil
+}
+
+// CIS benchmark methods
+
+// AddCISRun stores a run under the next version number of its cluster
+func (s *MemoryStorage) AddCISRun(run models.CISRun) (models.CISRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := s.cis[run.ClusterID]
+	run.Version = 1
+	if len(runs) > 0 {
+		run.Version = runs[len(runs)-1].Version + 1
+	}
+	s.cis[run.ClusterID] = append(runs, run)
+	return run, nil
+}
+
+func (s *MemoryStorage) GetCISRuns(clusterID string) []models.CISRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.CISRun(nil), s.cis[clusterID]...)
+}
+
+func (s *MemoryStorage) GetCISRun(clusterID string, version int) (models.CISRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, run := range s.cis[clusterID] {
+		if run.Version == version {
+			return run, nil
+		}
+	}
+	return models.CISRun{}, notFound("benchmark run")
+}
+
+func (s *MemoryStorage) DeleteCISRuns(clusterID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cis, clusterID)
+	return nil
+}
+
+// list filters, sorts and pages records held in memory
+func list[T any](ctx context.Context, q listQuery, records iter.Seq[T], key func(T) (time.Time, string), match func(T) bool) (Page[T], error) {
+	if err := ctx.Err(); err != nil {
+		return Page[T]{}, err
+	}
+	items := []T{}
+	for r := range records {
+		if t, id := key(r); q.matches(t, id) && match(r) {
+			items = append(items, r)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		t, id := key(items[i])
+		u, other := key(items[j])
+		return q.sorted(t, id, u, other)
+	})
+	return paginate(q, items, key), nil
+}
+
+// deleteIDs removes the records with the given IDs from a slice
+func deleteIDs[T any](records []T, ids []string, key func(T) (time.Time, string)) ([]T, int) {
+	doomed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		doomed[id] = true
+	}
+	before := len(records)
+	records = slices.DeleteFunc(records, func(r T) bool {
+		_, id := key(r)
+		return doomed[id]
+	})
+	return records, before - len(records)
+}
+
+// restoreIDs adds the records whose IDs are not taken to a slice kept in time
+// order, which UpsertAlert relies on to find the latest alert
+func restoreIDs[T any](records, restored []T, key func(T) (time.Time, string)) ([]T, int) {
+	taken := make(map[string]bool, len(records))
+	for _, r := range records {
+		_, id := key(r)
+		taken[id] = true
+	}
+	n := 0
+	for _, r := range restored {
+		if _, id := key(r); !taken[id] {
+			taken[id] = true
+			records = append(records, r)
+			n++
+		}
+	}
+	slices.SortStableFunc(records, func(a, b T) int {
+		t, _ := key(a)
+		u, _ := key(b)
+		return t.Compare(u)
+	})
+	return records, n
 }
@@ -0,0 +1,202 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// testMigrator returns a migrator over a fresh SQLite database and the
+// versions of the embedded migrations in order
+func testMigrator(t *testing.T) (*storage.Migrator, *sql.DB, []int) {
+	t.Helper()
+	db, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "ksms.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := storage.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := storage.LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, mig := range migrations {
+		versions = append(versions, mig.Version)
+	}
+	return m, db, versions
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=$1", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+// appliedVersions returns the versions Status reports as applied
+func appliedVersions(t *testing.T, m *storage.Migrator) []int {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []int
+	for _, st := range statuses {
+		if st.AppliedAt != nil {
+			applied = append(applied, st.Version)
+		}
+	}
+	return applied
+}
+
+func reversed(versions []int) []int {
+	r := slices.Clone(versions)
+	slices.Reverse(r)
+	return r
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m, db, versions := testMigrator(t)
+	latest := versions[len(versions)-1]
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(versions) {
+		t.Fatalf("Status of a new database lists %d migrations, want %d", len(statuses), len(versions))
+	}
+	for _, st := range statuses {
+		if st.Name == "" || st.AppliedAt != nil {
+			t.Errorf("Status of a new database = %+v, want a named pending migration", st)
+		}
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(applied, versions) {
+		t.Errorf("Up applied %v, want %v", applied, versions)
+	}
+	if got := appliedVersions(t, m); !slices.Equal(got, versions) {
+		t.Errorf("applied after Up = %v, want %v", got, versions)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Up = %v, %v, want nothing to apply", applied, err)
+	}
+	if !hasTable(t, db, "scans") {
+		t.Error("Up did not create the scans table")
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{latest, versions[len(versions)-2]}; !slices.Equal(reverted, want) {
+		t.Errorf("Down(2) rolled back %v, want %v", reverted, want)
+	}
+	if got := appliedVersions(t, m); !slices.Equal(got, versions[:len(versions)-2]) {
+		t.Errorf("applied after Down(2) = %v, want %v", got, versions[:len(versions)-2])
+	}
+	if hasTable(t, db, "scans") {
+		t.Error("Down left the scans table")
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := versions[len(versions)-2:]; !slices.Equal(applied, want) {
+		t.Errorf("Up after Down(2) applied %v, want %v", applied, want)
+	}
+}
+
+func TestMigrateDownPastFirstVersion(t *testing.T) {
+	ctx := context.Background()
+	m, db, versions := testMigrator(t)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !hasTable(t, db, "alerts") {
+		t.Fatal("Up did not create the alerts table")
+	}
+
+	reverted, err := m.Down(ctx, len(versions)+5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(reverted, reversed(versions)) {
+		t.Errorf("Down past the first version rolled back %v, want %v", reverted, reversed(versions))
+	}
+	if got := appliedVersions(t, m); len(got) != 0 {
+		t.Errorf("applied after rolling back everything = %v", got)
+	}
+	for _, table := range []string{"clusters", "policies", "alerts", "reports", "users"} {
+		if hasTable(t, db, table) {
+			t.Errorf("rolling back everything left table %s", table)
+		}
+	}
+
+	if reverted, err := m.Down(ctx, 1); err != nil || len(reverted) != 0 {
+		t.Errorf("Down of an empty schema = %v, %v, want nothing to roll back", reverted, err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(applied, versions) {
+		t.Errorf("Up after rolling back everything applied %v, want %v", applied, versions)
+	}
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	ctx := context.Background()
+	m, db, versions := testMigrator(t)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// a newer build applied a migration this one does not embed
+	const unknown = 9999
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", unknown, "future", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(versions)+1 {
+		t.Fatalf("Status lists %d migrations, want %d", len(statuses), len(versions)+1)
+	}
+	if last := statuses[len(statuses)-1]; last.Version != unknown || last.Name != "" || last.AppliedAt == nil {
+		t.Errorf("Status of the unknown version = %+v, want it applied without a name", last)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Up under an unknown version = %v, %v, want nothing to apply", applied, err)
+	}
+	reverted, err := m.Down(ctx, 1)
+	if err == nil || !strings.Contains(err.Error(), "9999") {
+		t.Errorf("Down under an unknown version: err = %v, want a refusal naming it", err)
+	}
+	if len(reverted) != 0 {
+		t.Errorf("Down under an unknown version rolled back %v", reverted)
+	}
+	if got := appliedVersions(t, m); len(got) != len(versions)+1 {
+		t.Errorf("applied after the refused Down = %v", got)
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS policies;
+DROP TABLE IF EXISTS clusters;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	first_name TEXT,
+	last_name TEXT,
+	role TEXT,
+	token_keys JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS clusters (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	kube_config TEXT,
+	status TEXT,
+	metrics JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS policies (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	description TEXT,
+	rules JSONB,
+	namespace TEXT,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+	id TEXT PRIMARY KEY,
+	cluster_id TEXT,
+	severity TEXT,
+	message TEXT,
+	timestamp TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS reports (
+	id TEXT PRIMARY KEY,
+	alert_id TEXT,
+	details TEXT,
+	action_taken TEXT,
+	timestamp TIMESTAMP WITH TIME ZONE
+);
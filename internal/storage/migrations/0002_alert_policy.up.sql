@@ -0,0 +1 @@
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS policy_id TEXT;
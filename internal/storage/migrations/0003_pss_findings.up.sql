@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS pss_findings (
+	cluster_id TEXT,
+	kind TEXT,
+	namespace TEXT,
+	name TEXT,
+	level TEXT,
+	violations JSONB,
+	scanned_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (cluster_id, kind, namespace, name)
+);
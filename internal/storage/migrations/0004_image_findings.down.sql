@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS image_findings;
+ALTER TABLE policies DROP COLUMN IF EXISTS allowed_registries;
@@ -0,0 +1,15 @@
+ALTER TABLE policies ADD COLUMN IF NOT EXISTS allowed_registries JSONB;
+
+CREATE TABLE IF NOT EXISTS image_findings (
+	cluster_id TEXT,
+	policy_id TEXT,
+	namespace TEXT,
+	pod TEXT,
+	container TEXT,
+	image TEXT,
+	check_id TEXT,
+	severity TEXT,
+	message TEXT,
+	detected_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS image_findings_cluster_idx ON image_findings (cluster_id);
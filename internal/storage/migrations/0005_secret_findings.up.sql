@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS secret_findings (
+	cluster_id TEXT,
+	check_id TEXT,
+	severity TEXT,
+	kind TEXT,
+	namespace TEXT,
+	name TEXT,
+	reference TEXT,
+	message TEXT,
+	detected_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS secret_findings_cluster_idx ON secret_findings (cluster_id);
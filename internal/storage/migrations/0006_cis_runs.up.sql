@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS cis_runs (
+	id TEXT,
+	cluster_id TEXT,
+	version INTEGER,
+	benchmark TEXT,
+	controls JSONB,
+	started_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (cluster_id, version)
+);
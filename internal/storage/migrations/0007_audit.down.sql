@@ -0,0 +1,3 @@
+ALTER TABLE alerts DROP COLUMN IF EXISTS verb;
+ALTER TABLE alerts DROP COLUMN IF EXISTS actor;
+ALTER TABLE clusters DROP COLUMN IF EXISTS audit_token;
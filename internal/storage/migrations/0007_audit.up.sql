@@ -0,0 +1,3 @@
+ALTER TABLE clusters ADD COLUMN IF NOT EXISTS audit_token TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS actor TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS verb TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE alerts DROP COLUMN IF EXISTS history;
+ALTER TABLE alerts DROP COLUMN IF EXISTS resolution;
+ALTER TABLE alerts DROP COLUMN IF EXISTS assignee;
+ALTER TABLE alerts DROP COLUMN IF EXISTS status;
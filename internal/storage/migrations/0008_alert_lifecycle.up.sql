@@ -0,0 +1,4 @@
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS assignee TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS resolution TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS history JSONB;
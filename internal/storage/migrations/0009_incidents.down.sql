@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS incidents;
+DROP INDEX IF EXISTS alerts_fingerprint_idx;
+ALTER TABLE alerts DROP COLUMN IF EXISTS incident_id;
+ALTER TABLE alerts DROP COLUMN IF EXISTS last_seen;
+ALTER TABLE alerts DROP COLUMN IF EXISTS first_seen;
+ALTER TABLE alerts DROP COLUMN IF EXISTS count;
+ALTER TABLE alerts DROP COLUMN IF EXISTS fingerprint;
+ALTER TABLE alerts DROP COLUMN IF EXISTS resource;
+ALTER TABLE alerts DROP COLUMN IF EXISTS namespace;
+ALTER TABLE alerts DROP COLUMN IF EXISTS rule;
@@ -0,0 +1,21 @@
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS rule TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS namespace TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS resource TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS fingerprint TEXT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS count INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS first_seen TIMESTAMP WITH TIME ZONE;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS last_seen TIMESTAMP WITH TIME ZONE;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS incident_id TEXT;
+CREATE INDEX IF NOT EXISTS alerts_fingerprint_idx ON alerts (fingerprint);
+
+CREATE TABLE IF NOT EXISTS incidents (
+	id TEXT PRIMARY KEY,
+	cluster_id TEXT,
+	namespace TEXT,
+	rule TEXT,
+	severity TEXT,
+	alert_count INTEGER,
+	first_seen TIMESTAMP WITH TIME ZONE,
+	last_seen TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS incidents_group_idx ON incidents (cluster_id, namespace, rule, last_seen);
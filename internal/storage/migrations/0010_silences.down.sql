@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS silences;
+ALTER TABLE alerts DROP COLUMN IF EXISTS silence_id;
@@ -0,0 +1,14 @@
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS silence_id TEXT;
+
+CREATE TABLE IF NOT EXISTS silences (
+	id TEXT PRIMARY KEY,
+	cluster_id TEXT,
+	namespace TEXT,
+	severity TEXT,
+	rule TEXT,
+	starts_at TIMESTAMP WITH TIME ZONE,
+	ends_at TIMESTAMP WITH TIME ZONE,
+	created_by TEXT,
+	comment TEXT,
+	created_at TIMESTAMP WITH TIME ZONE
+);
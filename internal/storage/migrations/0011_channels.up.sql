@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS channels (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	type TEXT,
+	config JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+	id TEXT PRIMARY KEY,
+	channel_id TEXT,
+	alert_id TEXT,
+	attempts INTEGER,
+	error TEXT,
+	failed_at TIMESTAMP WITH TIME ZONE
+);
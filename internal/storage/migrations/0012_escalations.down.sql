@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS escalations;
+DROP TABLE IF EXISTS escalation_policies;
+DROP TABLE IF EXISTS schedules;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS schedules (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	config JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS escalation_policies (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	config JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS escalations (
+	alert_id TEXT PRIMARY KEY,
+	policy_id TEXT,
+	next_tier INTEGER,
+	due_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS response_actions;
+ALTER TABLE reports DROP COLUMN IF EXISTS after_state;
+ALTER TABLE reports DROP COLUMN IF EXISTS before_state;
+ALTER TABLE reports DROP COLUMN IF EXISTS result;
+ALTER TABLE reports DROP COLUMN IF EXISTS action_id;
+ALTER TABLE policies DROP COLUMN IF EXISTS auto_respond;
+ALTER TABLE policies DROP COLUMN IF EXISTS responses;
@@ -0,0 +1,14 @@
+ALTER TABLE policies ADD COLUMN IF NOT EXISTS responses JSONB;
+ALTER TABLE policies ADD COLUMN IF NOT EXISTS auto_respond BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS action_id TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS result TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS before_state TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS after_state TEXT;
+
+CREATE TABLE IF NOT EXISTS response_actions (
+	id TEXT PRIMARY KEY,
+	cluster_id TEXT,
+	status TEXT,
+	config JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
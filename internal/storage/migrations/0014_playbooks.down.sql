@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS playbook_runs;
+DROP TABLE IF EXISTS playbooks;
+ALTER TABLE policies DROP COLUMN IF EXISTS playbooks;
+ALTER TABLE policies DROP COLUMN IF EXISTS severity;
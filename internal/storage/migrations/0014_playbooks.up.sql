@@ -0,0 +1,18 @@
+ALTER TABLE policies ADD COLUMN IF NOT EXISTS severity TEXT;
+ALTER TABLE policies ADD COLUMN IF NOT EXISTS playbooks JSONB;
+
+CREATE TABLE IF NOT EXISTS playbooks (
+	id TEXT PRIMARY KEY,
+	name TEXT,
+	config JSONB,
+	created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS playbook_runs (
+	id TEXT PRIMARY KEY,
+	playbook_id TEXT,
+	status TEXT,
+	config JSONB,
+	started_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS playbook_runs_playbook_idx ON playbook_runs (playbook_id, started_at);
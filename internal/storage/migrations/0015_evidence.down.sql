@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS evidence_bundles;
+DROP TABLE IF EXISTS evidence_blobs;
+ALTER TABLE reports DROP COLUMN IF EXISTS evidence_id;
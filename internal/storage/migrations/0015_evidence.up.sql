@@ -0,0 +1,13 @@
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS evidence_id TEXT;
+
+CREATE TABLE IF NOT EXISTS evidence_blobs (
+	sha256 TEXT PRIMARY KEY,
+	data BYTEA
+);
+
+CREATE TABLE IF NOT EXISTS evidence_bundles (
+	id TEXT PRIMARY KEY,
+	alert_id TEXT,
+	config JSONB,
+	collected_at TIMESTAMP WITH TIME ZONE
+);
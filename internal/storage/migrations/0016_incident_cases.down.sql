@@ -0,0 +1,8 @@
+ALTER TABLE incidents DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE incidents DROP COLUMN IF EXISTS post_mortem;
+ALTER TABLE incidents DROP COLUMN IF EXISTS evidence_ids;
+ALTER TABLE incidents DROP COLUMN IF EXISTS timeline;
+ALTER TABLE incidents DROP COLUMN IF EXISTS created_by;
+ALTER TABLE incidents DROP COLUMN IF EXISTS owner;
+ALTER TABLE incidents DROP COLUMN IF EXISTS status;
+ALTER TABLE incidents DROP COLUMN IF EXISTS title;
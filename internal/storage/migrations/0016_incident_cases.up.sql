@@ -0,0 +1,8 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS owner TEXT;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS created_by TEXT;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS timeline JSONB;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS evidence_ids JSONB;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS post_mortem JSONB;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS reports_timestamp_idx;
+DROP INDEX IF EXISTS alerts_incident_idx;
+DROP INDEX IF EXISTS alerts_cluster_timestamp_idx;
+DROP INDEX IF EXISTS alerts_timestamp_idx;
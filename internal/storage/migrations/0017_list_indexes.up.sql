@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS alerts_timestamp_idx ON alerts (timestamp, id);
+CREATE INDEX IF NOT EXISTS alerts_cluster_timestamp_idx ON alerts (cluster_id, timestamp);
+CREATE INDEX IF NOT EXISTS alerts_incident_idx ON alerts (incident_id);
+CREATE INDEX IF NOT EXISTS reports_timestamp_idx ON reports (timestamp, id);
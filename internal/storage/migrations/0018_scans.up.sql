@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS scans (
+	cluster_id TEXT,
+	kind TEXT,
+	scanned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (cluster_id, kind)
+);
+
+-- clusters with stored findings were scanned when they were found
+INSERT INTO scans (cluster_id, kind, scanned_at)
+	SELECT cluster_id, 'pss', MAX(scanned_at) FROM pss_findings WHERE scanned_at IS NOT NULL GROUP BY cluster_id
+	ON CONFLICT (cluster_id, kind) DO NOTHING;
+INSERT INTO scans (cluster_id, kind, scanned_at)
+	SELECT cluster_id, 'images', MAX(detected_at) FROM image_findings WHERE detected_at IS NOT NULL GROUP BY cluster_id
+	ON CONFLICT (cluster_id, kind) DO NOTHING;
+INSERT INTO scans (cluster_id, kind, scanned_at)
+	SELECT cluster_id, 'secrets', MAX(detected_at) FROM secret_findings WHERE detected_at IS NOT NULL GROUP BY cluster_id
+	ON CONFLICT (cluster_id, kind) DO NOTHING;
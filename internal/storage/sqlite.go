@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteDialect adapts SQL written for PostgreSQL to SQLite, which has no
+// IF [NOT] EXISTS on columns and only maps plain TIMESTAMP columns to time.Time
+var sqliteDialect = strings.NewReplacer(
+	"ADD COLUMN IF NOT EXISTS", "ADD COLUMN",
+	"DROP COLUMN IF EXISTS", "DROP COLUMN",
+	"TIMESTAMP WITH TIME ZONE", "TIMESTAMP",
+)
+
+// NewSQLiteStorage opens, creating it if needed, the SQLite database at path
+// and applies pending migrations. It needs no external services, which suits
+// small installations and CI.
+func NewSQLiteStorage(path string) (*DatabaseStorage, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabaseStorage(db)
+}
+
+// OpenSQLite opens the SQLite database at path. It uses a single connection,
+// so transactions run one at a time instead of failing with SQLITE_BUSY.
+// Times are written in UTC in SQLite's own format, which sorts as text, so
+// time ranges and list cursors compare them in SQL.
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_time_format=sqlite&_timezone=UTC")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func isSQLite(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite.Driver)
+	return ok
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
@@ -0,0 +1,20 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+	"KubernetesSecurityMonitoringSystem/internal/storage/storagetest"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "ksms.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
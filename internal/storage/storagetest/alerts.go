@@ -0,0 +1,217 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// alert returns a crashloop alert of pod web in namespace ns of cluster c1
+// raised n minutes after base
+func alert(id string, n int) models.Alert {
+	return models.Alert{ID: id, ClusterID: "c1", Rule: "crashloop", Namespace: "ns", Resource: "Pod ns/web",
+		Severity: models.SeverityMedium, Message: "back-off restarting", Timestamp: at(n)}
+}
+
+// testAlerts checks that repeats fold into the open alert with the same
+// fingerprint and that alerts of one rule are grouped into an incident
+func testAlerts(t *testing.T, s storage.Storage) {
+	a, created, err := s.UpsertAlert(alert("a1", 0))
+	must(t, err, "UpsertAlert")
+	if !created || a.Status != models.AlertOpen || a.Count != 1 || a.IncidentID != "inc-a1" {
+		t.Errorf("first UpsertAlert = %+v, created %v", a, created)
+	}
+
+	repeat := alert("a2", 1)
+	repeat.Severity, repeat.Message = models.SeverityHigh, "still restarting"
+	a, created, err = s.UpsertAlert(repeat)
+	must(t, err, "UpsertAlert of a repeat")
+	if created || a.ID != "a1" || a.Count != 2 || a.Severity != models.SeverityHigh || a.Message != "still restarting" {
+		t.Errorf("UpsertAlert of a repeat = %+v, created %v", a, created)
+	}
+	wantTime(t, a.FirstSeen, at(0), "FirstSeen")
+	wantTime(t, a.LastSeen, at(1), "LastSeen")
+
+	other := alert("a3", 2)
+	other.Resource = "Pod ns/db"
+	a, created, err = s.UpsertAlert(other)
+	must(t, err, "UpsertAlert of another pod")
+	if !created || a.IncidentID != "inc-a1" {
+		t.Errorf("UpsertAlert of another pod = %+v, created %v", a, created)
+	}
+	inc, err := s.GetIncident("inc-a1")
+	must(t, err, "GetIncident")
+	if inc.AlertCount != 2 || inc.Severity != models.SeverityHigh {
+		t.Errorf("incident = %+v, want 2 alerts of high severity", inc)
+	}
+	wantTime(t, inc.LastSeen, at(2), "incident LastSeen")
+
+	wantIDs(t, all(t, s.GetAlerts), func(a models.Alert) string { return a.ID }, "a3", "a1")
+	got, err := s.GetAlert("a1")
+	must(t, err, "GetAlert")
+	if got.Count != 2 || got.Fingerprint == "" {
+		t.Errorf("GetAlert = %+v", got)
+	}
+	_, err = s.GetAlert("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetAlert")
+
+	a, err = s.TransitionAlert("a1", models.AlertTransition{To: models.AlertAcknowledged, Assignee: "u1", By: "u1", At: at(3)})
+	must(t, err, "TransitionAlert")
+	if a.Status != models.AlertAcknowledged || a.Assignee != "u1" || len(a.History) != 1 || a.History[0].From != models.AlertOpen {
+		t.Errorf("TransitionAlert = %+v", a)
+	}
+	_, err = s.TransitionAlert("a1", models.AlertTransition{To: models.AlertAcknowledged, By: "u1", At: at(4)})
+	wantErr(t, err, storage.ErrConflict, "TransitionAlert to the same status")
+	_, err = s.TransitionAlert("missing", models.AlertTransition{To: models.AlertResolved, By: "u1", At: at(4)})
+	wantErr(t, err, storage.ErrNotFound, "TransitionAlert")
+
+	_, err = s.TransitionAlert("a1", models.AlertTransition{To: models.AlertResolved, Note: "fixed", By: "u1", At: at(5)})
+	must(t, err, "TransitionAlert to resolved")
+	got, _ = s.GetAlert("a1")
+	if got.Status != models.AlertResolved || got.Resolution != "fixed" || len(got.History) != 2 {
+		t.Errorf("resolved alert = %+v", got)
+	}
+
+	a, created, err = s.UpsertAlert(alert("a4", 6))
+	must(t, err, "UpsertAlert after resolving")
+	if !created || a.ID != "a4" || a.Count != 1 {
+		t.Errorf("UpsertAlert after resolving = %+v, created %v, want a new alert", a, created)
+	}
+	if got, _ := s.GetAlert("a1"); got.Count != 2 {
+		t.Errorf("resolved alert was folded into: count %d", got.Count)
+	}
+
+	must(t, s.AddAlert(alert("a5", 7)), "AddAlert of a repeat")
+	if got, _ := s.GetAlert("a4"); got.Count != 2 {
+		t.Errorf("AddAlert of a repeat left a4 at count %d, want 2", got.Count)
+	}
+}
+
+// testIncidents checks incidents opened by hand and user changes to them
+func testIncidents(t *testing.T, s storage.Storage) {
+	for i, id := range []string{"a1", "a2"} {
+		a := alert(id, i)
+		a.Resource = "Pod ns/" + id
+		_, _, err := s.UpsertAlert(a)
+		must(t, err, "UpsertAlert")
+	}
+	_, err := s.GetIncident("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetIncident")
+
+	a2, _ := s.GetAlert("a2")
+	inc := models.OpenIncident("i1", "web outage", "u1", []models.Alert{a2})
+	must(t, s.CreateIncident(inc, []string{"a2"}), "CreateIncident")
+	if a, _ := s.GetAlert("a2"); a.IncidentID != "i1" {
+		t.Errorf("alert moved to incident %q, want i1", a.IncidentID)
+	}
+	if old, _ := s.GetIncident("inc-a1"); old.AlertCount != 1 {
+		t.Errorf("incident left by the alert has %d alerts, want 1", old.AlertCount)
+	}
+	got, err := s.GetIncident("i1")
+	must(t, err, "GetIncident")
+	if got.Title != "web outage" || got.CreatedBy != "u1" || got.AlertCount != 1 || got.Status != models.IncidentOpen {
+		t.Errorf("GetIncident = %+v", got)
+	}
+
+	wantErr(t, s.CreateIncident(models.Incident{ID: "i1", Status: models.IncidentOpen, FirstSeen: at(0), LastSeen: at(0)}, nil),
+		storage.ErrConflict, "CreateIncident with a taken ID")
+	wantErr(t, s.CreateIncident(models.Incident{ID: "i2", Status: models.IncidentOpen, FirstSeen: at(0), LastSeen: at(0)}, []string{"a1", "missing"}),
+		storage.ErrNotFound, "CreateIncident with a missing alert")
+	_, err = s.GetIncident("i2")
+	wantErr(t, err, storage.ErrNotFound, "GetIncident of an incident that failed to be created")
+	if a, _ := s.GetAlert("a1"); a.IncidentID != "inc-a1" {
+		t.Errorf("alert of an incident that failed to be created moved to %q", a.IncidentID)
+	}
+
+	got, err = s.UpdateIncident("i1", models.IncidentUpdate{Owner: "u2", Comment: "looking", By: "u1", At: at(3)})
+	must(t, err, "UpdateIncident")
+	if got.Owner != "u2" || len(got.Timeline) != 2 {
+		t.Errorf("UpdateIncident = %+v", got)
+	}
+	got, err = s.UpdateIncident("i1", models.IncidentUpdate{Status: models.IncidentClosed, By: "u2", At: at(4)})
+	must(t, err, "UpdateIncident to closed")
+	if got.ClosedAt == nil {
+		t.Fatalf("closed incident has no ClosedAt")
+	}
+	got, _ = s.GetIncident("i1")
+	if got.Status != models.IncidentClosed || got.Owner != "u2" || len(got.Timeline) != 3 || got.ClosedAt == nil {
+		t.Fatalf("closed incident = %+v", got)
+	}
+	wantTime(t, *got.ClosedAt, at(4), "ClosedAt")
+	_, err = s.UpdateIncident("i1", models.IncidentUpdate{Status: models.IncidentClosed, By: "u2", At: at(5)})
+	wantErr(t, err, storage.ErrConflict, "UpdateIncident closing twice")
+	_, err = s.UpdateIncident("missing", models.IncidentUpdate{Comment: "hello", By: "u1", At: at(5)})
+	wantErr(t, err, storage.ErrNotFound, "UpdateIncident")
+
+	repeat := alert("a3", 6)
+	repeat.Resource = "Pod ns/a1"
+	_, _, err = s.UpsertAlert(repeat)
+	must(t, err, "UpsertAlert of a repeat")
+	wantIDs(t, all(t, s.GetIncidents), func(inc models.Incident) string { return inc.ID }, "inc-a1", "i1")
+}
+
+// testArchival checks that alerts and reports deleted in bulk can be restored
+// as they were, and that neither counts IDs it does not act on
+func testArchival(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for i, id := range []string{"a1", "a2", "a3"} {
+		a := alert(id, i)
+		a.Fingerprint = id
+		_, _, err := s.UpsertAlert(a)
+		must(t, err, "UpsertAlert")
+	}
+	_, err := s.TransitionAlert("a1", models.AlertTransition{To: models.AlertAcknowledged, By: "u1", At: at(5)})
+	must(t, err, "TransitionAlert")
+	archived := all(t, s.GetAlerts)
+
+	n, err := s.DeleteAlerts(ctx, []string{"a1", "a3", "missing"})
+	must(t, err, "DeleteAlerts")
+	if n != 2 {
+		t.Errorf("DeleteAlerts deleted %d alerts, want 2", n)
+	}
+	wantIDs(t, all(t, s.GetAlerts), func(a models.Alert) string { return a.ID }, "a2")
+	_, err = s.GetAlert("a1")
+	wantErr(t, err, storage.ErrNotFound, "GetAlert after DeleteAlerts")
+
+	n, err = s.RestoreAlerts(ctx, archived)
+	must(t, err, "RestoreAlerts")
+	if n != 2 {
+		t.Errorf("RestoreAlerts stored %d alerts, want 2", n)
+	}
+	wantIDs(t, all(t, s.GetAlerts), func(a models.Alert) string { return a.ID }, "a3", "a2", "a1")
+	a1, err := s.GetAlert("a1")
+	must(t, err, "GetAlert after RestoreAlerts")
+	if a1.Status != models.AlertAcknowledged || len(a1.History) != 1 || a1.IncidentID != archived[2].IncidentID {
+		t.Errorf("restored alert = %+v, want %+v", a1, archived[2])
+	}
+	wantTime(t, a1.FirstSeen, at(0), "restored FirstSeen")
+
+	// the latest alert with a fingerprint still takes its repeats
+	repeat := alert("a4", 6)
+	repeat.Fingerprint = "a3"
+	a, created, err := s.UpsertAlert(repeat)
+	must(t, err, "UpsertAlert of a repeat")
+	if created || a.ID != "a3" || a.Count != 2 {
+		t.Errorf("UpsertAlert of a repeat of a restored alert = %+v, created %v", a, created)
+	}
+
+	must(t, s.AddReport(models.IncidentReport{ID: "r1", AlertID: "a1", Details: "first", Action: "delete-pod", Timestamp: at(1)}), "AddReport")
+	must(t, s.AddReport(models.IncidentReport{ID: "r2", AlertID: "a2", Details: "second", Action: "delete-pod", Result: "done", Timestamp: at(2)}), "AddReport")
+	reports := all(t, s.GetReports)
+	n, err = s.DeleteReports(ctx, []string{"r2"})
+	must(t, err, "DeleteReports")
+	if n != 1 {
+		t.Errorf("DeleteReports deleted %d reports, want 1", n)
+	}
+	wantIDs(t, all(t, s.GetReports), func(r models.IncidentReport) string { return r.ID }, "r1")
+	n, err = s.RestoreReports(ctx, reports)
+	must(t, err, "RestoreReports")
+	if n != 1 {
+		t.Errorf("RestoreReports stored %d reports, want 1", n)
+	}
+	if got := all(t, s.GetReports); len(got) != 2 || got[0].ID != "r2" || got[0].Result != "done" {
+		t.Errorf("reports after RestoreReports = %+v", got)
+	}
+}
@@ -0,0 +1,169 @@
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// parallel calls fn n times at once and waits for all calls to return
+func parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// testConcurrency checks that concurrent writers neither lose records nor
+// get past the checks that keep IDs unique and transitions valid
+func testConcurrency(t *testing.T, s storage.Storage) {
+	const n = 10
+
+	parallel(n, func(i int) {
+		u := models.User{ID: fmt.Sprintf("u%02d", i), Email: fmt.Sprintf("user%02d@example.com", i), Role: models.RoleSecurityAnalyst, CreatedAt: at(i)}
+		if err := s.AddUser(u); err != nil {
+			t.Errorf("AddUser %s: %v", u.ID, err)
+		}
+	})
+	if users := all(t, s.GetAllUsers); len(users) != n {
+		t.Errorf("got %d users after %d concurrent AddUser, want %d", len(users), n, n)
+	}
+
+	var added, conflicts atomic.Int32
+	parallel(n, func(i int) {
+		err := s.AddCluster(models.Cluster{ID: "c1", Name: fmt.Sprintf("cluster %d", i), CreatedAt: at(i)})
+		switch {
+		case err == nil:
+			added.Add(1)
+		case errors.Is(err, storage.ErrConflict):
+			conflicts.Add(1)
+		default:
+			t.Errorf("AddCluster: %v", err)
+		}
+	})
+	if added.Load() != 1 || conflicts.Load() != n-1 {
+		t.Errorf("concurrent AddCluster of one ID: %d added and %d conflicts, want 1 and %d", added.Load(), conflicts.Load(), n-1)
+	}
+
+	var created atomic.Int32
+	parallel(n, func(i int) {
+		_, ok, err := s.UpsertAlert(alert(fmt.Sprintf("a%02d", i), 0))
+		if err != nil {
+			t.Errorf("UpsertAlert: %v", err)
+		}
+		if ok {
+			created.Add(1)
+		}
+	})
+	alerts := all(t, s.GetAlerts)
+	if created.Load() != 1 || len(alerts) != 1 || alerts[0].Count != n {
+		t.Fatalf("concurrent UpsertAlert of one alert: %d created, %d stored, want 1 alert counted %d times", created.Load(), len(alerts), n)
+	}
+	if inc, err := s.GetIncident(alerts[0].IncidentID); err != nil || inc.AlertCount != 1 {
+		t.Errorf("incident of the alert = %+v, %v, want 1 alert", inc, err)
+	}
+
+	var moved atomic.Int32
+	parallel(n, func(i int) {
+		_, err := s.TransitionAlert(alerts[0].ID, models.AlertTransition{To: models.AlertAcknowledged, By: fmt.Sprintf("u%02d", i), At: at(1)})
+		switch {
+		case err == nil:
+			moved.Add(1)
+		case !errors.Is(err, storage.ErrConflict):
+			t.Errorf("TransitionAlert: %v", err)
+		}
+	})
+	if moved.Load() != 1 {
+		t.Errorf("%d of %d concurrent identical transitions succeeded, want 1", moved.Load(), n)
+	}
+	if a, _ := s.GetAlert(alerts[0].ID); len(a.History) != 1 {
+		t.Errorf("alert history has %d entries, want 1", len(a.History))
+	}
+
+	must(t, s.AddResponseAction(models.ResponseAction{ID: "ra1", ClusterID: "c1", Type: models.ActionDeletePod, Kind: "Pod", Namespace: "ns", Name: "web",
+		Status: models.ActionPending, RequestedBy: "u1", CreatedAt: at(1)}), "AddResponseAction")
+	var decided atomic.Int32
+	parallel(n, func(i int) {
+		err := s.DecideResponseAction(models.ResponseAction{ID: "ra1", Status: models.ActionRunning, DecidedBy: fmt.Sprintf("u%02d", i), DecidedAt: at(2)})
+		switch {
+		case err == nil:
+			decided.Add(1)
+		case !errors.Is(err, storage.ErrConflict):
+			t.Errorf("DecideResponseAction: %v", err)
+		}
+	})
+	if decided.Load() != 1 {
+		t.Errorf("%d of %d concurrent decisions on one action succeeded, want 1", decided.Load(), n)
+	}
+
+	versions := make([]bool, n+1)
+	var mu sync.Mutex
+	parallel(n, func(i int) {
+		run, err := s.AddCISRun(models.CISRun{ID: fmt.Sprintf("run%02d", i), ClusterID: "c1", Benchmark: "cis-1.8", StartedAt: at(i)})
+		if err != nil {
+			t.Errorf("AddCISRun: %v", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if run.Version < 1 || run.Version > n || versions[run.Version] {
+			t.Errorf("AddCISRun returned version %d twice or out of 1..%d", run.Version, n)
+			return
+		}
+		versions[run.Version] = true
+	})
+	if runs := s.GetCISRuns("c1"); len(runs) != n {
+		t.Errorf("got %d CIS runs after %d concurrent AddCISRun, want %d", len(runs), n, n)
+	}
+}
+
+// testIsolation checks that values read from or given to a storage share no
+// memory with it, so callers may modify them freely
+func testIsolation(t *testing.T, s storage.Storage) {
+	must(t, s.AddCluster(models.Cluster{ID: "c1", Name: "prod", CreatedAt: at(0)}), "AddCluster")
+	all(t, s.GetClusters)[0].Name = "changed"
+	if c, _ := s.GetCluster("c1"); c.Name != "prod" {
+		t.Errorf("changing a listed cluster changed the stored one: %q", c.Name)
+	}
+
+	_, _, err := s.UpsertAlert(alert("a1", 0))
+	must(t, err, "UpsertAlert")
+	_, err = s.TransitionAlert("a1", models.AlertTransition{To: models.AlertAcknowledged, By: "u1", At: at(1)})
+	must(t, err, "TransitionAlert")
+	listed := all(t, s.GetAlerts)
+	listed[0].Status = models.AlertResolved
+	if a, _ := s.GetAlert("a1"); a.Status != models.AlertAcknowledged {
+		t.Errorf("changing a listed alert changed the stored one: %+v", a)
+	}
+
+	must(t, s.AddReport(models.IncidentReport{ID: "r1", AlertID: "a1", Details: "stored", Timestamp: at(0)}), "AddReport")
+	all(t, s.GetReports)[0].Details = "changed"
+	if r := all(t, s.GetReports); r[0].Details != "stored" {
+		t.Errorf("changing a listed report changed the stored one: %q", r[0].Details)
+	}
+
+	findings := []models.PSSFinding{{ClusterID: "c1", Kind: "Pod", Namespace: "ns", Name: "web", Level: models.PSSBaseline, ScannedAt: at(0)}}
+	must(t, s.SavePSSFindings("c1", findings), "SavePSSFindings")
+	findings[0].Level = models.PSSPrivileged
+	s.GetPSSFindings("c1")[0].Name = "changed"
+	if f := s.GetPSSFindings("c1"); f[0].Level != models.PSSBaseline || f[0].Name != "web" {
+		t.Errorf("changing saved or listed findings changed the stored ones: %+v", f[0])
+	}
+
+	_, err = s.AddCISRun(models.CISRun{ID: "run1", ClusterID: "c1", Benchmark: "cis-1.8", StartedAt: at(0)})
+	must(t, err, "AddCISRun")
+	s.GetCISRuns("c1")[0].Benchmark = "changed"
+	if r, _ := s.GetCISRun("c1", 1); r.Benchmark != "cis-1.8" {
+		t.Errorf("changing a listed CIS run changed the stored one: %q", r.Benchmark)
+	}
+}
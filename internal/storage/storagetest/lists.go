@@ -0,0 +1,213 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// testLists checks that list options filter, sort and page every list the same
+// way, that pages follow each other without gaps or repeats, and that options
+// which cannot be used are refused with storage.ErrInvalidQuery
+func testLists(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	alertID := func(a models.Alert) string { return a.ID }
+	for _, a := range []models.Alert{
+		{ID: "a1", ClusterID: "c1", Rule: "crashloop", Namespace: "web", Severity: models.SeverityHigh, Message: "Back-off restarting", Timestamp: at(1)},
+		{ID: "a2", ClusterID: "c2", Rule: "privileged", Namespace: "web", Severity: models.SeverityMedium, Message: "container has host network", Timestamp: at(2)},
+		{ID: "a3", ClusterID: "c1", Rule: "privileged", Namespace: "db", Severity: models.SeverityHigh, Message: "run_as_root", Timestamp: at(3)},
+		{ID: "a4", ClusterID: "c2", Rule: "crashloop", Namespace: "db", Severity: models.SeverityLow, Message: "OOM killed", Timestamp: at(4)},
+		{ID: "a5", ClusterID: "c1", Rule: "exec", Namespace: "web", Severity: models.SeverityLow, Message: "shell in PRIVILEGED pod", Timestamp: at(4)},
+	} {
+		a.Fingerprint = a.ID
+		_, _, err := s.UpsertAlert(a)
+		must(t, err, "UpsertAlert "+a.ID)
+	}
+
+	// alerts at the same time are ordered by ID
+	var pages [][]string
+	opts := storage.ListOptions{Limit: 2}
+	for {
+		page, err := s.GetAlerts(ctx, opts)
+		must(t, err, "GetAlerts")
+		var ids []string
+		for _, a := range page.Items {
+			ids = append(ids, a.ID)
+		}
+		pages = append(pages, ids)
+		if page.NextCursor == "" || len(pages) > 3 {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if len(pages) != 3 || len(pages[0]) != 2 || pages[0][0] != "a5" || pages[1][1] != "a2" || len(pages[2]) != 1 || pages[2][0] != "a1" {
+		t.Errorf("pages of 2 alerts = %v, want [[a5 a4] [a3 a2] [a1]]", pages)
+	}
+	oldest, err := storage.All(ctx, s.GetAlerts, storage.ListOptions{Order: storage.Oldest})
+	must(t, err, "GetAlerts oldest first")
+	wantIDs(t, oldest, alertID, "a1", "a2", "a3", "a4", "a5")
+
+	alerts := func(opts storage.ListOptions) []models.Alert {
+		t.Helper()
+		page, err := s.GetAlerts(ctx, opts)
+		must(t, err, "GetAlerts")
+		if page.NextCursor != "" {
+			t.Errorf("GetAlerts(%+v) has a next page", opts)
+		}
+		return page.Items
+	}
+	wantIDs(t, alerts(storage.ListOptions{ClusterID: "c2"}), alertID, "a4", "a2")
+	wantIDs(t, alerts(storage.ListOptions{Severity: models.SeverityHigh}), alertID, "a3", "a1")
+	wantIDs(t, alerts(storage.ListOptions{Namespace: "db", Order: storage.Oldest}), alertID, "a3", "a4")
+	wantIDs(t, alerts(storage.ListOptions{ClusterID: "c1", Namespace: "web"}), alertID, "a5", "a1")
+	wantIDs(t, alerts(storage.ListOptions{Text: "privileged"}), alertID, "a5", "a3", "a2")
+	wantIDs(t, alerts(storage.ListOptions{Text: "_as"}), alertID, "a3")
+	wantIDs(t, alerts(storage.ListOptions{Text: "%"}), alertID)
+	wantIDs(t, alerts(storage.ListOptions{Since: at(2), Until: at(4)}), alertID, "a3", "a2")
+	a3, err := s.GetAlert("a3")
+	must(t, err, "GetAlert")
+	wantIDs(t, alerts(storage.ListOptions{IncidentID: a3.IncidentID}), alertID, "a3")
+
+	for _, opts := range []storage.ListOptions{
+		{Cursor: "not a cursor"},
+		{Order: "sideways"},
+		{Limit: -1},
+	} {
+		_, err := s.GetAlerts(ctx, opts)
+		wantErr(t, err, storage.ErrInvalidQuery, "GetAlerts with bad options")
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.GetAlerts(cancelled, storage.ListOptions{}); err == nil {
+		t.Error("GetAlerts with a cancelled context succeeded")
+	}
+
+	for _, p := range []models.Policy{
+		{ID: "p1", Name: "no-latest", Description: "Images must be pinned", Namespace: "web", Severity: models.SeverityHigh, CreatedAt: at(1)},
+		{ID: "p2", Name: "no-privileged", Namespace: "db", Severity: models.SeverityHigh, CreatedAt: at(2)},
+		{ID: "p3", Name: "registries", Namespace: "web", CreatedAt: at(3)},
+	} {
+		must(t, s.AddPolicy(p), "AddPolicy "+p.ID)
+	}
+	policies := func(opts storage.ListOptions) []models.Policy {
+		t.Helper()
+		page, err := s.GetPolicies(ctx, opts)
+		must(t, err, "GetPolicies")
+		return page.Items
+	}
+	policyID := func(p models.Policy) string { return p.ID }
+	wantIDs(t, policies(storage.ListOptions{Severity: models.SeverityHigh, Namespace: "web"}), policyID, "p1")
+	wantIDs(t, policies(storage.ListOptions{Text: "PINNED"}), policyID, "p1")
+	wantIDs(t, policies(storage.ListOptions{Order: storage.Newest, Limit: 2}), policyID, "p3", "p2")
+
+	for i, email := range []string{"alice@example.com", "bob@example.com", "carol@example.org"} {
+		must(t, s.AddUser(models.User{ID: email[:1], Email: email, Role: models.RoleSecurityAnalyst, CreatedAt: at(i)}), "AddUser")
+	}
+	users, err := s.GetAllUsers(ctx, storage.ListOptions{Text: "example.com"})
+	must(t, err, "GetAllUsers")
+	wantIDs(t, users.Items, func(u models.User) string { return u.ID }, "a", "b")
+
+	must(t, s.AddCluster(models.Cluster{ID: "c1", Name: "Production", CreatedAt: at(1)}), "AddCluster")
+	must(t, s.AddCluster(models.Cluster{ID: "c2", Name: "staging", CreatedAt: at(2)}), "AddCluster")
+	clusters, err := s.GetClusters(ctx, storage.ListOptions{Text: "prod"})
+	must(t, err, "GetClusters")
+	wantIDs(t, clusters.Items, func(c models.Cluster) string { return c.ID }, "c1")
+
+	must(t, s.AddReport(models.IncidentReport{ID: "r1", AlertID: "a1", Details: "pod deleted", Action: "delete-pod", Timestamp: at(1)}), "AddReport")
+	must(t, s.AddReport(models.IncidentReport{ID: "r2", AlertID: "a2", Details: "evidence captured", Action: "capture-evidence", Timestamp: at(2)}), "AddReport")
+	must(t, s.AddReport(models.IncidentReport{ID: "r3", AlertID: "a3", Details: "pod deleted", Action: "delete-pod", Timestamp: at(3)}), "AddReport")
+	reports, err := s.GetReports(ctx, storage.ListOptions{Text: "delete", Since: at(2)})
+	must(t, err, "GetReports")
+	wantIDs(t, reports.Items, func(r models.IncidentReport) string { return r.ID }, "r3")
+
+	// every alert above opened its own incident
+	incidentID := func(inc models.Incident) string { return inc.ID }
+	_, err = s.UpdateIncident(a3.IncidentID, models.IncidentUpdate{Owner: "u1", Status: models.IncidentInvestigating, By: "u1", At: at(5)})
+	must(t, err, "UpdateIncident")
+	incidents, err := s.GetIncidents(ctx, storage.ListOptions{ClusterID: "c1", Severity: models.SeverityHigh})
+	must(t, err, "GetIncidents")
+	wantIDs(t, incidents.Items, incidentID, a3.IncidentID, "inc-a1")
+	incidents, err = s.GetIncidents(ctx, storage.ListOptions{Status: models.IncidentInvestigating, Owner: "u1"})
+	must(t, err, "GetIncidents by status and owner")
+	wantIDs(t, incidents.Items, incidentID, a3.IncidentID)
+	incidents, err = s.GetIncidents(ctx, storage.ListOptions{Namespace: "web", Limit: 2})
+	must(t, err, "GetIncidents")
+	if len(incidents.Items) != 2 || incidents.NextCursor == "" {
+		t.Errorf("first page of 2 of 3 incidents in web = %+v", incidents)
+	}
+
+	now := time.Now()
+	for _, sl := range []models.Silence{
+		{ID: "s1", Namespace: "web", Comment: "Upgrade", StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)},
+		{ID: "s2", Namespace: "web", Comment: "node drain", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		{ID: "s3", ClusterID: "c1", Comment: "upgrade", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+	} {
+		must(t, s.AddSilence(sl), "AddSilence "+sl.ID)
+	}
+	silenceID := func(sl models.Silence) string { return sl.ID }
+	for status, want := range map[string][]string{
+		models.SilenceExpired: {"s1"},
+		models.SilenceActive:  {"s2"},
+		models.SilencePending: {"s3"},
+		"":                    {"s3", "s2", "s1"},
+	} {
+		silences, err := s.GetSilences(ctx, storage.ListOptions{Status: status})
+		must(t, err, "GetSilences "+status)
+		wantIDs(t, silences.Items, silenceID, want...)
+	}
+	silences, err := s.GetSilences(ctx, storage.ListOptions{Namespace: "web", Text: "upgrade"})
+	must(t, err, "GetSilences")
+	wantIDs(t, silences.Items, silenceID, "s1")
+	_, err = s.GetSilences(ctx, storage.ListOptions{Status: "snoozed"})
+	wantErr(t, err, storage.ErrInvalidQuery, "GetSilences with an unknown state")
+
+	for i, status := range []string{models.ActionPending, models.ActionSucceeded, models.ActionPending} {
+		must(t, s.AddResponseAction(models.ResponseAction{ID: fmt.Sprintf("ra%d", i+1), ClusterID: fmt.Sprintf("c%d", i%2+1), Type: models.ActionDeletePod,
+			Kind: "Pod", Namespace: "web", Name: "api", Status: status, CreatedAt: at(i)}), "AddResponseAction")
+	}
+	actions, err := s.GetResponseActions(ctx, storage.ListOptions{Status: models.ActionPending})
+	must(t, err, "GetResponseActions")
+	wantIDs(t, actions.Items, func(a models.ResponseAction) string { return a.ID }, "ra3", "ra1")
+	actions, err = s.GetResponseActions(ctx, storage.ListOptions{ClusterID: "c2"})
+	must(t, err, "GetResponseActions")
+	wantIDs(t, actions.Items, func(a models.ResponseAction) string { return a.ID }, "ra2")
+
+	for i, alertID := range []string{"a1", "a2", "a1"} {
+		id := fmt.Sprintf("e%d", i+1)
+		must(t, s.SaveEvidence(models.EvidenceBundle{ID: id, ClusterID: "c1", AlertID: alertID, CollectedAt: at(i)}, nil), "SaveEvidence")
+		must(t, s.AddDeadLetter(models.DeadLetter{ID: fmt.Sprintf("d%d", i+1), ChannelID: "ch1", AlertID: alertID, Error: "503 from " + id, FailedAt: at(i)}), "AddDeadLetter")
+	}
+	bundles, err := s.GetEvidenceBundles(ctx, storage.ListOptions{AlertID: "a1", Order: storage.Oldest})
+	must(t, err, "GetEvidenceBundles")
+	wantIDs(t, bundles.Items, func(b models.EvidenceBundle) string { return b.ID }, "e1", "e3")
+	deadLetterID := func(d models.DeadLetter) string { return d.ID }
+	dead, err := s.GetDeadLetters(ctx, storage.ListOptions{AlertID: "a1", Limit: 1})
+	must(t, err, "GetDeadLetters")
+	wantIDs(t, dead.Items, deadLetterID, "d3")
+	dead, err = s.GetDeadLetters(ctx, storage.ListOptions{AlertID: "a1", Cursor: dead.NextCursor})
+	must(t, err, "GetDeadLetters next page")
+	wantIDs(t, dead.Items, deadLetterID, "d1")
+	dead, err = s.GetDeadLetters(ctx, storage.ListOptions{Text: "E2"})
+	must(t, err, "GetDeadLetters")
+	wantIDs(t, dead.Items, deadLetterID, "d2")
+
+	must(t, s.AddChannel(models.Channel{ID: "ch1", Name: "SOC mail", Type: models.ChannelSMTP, CreatedAt: at(1)}), "AddChannel")
+	must(t, s.AddChannel(models.Channel{ID: "ch2", Name: "ops hook", Type: models.ChannelWebhook, CreatedAt: at(2)}), "AddChannel")
+	channels, err := s.GetChannels(ctx, storage.ListOptions{Text: "soc"})
+	must(t, err, "GetChannels")
+	wantIDs(t, channels.Items, func(c models.Channel) string { return c.ID }, "ch1")
+	must(t, s.AddSchedule(models.Schedule{ID: "sc1", Name: "primary", Rotation: []string{"u1"}, CreatedAt: at(1)}), "AddSchedule")
+	must(t, s.AddSchedule(models.Schedule{ID: "sc2", Name: "secondary", Rotation: []string{"u2"}, CreatedAt: at(2)}), "AddSchedule")
+	schedules, err := s.GetSchedules(ctx, storage.ListOptions{Order: storage.Newest, Limit: 1})
+	must(t, err, "GetSchedules")
+	wantIDs(t, schedules.Items, func(sc models.Schedule) string { return sc.ID }, "sc2")
+	must(t, s.AddPlaybook(models.Playbook{ID: "pb1", Name: "contain", CreatedAt: at(1)}), "AddPlaybook")
+	must(t, s.AddPlaybook(models.Playbook{ID: "pb2", Name: "notify", CreatedAt: at(2)}), "AddPlaybook")
+	playbooks, err := s.GetPlaybooks(ctx, storage.ListOptions{Since: at(2)})
+	must(t, err, "GetPlaybooks")
+	wantIDs(t, playbooks.Items, func(pb models.Playbook) string { return pb.ID }, "pb2")
+}
@@ -0,0 +1,432 @@
+package storagetest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+func testUsers(t *testing.T, s storage.Storage) {
+	bob := models.User{ID: "u2", Email: "bob@example.com", FirstName: "Bob", Role: models.RoleSecurityAnalyst, CreatedAt: at(2)}
+	alice := models.User{ID: "u1", Email: "alice@example.com", FirstName: "Alice", Role: models.RoleAdmin, TokenKeys: []string{"k1"}, CreatedAt: at(1)}
+	must(t, s.AddUser(bob), "AddUser")
+	must(t, s.AddUser(alice), "AddUser")
+	wantErr(t, s.AddUser(models.User{ID: "u1", Email: "other@example.com"}), storage.ErrConflict, "AddUser with a taken ID")
+	wantErr(t, s.AddUser(models.User{ID: "u3", Email: "alice@example.com"}), storage.ErrConflict, "AddUser with a taken email")
+
+	u, err := s.GetUser("u1")
+	must(t, err, "GetUser")
+	if u.Email != alice.Email || u.Role != alice.Role || len(u.TokenKeys) != 1 || u.TokenKeys[0] != "k1" {
+		t.Errorf("GetUser = %+v, want %+v", u, alice)
+	}
+	wantTime(t, u.CreatedAt, alice.CreatedAt, "CreatedAt")
+	u, err = s.GetUserByEmail("bob@example.com")
+	must(t, err, "GetUserByEmail")
+	if u.ID != "u2" {
+		t.Errorf("GetUserByEmail returned %s, want u2", u.ID)
+	}
+	_, err = s.GetUser("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetUser")
+	_, err = s.GetUserByEmail("missing@example.com")
+	wantErr(t, err, storage.ErrNotFound, "GetUserByEmail")
+	wantIDs(t, all(t, s.GetAllUsers), func(u models.User) string { return u.ID }, "u1", "u2")
+
+	alice.FirstName = "Alicia"
+	must(t, s.UpdateUser(alice), "UpdateUser")
+	if u, _ := s.GetUser("u1"); u.FirstName != "Alicia" {
+		t.Errorf("FirstName after UpdateUser = %q", u.FirstName)
+	}
+	alice.Email = bob.Email
+	wantErr(t, s.UpdateUser(alice), storage.ErrConflict, "UpdateUser to a taken email")
+	wantErr(t, s.UpdateUser(models.User{ID: "missing", Email: "missing@example.com"}), storage.ErrNotFound, "UpdateUser")
+
+	must(t, s.DeleteUser("u1"), "DeleteUser")
+	_, err = s.GetUser("u1")
+	wantErr(t, err, storage.ErrNotFound, "GetUser after DeleteUser")
+	wantErr(t, s.DeleteUser("u1"), storage.ErrNotFound, "DeleteUser twice")
+}
+
+func testClusters(t *testing.T, s storage.Storage) {
+	must(t, s.AddCluster(models.Cluster{ID: "c2", Name: "staging", KubeConfig: "kc2", Status: "connected", CreatedAt: at(2)}), "AddCluster")
+	must(t, s.AddCluster(models.Cluster{ID: "c1", Name: "prod", KubeConfig: "kc1", Status: "connected", Metrics: models.Metrics{PodCount: 3}, CreatedAt: at(1)}), "AddCluster")
+	wantErr(t, s.AddCluster(models.Cluster{ID: "c1", Name: "again", CreatedAt: at(3)}), storage.ErrConflict, "AddCluster with a taken ID")
+
+	c, err := s.GetCluster("c1")
+	must(t, err, "GetCluster")
+	if c.Name != "prod" || c.KubeConfig != "kc1" || c.Metrics.PodCount != 3 {
+		t.Errorf("GetCluster = %+v", c)
+	}
+	_, err = s.GetCluster("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetCluster")
+	wantIDs(t, all(t, s.GetClusters), func(c models.Cluster) string { return c.ID }, "c1", "c2")
+
+	must(t, s.SetAuditToken("c1", "token"), "SetAuditToken")
+	must(t, s.SetKubeConfig("c1", "kc1-new"), "SetKubeConfig")
+	if c, _ := s.GetCluster("c1"); c.AuditToken != "token" || c.KubeConfig != "kc1-new" {
+		t.Errorf("after SetAuditToken and SetKubeConfig: %+v", c)
+	}
+	wantErr(t, s.SetAuditToken("missing", "token"), storage.ErrNotFound, "SetAuditToken")
+	wantErr(t, s.SetKubeConfig("missing", "kc"), storage.ErrNotFound, "SetKubeConfig")
+
+	must(t, s.DeleteCluster("c1"), "DeleteCluster")
+	wantErr(t, s.DeleteCluster("c1"), storage.ErrNotFound, "DeleteCluster twice")
+	wantIDs(t, all(t, s.GetClusters), func(c models.Cluster) string { return c.ID }, "c2")
+}
+
+func testPolicies(t *testing.T, s storage.Storage) {
+	p := models.Policy{ID: "p1", Name: "no-latest", Rules: []string{"no-latest-tag"}, Namespace: "default", Severity: models.SeverityHigh,
+		Responses: []string{models.ActionDeletePod}, AutoRespond: true, Playbooks: []string{"pb1"}, CreatedAt: at(2)}
+	must(t, s.AddPolicy(p), "AddPolicy")
+	must(t, s.AddPolicy(models.Policy{ID: "p0", Name: "older", CreatedAt: at(1)}), "AddPolicy")
+	wantErr(t, s.AddPolicy(models.Policy{ID: "p1", Name: "again"}), storage.ErrConflict, "AddPolicy with a taken ID")
+
+	got, err := s.GetPolicy("p1")
+	must(t, err, "GetPolicy")
+	if got.Name != p.Name || got.Severity != p.Severity || !got.AutoRespond || len(got.Rules) != 1 || len(got.Responses) != 1 || len(got.Playbooks) != 1 {
+		t.Errorf("GetPolicy = %+v, want %+v", got, p)
+	}
+	_, err = s.GetPolicy("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetPolicy")
+	wantIDs(t, all(t, s.GetPolicies), func(p models.Policy) string { return p.ID }, "p0", "p1")
+
+	must(t, s.DeletePolicy("p1"), "DeletePolicy")
+	wantErr(t, s.DeletePolicy("p1"), storage.ErrNotFound, "DeletePolicy twice")
+}
+
+func testSilences(t *testing.T, s storage.Storage) {
+	must(t, s.AddSilence(models.Silence{ID: "s1", Rule: "crashloop", StartsAt: at(1), EndsAt: at(60), CreatedBy: "u1", CreatedAt: at(1)}), "AddSilence")
+	must(t, s.AddSilence(models.Silence{ID: "s2", ClusterID: "c1", StartsAt: at(2), EndsAt: at(60), CreatedBy: "u1", CreatedAt: at(2)}), "AddSilence")
+	wantErr(t, s.AddSilence(models.Silence{ID: "s1", StartsAt: at(3), EndsAt: at(4)}), storage.ErrConflict, "AddSilence with a taken ID")
+	wantIDs(t, all(t, s.GetSilences), func(sl models.Silence) string { return sl.ID }, "s2", "s1")
+
+	sl, err := s.GetSilence("s1")
+	must(t, err, "GetSilence")
+	if sl.Rule != "crashloop" {
+		t.Errorf("GetSilence = %+v", sl)
+	}
+	wantTime(t, sl.EndsAt, at(60), "EndsAt")
+	_, err = s.GetSilence("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetSilence")
+
+	sl.Comment, sl.EndsAt = "maintenance", at(30)
+	must(t, s.UpdateSilence(sl), "UpdateSilence")
+	if got, _ := s.GetSilence("s1"); got.Comment != "maintenance" || !got.EndsAt.Equal(at(30)) {
+		t.Errorf("after UpdateSilence: %+v", got)
+	}
+	wantErr(t, s.UpdateSilence(models.Silence{ID: "missing"}), storage.ErrNotFound, "UpdateSilence")
+
+	must(t, s.DeleteSilence("s1"), "DeleteSilence")
+	wantErr(t, s.DeleteSilence("s1"), storage.ErrNotFound, "DeleteSilence twice")
+}
+
+func testChannels(t *testing.T, s storage.Storage) {
+	must(t, s.AddChannel(models.Channel{ID: "ch2", Name: "mail", Type: models.ChannelSMTP, SMTPAddr: "smtp:25", From: "ksms@example.com", To: []string{"soc@example.com"}, CreatedAt: at(2)}), "AddChannel")
+	must(t, s.AddChannel(models.Channel{ID: "ch1", Name: "hook", Type: models.ChannelWebhook, URL: "https://example.com/hook", CreatedAt: at(1)}), "AddChannel")
+	wantErr(t, s.AddChannel(models.Channel{ID: "ch1", Name: "again", Type: models.ChannelWebhook}), storage.ErrConflict, "AddChannel with a taken ID")
+	wantIDs(t, all(t, s.GetChannels), func(c models.Channel) string { return c.ID }, "ch1", "ch2")
+
+	c, err := s.GetChannel("ch2")
+	must(t, err, "GetChannel")
+	if c.Type != models.ChannelSMTP || len(c.To) != 1 {
+		t.Errorf("GetChannel = %+v", c)
+	}
+	_, err = s.GetChannel("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetChannel")
+
+	must(t, s.DeleteChannel("ch1"), "DeleteChannel")
+	wantErr(t, s.DeleteChannel("ch1"), storage.ErrNotFound, "DeleteChannel twice")
+}
+
+func testDeadLetters(t *testing.T, s storage.Storage) {
+	must(t, s.AddDeadLetter(models.DeadLetter{ID: "d1", ChannelID: "ch1", AlertID: "a1", Attempts: 3, Error: "timeout", FailedAt: at(1)}), "AddDeadLetter")
+	must(t, s.AddDeadLetter(models.DeadLetter{ID: "d2", ChannelID: "ch1", AlertID: "a2", Attempts: 3, Error: "timeout", FailedAt: at(2)}), "AddDeadLetter")
+	wantErr(t, s.AddDeadLetter(models.DeadLetter{ID: "d1", FailedAt: at(3)}), storage.ErrConflict, "AddDeadLetter with a taken ID")
+	wantIDs(t, all(t, s.GetDeadLetters), func(d models.DeadLetter) string { return d.ID }, "d2", "d1")
+}
+
+func testSchedules(t *testing.T, s storage.Storage) {
+	sc := models.Schedule{ID: "sc1", Name: "primary", Rotation: []string{"u1", "u2"}, RotationStart: at(0), CreatedAt: at(2)}
+	must(t, s.AddSchedule(sc), "AddSchedule")
+	must(t, s.AddSchedule(models.Schedule{ID: "sc0", Name: "secondary", Rotation: []string{"u3"}, RotationStart: at(0), CreatedAt: at(1)}), "AddSchedule")
+	wantErr(t, s.AddSchedule(models.Schedule{ID: "sc1", Name: "again"}), storage.ErrConflict, "AddSchedule with a taken ID")
+	wantIDs(t, all(t, s.GetSchedules), func(sc models.Schedule) string { return sc.ID }, "sc0", "sc1")
+
+	got, err := s.GetSchedule("sc1")
+	must(t, err, "GetSchedule")
+	if len(got.Rotation) != 2 {
+		t.Errorf("GetSchedule = %+v", got)
+	}
+	_, err = s.GetSchedule("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetSchedule")
+
+	sc.Name = "renamed"
+	must(t, s.UpdateSchedule(sc), "UpdateSchedule")
+	if got, _ := s.GetSchedule("sc1"); got.Name != "renamed" {
+		t.Errorf("Name after UpdateSchedule = %q", got.Name)
+	}
+	wantErr(t, s.UpdateSchedule(models.Schedule{ID: "missing"}), storage.ErrNotFound, "UpdateSchedule")
+
+	must(t, s.DeleteSchedule("sc1"), "DeleteSchedule")
+	wantErr(t, s.DeleteSchedule("sc1"), storage.ErrNotFound, "DeleteSchedule twice")
+}
+
+func testEscalationPolicies(t *testing.T, s storage.Storage) {
+	must(t, s.AddEscalationPolicy(models.EscalationPolicy{ID: "ep2", Name: "night", MinSeverity: models.SeverityHigh, CreatedAt: at(2)}), "AddEscalationPolicy")
+	must(t, s.AddEscalationPolicy(models.EscalationPolicy{ID: "ep1", Name: "day", MinSeverity: models.SeverityMedium, CreatedAt: at(1)}), "AddEscalationPolicy")
+	wantErr(t, s.AddEscalationPolicy(models.EscalationPolicy{ID: "ep1", Name: "again"}), storage.ErrConflict, "AddEscalationPolicy with a taken ID")
+	wantIDs(t, s.GetEscalationPolicies(), func(p models.EscalationPolicy) string { return p.ID }, "ep1", "ep2")
+
+	p, err := s.GetEscalationPolicy("ep2")
+	must(t, err, "GetEscalationPolicy")
+	if p.MinSeverity != models.SeverityHigh {
+		t.Errorf("GetEscalationPolicy = %+v", p)
+	}
+	_, err = s.GetEscalationPolicy("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetEscalationPolicy")
+
+	must(t, s.DeleteEscalationPolicy("ep1"), "DeleteEscalationPolicy")
+	wantErr(t, s.DeleteEscalationPolicy("ep1"), storage.ErrNotFound, "DeleteEscalationPolicy twice")
+}
+
+// testEscalations checks that escalations are kept one per alert, replaced
+// when saved again, advanced only from the tier they are at, and that
+// deleting them is idempotent
+func testEscalations(t *testing.T, s storage.Storage) {
+	must(t, s.SaveEscalation(models.Escalation{AlertID: "a1", PolicyID: "ep1", NextTier: 1, DueAt: at(2)}), "SaveEscalation")
+	must(t, s.SaveEscalation(models.Escalation{AlertID: "a2", PolicyID: "ep1", NextTier: 1, DueAt: at(1)}), "SaveEscalation")
+	wantIDs(t, s.GetEscalations(), func(e models.Escalation) string { return e.AlertID }, "a2", "a1")
+
+	must(t, s.SaveEscalation(models.Escalation{AlertID: "a1", PolicyID: "ep1", NextTier: 2, DueAt: at(0)}), "SaveEscalation again")
+	escalations := s.GetEscalations()
+	wantIDs(t, escalations, func(e models.Escalation) string { return e.AlertID }, "a1", "a2")
+	if len(escalations) > 0 && escalations[0].NextTier != 2 {
+		t.Errorf("NextTier after saving again = %d, want 2", escalations[0].NextTier)
+	}
+
+	must(t, s.AdvanceEscalation(models.Escalation{AlertID: "a2", PolicyID: "ep1", NextTier: 2, DueAt: at(3)}), "AdvanceEscalation")
+	wantErr(t, s.AdvanceEscalation(models.Escalation{AlertID: "a2", PolicyID: "ep1", NextTier: 2, DueAt: at(4)}), storage.ErrConflict, "AdvanceEscalation of the same tier twice")
+	wantErr(t, s.AdvanceEscalation(models.Escalation{AlertID: "a2", PolicyID: "ep2", NextTier: 3, DueAt: at(4)}), storage.ErrConflict, "AdvanceEscalation under another policy")
+	escalations = s.GetEscalations()
+	if len(escalations) != 2 || escalations[1].AlertID != "a2" || escalations[1].NextTier != 2 || !escalations[1].DueAt.Equal(at(3)) {
+		t.Errorf("escalations after advancing a2 = %+v", escalations)
+	}
+
+	must(t, s.DeleteEscalation("a1"), "DeleteEscalation")
+	must(t, s.DeleteEscalation("a1"), "DeleteEscalation twice")
+	wantIDs(t, s.GetEscalations(), func(e models.Escalation) string { return e.AlertID }, "a2")
+	wantErr(t, s.AdvanceEscalation(models.Escalation{AlertID: "a1", PolicyID: "ep1", NextTier: 3, DueAt: at(4)}), storage.ErrNotFound, "AdvanceEscalation after DeleteEscalation")
+	wantIDs(t, s.GetEscalations(), func(e models.Escalation) string { return e.AlertID }, "a2")
+}
+
+func testReports(t *testing.T, s storage.Storage) {
+	must(t, s.AddReport(models.IncidentReport{ID: "r1", AlertID: "a1", Details: "first", Action: "delete-pod", Timestamp: at(1)}), "AddReport")
+	must(t, s.AddReport(models.IncidentReport{ID: "r2", AlertID: "a1", Details: "third", Action: "delete-pod", Result: "done", Timestamp: at(3)}), "AddReport")
+	must(t, s.AddReport(models.IncidentReport{ID: "r3", AlertID: "a2", Details: "second", Action: "capture-evidence", EvidenceID: "e1", Timestamp: at(2)}), "AddReport")
+	wantErr(t, s.AddReport(models.IncidentReport{ID: "r1", AlertID: "a3", Details: "again", Action: "delete-pod", Timestamp: at(4)}), storage.ErrConflict, "AddReport with a taken ID")
+	reports := all(t, s.GetReports)
+	wantIDs(t, reports, func(r models.IncidentReport) string { return r.ID }, "r2", "r3", "r1")
+	if len(reports) == 3 && (reports[0].Result != "done" || reports[1].EvidenceID != "e1") {
+		t.Errorf("GetReports = %+v", reports)
+	}
+}
+
+func testResponseActions(t *testing.T, s storage.Storage) {
+	a := models.ResponseAction{ID: "ra1", ClusterID: "c1", AlertID: "a1", Type: models.ActionDeletePod, Kind: "Pod", Namespace: "ns", Name: "web",
+		Status: models.ActionPending, RequestedBy: "u1", CreatedAt: at(1)}
+	must(t, s.AddResponseAction(a), "AddResponseAction")
+	must(t, s.AddResponseAction(models.ResponseAction{ID: "ra2", ClusterID: "c1", Type: models.ActionDrainNode, Kind: "Node", Name: "node-1",
+		Status: models.ActionPending, RequestedBy: "u1", CreatedAt: at(2)}), "AddResponseAction")
+	wantErr(t, s.AddResponseAction(models.ResponseAction{ID: "ra1", Status: models.ActionPending}), storage.ErrConflict, "AddResponseAction with a taken ID")
+	wantIDs(t, all(t, s.GetResponseActions), func(a models.ResponseAction) string { return a.ID }, "ra2", "ra1")
+
+	got, err := s.GetResponseAction("ra1")
+	must(t, err, "GetResponseAction")
+	if got.Name != "web" || got.Status != models.ActionPending {
+		t.Errorf("GetResponseAction = %+v", got)
+	}
+	_, err = s.GetResponseAction("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetResponseAction")
+
+	a.Status, a.DecidedBy, a.DecidedAt = models.ActionRejected, "u2", at(3)
+	must(t, s.UpdateResponseAction(a), "UpdateResponseAction")
+	if got, _ := s.GetResponseAction("ra1"); got.Status != models.ActionRejected || got.DecidedBy != "u2" {
+		t.Errorf("after UpdateResponseAction: %+v", got)
+	}
+	wantErr(t, s.UpdateResponseAction(models.ResponseAction{ID: "missing"}), storage.ErrNotFound, "UpdateResponseAction")
+
+	b, _ := s.GetResponseAction("ra2")
+	b.Status, b.DecidedBy, b.DecidedAt = models.ActionRunning, "u2", at(4)
+	must(t, s.DecideResponseAction(b), "DecideResponseAction")
+	if got, _ := s.GetResponseAction("ra2"); got.Status != models.ActionRunning || got.DecidedBy != "u2" {
+		t.Errorf("after DecideResponseAction: %+v", got)
+	}
+	b.Status, b.DecidedBy = models.ActionRejected, "u3"
+	wantErr(t, s.DecideResponseAction(b), storage.ErrConflict, "DecideResponseAction of a decided action")
+	if got, _ := s.GetResponseAction("ra2"); got.Status != models.ActionRunning || got.DecidedBy != "u2" {
+		t.Errorf("refused decision changed the action: %+v", got)
+	}
+	wantErr(t, s.DecideResponseAction(models.ResponseAction{ID: "missing"}), storage.ErrNotFound, "DecideResponseAction")
+}
+
+func testPlaybooks(t *testing.T, s storage.Storage) {
+	pb := models.Playbook{ID: "pb1", Name: "contain", Steps: []models.PlaybookStep{{Action: models.ActionDeletePod}}, CreatedAt: at(2)}
+	must(t, s.AddPlaybook(pb), "AddPlaybook")
+	must(t, s.AddPlaybook(models.Playbook{ID: "pb0", Name: "notify", CreatedAt: at(1)}), "AddPlaybook")
+	wantErr(t, s.AddPlaybook(models.Playbook{ID: "pb1", Name: "again"}), storage.ErrConflict, "AddPlaybook with a taken ID")
+	wantIDs(t, all(t, s.GetPlaybooks), func(pb models.Playbook) string { return pb.ID }, "pb0", "pb1")
+
+	got, err := s.GetPlaybook("pb1")
+	must(t, err, "GetPlaybook")
+	if len(got.Steps) != 1 || got.Steps[0].Action != models.ActionDeletePod {
+		t.Errorf("GetPlaybook = %+v", got)
+	}
+	_, err = s.GetPlaybook("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetPlaybook")
+
+	pb.Description = "isolate the pod"
+	must(t, s.UpdatePlaybook(pb), "UpdatePlaybook")
+	if got, _ := s.GetPlaybook("pb1"); got.Description != pb.Description {
+		t.Errorf("Description after UpdatePlaybook = %q", got.Description)
+	}
+	wantErr(t, s.UpdatePlaybook(models.Playbook{ID: "missing"}), storage.ErrNotFound, "UpdatePlaybook")
+
+	must(t, s.DeletePlaybook("pb1"), "DeletePlaybook")
+	wantErr(t, s.DeletePlaybook("pb1"), storage.ErrNotFound, "DeletePlaybook twice")
+}
+
+func testPlaybookRuns(t *testing.T, s storage.Storage) {
+	run := models.PlaybookRun{ID: "run1", PlaybookID: "pb1", ClusterID: "c1", Kind: "Pod", Name: "web", Status: models.PlaybookRunning, StartedAt: at(1)}
+	must(t, s.AddPlaybookRun(run), "AddPlaybookRun")
+	must(t, s.AddPlaybookRun(models.PlaybookRun{ID: "run2", PlaybookID: "pb1", ClusterID: "c1", Status: models.PlaybookRunning, StartedAt: at(2)}), "AddPlaybookRun")
+	must(t, s.AddPlaybookRun(models.PlaybookRun{ID: "run3", PlaybookID: "pb2", ClusterID: "c1", Status: models.PlaybookRunning, StartedAt: at(3)}), "AddPlaybookRun")
+	wantErr(t, s.AddPlaybookRun(models.PlaybookRun{ID: "run1", PlaybookID: "pb1"}), storage.ErrConflict, "AddPlaybookRun with a taken ID")
+	wantIDs(t, s.GetPlaybookRuns("pb1"), func(r models.PlaybookRun) string { return r.ID }, "run2", "run1")
+	wantIDs(t, s.GetPlaybookRuns("missing"), func(r models.PlaybookRun) string { return r.ID })
+
+	run.Status, run.FinishedAt = models.PlaybookSucceeded, at(4)
+	must(t, s.UpdatePlaybookRun(run), "UpdatePlaybookRun")
+	got, err := s.GetPlaybookRun("run1")
+	must(t, err, "GetPlaybookRun")
+	if got.Status != models.PlaybookSucceeded {
+		t.Errorf("Status after UpdatePlaybookRun = %q", got.Status)
+	}
+	wantTime(t, got.FinishedAt, at(4), "FinishedAt")
+	wantErr(t, s.UpdatePlaybookRun(models.PlaybookRun{ID: "missing"}), storage.ErrNotFound, "UpdatePlaybookRun")
+	_, err = s.GetPlaybookRun("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetPlaybookRun")
+}
+
+// testEvidence checks that evidence, being content addressed, can be saved
+// again without error and shares blobs between bundles
+func testEvidence(t *testing.T, s storage.Storage) {
+	blob := []byte{0, 1, 2, 0xff}
+	b1 := models.EvidenceBundle{ID: "e1", ClusterID: "c1", AlertID: "a1", Namespace: "ns", Pod: "web",
+		Files: []models.EvidenceFile{{Path: "pod.yaml", SHA256: "sum1", Size: len(blob)}}, CollectedAt: at(1)}
+	must(t, s.SaveEvidence(b1, map[string][]byte{"sum1": blob}), "SaveEvidence")
+	must(t, s.SaveEvidence(b1, map[string][]byte{"sum1": blob}), "SaveEvidence again")
+	must(t, s.SaveEvidence(models.EvidenceBundle{ID: "e2", ClusterID: "c1", AlertID: "a2", Namespace: "ns", Pod: "db",
+		Files: []models.EvidenceFile{{Path: "pod.yaml", SHA256: "sum1", Size: len(blob)}}, CollectedAt: at(2)}, map[string][]byte{"sum1": blob}), "SaveEvidence")
+	wantIDs(t, all(t, s.GetEvidenceBundles), func(b models.EvidenceBundle) string { return b.ID }, "e2", "e1")
+
+	got, err := s.GetEvidenceBundle("e1")
+	must(t, err, "GetEvidenceBundle")
+	if got.Pod != "web" || len(got.Files) != 1 || got.Files[0].SHA256 != "sum1" {
+		t.Errorf("GetEvidenceBundle = %+v", got)
+	}
+	_, err = s.GetEvidenceBundle("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetEvidenceBundle")
+
+	data, err := s.GetEvidenceBlob("sum1")
+	must(t, err, "GetEvidenceBlob")
+	if !bytes.Equal(data, blob) {
+		t.Errorf("GetEvidenceBlob = %v, want %v", data, blob)
+	}
+	_, err = s.GetEvidenceBlob("missing")
+	wantErr(t, err, storage.ErrNotFound, "GetEvidenceBlob")
+}
+
+// testFindings checks that scan findings replace the previous scan of their
+// cluster only and come back in a fixed order, and that every save records
+// the scan, even one that found nothing
+func testFindings(t *testing.T, s storage.Storage) {
+	for _, kind := range []string{models.ScanPSS, models.ScanImages, models.ScanSecrets} {
+		_, err := s.LastScan("c1", kind)
+		wantErr(t, err, storage.ErrNotFound, "LastScan of "+kind+" before any scan")
+	}
+	must(t, s.SavePSSFindings("c1", []models.PSSFinding{
+		{ClusterID: "c1", Kind: "Pod", Namespace: "ns-b", Name: "a", Level: "baseline", ScannedAt: at(1)},
+		{ClusterID: "c1", Kind: "Deployment", Namespace: "ns-a", Name: "z", Level: "privileged", ScannedAt: at(1)},
+		{ClusterID: "c1", Kind: "Deployment", Namespace: "ns-a", Name: "b", Level: "restricted", ScannedAt: at(1)},
+	}), "SavePSSFindings")
+	must(t, s.SavePSSFindings("c2", []models.PSSFinding{{ClusterID: "c2", Kind: "Pod", Namespace: "ns", Name: "x", Level: "baseline", ScannedAt: at(1)}}), "SavePSSFindings")
+	wantIDs(t, s.GetPSSFindings("c1"), func(f models.PSSFinding) string { return f.Namespace + "/" + f.Kind + "/" + f.Name },
+		"ns-a/Deployment/b", "ns-a/Deployment/z", "ns-b/Pod/a")
+	must(t, s.SavePSSFindings("c1", nil), "SavePSSFindings with no findings")
+	wantIDs(t, s.GetPSSFindings("c1"), func(f models.PSSFinding) string { return f.Name })
+	wantIDs(t, s.GetPSSFindings("c2"), func(f models.PSSFinding) string { return f.Name }, "x")
+
+	must(t, s.SaveImageFindings("c1", []models.ImageFinding{
+		{ClusterID: "c1", Namespace: "ns", Pod: "web", Container: "sidecar", Image: "proxy:latest", Check: "latest-tag", Severity: models.SeverityMedium, DetectedAt: at(1)},
+		{ClusterID: "c1", Namespace: "ns", Pod: "api", Container: "app", Image: "api:latest", Check: "latest-tag", Severity: models.SeverityMedium, DetectedAt: at(1)},
+		{ClusterID: "c1", Namespace: "ns", Pod: "web", Container: "app", Image: "web:latest", Check: "latest-tag", Severity: models.SeverityMedium, DetectedAt: at(1)},
+	}), "SaveImageFindings")
+	wantIDs(t, s.GetImageFindings("c1"), func(f models.ImageFinding) string { return f.Pod + "/" + f.Container }, "api/app", "web/app", "web/sidecar")
+	wantIDs(t, s.GetImageFindings("c2"), func(f models.ImageFinding) string { return f.Pod })
+
+	must(t, s.SaveSecretFindings("c1", []models.SecretFinding{
+		{ClusterID: "c1", Check: "env-secret", Severity: models.SeverityHigh, Kind: "Pod", Namespace: "ns", Name: "web", DetectedAt: at(1)},
+		{ClusterID: "c1", Check: "env-secret", Severity: models.SeverityHigh, Kind: "Deployment", Namespace: "ns", Name: "web", DetectedAt: at(1)},
+	}), "SaveSecretFindings")
+	wantIDs(t, s.GetSecretFindings("c1"), func(f models.SecretFinding) string { return f.Kind + "/" + f.Name }, "Deployment/web", "Pod/web")
+	wantIDs(t, s.GetSecretFindings("c2"), func(f models.SecretFinding) string { return f.Name })
+
+	for _, kind := range []string{models.ScanPSS, models.ScanImages, models.ScanSecrets} {
+		if scanned, err := s.LastScan("c1", kind); err != nil || scanned.IsZero() {
+			t.Errorf("LastScan of %s = %v, %v", kind, scanned, err)
+		}
+	}
+	_, err := s.LastScan("c2", models.ScanImages)
+	wantErr(t, err, storage.ErrNotFound, "LastScan of a cluster never scanned for images")
+	must(t, s.DeleteScans("c1"), "DeleteScans")
+	if len(s.GetPSSFindings("c1")) != 0 || len(s.GetImageFindings("c1")) != 0 || len(s.GetSecretFindings("c1")) != 0 {
+		t.Error("DeleteScans left findings of the cluster")
+	}
+	_, err = s.LastScan("c1", models.ScanPSS)
+	wantErr(t, err, storage.ErrNotFound, "LastScan after DeleteScans")
+	wantIDs(t, s.GetPSSFindings("c2"), func(f models.PSSFinding) string { return f.Name }, "x")
+}
+
+// testCISRuns checks that benchmark runs are numbered per cluster
+func testCISRuns(t *testing.T, s storage.Storage) {
+	for i, want := range []int{1, 2} {
+		run, err := s.AddCISRun(models.CISRun{ID: "c1-run", ClusterID: "c1", Benchmark: "cis-1.8", StartedAt: at(i)})
+		must(t, err, "AddCISRun")
+		if run.Version != want {
+			t.Errorf("AddCISRun version = %d, want %d", run.Version, want)
+		}
+	}
+	run, err := s.AddCISRun(models.CISRun{ID: "c2-run", ClusterID: "c2", Benchmark: "cis-1.8", StartedAt: at(0)})
+	must(t, err, "AddCISRun")
+	if run.Version != 1 {
+		t.Errorf("AddCISRun version of another cluster = %d, want 1", run.Version)
+	}
+	versions := func(r models.CISRun) string { return fmt.Sprintf("%s@%d", r.Benchmark, r.Version) }
+	wantIDs(t, s.GetCISRuns("c1"), versions, "cis-1.8@1", "cis-1.8@2")
+
+	got, err := s.GetCISRun("c1", 2)
+	must(t, err, "GetCISRun")
+	wantTime(t, got.StartedAt, at(1), "StartedAt")
+	_, err = s.GetCISRun("c1", 3)
+	wantErr(t, err, storage.ErrNotFound, "GetCISRun")
+
+	must(t, s.DeleteCISRuns("c1"), "DeleteCISRuns")
+	must(t, s.DeleteCISRuns("c1"), "DeleteCISRuns twice")
+	wantIDs(t, s.GetCISRuns("c1"), versions)
+	wantIDs(t, s.GetCISRuns("c2"), versions, "cis-1.8@1")
+	if run, _ := s.AddCISRun(models.CISRun{ID: "c1-run", ClusterID: "c1", Benchmark: "cis-1.8", StartedAt: at(5)}); run.Version != 1 {
+		t.Errorf("AddCISRun version after DeleteCISRuns = %d, want 1", run.Version)
+	}
+}
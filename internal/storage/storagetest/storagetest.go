@@ -0,0 +1,106 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. A backend passes it by calling Run from its own tests with
+// a function that opens an empty storage.
+//
+// The suite pins down what handlers and background workers rely on: missing
+// records are reported with storage.ErrNotFound, taken IDs and refused state
+// changes with storage.ErrConflict, lists come back in a fixed order and
+// filter and page alike, values read back are independent copies, and
+// concurrent writers do not lose or duplicate records.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// Run runs the suite. Every subtest gets its own empty storage from open.
+func Run(t *testing.T, open func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"Users", testUsers},
+		{"Clusters", testClusters},
+		{"Policies", testPolicies},
+		{"Alerts", testAlerts},
+		{"Lists", testLists},
+		{"Archival", testArchival},
+		{"Incidents", testIncidents},
+		{"Silences", testSilences},
+		{"Channels", testChannels},
+		{"DeadLetters", testDeadLetters},
+		{"Schedules", testSchedules},
+		{"EscalationPolicies", testEscalationPolicies},
+		{"Escalations", testEscalations},
+		{"Reports", testReports},
+		{"ResponseActions", testResponseActions},
+		{"Playbooks", testPlaybooks},
+		{"PlaybookRuns", testPlaybookRuns},
+		{"Evidence", testEvidence},
+		{"Findings", testFindings},
+		{"CISRuns", testCISRuns},
+		{"Concurrency", testConcurrency},
+		{"Isolation", testIsolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// base is the time records are created around. It has no sub-microsecond
+// part, so it survives databases with microsecond precision.
+var base = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// at returns base moved by n minutes
+func at(n int) time.Time {
+	return base.Add(time.Duration(n) * time.Minute)
+}
+
+func must(t *testing.T, err error, op string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", op, err)
+	}
+}
+
+func wantErr(t *testing.T, err, target error, op string) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s: got error %v, want %v", op, err, target)
+	}
+}
+
+func wantTime(t *testing.T, got, want time.Time, what string) {
+	t.Helper()
+	if !got.Equal(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+// all reads every page of a list
+func all[T any](t *testing.T, list func(context.Context, storage.ListOptions) (storage.Page[T], error)) []T {
+	t.Helper()
+	items, err := storage.All(context.Background(), list, storage.ListOptions{})
+	must(t, err, "list")
+	return items
+}
+
+// wantIDs checks the IDs of a list, in order
+func wantIDs[T any](t *testing.T, items []T, id func(T) string, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(items))
+	for _, item := range items {
+		got = append(got, id(item))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
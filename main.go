@@ -1,19 +1,40 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 	"text/template"
+	"time"
 
+	"KubernetesSecurityMonitoringSystem/internal/alerts"
+	"KubernetesSecurityMonitoringSystem/internal/checks"
+	"KubernetesSecurityMonitoringSystem/internal/forensics"
 	"KubernetesSecurityMonitoringSystem/internal/handlers"
 	"KubernetesSecurityMonitoringSystem/internal/kubernetes"
 	"KubernetesSecurityMonitoringSystem/internal/middleware"
+	"KubernetesSecurityMonitoringSystem/internal/models"
+	"KubernetesSecurityMonitoringSystem/internal/notify"
+	"KubernetesSecurityMonitoringSystem/internal/policies"
+	"KubernetesSecurityMonitoringSystem/internal/response"
+	"KubernetesSecurityMonitoringSystem/internal/retention"
+	"KubernetesSecurityMonitoringSystem/internal/secrets"
 	"KubernetesSecurityMonitoringSystem/internal/storage"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "rotate-keys":
+			os.Exit(runRotateKeys())
+		}
+	}
+
 	var store storage.Storage
 	var err error
 
@@ -23,12 +44,71 @@ func main() {
 		store = storage.NewMemoryStorage()
 	}
 
+	// Kubeconfigs are encrypted at rest; rows left under an old master key or
+	// in plain text are re-encrypted with the current one
+	keys, err := secrets.LoadKeyring()
+	if err != nil {
+		log.Fatalf("Failed to load master key: %v", err)
+	}
+	if n, err := secrets.Rotate(context.Background(), store, keys); err != nil {
+		log.Printf("Failed to re-encrypt kubeconfigs: %v", err)
+	} else if n > 0 {
+		log.Printf("Re-encrypted %d kubeconfigs with the current master key", n)
+	}
+	store = secrets.WithEncryption(store, keys)
+
+	// Alert bus; everything stored from here on is published to the SSE stream
+	bus := alerts.NewBus(1000, 100)
+	store = alerts.WithBus(store, bus)
+
 	k8sMgr := kubernetes.NewClusterManager()
 
+	// Cluster watchers
+	watchers := kubernetes.NewWatcherManager(store)
+	clusters, err := storage.All(context.Background(), store.GetClusters, storage.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list clusters: %v", err)
+	}
+	for _, c := range clusters {
+		client, err := k8sMgr.GetClient(c.ID, c.KubeConfig)
+		if err != nil {
+			log.Printf("Skipping watcher for cluster %s: %v", c.ID, err)
+			continue
+		}
+		watchers.Start(c.ID, client)
+	}
+
+	// Outbound notifications
+	notifier := notify.NewNotifier(store)
+	go notifier.Run(context.Background(), bus)
+	escalator := notify.NewEscalator(store, notifier)
+	go escalator.Run(context.Background(), bus, 30*time.Second)
+
+	// Incident response and policy evaluation
+	responder := response.NewResponder(store, k8sMgr)
+	playbooks := response.NewPlaybookRunner(store, k8sMgr, notifier)
+	evaluator := policies.NewEvaluator(store, k8sMgr, responder, playbooks)
+	go evaluator.Run(context.Background(), time.Minute)
+
+	// Forensic evidence for medium and higher alerts on pods
+	collector := forensics.NewCollector(store, k8sMgr, models.SeverityMedium)
+	go collector.Run(context.Background(), bus)
+
+	// Posture scans
+	scanner := checks.NewScanner(store, k8sMgr)
+	go scanner.Run(context.Background(), 10*time.Minute)
+
+	// Retention; expired alerts and reports are archived, then deleted
+	compactor, err := retention.LoadCompactor(store)
+	if err != nil {
+		log.Fatalf("Failed to set up retention: %v", err)
+	}
+	go compactor.Run(context.Background(), time.Hour)
+
 	// Handlers
 	authH := &handlers.AuthHandler{Storage: store}
 	userH := &handlers.UserHandler{Storage: store}
-	resH := &handlers.ResourceHandler{Storage: store, K8s: k8sMgr}
+	resH := &handlers.ResourceHandler{Storage: store, K8s: k8sMgr, Watchers: watchers, Scanner: scanner, Audit: kubernetes.NewAuditDetector(), Alerts: bus, Notifier: notifier, Responder: responder, Playbooks: playbooks, Evidence: collector, Retention: compactor}
 
 	r := mux.NewRouter()
 
@@ -53,14 +133,123 @@ func main() {
 	api.HandleFunc("/clusters", resH.GetClusters).Methods("GET")
 	api.HandleFunc("/clusters", resH.CreateCluster).Methods("POST")
 	api.HandleFunc("/clusters/{clusterId}", resH.DeleteCluster).Methods("DELETE")
+	api.HandleFunc("/clusters/{clusterId}/pss", resH.GetPodSecurity).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/rbac", resH.GetRBAC).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/network", resH.GetNetwork).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/images", resH.GetImages).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/secrets", resH.GetSecrets).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/audit", resH.ReceiveAudit).Methods("POST")
+	api.HandleFunc("/clusters/{clusterId}/cis", resH.GetCISRuns).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/cis", resH.RunCIS).Methods("POST")
+	api.HandleFunc("/clusters/{clusterId}/cis/diff", resH.DiffCIS).Methods("GET")
+	api.HandleFunc("/clusters/{clusterId}/cis/{version:[0-9]+}", resH.GetCISRun).Methods("GET")
+
+	// Only Administrators issue audit webhook tokens; the webhook itself
+	// authenticates with the token
+	auditToken := api.PathPrefix("/clusters/{clusterId}/audit/token").Subrouter()
+	auditToken.Use(middleware.RequireRole("Administrator"))
+	auditToken.HandleFunc("", resH.RotateAuditToken).Methods("POST")
 
 	// Policies API
 	api.HandleFunc("/policies", resH.GetPolicies).Methods("GET")
 	api.HandleFunc("/policies", resH.CreatePolicy).Methods("POST")
 
 	// Alerts and Reports API
-	api.HandleFunc("/tests", resH.GetAlerts).Methods("GET")           // As per 4.7 URI
-	api.HandleFunc("/tests/{testId}", resH.GetReports).Methods("GET") // As per 4.8 URI (mapping to reports)
+	api.HandleFunc("/tests", resH.GetAlerts).Methods("GET")            // As per 4.7 URI
+	api.HandleFunc("/tests/{testId}", resH.GetIncident).Methods("GET") // As per 4.8 URI, an incident by ID
+	api.HandleFunc("/reports", resH.GetReports).Methods("GET")
+	api.HandleFunc("/alerts/{alertId}", resH.UpdateAlert).Methods("PATCH")
+	api.HandleFunc("/incidents", resH.GetIncidents).Methods("GET")
+	api.HandleFunc("/incidents/{incidentId}", resH.GetIncident).Methods("GET")
+
+	// Incident case management
+	incidentCases := api.PathPrefix("/incidents").Subrouter()
+	incidentCases.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	incidentCases.HandleFunc("", resH.CreateIncident).Methods("POST")
+	incidentCases.HandleFunc("/{incidentId}", resH.UpdateIncident).Methods("PATCH")
+	incidentCases.HandleFunc("/{incidentId}/comments", resH.CommentIncident).Methods("POST")
+	incidentCases.HandleFunc("/{incidentId}/evidence", resH.LinkIncidentEvidence).Methods("POST")
+	incidentCases.HandleFunc("/{incidentId}/post-mortem", resH.UpdatePostMortem).Methods("PUT")
+	incidentCases.HandleFunc("/{incidentId}/close", resH.CloseIncident).Methods("POST")
+
+	// Silences API
+	silences := api.PathPrefix("/silences").Subrouter()
+	silences.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	silences.HandleFunc("", resH.GetSilences).Methods("GET")
+	silences.HandleFunc("", resH.CreateSilence).Methods("POST")
+	silences.HandleFunc("/{silenceId}", resH.GetSilence).Methods("GET")
+	silences.HandleFunc("/{silenceId}", resH.UpdateSilence).Methods("PUT")
+	silences.HandleFunc("/{silenceId}", resH.DeleteSilence).Methods("DELETE")
+
+	// Notification channels API
+	channels := api.PathPrefix("/channels").Subrouter()
+	channels.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	channels.HandleFunc("", resH.GetChannels).Methods("GET")
+	channels.HandleFunc("", resH.CreateChannel).Methods("POST")
+	channels.HandleFunc("/dead-letters", resH.GetDeadLetters).Methods("GET")
+	channels.HandleFunc("/{channelId}", resH.DeleteChannel).Methods("DELETE")
+	channels.HandleFunc("/{channelId}/test", resH.TestChannel).Methods("POST")
+
+	// On-call and escalation API
+	schedules := api.PathPrefix("/schedules").Subrouter()
+	schedules.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	schedules.HandleFunc("", resH.GetSchedules).Methods("GET")
+	schedules.HandleFunc("", resH.CreateSchedule).Methods("POST")
+	schedules.HandleFunc("/{scheduleId}", resH.UpdateSchedule).Methods("PUT")
+	schedules.HandleFunc("/{scheduleId}", resH.DeleteSchedule).Methods("DELETE")
+	schedules.HandleFunc("/{scheduleId}/oncall", resH.GetOnCall).Methods("GET")
+
+	escalationPolicies := api.PathPrefix("/escalation-policies").Subrouter()
+	escalationPolicies.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	escalationPolicies.HandleFunc("", resH.GetEscalationPolicies).Methods("GET")
+	escalationPolicies.HandleFunc("", resH.CreateEscalationPolicy).Methods("POST")
+	escalationPolicies.HandleFunc("/{policyId}", resH.DeleteEscalationPolicy).Methods("DELETE")
+
+	escalations := api.PathPrefix("/escalations").Subrouter()
+	escalations.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	escalations.HandleFunc("", resH.GetEscalations).Methods("GET")
+
+	// Response actions API; only Administrators decide on pending actions
+	actions := api.PathPrefix("/actions").Subrouter()
+	actions.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	actions.HandleFunc("", resH.GetActions).Methods("GET")
+	actions.HandleFunc("", resH.CreateAction).Methods("POST")
+	actions.HandleFunc("/{actionId}", resH.GetAction).Methods("GET")
+
+	decisions := api.PathPrefix("/actions/{actionId}").Subrouter()
+	decisions.Use(middleware.RequireRole("Administrator"))
+	decisions.HandleFunc("/approve", resH.ApproveAction).Methods("POST")
+	decisions.HandleFunc("/reject", resH.RejectAction).Methods("POST")
+
+	// Playbooks API; live runs are further restricted to Administrators
+	playbookRoutes := api.PathPrefix("/playbooks").Subrouter()
+	playbookRoutes.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	playbookRoutes.HandleFunc("", resH.GetPlaybooks).Methods("GET")
+	playbookRoutes.HandleFunc("", resH.CreatePlaybook).Methods("POST")
+	playbookRoutes.HandleFunc("/runs/{runId}", resH.GetPlaybookRun).Methods("GET")
+	playbookRoutes.HandleFunc("/{playbookId}", resH.GetPlaybook).Methods("GET")
+	playbookRoutes.HandleFunc("/{playbookId}", resH.UpdatePlaybook).Methods("PUT")
+	playbookRoutes.HandleFunc("/{playbookId}", resH.DeletePlaybook).Methods("DELETE")
+	playbookRoutes.HandleFunc("/{playbookId}/run", resH.RunPlaybook).Methods("POST")
+	playbookRoutes.HandleFunc("/{playbookId}/runs", resH.GetPlaybookRuns).Methods("GET")
+
+	// Forensic evidence API
+	evidence := api.PathPrefix("/evidence").Subrouter()
+	evidence.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	evidence.HandleFunc("", resH.GetEvidenceBundles).Methods("GET")
+	evidence.HandleFunc("/{bundleId}", resH.GetEvidenceBundle).Methods("GET")
+	evidence.HandleFunc("/{bundleId}/download", resH.DownloadEvidence).Methods("GET")
+
+	capture := api.PathPrefix("/alerts/{alertId}/evidence").Subrouter()
+	capture.Use(middleware.RequireRole("Administrator", "Security Analyst"))
+	capture.HandleFunc("", resH.CaptureEvidence).Methods("POST")
+
+	// Retention API
+	retentionRoutes := api.PathPrefix("/retention").Subrouter()
+	retentionRoutes.Use(middleware.RequireRole("Administrator"))
+	retentionRoutes.HandleFunc("", resH.GetRetention).Methods("GET")
+	retentionRoutes.HandleFunc("/run", resH.RunRetention).Methods("POST")
+	retentionRoutes.HandleFunc("/restore", resH.RestoreArchive).Methods("POST")
 
 	// Metrics
 	r.Handle("/metrics", promhttp.Handler())
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+const migrateUsage = `usage: ksms migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down [N]  roll back the latest N applied migrations (default 1)
+  status    list migrations and when they were applied`
+
+// runMigrate implements the migrate sub-command and returns the exit code
+func runMigrate(args []string) int {
+	steps := 1
+	valid := len(args) == 1 && (args[0] == "up" || args[0] == "down" || args[0] == "status")
+	if len(args) == 2 && args[0] == "down" {
+		n, err := strconv.Atoi(args[1])
+		steps, valid = n, err == nil && n > 0
+	}
+	if !valid {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := storage.OpenDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+	migrator, err := storage.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, v := range applied {
+			fmt.Printf("applied %04d\n", v)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, v := range reverted {
+			fmt.Printf("rolled back %04d\n", v)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, st := range statuses {
+			name, applied := st.Name, "pending"
+			if name == "" {
+				name = "(unknown to this build)"
+			}
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", st.Version, name, applied)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"KubernetesSecurityMonitoringSystem/internal/secrets"
+	"KubernetesSecurityMonitoringSystem/internal/storage"
+)
+
+// runRotateKeys implements the rotate-keys sub-command: it re-encrypts every
+// stored kubeconfig with the current master key. The keys being replaced
+// must be listed in KSMS_PREVIOUS_MASTER_KEYS.
+func runRotateKeys() int {
+	keys, err := secrets.LoadKeyring()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load master key: %v\n", err)
+		return 1
+	}
+	store, err := storage.NewDatabaseStorage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+	n, err := secrets.Rotate(context.Background(), store, keys)
+	fmt.Printf("re-encrypted %d kubeconfigs\n", n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}